@@ -17,6 +17,92 @@ const (
 	Cookie_authScopes = "cookie_auth.Scopes"
 )
 
+// Defines values for FilePropertiesTypes.
+const (
+	Checkbox FilePropertiesTypes = "checkbox"
+	Date     FilePropertiesTypes = "date"
+	Datetime FilePropertiesTypes = "datetime"
+	Empty    FilePropertiesTypes = "empty"
+	List     FilePropertiesTypes = "list"
+	Number   FilePropertiesTypes = "number"
+	Object   FilePropertiesTypes = "object"
+	Text     FilePropertiesTypes = "text"
+)
+
+// Defines values for ImportResultStatus.
+const (
+	Created     ImportResultStatus = "created"
+	Overwritten ImportResultStatus = "overwritten"
+	Skipped     ImportResultStatus = "skipped"
+)
+
+// Defines values for TreeNodeType.
+const (
+	TreeNodeTypeFile   TreeNodeType = "file"
+	TreeNodeTypeFolder TreeNodeType = "folder"
+)
+
+// Defines values for VaultRole.
+const (
+	Editor VaultRole = "editor"
+	Owner  VaultRole = "owner"
+	Viewer VaultRole = "viewer"
+)
+
+// Defines values for GetExportParamsFormat.
+const (
+	TarGz GetExportParamsFormat = "tar.gz"
+	Zip   GetExportParamsFormat = "zip"
+)
+
+// Defines values for PostImportParamsConflict.
+const (
+	KeepBoth  PostImportParamsConflict = "keep-both"
+	Overwrite PostImportParamsConflict = "overwrite"
+	Skip      PostImportParamsConflict = "skip"
+)
+
+// Defines values for GetListFilesParamsSort.
+const (
+	Path      GetListFilesParamsSort = "path"
+	UpdatedAt GetListFilesParamsSort = "updated_at"
+)
+
+// Defines values for GetListFilesParamsOrder.
+const (
+	Asc  GetListFilesParamsOrder = "asc"
+	Desc GetListFilesParamsOrder = "desc"
+)
+
+// Defines values for GetSTokenParamsFormat.
+const (
+	Html GetSTokenParamsFormat = "html"
+	Raw  GetSTokenParamsFormat = "raw"
+)
+
+// Defines values for GetTagsFilesParamsMatch.
+const (
+	Exact  GetTagsFilesParamsMatch = "exact"
+	Nested GetTagsFilesParamsMatch = "nested"
+	Prefix GetTagsFilesParamsMatch = "prefix"
+)
+
+// AdminPasswordReset defines model for AdminPasswordReset.
+type AdminPasswordReset struct {
+	Password string `json:"password"`
+}
+
+// AdminUser defines model for AdminUser.
+type AdminUser struct {
+	Disabled      bool         `json:"disabled"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"emailVerified"`
+	Id            int64        `json:"id"`
+	IsAdmin       bool         `json:"isAdmin"`
+	Storage       StorageUsage `json:"storage"`
+	Username      string       `json:"username"`
+}
+
 // ApiKey defines model for ApiKey.
 type ApiKey struct {
 	Active *bool   `json:"active,omitempty"`
@@ -30,38 +116,475 @@ type ApiResponse struct {
 	Message *string `json:"message,omitempty"`
 }
 
+// Commit defines model for Commit.
+type Commit struct {
+	// Author Username of the user that made the changes
+	Author string    `json:"author"`
+	Date   time.Time `json:"date"`
+
+	// Device Device the changes were made from, from its `Obsync-Device` header
+	Device  *string `json:"device,omitempty"`
+	Email   string  `json:"email"`
+	Hash    string  `json:"hash"`
+	Message string  `json:"message"`
+}
+
+// CredentialRevocation defines model for CredentialRevocation.
+type CredentialRevocation struct {
+	// ApiKeys Number of API keys that were deactivated
+	ApiKeys int64 `json:"apiKeys"`
+
+	// Sessions Number of sessions that were deleted
+	Sessions int64 `json:"sessions"`
+}
+
 // File defines model for File.
 type File struct {
 	CreatedAt *time.Time `json:"createdAt,omitempty"`
 
 	// Etag md5 hash of the file
-	Etag      *string    `json:"etag,omitempty"`
-	Filename  *string    `json:"filename,omitempty"`
-	Id        *int64     `json:"id,omitempty"`
+	Etag     *string `json:"etag,omitempty"`
+	Filename *string `json:"filename,omitempty"`
+	Id       *int64  `json:"id,omitempty"`
+
+	// Size size of the file in bytes
+	Size      *int64     `json:"size,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+
+	// UpdatedBy id of the user that last changed the file
+	UpdatedBy *int64 `json:"updatedBy,omitempty"`
+}
+
+// FileProperties defines model for FileProperties.
+type FileProperties struct {
+	// Error Why the frontmatter couldn't be parsed
+	Error    *string `json:"error,omitempty"`
+	Filename string  `json:"filename"`
+
+	// Properties Frontmatter properties by name. Dates are formatted like 2024-05-01.
+	Properties map[string]interface{} `json:"properties"`
+
+	// Types Type of each property
+	Types map[string]FilePropertiesTypes `json:"types"`
+}
+
+// FilePropertiesTypes defines model for FileProperties.Types.
+type FilePropertiesTypes string
+
+// FileRename defines model for FileRename.
+type FileRename struct {
+	Filename string `json:"filename"`
+}
+
+// Graph defines model for Graph.
+type Graph struct {
+	Edges []GraphEdge `json:"edges"`
+	Nodes []GraphNode `json:"nodes"`
+}
+
+// GraphEdge defines model for GraphEdge.
+type GraphEdge struct {
+	// Count number of links from the source to the target
+	Count int `json:"count"`
+
+	// Source id of the node the links are in
+	Source int64 `json:"source"`
+
+	// Target id of the node the links point to
+	Target int64 `json:"target"`
+}
+
+// GraphNode defines model for GraphNode.
+type GraphNode struct {
+	Filename string `json:"filename"`
+	Id       int64  `json:"id"`
+}
+
+// IgnoreRules defines model for IgnoreRules.
+type IgnoreRules struct {
+	// Rules Paths the vault doesn't sync, in gitignore syntax
+	Rules     string     `json:"rules"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// ImportReport defines model for ImportReport.
+type ImportReport struct {
+	Created     int            `json:"created"`
+	Files       []ImportResult `json:"files"`
+	Overwritten int            `json:"overwritten"`
+	Skipped     int            `json:"skipped"`
+}
+
+// ImportResult defines model for ImportResult.
+type ImportResult struct {
+	// Filename filename the file was synced as
+	Filename *string `json:"filename,omitempty"`
+
+	// Message reason the file was skipped
+	Message *string `json:"message,omitempty"`
+
+	// Path path of the file in the archive
+	Path   string             `json:"path"`
+	Status ImportResultStatus `json:"status"`
+}
+
+// ImportResultStatus defines model for ImportResult.Status.
+type ImportResultStatus string
+
+// Invite defines model for Invite.
+type Invite struct {
+	Code      string     `json:"code"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Id        int64      `json:"id"`
+	MaxUses   *int       `json:"max_uses,omitempty"`
+	Uses      int        `json:"uses"`
+}
+
+// InviteCreate defines model for InviteCreate.
+type InviteCreate struct {
+	// ExpiresAt When the invite stops working
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// MaxUses Number of users that can sign up with the invite
+	MaxUses *int `json:"max_uses,omitempty"`
+}
+
+// Link defines model for Link.
+type Link struct {
+	Alias *string `json:"alias,omitempty"`
+	Embed bool    `json:"embed"`
+
+	// Link path in the link, as written in the note
+	Link string `json:"link"`
+
+	// Source filename of the note the link is in
+	Source string `json:"source"`
+
+	// Subpath heading or block reference in the link
+	Subpath *string `json:"subpath,omitempty"`
+
+	// Target filename of the file the link resolves to, if it resolves to a file
+	Target *string `json:"target,omitempty"`
+}
+
+// OrphanReport defines model for OrphanReport.
+type OrphanReport struct {
+	Count   int    `json:"count"`
+	Orphans []File `json:"orphans"`
+
+	// TotalSize Total size of the orphaned attachments in bytes
+	TotalSize int64 `json:"totalSize"`
+}
+
+// OrphanTrashReport defines model for OrphanTrashReport.
+type OrphanTrashReport struct {
+	DryRun  bool          `json:"dryRun"`
+	Skipped []TrashResult `json:"skipped"`
+
+	// TotalSize Total size of the moved files in bytes
+	TotalSize int64         `json:"totalSize"`
+	Trashed   []TrashResult `json:"trashed"`
+}
+
+// OrphanTrashRequest defines model for OrphanTrashRequest.
+type OrphanTrashRequest struct {
+	// DryRun Report what would be moved without moving anything
+	DryRun    *bool    `json:"dryRun,omitempty"`
+	Filenames []string `json:"filenames"`
+}
+
+// PasswordForgot defines model for PasswordForgot.
+type PasswordForgot struct {
+	Email string `json:"email"`
+}
+
+// PasswordReset defines model for PasswordReset.
+type PasswordReset struct {
+	Password string `json:"password"`
+
+	// Token Token from the password reset email
+	Token string `json:"token"`
+}
+
+// PropertyError defines model for PropertyError.
+type PropertyError struct {
+	Filename string `json:"filename"`
+	Message  string `json:"message"`
+}
+
+// SearchResult defines model for SearchResult.
+type SearchResult struct {
+	Filename string `json:"filename"`
+
+	// Rank bm25 rank of the match, lower ranks are better matches
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Share defines model for Share.
+type Share struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Filename Shared file. Deleting the file deletes the share.
+	Filename *string `json:"filename,omitempty"`
+
+	// Folder Shared folder
+	Folder            *string `json:"folder,omitempty"`
+	Id                int64   `json:"id"`
+	MaxViews          *int    `json:"max_views,omitempty"`
+	PasswordProtected bool    `json:"password_protected"`
+	Token             string  `json:"token"`
+
+	// Url Path of the share's link on the server
+	Url   string `json:"url"`
+	Views int    `json:"views"`
+}
+
+// ShareCreate Share of either a file or a folder
+type ShareCreate struct {
+	// ExpiresAt When the share stops working
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Filename File to share
+	Filename *string `json:"filename,omitempty"`
+
+	// Folder Folder to share
+	Folder *string `json:"folder,omitempty"`
+
+	// MaxViews Number of times the share can be viewed
+	MaxViews *int `json:"max_views,omitempty"`
+
+	// Password Password needed to view the share
+	Password *string `json:"password,omitempty"`
+}
+
+// SharedFile defines model for SharedFile.
+type SharedFile struct {
+	// Filename Path of the file in the shared folder
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Snapshot defines model for Snapshot.
+type Snapshot struct {
+	CreatedAt time.Time `json:"createdAt"`
+
+	// CreatedBy Username of the user that took the snapshot
+	CreatedBy string `json:"createdBy"`
+	FileCount int    `json:"fileCount"`
+	Name      string `json:"name"`
+
+	// TotalSize Total size of the files in the snapshot in bytes
+	TotalSize int64 `json:"totalSize"`
+}
+
+// SnapshotCreate defines model for SnapshotCreate.
+type SnapshotCreate struct {
+	Name string `json:"name"`
+}
+
+// SnapshotFile defines model for SnapshotFile.
+type SnapshotFile struct {
+	Etag      string    `json:"etag"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SnapshotRestoreReport defines model for SnapshotRestoreReport.
+type SnapshotRestoreReport struct {
+	// Backup Name of the snapshot taken of the vault before it was restored
+	Backup string `json:"backup"`
+
+	// Deleted Files that were added since the snapshot
+	Deleted []string `json:"deleted"`
+
+	// Restored Files that were changed or recreated
+	Restored []string `json:"restored"`
+
+	// Unchanged Number of files that were already the same as in the snapshot
+	Unchanged int `json:"unchanged"`
+}
+
+// StorageUsage defines model for StorageUsage.
+type StorageUsage struct {
+	FileCount int64 `json:"fileCount"`
+
+	// FileSize Total size of the user's synced files in bytes
+	FileSize int64 `json:"fileSize"`
+
+	// SnapshotSize Size of the content kept for the user's snapshots in bytes, counting content that's in
+	// several snapshots once
+	SnapshotSize int64 `json:"snapshotSize"`
+}
+
+// TagCount defines model for TagCount.
+type TagCount struct {
+	// Count number of files with the tag
+	Count int    `json:"count"`
+	Tag   string `json:"tag"`
+}
+
+// TrashResult defines model for TrashResult.
+type TrashResult struct {
+	Filename string `json:"filename"`
+
+	// Message Why the file was skipped
+	Message *string `json:"message,omitempty"`
+
+	// TrashedAs Path the file was, or would be, moved to
+	TrashedAs *string `json:"trashedAs,omitempty"`
+}
+
+// TreeNode defines model for TreeNode.
+type TreeNode struct {
+	// Children Folders then files in the folder, sorted by name. Left out for files, and for folders
+	// below the requested depth.
+	Children *[]TreeNode `json:"children,omitempty"`
+
+	// FileCount Number of files in the folder and its subfolders
+	FileCount *int `json:"fileCount,omitempty"`
+
+	// FolderCount Number of folders in the folder and its subfolders
+	FolderCount *int   `json:"folderCount,omitempty"`
+	Name        string `json:"name"`
+
+	// Path Path of the file or folder, which is empty for the vault's root
+	Path string `json:"path"`
+
+	// Size Size of the file, or the total size of the files in the folder
+	Size int64        `json:"size"`
+	Type TreeNodeType `json:"type"`
+
+	// UpdatedAt When the file was last changed, or when a file in the folder was last changed. Empty
+	// folders don't have one.
 	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
 }
 
+// TreeNodeType defines model for TreeNode.Type.
+type TreeNodeType string
+
 // User defines model for User.
 type User struct {
-	Email    string `json:"email"`
-	Id       *int64 `json:"id,omitempty"`
-	Password string `json:"password"`
-	Username string `json:"username"`
+	Email string `json:"email"`
+	Id    *int64 `json:"id,omitempty"`
+
+	// Invite Invite code, needed when registration is invite-only
+	Invite   *string `json:"invite,omitempty"`
+	Password string  `json:"password"`
+	Username string  `json:"username"`
+}
+
+// Vault defines model for Vault.
+type Vault struct {
+	// Owner Username of the vault's owner
+	Owner string `json:"owner"`
+
+	// Role Owners can change files and manage members, editors can change files, and viewers can only
+	// read them
+	Role VaultRole `json:"role"`
+}
+
+// VaultMember defines model for VaultMember.
+type VaultMember struct {
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Folders Roles that override the member's role for files in a folder
+	Folders map[string]VaultRole `json:"folders"`
+
+	// Role Owners can change files and manage members, editors can change files, and viewers can only
+	// read them
+	Role     VaultRole `json:"role"`
+	Username string    `json:"username"`
+}
+
+// VaultMemberUpdate defines model for VaultMemberUpdate.
+type VaultMemberUpdate struct {
+	// Folders Roles that override the member's role for files in a folder and its subfolders. Folder
+	// roles can only be editor or viewer.
+	Folders *map[string]VaultRole `json:"folders,omitempty"`
+
+	// Role Owners can change files and manage members, editors can change files, and viewers can only
+	// read them
+	Role VaultRole `json:"role"`
 }
 
+// VaultRole Owners can change files and manage members, editors can change files, and viewers can only
+// read them
+type VaultRole string
+
+// AdminOnly defines model for AdminOnly.
+type AdminOnly = ApiResponse
+
+// EmailDisabled defines model for EmailDisabled.
+type EmailDisabled = ApiResponse
+
 // FileList defines model for FileList.
 type FileList = []File
 
+// Forbidden defines model for Forbidden.
+type Forbidden = ApiResponse
+
+// HistoryDisabled defines model for HistoryDisabled.
+type HistoryDisabled = ApiResponse
+
+// Ignored defines model for Ignored.
+type Ignored = ApiResponse
+
+// LinkList defines model for LinkList.
+type LinkList = []Link
+
+// TooManyEmails defines model for TooManyEmails.
+type TooManyEmails = ApiResponse
+
+// Unauthorized defines model for Unauthorized.
+type Unauthorized = ApiResponse
+
+// GetAdminUsersParams defines parameters for GetAdminUsers.
+type GetAdminUsersParams struct {
+	// Search Only list users whose username or email contains this
+	Search *string `form:"search,omitempty" json:"search,omitempty"`
+}
+
 // GetApikeysParams defines parameters for GetApikeys.
 type GetApikeysParams struct {
 	// Name Name of the API key
 	Name *string `form:"name,omitempty" json:"name,omitempty"`
 }
 
+// GetAttachmentsOrphansParams defines parameters for GetAttachmentsOrphans.
+type GetAttachmentsOrphansParams struct {
+	// Folder Only report orphaned attachments in this folder
+	Folder *string `form:"folder,omitempty" json:"folder,omitempty"`
+}
+
+// GetExportParams defines parameters for GetExport.
+type GetExportParams struct {
+	// Format Format of the archive
+	Format *GetExportParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+
+	// Prefix Only export files in this folder
+	Prefix *string `form:"prefix,omitempty" json:"prefix,omitempty"`
+}
+
+// GetExportParamsFormat defines parameters for GetExport.
+type GetExportParamsFormat string
+
 // GetFilesFilenameParams defines parameters for GetFilesFilename.
 type GetFilesFilenameParams struct {
 	// IfNoneMatch MD5 hash used to detect whether a file is already downloaded locally
 	IfNoneMatch *string `json:"If-None-Match,omitempty"`
+
+	// Range Byte ranges of the file to download. Requests for several ranges are answered with a
+	// `multipart/byteranges` response.
+	Range *string `json:"Range,omitempty"`
+
+	// IfRange Only send the requested ranges if the file's etag or last modification date still
+	// matches, otherwise send the whole file.
+	IfRange *string `json:"If-Range,omitempty"`
 }
 
 // PutFilesFilenameParams defines parameters for PutFilesFilename.
@@ -70,9 +593,170 @@ type PutFilesFilenameParams struct {
 	IfNoneMatch *string `json:"If-None-Match,omitempty"`
 }
 
+// GetFilesFilenameThumbnailParams defines parameters for GetFilesFilenameThumbnail.
+type GetFilesFilenameThumbnailParams struct {
+	// Size Largest width and height of the thumbnail, in pixels. Sizes are rounded up to 32, 64,
+	// 128, 256, 512 or 1024, which are the sizes thumbnails are generated in.
+	Size *int `form:"size,omitempty" json:"size,omitempty"`
+
+	// IfNoneMatch ETag of a thumbnail that is already downloaded
+	IfNoneMatch *string `json:"If-None-Match,omitempty"`
+}
+
+// GetGraphParams defines parameters for GetGraph.
+type GetGraphParams struct {
+	// Folder Only include files in this folder
+	Folder *string `form:"folder,omitempty" json:"folder,omitempty"`
+}
+
+// GetHistoryParams defines parameters for GetHistory.
+type GetHistoryParams struct {
+	// Path Path of the file or folder, or the whole vault if it's left out
+	Path *string `form:"path,omitempty" json:"path,omitempty"`
+
+	// Limit Largest number of commits to return
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// PostImportParams defines parameters for PostImport.
+type PostImportParams struct {
+	// Conflict What to do with files in the archive that are already synced to the server. `skip`
+	// leaves the existing file alone, `overwrite` replaces it, and `keep-both` saves the
+	// imported file under a new name, like `note (1).md`.
+	Conflict *PostImportParamsConflict `form:"conflict,omitempty" json:"conflict,omitempty"`
+}
+
+// PostImportParamsConflict defines parameters for PostImport.
+type PostImportParamsConflict string
+
+// GetListFilesParams defines parameters for GetListFiles.
+type GetListFilesParams struct {
+	// Limit Maximum number of files to return
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+
+	// Cursor Cursor from the `Obsync-Next-Cursor` header of the previous page
+	Cursor *string `form:"cursor,omitempty" json:"cursor,omitempty"`
+
+	// Prefix Only list files with paths that start with this prefix
+	Prefix *string `form:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// ModifiedSince Only list files that were changed at or after this time
+	ModifiedSince *time.Time `form:"modified_since,omitempty" json:"modified_since,omitempty"`
+
+	// Extension Only list files with one of these extensions
+	Extension *[]string `form:"extension,omitempty" json:"extension,omitempty"`
+
+	// MinSize Only list files of at least this many bytes
+	MinSize *int64 `form:"min_size,omitempty" json:"min_size,omitempty"`
+
+	// MaxSize Only list files of at most this many bytes
+	MaxSize *int64 `form:"max_size,omitempty" json:"max_size,omitempty"`
+
+	// Sort Field to sort files by
+	Sort *GetListFilesParamsSort `form:"sort,omitempty" json:"sort,omitempty"`
+
+	// Order Order to sort files in
+	Order *GetListFilesParamsOrder `form:"order,omitempty" json:"order,omitempty"`
+}
+
+// GetListFilesParamsSort defines parameters for GetListFiles.
+type GetListFilesParamsSort string
+
+// GetListFilesParamsOrder defines parameters for GetListFiles.
+type GetListFilesParamsOrder string
+
+// GetQueryParams defines parameters for GetQuery.
+type GetQueryParams struct {
+	// Where Conditions the properties have to match
+	Where *[]string `form:"where,omitempty" json:"where,omitempty"`
+
+	// Sort Comma-separated properties to sort by, prefixed with `-` to sort in descending order.
+	// Notes without a property are sorted last, and notes are sorted by filename after that.
+	Sort *string `form:"sort,omitempty" json:"sort,omitempty"`
+
+	// Limit Maximum number of notes to return
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetSTokenParams defines parameters for GetSToken.
+type GetSTokenParams struct {
+	// File File to view. For shared folders, this is the path of a file in the folder relative to
+	// the folder. For shared notes, this is the path of a file embedded in the note.
+	File *string `form:"file,omitempty" json:"file,omitempty"`
+
+	// Format Whether to render notes as HTML or serve them as markdown
+	Format *GetSTokenParamsFormat `form:"format,omitempty" json:"format,omitempty"`
+
+	// View Key of the view of a rendered note that embeds the file. Rendered notes add it to the URLs
+	// of their embeds, which then don't count as another view.
+	View *string `form:"view,omitempty" json:"view,omitempty"`
+}
+
+// GetSTokenParamsFormat defines parameters for GetSToken.
+type GetSTokenParamsFormat string
+
+// GetSearchParams defines parameters for GetSearch.
+type GetSearchParams struct {
+	// Q Text to search for
+	Q string `form:"q" json:"q"`
+
+	// Phrase Match the text as an exact phrase instead of as separate terms
+	Phrase *bool `form:"phrase,omitempty" json:"phrase,omitempty"`
+
+	// Prefix Match words that start with the last term, like `sched` matching `scheduling`
+	Prefix *bool `form:"prefix,omitempty" json:"prefix,omitempty"`
+
+	// Folder Only search files in this folder
+	Folder *string `form:"folder,omitempty" json:"folder,omitempty"`
+
+	// Limit Maximum number of results
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty"`
+}
+
+// GetSnapshotsSnapshotFilesParams defines parameters for GetSnapshotsSnapshotFiles.
+type GetSnapshotsSnapshotFilesParams struct {
+	// Folder Only include files in this folder
+	Folder *string `form:"folder,omitempty" json:"folder,omitempty"`
+}
+
+// GetTagsParams defines parameters for GetTags.
+type GetTagsParams struct {
+	// Prefix Only include tags that start with this prefix
+	Prefix *string `form:"prefix,omitempty" json:"prefix,omitempty"`
+}
+
+// GetTagsFilesParams defines parameters for GetTagsFiles.
+type GetTagsFilesParams struct {
+	// Tag Tag to look up, with or without the leading `#`
+	Tag string `form:"tag" json:"tag"`
+
+	// Match How the tag is matched. `exact` only matches the tag itself, `nested` also matches tags
+	// nested in it (`project` matches `project/alpha`), and `prefix` matches every tag that
+	// starts with it.
+	Match *GetTagsFilesParamsMatch `form:"match,omitempty" json:"match,omitempty"`
+}
+
+// GetTagsFilesParamsMatch defines parameters for GetTagsFiles.
+type GetTagsFilesParamsMatch string
+
+// GetTreeParams defines parameters for GetTree.
+type GetTreeParams struct {
+	// Path Folder at the root of the tree, which is the vault's root by default
+	Path *string `form:"path,omitempty" json:"path,omitempty"`
+
+	// Depth Number of levels of files and folders to include below the folder
+	Depth *int `form:"depth,omitempty" json:"depth,omitempty"`
+}
+
 // PutUserEmailJSONBody defines parameters for PutUserEmail.
 type PutUserEmailJSONBody = string
 
+// GetUserEmailVerifyParams defines parameters for GetUserEmailVerify.
+type GetUserEmailVerifyParams struct {
+	// Token Token from the verification email
+	Token string `form:"token" json:"token"`
+}
+
 // PostUserLoginJSONBody defines parameters for PostUserLogin.
 type PostUserLoginJSONBody struct {
 	Password string `json:"password"`
@@ -85,9 +769,33 @@ type PutUserPasswordJSONBody = string
 // PutUserUsernameJSONBody defines parameters for PutUserUsername.
 type PutUserUsernameJSONBody = string
 
+// PostAdminUsersJSONRequestBody defines body for PostAdminUsers for application/json ContentType.
+type PostAdminUsersJSONRequestBody = User
+
+// PostAdminUsersUsernamePasswordJSONRequestBody defines body for PostAdminUsersUsernamePassword for application/json ContentType.
+type PostAdminUsersUsernamePasswordJSONRequestBody = AdminPasswordReset
+
 // PostApikeysJSONRequestBody defines body for PostApikeys for application/json ContentType.
 type PostApikeysJSONRequestBody = ApiKey
 
+// PostAttachmentsOrphansTrashJSONRequestBody defines body for PostAttachmentsOrphansTrash for application/json ContentType.
+type PostAttachmentsOrphansTrashJSONRequestBody = OrphanTrashRequest
+
+// PostFilesFilenameRenameJSONRequestBody defines body for PostFilesFilenameRename for application/json ContentType.
+type PostFilesFilenameRenameJSONRequestBody = FileRename
+
+// PutIgnoreRulesJSONRequestBody defines body for PutIgnoreRules for application/json ContentType.
+type PutIgnoreRulesJSONRequestBody = IgnoreRules
+
+// PostInvitesJSONRequestBody defines body for PostInvites for application/json ContentType.
+type PostInvitesJSONRequestBody = InviteCreate
+
+// PostSharesJSONRequestBody defines body for PostShares for application/json ContentType.
+type PostSharesJSONRequestBody = ShareCreate
+
+// PostSnapshotsJSONRequestBody defines body for PostSnapshots for application/json ContentType.
+type PostSnapshotsJSONRequestBody = SnapshotCreate
+
 // PostUserJSONRequestBody defines body for PostUser for application/json ContentType.
 type PostUserJSONRequestBody = User
 
@@ -100,11 +808,41 @@ type PostUserLoginJSONRequestBody PostUserLoginJSONBody
 // PutUserPasswordJSONRequestBody defines body for PutUserPassword for application/json ContentType.
 type PutUserPasswordJSONRequestBody = PutUserPasswordJSONBody
 
+// PostUserPasswordForgotJSONRequestBody defines body for PostUserPasswordForgot for application/json ContentType.
+type PostUserPasswordForgotJSONRequestBody = PasswordForgot
+
+// PostUserPasswordResetJSONRequestBody defines body for PostUserPasswordReset for application/json ContentType.
+type PostUserPasswordResetJSONRequestBody = PasswordReset
+
 // PutUserUsernameJSONRequestBody defines body for PutUserUsername for application/json ContentType.
 type PutUserUsernameJSONRequestBody = PutUserUsernameJSONBody
 
+// PutVaultsOwnerMembersUsernameJSONRequestBody defines body for PutVaultsOwnerMembersUsername for application/json ContentType.
+type PutVaultsOwnerMembersUsernameJSONRequestBody = VaultMemberUpdate
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// List users
+	// (GET /admin/users)
+	GetAdminUsers(ctx echo.Context, params GetAdminUsersParams) error
+	// Create a user
+	// (POST /admin/users)
+	PostAdminUsers(ctx echo.Context) error
+	// Get a user
+	// (GET /admin/users/{username})
+	GetAdminUsersUsername(ctx echo.Context, username string) error
+	// Disable a user
+	// (POST /admin/users/{username}/disable)
+	PostAdminUsersUsernameDisable(ctx echo.Context, username string) error
+	// Enable a user
+	// (POST /admin/users/{username}/enable)
+	PostAdminUsersUsernameEnable(ctx echo.Context, username string) error
+	// Reset a user's password
+	// (POST /admin/users/{username}/password)
+	PostAdminUsersUsernamePassword(ctx echo.Context, username string) error
+	// Revoke a user's sessions and API keys
+	// (POST /admin/users/{username}/revoke)
+	PostAdminUsersUsernameRevoke(ctx echo.Context, username string) error
 	// Delete an API key
 	// (DELETE /apikeys)
 	DeleteApikeys(ctx echo.Context) error
@@ -114,9 +852,18 @@ type ServerInterface interface {
 	// Create an API key
 	// (POST /apikeys)
 	PostApikeys(ctx echo.Context) error
+	// Find attachments that no note references
+	// (GET /attachments/orphans)
+	GetAttachmentsOrphans(ctx echo.Context, params GetAttachmentsOrphansParams) error
+	// Move orphaned attachments to the trash
+	// (POST /attachments/orphans/trash)
+	PostAttachmentsOrphansTrash(ctx echo.Context) error
 	// Get the Redoc OpenAPI documentation page
 	// (GET /docs)
 	GetDocs(ctx echo.Context) error
+	// Download an archive of every file synced to the server
+	// (GET /export)
+	GetExport(ctx echo.Context, params GetExportParams) error
 	// Delete a file on the sync server
 	// (DELETE /files/{filename})
 	DeleteFilesFilename(ctx echo.Context, filename string) error
@@ -129,15 +876,114 @@ type ServerInterface interface {
 	// Update a file on the sync server
 	// (PUT /files/{filename})
 	PutFilesFilename(ctx echo.Context, filename string, params PutFilesFilenameParams) error
+	// Get the links to a file from other notes
+	// (GET /files/{filename}/backlinks)
+	GetFilesFilenameBacklinks(ctx echo.Context, filename string) error
+	// Download a file as it was after a commit
+	// (GET /files/{filename}/history/{commit})
+	GetFilesFilenameHistoryCommit(ctx echo.Context, filename string, commit string) error
+	// Get the links in a note
+	// (GET /files/{filename}/outlinks)
+	GetFilesFilenameOutlinks(ctx echo.Context, filename string) error
+	// Get the frontmatter properties of a note
+	// (GET /files/{filename}/properties)
+	GetFilesFilenameProperties(ctx echo.Context, filename string) error
+	// Rename a file on the sync server
+	// (POST /files/{filename}/rename)
+	PostFilesFilenameRename(ctx echo.Context, filename string) error
+	// Render a note as HTML
+	// (GET /files/{filename}/render)
+	GetFilesFilenameRender(ctx echo.Context, filename string) error
+	// Get a thumbnail of an image
+	// (GET /files/{filename}/thumbnail)
+	GetFilesFilenameThumbnail(ctx echo.Context, filename string, params GetFilesFilenameThumbnailParams) error
+	// Delete an empty folder
+	// (DELETE /folders/{folder})
+	DeleteFoldersFolder(ctx echo.Context, folder string) error
+	// Create an empty folder
+	// (POST /folders/{folder})
+	PostFoldersFolder(ctx echo.Context, folder string) error
+	// Get the graph of links between files
+	// (GET /graph)
+	GetGraph(ctx echo.Context, params GetGraphParams) error
+	// Get the commits that changed a file or folder
+	// (GET /history)
+	GetHistory(ctx echo.Context, params GetHistoryParams) error
+	// Get the vault's ignore rules
+	// (GET /ignore-rules)
+	GetIgnoreRules(ctx echo.Context) error
+	// Replace the vault's ignore rules
+	// (PUT /ignore-rules)
+	PutIgnoreRules(ctx echo.Context) error
+	// Upload a ZIP archive of files to sync to the server
+	// (POST /import)
+	PostImport(ctx echo.Context, params PostImportParams) error
+	// Get the user's invites
+	// (GET /invites)
+	GetInvites(ctx echo.Context) error
+	// Create an invite
+	// (POST /invites)
+	PostInvites(ctx echo.Context) error
+	// Revoke an invite
+	// (DELETE /invites/{id})
+	DeleteInvitesId(ctx echo.Context, id int64) error
 	// Get a list of files that are synced to the server
 	// (GET /list-files)
-	GetListFiles(ctx echo.Context) error
+	GetListFiles(ctx echo.Context, params GetListFilesParams) error
 	// Get the OpenAPI spec in YAML format
 	// (GET /openapi.yaml)
 	GetOpenapiYaml(ctx echo.Context) error
+	// Get the notes with frontmatter that couldn't be parsed
+	// (GET /properties/errors)
+	GetPropertiesErrors(ctx echo.Context) error
+	// Query notes by their frontmatter properties
+	// (GET /query)
+	GetQuery(ctx echo.Context, params GetQueryParams) error
 	// Get the Redoc script that's stored locally on the server
 	// (GET /redoc.standalone.js)
 	GetRedocStandaloneJs(ctx echo.Context) error
+	// View a shared file or folder
+	// (GET /s/{token})
+	GetSToken(ctx echo.Context, token string, params GetSTokenParams) error
+	// Search the contents of synced markdown files
+	// (GET /search)
+	GetSearch(ctx echo.Context, params GetSearchParams) error
+	// Get the user's share links
+	// (GET /shares)
+	GetShares(ctx echo.Context) error
+	// Create a share link for a file or folder
+	// (POST /shares)
+	PostShares(ctx echo.Context) error
+	// Revoke a share link
+	// (DELETE /shares/{id})
+	DeleteSharesId(ctx echo.Context, id int64) error
+	// Get the vault's snapshots
+	// (GET /snapshots)
+	GetSnapshots(ctx echo.Context) error
+	// Take a snapshot of the vault
+	// (POST /snapshots)
+	PostSnapshots(ctx echo.Context) error
+	// Delete a snapshot
+	// (DELETE /snapshots/{snapshot})
+	DeleteSnapshotsSnapshot(ctx echo.Context, snapshot string) error
+	// Get the files in a snapshot
+	// (GET /snapshots/{snapshot}/files)
+	GetSnapshotsSnapshotFiles(ctx echo.Context, snapshot string, params GetSnapshotsSnapshotFilesParams) error
+	// Download a file as it was in a snapshot
+	// (GET /snapshots/{snapshot}/files/{filename})
+	GetSnapshotsSnapshotFilesFilename(ctx echo.Context, snapshot string, filename string) error
+	// Restore the vault to a snapshot
+	// (POST /snapshots/{snapshot}/restore)
+	PostSnapshotsSnapshotRestore(ctx echo.Context, snapshot string) error
+	// Get the tags in a user's notes
+	// (GET /tags)
+	GetTags(ctx echo.Context, params GetTagsParams) error
+	// Get the files with a tag
+	// (GET /tags/files)
+	GetTagsFiles(ctx echo.Context, params GetTagsFilesParams) error
+	// Get the folder tree of the vault
+	// (GET /tree)
+	GetTree(ctx echo.Context, params GetTreeParams) error
 	// Delete a user
 	// (DELETE /user)
 	DeleteUser(ctx echo.Context) error
@@ -147,6 +993,12 @@ type ServerInterface interface {
 	// Let users update their email
 	// (PUT /user/email)
 	PutUserEmail(ctx echo.Context) error
+	// Send an email to verify the user's email address
+	// (POST /user/email/verification)
+	PostUserEmailVerification(ctx echo.Context) error
+	// Verify the user's email address
+	// (GET /user/email/verify)
+	GetUserEmailVerify(ctx echo.Context, params GetUserEmailVerifyParams) error
 	// Log in a user
 	// (POST /user/login)
 	PostUserLogin(ctx echo.Context) error
@@ -156,76 +1008,1108 @@ type ServerInterface interface {
 	// Let users update their password
 	// (PUT /user/password)
 	PutUserPassword(ctx echo.Context) error
+	// Send a password reset email
+	// (POST /user/password/forgot)
+	PostUserPasswordForgot(ctx echo.Context) error
+	// Reset a forgotten password
+	// (POST /user/password/reset)
+	PostUserPasswordReset(ctx echo.Context) error
 	// Let users update their username
 	// (PUT /user/username)
 	PutUserUsername(ctx echo.Context) error
+	// Get the vaults the user can access
+	// (GET /vaults)
+	GetVaults(ctx echo.Context) error
+	// Get the members of a vault
+	// (GET /vaults/{owner}/members)
+	GetVaultsOwnerMembers(ctx echo.Context, owner string) error
+	// Remove a member from a vault
+	// (DELETE /vaults/{owner}/members/{username})
+	DeleteVaultsOwnerMembersUsername(ctx echo.Context, owner string, username string) error
+	// Add a member to a vault or change their role
+	// (PUT /vaults/{owner}/members/{username})
+	PutVaultsOwnerMembersUsername(ctx echo.Context, owner string, username string) error
+}
+
+// ServerInterfaceWrapper converts echo contexts to parameters.
+type ServerInterfaceWrapper struct {
+	Handler ServerInterface
 }
 
-// ServerInterfaceWrapper converts echo contexts to parameters.
-type ServerInterfaceWrapper struct {
-	Handler ServerInterface
+// GetAdminUsers converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAdminUsers(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAdminUsersParams
+	// ------------- Optional query parameter "search" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "search", ctx.QueryParams(), &params.Search)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter search: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetAdminUsers(ctx, params)
+	return err
+}
+
+// PostAdminUsers converts echo context to params.
+func (w *ServerInterfaceWrapper) PostAdminUsers(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostAdminUsers(ctx)
+	return err
+}
+
+// GetAdminUsersUsername converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAdminUsersUsername(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", ctx.Param("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetAdminUsersUsername(ctx, username)
+	return err
+}
+
+// PostAdminUsersUsernameDisable converts echo context to params.
+func (w *ServerInterfaceWrapper) PostAdminUsersUsernameDisable(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", ctx.Param("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostAdminUsersUsernameDisable(ctx, username)
+	return err
+}
+
+// PostAdminUsersUsernameEnable converts echo context to params.
+func (w *ServerInterfaceWrapper) PostAdminUsersUsernameEnable(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", ctx.Param("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostAdminUsersUsernameEnable(ctx, username)
+	return err
+}
+
+// PostAdminUsersUsernamePassword converts echo context to params.
+func (w *ServerInterfaceWrapper) PostAdminUsersUsernamePassword(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", ctx.Param("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostAdminUsersUsernamePassword(ctx, username)
+	return err
+}
+
+// PostAdminUsersUsernameRevoke converts echo context to params.
+func (w *ServerInterfaceWrapper) PostAdminUsersUsernameRevoke(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", ctx.Param("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostAdminUsersUsernameRevoke(ctx, username)
+	return err
+}
+
+// DeleteApikeys converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteApikeys(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteApikeys(ctx)
+	return err
+}
+
+// GetApikeys converts echo context to params.
+func (w *ServerInterfaceWrapper) GetApikeys(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetApikeysParams
+	// ------------- Optional query parameter "name" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "name", ctx.QueryParams(), &params.Name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetApikeys(ctx, params)
+	return err
+}
+
+// PostApikeys converts echo context to params.
+func (w *ServerInterfaceWrapper) PostApikeys(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostApikeys(ctx)
+	return err
+}
+
+// GetAttachmentsOrphans converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAttachmentsOrphans(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetAttachmentsOrphansParams
+	// ------------- Optional query parameter "folder" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "folder", ctx.QueryParams(), &params.Folder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter folder: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetAttachmentsOrphans(ctx, params)
+	return err
+}
+
+// PostAttachmentsOrphansTrash converts echo context to params.
+func (w *ServerInterfaceWrapper) PostAttachmentsOrphansTrash(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostAttachmentsOrphansTrash(ctx)
+	return err
+}
+
+// GetDocs converts echo context to params.
+func (w *ServerInterfaceWrapper) GetDocs(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetDocs(ctx)
+	return err
+}
+
+// GetExport converts echo context to params.
+func (w *ServerInterfaceWrapper) GetExport(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetExportParams
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
+
+	// ------------- Optional query parameter "prefix" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "prefix", ctx.QueryParams(), &params.Prefix)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter prefix: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetExport(ctx, params)
+	return err
+}
+
+// DeleteFilesFilename converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteFilesFilename(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteFilesFilename(ctx, filename)
+	return err
+}
+
+// GetFilesFilename converts echo context to params.
+func (w *ServerInterfaceWrapper) GetFilesFilename(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetFilesFilenameParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-None-Match", valueList[0], &IfNoneMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+	// ------------- Optional header parameter "Range" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("Range")]; found {
+		var Range string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for Range, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "Range", valueList[0], &Range, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter Range: %s", err))
+		}
+
+		params.Range = &Range
+	}
+	// ------------- Optional header parameter "If-Range" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-Range")]; found {
+		var IfRange string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-Range, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-Range", valueList[0], &IfRange, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-Range: %s", err))
+		}
+
+		params.IfRange = &IfRange
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetFilesFilename(ctx, filename, params)
+	return err
+}
+
+// PostFilesFilename converts echo context to params.
+func (w *ServerInterfaceWrapper) PostFilesFilename(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostFilesFilename(ctx, filename)
+	return err
+}
+
+// PutFilesFilename converts echo context to params.
+func (w *ServerInterfaceWrapper) PutFilesFilename(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PutFilesFilenameParams
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-None-Match", valueList[0], &IfNoneMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PutFilesFilename(ctx, filename, params)
+	return err
+}
+
+// GetFilesFilenameBacklinks converts echo context to params.
+func (w *ServerInterfaceWrapper) GetFilesFilenameBacklinks(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetFilesFilenameBacklinks(ctx, filename)
+	return err
+}
+
+// GetFilesFilenameHistoryCommit converts echo context to params.
+func (w *ServerInterfaceWrapper) GetFilesFilenameHistoryCommit(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	// ------------- Path parameter "commit" -------------
+	var commit string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "commit", ctx.Param("commit"), &commit, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter commit: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetFilesFilenameHistoryCommit(ctx, filename, commit)
+	return err
+}
+
+// GetFilesFilenameOutlinks converts echo context to params.
+func (w *ServerInterfaceWrapper) GetFilesFilenameOutlinks(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetFilesFilenameOutlinks(ctx, filename)
+	return err
+}
+
+// GetFilesFilenameProperties converts echo context to params.
+func (w *ServerInterfaceWrapper) GetFilesFilenameProperties(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetFilesFilenameProperties(ctx, filename)
+	return err
+}
+
+// PostFilesFilenameRename converts echo context to params.
+func (w *ServerInterfaceWrapper) PostFilesFilenameRename(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostFilesFilenameRename(ctx, filename)
+	return err
+}
+
+// GetFilesFilenameRender converts echo context to params.
+func (w *ServerInterfaceWrapper) GetFilesFilenameRender(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetFilesFilenameRender(ctx, filename)
+	return err
+}
+
+// GetFilesFilenameThumbnail converts echo context to params.
+func (w *ServerInterfaceWrapper) GetFilesFilenameThumbnail(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "filename" -------------
+	var filename string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetFilesFilenameThumbnailParams
+	// ------------- Optional query parameter "size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "size", ctx.QueryParams(), &params.Size)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter size: %s", err))
+	}
+
+	headers := ctx.Request().Header
+	// ------------- Optional header parameter "If-None-Match" -------------
+	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
+		var IfNoneMatch string
+		n := len(valueList)
+		if n != 1 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
+		}
+
+		err = runtime.BindStyledParameterWithOptions("simple", "If-None-Match", valueList[0], &IfNoneMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
+		}
+
+		params.IfNoneMatch = &IfNoneMatch
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetFilesFilenameThumbnail(ctx, filename, params)
+	return err
+}
+
+// DeleteFoldersFolder converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteFoldersFolder(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "folder" -------------
+	var folder string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "folder", ctx.Param("folder"), &folder, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter folder: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteFoldersFolder(ctx, folder)
+	return err
+}
+
+// PostFoldersFolder converts echo context to params.
+func (w *ServerInterfaceWrapper) PostFoldersFolder(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "folder" -------------
+	var folder string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "folder", ctx.Param("folder"), &folder, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter folder: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostFoldersFolder(ctx, folder)
+	return err
+}
+
+// GetGraph converts echo context to params.
+func (w *ServerInterfaceWrapper) GetGraph(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetGraphParams
+	// ------------- Optional query parameter "folder" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "folder", ctx.QueryParams(), &params.Folder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter folder: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetGraph(ctx, params)
+	return err
+}
+
+// GetHistory converts echo context to params.
+func (w *ServerInterfaceWrapper) GetHistory(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetHistoryParams
+	// ------------- Optional query parameter "path" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "path", ctx.QueryParams(), &params.Path)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter path: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetHistory(ctx, params)
+	return err
+}
+
+// GetIgnoreRules converts echo context to params.
+func (w *ServerInterfaceWrapper) GetIgnoreRules(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetIgnoreRules(ctx)
+	return err
+}
+
+// PutIgnoreRules converts echo context to params.
+func (w *ServerInterfaceWrapper) PutIgnoreRules(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PutIgnoreRules(ctx)
+	return err
+}
+
+// PostImport converts echo context to params.
+func (w *ServerInterfaceWrapper) PostImport(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params PostImportParams
+	// ------------- Optional query parameter "conflict" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "conflict", ctx.QueryParams(), &params.Conflict)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter conflict: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostImport(ctx, params)
+	return err
+}
+
+// GetInvites converts echo context to params.
+func (w *ServerInterfaceWrapper) GetInvites(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetInvites(ctx)
+	return err
+}
+
+// PostInvites converts echo context to params.
+func (w *ServerInterfaceWrapper) PostInvites(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostInvites(ctx)
+	return err
+}
+
+// DeleteInvitesId converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteInvitesId(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteInvitesId(ctx, id)
+	return err
+}
+
+// GetListFiles converts echo context to params.
+func (w *ServerInterfaceWrapper) GetListFiles(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetListFilesParams
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// ------------- Optional query parameter "cursor" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "cursor", ctx.QueryParams(), &params.Cursor)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter cursor: %s", err))
+	}
+
+	// ------------- Optional query parameter "prefix" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "prefix", ctx.QueryParams(), &params.Prefix)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter prefix: %s", err))
+	}
+
+	// ------------- Optional query parameter "modified_since" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "modified_since", ctx.QueryParams(), &params.ModifiedSince)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter modified_since: %s", err))
+	}
+
+	// ------------- Optional query parameter "extension" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "extension", ctx.QueryParams(), &params.Extension)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter extension: %s", err))
+	}
+
+	// ------------- Optional query parameter "min_size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "min_size", ctx.QueryParams(), &params.MinSize)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter min_size: %s", err))
+	}
+
+	// ------------- Optional query parameter "max_size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "max_size", ctx.QueryParams(), &params.MaxSize)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter max_size: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	// ------------- Optional query parameter "order" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "order", ctx.QueryParams(), &params.Order)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter order: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetListFiles(ctx, params)
+	return err
+}
+
+// GetOpenapiYaml converts echo context to params.
+func (w *ServerInterfaceWrapper) GetOpenapiYaml(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetOpenapiYaml(ctx)
+	return err
+}
+
+// GetPropertiesErrors converts echo context to params.
+func (w *ServerInterfaceWrapper) GetPropertiesErrors(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetPropertiesErrors(ctx)
+	return err
+}
+
+// GetQuery converts echo context to params.
+func (w *ServerInterfaceWrapper) GetQuery(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetQueryParams
+	// ------------- Optional query parameter "where" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "where", ctx.QueryParams(), &params.Where)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter where: %s", err))
+	}
+
+	// ------------- Optional query parameter "sort" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "sort", ctx.QueryParams(), &params.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sort: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetQuery(ctx, params)
+	return err
+}
+
+// GetRedocStandaloneJs converts echo context to params.
+func (w *ServerInterfaceWrapper) GetRedocStandaloneJs(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetRedocStandaloneJs(ctx)
+	return err
+}
+
+// GetSToken converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSToken(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "token" -------------
+	var token string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "token", ctx.Param("token"), &token, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter token: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetSTokenParams
+	// ------------- Optional query parameter "file" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "file", ctx.QueryParams(), &params.File)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter file: %s", err))
+	}
+
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
+
+	// ------------- Optional query parameter "view" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "view", ctx.QueryParams(), &params.View)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter view: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetSToken(ctx, token, params)
+	return err
+}
+
+// GetSearch converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSearch(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetSearchParams
+	// ------------- Required query parameter "q" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "q", ctx.QueryParams(), &params.Q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter q: %s", err))
+	}
+
+	// ------------- Optional query parameter "phrase" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "phrase", ctx.QueryParams(), &params.Phrase)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter phrase: %s", err))
+	}
+
+	// ------------- Optional query parameter "prefix" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "prefix", ctx.QueryParams(), &params.Prefix)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter prefix: %s", err))
+	}
+
+	// ------------- Optional query parameter "folder" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "folder", ctx.QueryParams(), &params.Folder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter folder: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "limit", ctx.QueryParams(), &params.Limit)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetSearch(ctx, params)
+	return err
 }
 
-// DeleteApikeys converts echo context to params.
-func (w *ServerInterfaceWrapper) DeleteApikeys(ctx echo.Context) error {
+// GetShares converts echo context to params.
+func (w *ServerInterfaceWrapper) GetShares(ctx echo.Context) error {
 	var err error
 
 	ctx.Set(Cookie_authScopes, []string{})
 
+	ctx.Set(Api_keyScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.DeleteApikeys(ctx)
+	err = w.Handler.GetShares(ctx)
 	return err
 }
 
-// GetApikeys converts echo context to params.
-func (w *ServerInterfaceWrapper) GetApikeys(ctx echo.Context) error {
+// PostShares converts echo context to params.
+func (w *ServerInterfaceWrapper) PostShares(ctx echo.Context) error {
 	var err error
 
 	ctx.Set(Cookie_authScopes, []string{})
 
-	// Parameter object where we will unmarshal all parameters from the context
-	var params GetApikeysParams
-	// ------------- Optional query parameter "name" -------------
+	ctx.Set(Api_keyScopes, []string{})
 
-	err = runtime.BindQueryParameter("form", true, false, "name", ctx.QueryParams(), &params.Name)
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostShares(ctx)
+	return err
+}
+
+// DeleteSharesId converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteSharesId(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id int64
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter name: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetApikeys(ctx, params)
+	err = w.Handler.DeleteSharesId(ctx, id)
 	return err
 }
 
-// PostApikeys converts echo context to params.
-func (w *ServerInterfaceWrapper) PostApikeys(ctx echo.Context) error {
+// GetSnapshots converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSnapshots(ctx echo.Context) error {
 	var err error
 
 	ctx.Set(Cookie_authScopes, []string{})
 
+	ctx.Set(Api_keyScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.PostApikeys(ctx)
+	err = w.Handler.GetSnapshots(ctx)
 	return err
 }
 
-// GetDocs converts echo context to params.
-func (w *ServerInterfaceWrapper) GetDocs(ctx echo.Context) error {
+// PostSnapshots converts echo context to params.
+func (w *ServerInterfaceWrapper) PostSnapshots(ctx echo.Context) error {
 	var err error
 
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetDocs(ctx)
+	err = w.Handler.PostSnapshots(ctx)
 	return err
 }
 
-// DeleteFilesFilename converts echo context to params.
-func (w *ServerInterfaceWrapper) DeleteFilesFilename(ctx echo.Context) error {
+// DeleteSnapshotsSnapshot converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteSnapshotsSnapshot(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "filename" -------------
-	var filename string
+	// ------------- Path parameter "snapshot" -------------
+	var snapshot string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	err = runtime.BindStyledParameterWithOptions("simple", "snapshot", ctx.Param("snapshot"), &snapshot, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter snapshot: %s", err))
 	}
 
 	ctx.Set(Cookie_authScopes, []string{})
@@ -233,19 +2117,19 @@ func (w *ServerInterfaceWrapper) DeleteFilesFilename(ctx echo.Context) error {
 	ctx.Set(Api_keyScopes, []string{})
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.DeleteFilesFilename(ctx, filename)
+	err = w.Handler.DeleteSnapshotsSnapshot(ctx, snapshot)
 	return err
 }
 
-// GetFilesFilename converts echo context to params.
-func (w *ServerInterfaceWrapper) GetFilesFilename(ctx echo.Context) error {
+// GetSnapshotsSnapshotFiles converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSnapshotsSnapshotFiles(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "filename" -------------
-	var filename string
+	// ------------- Path parameter "snapshot" -------------
+	var snapshot string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	err = runtime.BindStyledParameterWithOptions("simple", "snapshot", ctx.Param("snapshot"), &snapshot, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter snapshot: %s", err))
 	}
 
 	ctx.Set(Cookie_authScopes, []string{})
@@ -253,33 +2137,30 @@ func (w *ServerInterfaceWrapper) GetFilesFilename(ctx echo.Context) error {
 	ctx.Set(Api_keyScopes, []string{})
 
 	// Parameter object where we will unmarshal all parameters from the context
-	var params GetFilesFilenameParams
+	var params GetSnapshotsSnapshotFilesParams
+	// ------------- Optional query parameter "folder" -------------
 
-	headers := ctx.Request().Header
-	// ------------- Optional header parameter "If-None-Match" -------------
-	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
-		var IfNoneMatch string
-		n := len(valueList)
-		if n != 1 {
-			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
-		}
-
-		err = runtime.BindStyledParameterWithOptions("simple", "If-None-Match", valueList[0], &IfNoneMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
-		}
-
-		params.IfNoneMatch = &IfNoneMatch
+	err = runtime.BindQueryParameter("form", true, false, "folder", ctx.QueryParams(), &params.Folder)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter folder: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetFilesFilename(ctx, filename, params)
+	err = w.Handler.GetSnapshotsSnapshotFiles(ctx, snapshot, params)
 	return err
 }
 
-// PostFilesFilename converts echo context to params.
-func (w *ServerInterfaceWrapper) PostFilesFilename(ctx echo.Context) error {
+// GetSnapshotsSnapshotFilesFilename converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSnapshotsSnapshotFilesFilename(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "snapshot" -------------
+	var snapshot string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "snapshot", ctx.Param("snapshot"), &snapshot, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter snapshot: %s", err))
+	}
+
 	// ------------- Path parameter "filename" -------------
 	var filename string
 
@@ -293,78 +2174,107 @@ func (w *ServerInterfaceWrapper) PostFilesFilename(ctx echo.Context) error {
 	ctx.Set(Api_keyScopes, []string{})
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.PostFilesFilename(ctx, filename)
+	err = w.Handler.GetSnapshotsSnapshotFilesFilename(ctx, snapshot, filename)
 	return err
 }
 
-// PutFilesFilename converts echo context to params.
-func (w *ServerInterfaceWrapper) PutFilesFilename(ctx echo.Context) error {
+// PostSnapshotsSnapshotRestore converts echo context to params.
+func (w *ServerInterfaceWrapper) PostSnapshotsSnapshotRestore(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "filename" -------------
-	var filename string
+	// ------------- Path parameter "snapshot" -------------
+	var snapshot string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "filename", ctx.Param("filename"), &filename, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	err = runtime.BindStyledParameterWithOptions("simple", "snapshot", ctx.Param("snapshot"), &snapshot, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter filename: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter snapshot: %s", err))
 	}
 
 	ctx.Set(Cookie_authScopes, []string{})
 
 	ctx.Set(Api_keyScopes, []string{})
 
-	// Parameter object where we will unmarshal all parameters from the context
-	var params PutFilesFilenameParams
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostSnapshotsSnapshotRestore(ctx, snapshot)
+	return err
+}
 
-	headers := ctx.Request().Header
-	// ------------- Optional header parameter "If-None-Match" -------------
-	if valueList, found := headers[http.CanonicalHeaderKey("If-None-Match")]; found {
-		var IfNoneMatch string
-		n := len(valueList)
-		if n != 1 {
-			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Expected one value for If-None-Match, got %d", n))
-		}
+// GetTags converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTags(ctx echo.Context) error {
+	var err error
 
-		err = runtime.BindStyledParameterWithOptions("simple", "If-None-Match", valueList[0], &IfNoneMatch, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationHeader, Explode: false, Required: false})
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter If-None-Match: %s", err))
-		}
+	ctx.Set(Cookie_authScopes, []string{})
 
-		params.IfNoneMatch = &IfNoneMatch
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTagsParams
+	// ------------- Optional query parameter "prefix" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "prefix", ctx.QueryParams(), &params.Prefix)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter prefix: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.PutFilesFilename(ctx, filename, params)
+	err = w.Handler.GetTags(ctx, params)
 	return err
 }
 
-// GetListFiles converts echo context to params.
-func (w *ServerInterfaceWrapper) GetListFiles(ctx echo.Context) error {
+// GetTagsFiles converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTagsFiles(ctx echo.Context) error {
 	var err error
 
 	ctx.Set(Cookie_authScopes, []string{})
 
 	ctx.Set(Api_keyScopes, []string{})
 
-	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetListFiles(ctx)
-	return err
-}
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTagsFilesParams
+	// ------------- Required query parameter "tag" -------------
 
-// GetOpenapiYaml converts echo context to params.
-func (w *ServerInterfaceWrapper) GetOpenapiYaml(ctx echo.Context) error {
-	var err error
+	err = runtime.BindQueryParameter("form", true, true, "tag", ctx.QueryParams(), &params.Tag)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter tag: %s", err))
+	}
+
+	// ------------- Optional query parameter "match" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "match", ctx.QueryParams(), &params.Match)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter match: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetOpenapiYaml(ctx)
+	err = w.Handler.GetTagsFiles(ctx, params)
 	return err
 }
 
-// GetRedocStandaloneJs converts echo context to params.
-func (w *ServerInterfaceWrapper) GetRedocStandaloneJs(ctx echo.Context) error {
+// GetTree converts echo context to params.
+func (w *ServerInterfaceWrapper) GetTree(ctx echo.Context) error {
 	var err error
 
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetTreeParams
+	// ------------- Optional query parameter "path" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "path", ctx.QueryParams(), &params.Path)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter path: %s", err))
+	}
+
+	// ------------- Optional query parameter "depth" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "depth", ctx.QueryParams(), &params.Depth)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter depth: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.GetRedocStandaloneJs(ctx)
+	err = w.Handler.GetTree(ctx, params)
 	return err
 }
 
@@ -401,6 +2311,35 @@ func (w *ServerInterfaceWrapper) PutUserEmail(ctx echo.Context) error {
 	return err
 }
 
+// PostUserEmailVerification converts echo context to params.
+func (w *ServerInterfaceWrapper) PostUserEmailVerification(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostUserEmailVerification(ctx)
+	return err
+}
+
+// GetUserEmailVerify converts echo context to params.
+func (w *ServerInterfaceWrapper) GetUserEmailVerify(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetUserEmailVerifyParams
+	// ------------- Required query parameter "token" -------------
+
+	err = runtime.BindQueryParameter("form", true, true, "token", ctx.QueryParams(), &params.Token)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter token: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetUserEmailVerify(ctx, params)
+	return err
+}
+
 // PostUserLogin converts echo context to params.
 func (w *ServerInterfaceWrapper) PostUserLogin(ctx echo.Context) error {
 	var err error
@@ -432,6 +2371,24 @@ func (w *ServerInterfaceWrapper) PutUserPassword(ctx echo.Context) error {
 	return err
 }
 
+// PostUserPasswordForgot converts echo context to params.
+func (w *ServerInterfaceWrapper) PostUserPasswordForgot(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostUserPasswordForgot(ctx)
+	return err
+}
+
+// PostUserPasswordReset converts echo context to params.
+func (w *ServerInterfaceWrapper) PostUserPasswordReset(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PostUserPasswordReset(ctx)
+	return err
+}
+
 // PutUserUsername converts echo context to params.
 func (w *ServerInterfaceWrapper) PutUserUsername(ctx echo.Context) error {
 	var err error
@@ -443,6 +2400,95 @@ func (w *ServerInterfaceWrapper) PutUserUsername(ctx echo.Context) error {
 	return err
 }
 
+// GetVaults converts echo context to params.
+func (w *ServerInterfaceWrapper) GetVaults(ctx echo.Context) error {
+	var err error
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetVaults(ctx)
+	return err
+}
+
+// GetVaultsOwnerMembers converts echo context to params.
+func (w *ServerInterfaceWrapper) GetVaultsOwnerMembers(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", ctx.Param("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter owner: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.GetVaultsOwnerMembers(ctx, owner)
+	return err
+}
+
+// DeleteVaultsOwnerMembersUsername converts echo context to params.
+func (w *ServerInterfaceWrapper) DeleteVaultsOwnerMembersUsername(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", ctx.Param("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter owner: %s", err))
+	}
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", ctx.Param("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.DeleteVaultsOwnerMembersUsername(ctx, owner, username)
+	return err
+}
+
+// PutVaultsOwnerMembersUsername converts echo context to params.
+func (w *ServerInterfaceWrapper) PutVaultsOwnerMembersUsername(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "owner" -------------
+	var owner string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "owner", ctx.Param("owner"), &owner, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter owner: %s", err))
+	}
+
+	// ------------- Path parameter "username" -------------
+	var username string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "username", ctx.Param("username"), &username, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter username: %s", err))
+	}
+
+	ctx.Set(Cookie_authScopes, []string{})
+
+	ctx.Set(Api_keyScopes, []string{})
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.PutVaultsOwnerMembersUsername(ctx, owner, username)
+	return err
+}
+
 // This is a simple interface which specifies echo.Route addition functions which
 // are present on both echo.Echo and echo.Group, since we want to allow using
 // either of them for path registration
@@ -471,23 +2517,74 @@ func RegisterHandlersWithBaseURL(router EchoRouter, si ServerInterface, baseURL
 		Handler: si,
 	}
 
+	router.GET(baseURL+"/admin/users", wrapper.GetAdminUsers)
+	router.POST(baseURL+"/admin/users", wrapper.PostAdminUsers)
+	router.GET(baseURL+"/admin/users/:username", wrapper.GetAdminUsersUsername)
+	router.POST(baseURL+"/admin/users/:username/disable", wrapper.PostAdminUsersUsernameDisable)
+	router.POST(baseURL+"/admin/users/:username/enable", wrapper.PostAdminUsersUsernameEnable)
+	router.POST(baseURL+"/admin/users/:username/password", wrapper.PostAdminUsersUsernamePassword)
+	router.POST(baseURL+"/admin/users/:username/revoke", wrapper.PostAdminUsersUsernameRevoke)
 	router.DELETE(baseURL+"/apikeys", wrapper.DeleteApikeys)
 	router.GET(baseURL+"/apikeys", wrapper.GetApikeys)
 	router.POST(baseURL+"/apikeys", wrapper.PostApikeys)
+	router.GET(baseURL+"/attachments/orphans", wrapper.GetAttachmentsOrphans)
+	router.POST(baseURL+"/attachments/orphans/trash", wrapper.PostAttachmentsOrphansTrash)
 	router.GET(baseURL+"/docs", wrapper.GetDocs)
+	router.GET(baseURL+"/export", wrapper.GetExport)
 	router.DELETE(baseURL+"/files/:filename", wrapper.DeleteFilesFilename)
 	router.GET(baseURL+"/files/:filename", wrapper.GetFilesFilename)
 	router.POST(baseURL+"/files/:filename", wrapper.PostFilesFilename)
 	router.PUT(baseURL+"/files/:filename", wrapper.PutFilesFilename)
+	router.GET(baseURL+"/files/:filename/backlinks", wrapper.GetFilesFilenameBacklinks)
+	router.GET(baseURL+"/files/:filename/history/:commit", wrapper.GetFilesFilenameHistoryCommit)
+	router.GET(baseURL+"/files/:filename/outlinks", wrapper.GetFilesFilenameOutlinks)
+	router.GET(baseURL+"/files/:filename/properties", wrapper.GetFilesFilenameProperties)
+	router.POST(baseURL+"/files/:filename/rename", wrapper.PostFilesFilenameRename)
+	router.GET(baseURL+"/files/:filename/render", wrapper.GetFilesFilenameRender)
+	router.GET(baseURL+"/files/:filename/thumbnail", wrapper.GetFilesFilenameThumbnail)
+	router.DELETE(baseURL+"/folders/:folder", wrapper.DeleteFoldersFolder)
+	router.POST(baseURL+"/folders/:folder", wrapper.PostFoldersFolder)
+	router.GET(baseURL+"/graph", wrapper.GetGraph)
+	router.GET(baseURL+"/history", wrapper.GetHistory)
+	router.GET(baseURL+"/ignore-rules", wrapper.GetIgnoreRules)
+	router.PUT(baseURL+"/ignore-rules", wrapper.PutIgnoreRules)
+	router.POST(baseURL+"/import", wrapper.PostImport)
+	router.GET(baseURL+"/invites", wrapper.GetInvites)
+	router.POST(baseURL+"/invites", wrapper.PostInvites)
+	router.DELETE(baseURL+"/invites/:id", wrapper.DeleteInvitesId)
 	router.GET(baseURL+"/list-files", wrapper.GetListFiles)
 	router.GET(baseURL+"/openapi.yaml", wrapper.GetOpenapiYaml)
+	router.GET(baseURL+"/properties/errors", wrapper.GetPropertiesErrors)
+	router.GET(baseURL+"/query", wrapper.GetQuery)
 	router.GET(baseURL+"/redoc.standalone.js", wrapper.GetRedocStandaloneJs)
+	router.GET(baseURL+"/s/:token", wrapper.GetSToken)
+	router.GET(baseURL+"/search", wrapper.GetSearch)
+	router.GET(baseURL+"/shares", wrapper.GetShares)
+	router.POST(baseURL+"/shares", wrapper.PostShares)
+	router.DELETE(baseURL+"/shares/:id", wrapper.DeleteSharesId)
+	router.GET(baseURL+"/snapshots", wrapper.GetSnapshots)
+	router.POST(baseURL+"/snapshots", wrapper.PostSnapshots)
+	router.DELETE(baseURL+"/snapshots/:snapshot", wrapper.DeleteSnapshotsSnapshot)
+	router.GET(baseURL+"/snapshots/:snapshot/files", wrapper.GetSnapshotsSnapshotFiles)
+	router.GET(baseURL+"/snapshots/:snapshot/files/:filename", wrapper.GetSnapshotsSnapshotFilesFilename)
+	router.POST(baseURL+"/snapshots/:snapshot/restore", wrapper.PostSnapshotsSnapshotRestore)
+	router.GET(baseURL+"/tags", wrapper.GetTags)
+	router.GET(baseURL+"/tags/files", wrapper.GetTagsFiles)
+	router.GET(baseURL+"/tree", wrapper.GetTree)
 	router.DELETE(baseURL+"/user", wrapper.DeleteUser)
 	router.POST(baseURL+"/user", wrapper.PostUser)
 	router.PUT(baseURL+"/user/email", wrapper.PutUserEmail)
+	router.POST(baseURL+"/user/email/verification", wrapper.PostUserEmailVerification)
+	router.GET(baseURL+"/user/email/verify", wrapper.GetUserEmailVerify)
 	router.POST(baseURL+"/user/login", wrapper.PostUserLogin)
 	router.POST(baseURL+"/user/logout", wrapper.PostUserLogout)
 	router.PUT(baseURL+"/user/password", wrapper.PutUserPassword)
+	router.POST(baseURL+"/user/password/forgot", wrapper.PostUserPasswordForgot)
+	router.POST(baseURL+"/user/password/reset", wrapper.PostUserPasswordReset)
 	router.PUT(baseURL+"/user/username", wrapper.PutUserUsername)
+	router.GET(baseURL+"/vaults", wrapper.GetVaults)
+	router.GET(baseURL+"/vaults/:owner/members", wrapper.GetVaultsOwnerMembers)
+	router.DELETE(baseURL+"/vaults/:owner/members/:username", wrapper.DeleteVaultsOwnerMembersUsername)
+	router.PUT(baseURL+"/vaults/:owner/members/:username", wrapper.PutVaultsOwnerMembersUsername)
 
 }
@@ -0,0 +1,503 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raian621/obsync-server/database"
+)
+
+const (
+	// Name of the database in backup archives
+	DatabaseName = "sqlite.db"
+	// Folder the file store's files are under in backup archives
+	FilesDir = "files"
+	// Name of the manifest in backup archives, which lists every other entry
+	// along with its checksum
+	ManifestName = "manifest.json"
+
+	DefaultInterval = 24 * time.Hour
+	DefaultKeep     = 7
+
+	manifestVersion = 1
+	archivePrefix   = "obsync-backup-"
+	archiveExt      = ".tar.gz"
+	// sorts in the order backups were made in
+	timeFormat = "20060102T150405.000000000Z"
+)
+
+var (
+	ErrInvalidArchive = errors.New("backup archive is invalid")
+)
+
+type Options struct {
+	// Directory backup archives are written to
+	Dir string
+	// Number of backup archives to keep. Older archives are deleted after a
+	// backup is made, and every archive is kept when it's 0 or less.
+	Keep int
+	// Held while the database and file store are copied, so files and the
+	// database can't change in between. The copies are quick, and the
+	// archive is written after it's released.
+	Lock sync.Locker
+}
+
+// Entries in a backup archive.
+type Manifest struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Entries   []ManifestEntry `json:"entries"`
+}
+
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Where the database and file store were moved to when a backup was restored
+// over them.
+type RestoreResult struct {
+	Manifest *Manifest
+	// Empty if there was no file store or database to move
+	OldRoot     string
+	OldDatabase string
+}
+
+// Back up a database and the file store at root to a new archive in the
+// options' directory, returning the archive's path.
+//
+// The database is copied with SQLite's online backup API and the file store's
+// files are hard linked into a staging directory, which works because the
+// file store replaces files instead of writing over them. Files are copied
+// instead when they can't be linked, like when the backup directory is on
+// another file system.
+func Create(db *sql.DB, root string, opts Options) (string, error) {
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	staging, err := os.MkdirTemp(dir, ".staging-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	createdAt := time.Now().UTC()
+	if err := copyLive(db, root, staging, dir, opts.Lock); err != nil {
+		return "", err
+	}
+
+	archivePath := filepath.Join(dir, archivePrefix+createdAt.Format(timeFormat)+archiveExt)
+	tmpPath := archivePath + ".tmp"
+	if err := writeArchive(staging, tmpPath, createdAt); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	if _, err := Rotate(dir, opts.Keep); err != nil {
+		return archivePath, err
+	}
+	return archivePath, nil
+}
+
+// Get the paths of the backup archives in a directory, newest first.
+func List(dir string) ([]string, error) {
+	archives, err := filepath.Glob(filepath.Join(dir, archivePrefix+"*"+archiveExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(archives)))
+	return archives, nil
+}
+
+// Delete all but the newest keep backup archives in a directory, returning
+// the paths of the deleted archives.
+func Rotate(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return []string{}, nil
+	}
+	archives, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+	for i := keep; i < len(archives); i++ {
+		if err := os.Remove(archives[i]); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, archives[i])
+	}
+	return deleted, nil
+}
+
+// Check that every entry of a backup archive matches its manifest and that the
+// archive's database isn't corrupt.
+func Verify(archivePath string) (*Manifest, error) {
+	staging, err := os.MkdirTemp("", "obsync-verify-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	return extract(archivePath, filepath.Join(staging, FilesDir), filepath.Join(staging, DatabaseName))
+}
+
+// Replace the database at dbPath and the file store at root with the ones in a
+// backup archive. The archive is extracted next to them and verified first,
+// and the current database and file store are only moved aside once it
+// passes. The server can't be running while a backup is restored.
+func Restore(archivePath, dbPath, root string) (*RestoreResult, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	dbPath, err = filepath.Abs(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	stamp := time.Now().UTC().Format(timeFormat)
+	rootStaging := root + ".restore-" + stamp
+	dbStaging := dbPath + ".restore-" + stamp
+	defer func() {
+		os.RemoveAll(rootStaging)
+		os.Remove(dbStaging)
+	}()
+
+	manifest, err := extract(archivePath, rootStaging, dbStaging)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Manifest: manifest}
+	if _, err := os.Stat(root); err == nil {
+		result.OldRoot = root + ".before-restore-" + stamp
+		if err := os.Rename(root, result.OldRoot); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Rename(rootStaging, root); err != nil {
+		// put the old file store back so the server can still start
+		if len(result.OldRoot) > 0 {
+			os.Rename(result.OldRoot, root)
+		}
+		return nil, err
+	}
+
+	// the journal of the old database would be applied to the restored one
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			continue
+		}
+		oldPath := dbPath + suffix + ".before-restore-" + stamp
+		if err := os.Rename(dbPath+suffix, oldPath); err != nil {
+			return result, err
+		}
+		if len(suffix) == 0 {
+			result.OldDatabase = oldPath
+		}
+	}
+	if err := os.Rename(dbStaging, dbPath); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// Copy the database and the file store into a staging directory, leaving out
+// the backup directory if it's inside the file store.
+func copyLive(db *sql.DB, root, staging, backupDir string, lock sync.Locker) error {
+	if lock != nil {
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	// snapshot content is written before the rows that use it and deleted
+	// after them, so copying the database first means every file it needs
+	// gets copied too
+	if err := database.Backup(db, filepath.Join(staging, DatabaseName)); err != nil {
+		return err
+	}
+
+	filesDir := filepath.Join(staging, FilesDir)
+	return filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(filesDir, rel)
+		if d.IsDir() {
+			if filePath == backupDir {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(dest, 0777)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if err := os.Link(filePath, dest); err == nil {
+			return nil
+		}
+		return copyFile(filePath, dest)
+	})
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Write the files in a staging directory to a gzipped tar archive, followed by
+// a manifest of them.
+func writeArchive(staging, archivePath string, createdAt time.Time) error {
+	file, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	manifest := Manifest{Version: manifestVersion, CreatedAt: createdAt, Entries: []ManifestEntry{}}
+	err = filepath.WalkDir(staging, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(staging, filePath)
+		if err != nil {
+			return err
+		}
+		entry, err := writeEntry(tw, filepath.ToSlash(rel), filePath)
+		if err != nil {
+			return err
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:     ManifestName,
+		Mode:     0640,
+		Size:     int64(len(data)),
+		ModTime:  createdAt,
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func writeEntry(tw *tar.Writer, name, filePath string) (ManifestEntry, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	err = tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0640,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tw, hash), file)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	return ManifestEntry{Path: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// Extract a backup archive, putting its files under filesDir and its database
+// at dbPath, and check it against its manifest.
+func extract(archivePath, filesDir, dbPath string) (*Manifest, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+	if err := os.MkdirAll(filesDir, 0777); err != nil {
+		return nil, err
+	}
+
+	var manifest *Manifest
+	extracted := map[string]ManifestEntry{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%w: %s is not a regular file", ErrInvalidArchive, header.Name)
+		}
+
+		if header.Name == ManifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+			}
+			continue
+		}
+		dest, err := entryPath(header.Name, filesDir, dbPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := extracted[header.Name]; ok {
+			return nil, fmt.Errorf("%w: %s is in the archive twice", ErrInvalidArchive, header.Name)
+		}
+		entry, err := extractEntry(tr, header.Name, dest)
+		if err != nil {
+			return nil, err
+		}
+		extracted[header.Name] = entry
+	}
+	// the gzip checksum is only checked once the end of the stream is read
+	if _, err := io.Copy(io.Discard, gz); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, ManifestName)
+	}
+	if manifest.Version != manifestVersion {
+		return nil, fmt.Errorf("%w: unsupported manifest version %d", ErrInvalidArchive, manifest.Version)
+	}
+	if len(manifest.Entries) != len(extracted) {
+		return nil, fmt.Errorf("%w: archive has %d entries but its manifest lists %d", ErrInvalidArchive, len(extracted), len(manifest.Entries))
+	}
+	for _, want := range manifest.Entries {
+		if got, ok := extracted[want.Path]; !ok || got != want {
+			return nil, fmt.Errorf("%w: %s doesn't match the manifest", ErrInvalidArchive, want.Path)
+		}
+	}
+	if _, ok := extracted[DatabaseName]; !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, DatabaseName)
+	}
+
+	db, err := sql.Open(database.SQL_PROVIDER, "file:"+dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := database.CheckIntegrity(db); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	return manifest, nil
+}
+
+// Get where an archive entry is extracted to, making sure files stay inside
+// filesDir.
+func entryPath(name, filesDir, dbPath string) (string, error) {
+	if name == DatabaseName {
+		return dbPath, nil
+	}
+	rel, ok := strings.CutPrefix(name, FilesDir+"/")
+	if !ok || len(rel) == 0 || path.Clean(rel) != rel || path.IsAbs(rel) ||
+		rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%w: unexpected entry %s", ErrInvalidArchive, name)
+	}
+	return filepath.Join(filesDir, filepath.FromSlash(rel)), nil
+}
+
+func extractEntry(r io.Reader, name, dest string) (ManifestEntry, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0777); err != nil {
+		return ManifestEntry{}, err
+	}
+	file, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), r)
+	if err != nil {
+		return ManifestEntry{}, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if err := file.Close(); err != nil {
+		return ManifestEntry{}, err
+	}
+
+	return ManifestEntry{Path: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAndRestore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "files")
+	dbPath := filepath.Join(dir, "sqlite.db")
+	backupDir := filepath.Join(root, "backups")
+	writeFile := func(name, content string) {
+		t.Helper()
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0777))
+		assert.NoError(t, os.WriteFile(filePath, []byte(content), 0640))
+	}
+	readFile := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	db, err := sql.Open(database.SQL_PROVIDER, "file:"+dbPath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, database.ApplyMigrations(db))
+	_, err = database.CreateUser(db, "test-backup", "test-backup@example.com", "not a secure password")
+	assert.NoError(t, err)
+	writeFile("1/a.md", "# A")
+	writeFile("1/notes/b.md", "# B")
+
+	var lock sync.Mutex
+	archivePath, err := Create(db, root, Options{Dir: backupDir, Keep: 2, Lock: &lock})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	manifest, err := Verify(archivePath)
+	if assert.NoError(t, err) {
+		paths := []string{}
+		for _, entry := range manifest.Entries {
+			paths = append(paths, entry.Path)
+		}
+		// the backup directory is inside the file store, but isn't backed up
+		assert.ElementsMatch(t, []string{"sqlite.db", "files/1/a.md", "files/1/notes/b.md"}, paths)
+	}
+
+	// the backup keeps the content files had when it was made
+	writeFile("1/a.md", "# A changed")
+	writeFile("1/c.md", "# C")
+	_, err = database.CreateUser(db, "test-backup-later", "test-backup-later@example.com", "not a secure password")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	result, err := Restore(archivePath, dbPath, root)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "# A", readFile("1/a.md"))
+	assert.Equal(t, "# B", readFile("1/notes/b.md"))
+	_, err = os.Stat(filepath.Join(root, "1/c.md"))
+	assert.True(t, os.IsNotExist(err))
+	// the old file store and database are kept
+	data, err := os.ReadFile(filepath.Join(result.OldRoot, "1/a.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# A changed", string(data))
+	_, err = os.Stat(result.OldDatabase)
+	assert.NoError(t, err)
+
+	db, err = sql.Open(database.SQL_PROVIDER, "file:"+dbPath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	_, err = database.GetUserByUsername(db, "test-backup")
+	assert.NoError(t, err)
+	_, err = database.GetUserByUsername(db, "test-backup-later")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRestoreInvalidArchive(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	root := filepath.Join(dir, "files")
+	dbPath := filepath.Join(dir, "sqlite.db")
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "1"), 0777))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "1", "a.md"), []byte("# A"), 0640))
+	db, err := sql.Open(database.SQL_PROVIDER, "file:"+dbPath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	assert.NoError(t, database.ApplyMigrations(db))
+
+	archivePath, err := Create(db, root, Options{Dir: filepath.Join(dir, "backups")})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data, err := os.ReadFile(archivePath)
+	assert.NoError(t, err)
+	data[len(data)/2] ^= 0xff
+	assert.NoError(t, os.WriteFile(archivePath, data, 0640))
+
+	_, err = Verify(archivePath)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+	_, err = Restore(archivePath, dbPath, root)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+	// nothing is swapped in when the archive is invalid
+	content, err := os.ReadFile(filepath.Join(root, "1", "a.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# A", string(content))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3, "staging files are cleaned up")
+}
+
+func TestRotate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	root := t.TempDir()
+	db, err := sql.Open(database.SQL_PROVIDER, "file:"+filepath.Join(dir, "sqlite.db"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	backupDir := filepath.Join(dir, "backups")
+
+	created := []string{}
+	for range 3 {
+		archivePath, err := Create(db, root, Options{Dir: backupDir, Keep: 2})
+		assert.NoError(t, err)
+		created = append(created, archivePath)
+	}
+	archives, err := List(backupDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{created[2], created[1]}, archives)
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/raian621/obsync-server/database"
+)
+
+var ErrApiKeyNotFound = errors.New("API key not found")
+
+type apiKeyView struct {
+	Name      string    `json:"name"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func runApiKey(e *env, args []string) error {
+	name, args, err := subcommand(args)
+	if err != nil {
+		return err
+	}
+	flags := e.flags("apikey " + name)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	db, err := e.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch name {
+	case "list":
+		user, err := userArg(db, args)
+		if err != nil {
+			return err
+		}
+		apiKeys, err := database.GetApiKeys(db, user.Id)
+		if err != nil {
+			return err
+		}
+		views := make([]apiKeyView, 0, len(apiKeys))
+		rows := make([][]string, 0, len(apiKeys))
+		for _, apiKey := range apiKeys {
+			views = append(views, apiKeyView{Name: apiKey.Name, Active: apiKey.Active, CreatedAt: apiKey.CreatedAt})
+			rows = append(rows, []string{
+				apiKey.Name,
+				strconv.FormatBool(apiKey.Active),
+				apiKey.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		return e.print(views, []string{"NAME", "ACTIVE", "CREATED"}, rows)
+
+	case "revoke":
+		if len(args) != 2 {
+			return usageError("expected a username and the name of an API key")
+		}
+		user, err := findUser(db, args[0])
+		if err != nil {
+			return err
+		}
+		if err := database.SetApiKeyActivation(db, user.Id, args[1], false); err != nil {
+			if errors.Is(err, database.ErrNoResults) {
+				return fmt.Errorf("%w: %s", ErrApiKeyNotFound, args[1])
+			}
+			return err
+		}
+		return e.printMessage(apiKeyView{Name: args[1]}, "Revoked %s's API key %s", user.Username, args[1])
+
+	default:
+		return usageError("unknown subcommand %q", name)
+	}
+}
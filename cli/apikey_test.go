@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunApiKey(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	db := te.db()
+	user, err := database.CreateUser(db, "test-cli-apikey", "test-cli-apikey@example.com", "not a password")
+	assert.NoError(t, err)
+	_, err = database.CreateApiKey(db, user.Id, "test-cli-apikey-laptop", "not a key")
+	assert.NoError(t, err)
+
+	apiKeys := []apiKeyView{}
+	result := te.runJSON(&apiKeys, "apikey", "list", "test-cli-apikey")
+	assert.Equal(t, 0, result.code, result.stderr)
+	if assert.Len(t, apiKeys, 1) {
+		assert.Equal(t, "test-cli-apikey-laptop", apiKeys[0].Name)
+		assert.True(t, apiKeys[0].Active)
+	}
+
+	result = te.run("", "apikey", "revoke", "test-cli-apikey", "test-cli-apikey-laptop")
+	assert.Equal(t, 0, result.code, result.stderr)
+	result = te.run("", "apikey", "list", "test-cli-apikey")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "test-cli-apikey-laptop")
+	assert.Contains(t, result.stdout, "false")
+
+	result = te.run("", "apikey", "revoke", "test-cli-apikey", "test-cli-apikey-phone")
+	assert.Equal(t, 1, result.code)
+	assert.Contains(t, result.stderr, ErrApiKeyNotFound.Error())
+
+	result = te.run("", "apikey", "revoke", "test-cli-apikey")
+	assert.Equal(t, 2, result.code)
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"sync"
+
+	"github.com/raian621/obsync-server/backup"
+	"github.com/raian621/obsync-server/config"
+)
+
+type backupView struct {
+	Archive string `json:"archive"`
+}
+
+type restoreView struct {
+	Archive     string `json:"archive"`
+	Files       int    `json:"files"`
+	OldRoot     string `json:"old_root,omitempty"`
+	OldDatabase string `json:"old_database,omitempty"`
+}
+
+// Back up the database and file store once. Backups made while the server is
+// running can't stop it from changing files in between copying the database
+// and the file store, so scheduled backups are better for a running server.
+func runBackup(e *env, args []string) error {
+	flags := e.flags("backup")
+	dir := flags.String("dir", "", "directory to write the archive to, instead of the configured one")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usageError("unexpected arguments")
+	}
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return err
+	}
+	if len(*dir) > 0 {
+		cfg.Backup.Dir = *dir
+	}
+	if len(cfg.Backup.Dir) == 0 {
+		return config.ErrBackupDirMissing
+	}
+	db, err := e.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	archivePath, err := backup.Create(db, cfg.Root, backupOptions(cfg, nil))
+	if err != nil {
+		return err
+	}
+	return e.printMessage(backupView{Archive: archivePath}, "Backed up to %s", archivePath)
+}
+
+// Replace the database and file store with a backup. The server can't be
+// running while it does.
+func runRestore(e *env, args []string) error {
+	flags := e.flags("restore")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError("expected the path of a backup archive")
+	}
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	archivePath := flags.Arg(0)
+	result, err := backup.Restore(archivePath, e.dbPath, cfg.Root)
+	if err != nil {
+		return err
+	}
+	view := restoreView{
+		Archive:     archivePath,
+		Files:       len(result.Manifest.Entries) - 1,
+		OldRoot:     result.OldRoot,
+		OldDatabase: result.OldDatabase,
+	}
+	if e.format == "json" {
+		return e.print(view, nil, nil)
+	}
+	e.printMessage(nil, "Restored %d files from %s", view.Files, archivePath)
+	if len(result.OldRoot) > 0 {
+		e.printMessage(nil, "Moved the old file store to %s", result.OldRoot)
+	}
+	if len(result.OldDatabase) > 0 {
+		e.printMessage(nil, "Moved the old database to %s", result.OldDatabase)
+	}
+	return nil
+}
+
+func backupOptions(cfg *config.Config, lock sync.Locker) backup.Options {
+	keep := cfg.Backup.Keep
+	if keep == 0 {
+		keep = backup.DefaultKeep
+	}
+	return backup.Options{Dir: cfg.Backup.Dir, Keep: keep, Lock: lock}
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBackupAndRestore(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	db := te.db()
+	_, err := database.CreateUser(db, "test-cli-backup", "test-cli-backup@example.com", "not a password")
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Join(te.root, "1"), 0777))
+	assert.NoError(t, os.WriteFile(filepath.Join(te.root, "1", "a.md"), []byte("# A"), 0640))
+
+	// backups need a directory
+	result := te.run("", "backup")
+	assert.Equal(t, 1, result.code)
+
+	view := backupView{}
+	result = te.runJSON(&view, "backup", "-dir", filepath.Join(filepath.Dir(te.root), "backups"))
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.FileExists(t, view.Archive)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(te.root, "1", "a.md"), []byte("# A changed"), 0640))
+	_, err = database.CreateUser(db, "test-cli-backup-later", "test-cli-backup-later@example.com", "not a password")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	restored := restoreView{}
+	result = te.runJSON(&restored, "restore", view.Archive)
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Equal(t, 1, restored.Files)
+	data, err := os.ReadFile(filepath.Join(te.root, "1", "a.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# A", string(data))
+
+	users := []userView{}
+	result = te.runJSON(&users, "user", "list")
+	assert.Equal(t, 0, result.code, result.stderr)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, "test-cli-backup", users[0].Username)
+	}
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/raian621/obsync-server/config"
+	"github.com/raian621/obsync-server/database"
+)
+
+const (
+	DefaultConfigPath = "config.yaml"
+	DefaultDBPath     = "sqlite.db"
+)
+
+var (
+	ErrUsage             = errors.New("invalid usage")
+	ErrPendingMigrations = errors.New("database has migrations that haven't been applied, run `obsync-server migrate up` first")
+	ErrUnknownFormat     = errors.New("unknown output format, use table or json")
+	// Returned by commands that ran but found problems, like fsck
+	ErrProblemsFound = errors.New("problems found")
+)
+
+// Environment commands run in, set by the flags that come before the command.
+type env struct {
+	stdin      *bufio.Reader
+	stdout     io.Writer
+	stderr     io.Writer
+	configPath string
+	dbPath     string
+	// table or json
+	format string
+}
+
+type command struct {
+	usage   string
+	summary string
+	run     func(e *env, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"serve":   {"serve", "Start the server (the default command)", runServe},
+		"migrate": {"migrate status|up", "Show or apply database migrations", runMigrate},
+		"user": {
+			"user create|list|delete|reset-password|disable|enable|promote|demote ...",
+			"Manage users",
+			runUser,
+		},
+		"apikey":  {"apikey list|revoke ...", "Manage users' API keys", runApiKey},
+		"fsck":    {"fsck [-user username]", "Check that synced files match the file store", runFsck},
+		"reindex": {"reindex", "Rebuild the search index from the file store", runReindex},
+		"backup":  {"backup [-dir dir]", "Back up the database and file store", runBackup},
+		"restore": {"restore <archive>", "Restore the database and file store from a backup", runRestore},
+		"config":  {"config validate", "Check the configuration file", runConfig},
+	}
+}
+
+// Run the command line interface with the given arguments, not including the
+// program's name, returning the exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: bufio.NewReader(stdin), stdout: stdout, stderr: stderr}
+	flags := flag.NewFlagSet("obsync-server", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&e.configPath, "config", DefaultConfigPath, "path of the configuration file")
+	flags.StringVar(&e.dbPath, "db", DefaultDBPath, "path of the SQLite database")
+	flags.StringVar(&e.format, "format", "table", "output format, table or json")
+	flags.Usage = func() { printUsage(stderr, flags) }
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if e.format != "table" && e.format != "json" {
+		fmt.Fprintln(stderr, "error:", ErrUnknownFormat)
+		return 2
+	}
+
+	args = flags.Args()
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "error: unknown command %q\n", name)
+		printUsage(stderr, flags)
+		return 2
+	}
+
+	if err := cmd.run(e, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintln(stderr, "error:", err)
+		if errors.Is(err, ErrUsage) {
+			fmt.Fprintln(stderr, "usage: obsync-server [flags]", cmd.usage)
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: obsync-server [flags] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", commands[name].usage, commands[name].summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "flags:")
+	flags.PrintDefaults()
+}
+
+// Make a flag set for a command's flags.
+func (e *env) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	return flags
+}
+
+func (e *env) loadConfig() (*config.Config, error) {
+	return config.ReadConfigFromFile(e.configPath)
+}
+
+// Open the database, making sure its migrations have been applied.
+func (e *env) openDB() (*sql.DB, error) {
+	db, err := database.NewDB(e.dbPath)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := database.GetMigrationStatus(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, status := range statuses {
+		if !status.Applied && len(status.MissingOption) == 0 {
+			db.Close()
+			return nil, ErrPendingMigrations
+		}
+	}
+	return db, nil
+}
+
+// Print a value as JSON, or its rows as a table with a header.
+func (e *env) print(value any, header []string, rows [][]string) error {
+	if e.format == "json" {
+		encoder := json.NewEncoder(e.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Print a message, or the value as JSON.
+func (e *env) printMessage(value any, format string, args ...any) error {
+	if e.format == "json" {
+		return e.print(value, nil, nil)
+	}
+	_, err := fmt.Fprintf(e.stdout, format+"\n", args...)
+	return err
+}
+
+// Read a line from stdin, like a password that shouldn't be passed as an
+// argument.
+func (e *env) readLine() (string, error) {
+	line, err := e.stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Split the arguments of a command with subcommands into the subcommand and
+// its arguments.
+func subcommand(args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("%w: missing subcommand", ErrUsage)
+	}
+	return args[0], args[1:], nil
+}
+
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// Config file, database and file store for running commands in a test.
+type testEnv struct {
+	t          *testing.T
+	configPath string
+	dbPath     string
+	root       string
+}
+
+type testResult struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+func newTestEnv(t *testing.T, extraConfig string) *testEnv {
+	t.Helper()
+	dir := t.TempDir()
+	te := &testEnv{
+		t:          t,
+		configPath: filepath.Join(dir, "config.yaml"),
+		dbPath:     filepath.Join(dir, "sqlite.db"),
+		root:       filepath.Join(dir, "files"),
+	}
+	assert.NoError(t, os.MkdirAll(te.root, 0777))
+	content := fmt.Sprintf("type: FileSystem\nroot: %s\nhost: localhost\nport: 8000\n%s", te.root, extraConfig)
+	assert.NoError(t, os.WriteFile(te.configPath, []byte(content), 0640))
+	return te
+}
+
+// Open the test's database with its migrations applied.
+func (te *testEnv) db() *sql.DB {
+	te.t.Helper()
+	db, err := database.NewDB(te.dbPath)
+	if !assert.NoError(te.t, err) {
+		te.t.FailNow()
+	}
+	te.t.Cleanup(func() { db.Close() })
+	assert.NoError(te.t, database.ApplyMigrations(db))
+	return db
+}
+
+func (te *testEnv) run(stdin string, args ...string) testResult {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-config", te.configPath, "-db", te.dbPath}, args...)
+	code := Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return testResult{code, stdout.String(), stderr.String()}
+}
+
+func (te *testEnv) runJSON(value any, args ...string) testResult {
+	te.t.Helper()
+	result := te.run("", append([]string{"-format", "json"}, args...)...)
+	if result.code == 0 {
+		assert.NoError(te.t, decodeJSON(result.stdout, value), result.stdout)
+	}
+	return result
+}
+
+func decodeJSON(data string, value any) error {
+	return json.Unmarshal([]byte(data), value)
+}
+
+func TestRunUsage(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	result := te.run("", "-h")
+	assert.Equal(t, 0, result.code)
+	assert.Contains(t, result.stderr, "usage: obsync-server")
+	for name := range commands {
+		assert.Contains(t, result.stderr, commands[name].summary)
+	}
+
+	result = te.run("", "unknown")
+	assert.Equal(t, 2, result.code)
+	assert.Contains(t, result.stderr, `unknown command "unknown"`)
+
+	result = te.run("", "-format", "xml", "user", "list")
+	assert.Equal(t, 2, result.code)
+	assert.Contains(t, result.stderr, ErrUnknownFormat.Error())
+
+	result = te.run("", "user")
+	assert.Equal(t, 2, result.code)
+	assert.Contains(t, result.stderr, "missing subcommand")
+}
+
+func TestRunPendingMigrations(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	result := te.run("", "user", "list")
+	assert.Equal(t, 1, result.code)
+	assert.Contains(t, result.stderr, ErrPendingMigrations.Error())
+}
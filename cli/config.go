@@ -0,0 +1,35 @@
+package cli
+
+import "github.com/raian621/obsync-server/mailer"
+
+type configView struct {
+	Path  string `json:"path"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+func runConfig(e *env, args []string) error {
+	name, args, err := subcommand(args)
+	if err != nil {
+		return err
+	}
+	if err := e.flags("config " + name).Parse(args); err != nil {
+		return err
+	}
+	if name != "validate" {
+		return usageError("unknown subcommand %q", name)
+	}
+
+	cfg, err := e.loadConfig()
+	// custom email templates are only loaded when the server starts
+	if err == nil && cfg.SMTP.Enabled {
+		_, err = mailer.LoadTemplates(cfg.SMTP.Templates)
+	}
+	if err != nil {
+		if e.format == "json" {
+			e.print(configView{Path: e.configPath, Error: err.Error()}, nil, nil)
+		}
+		return err
+	}
+	return e.printMessage(configView{Path: e.configPath, Valid: true}, "%s is valid", e.configPath)
+}
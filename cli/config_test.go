@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	view := configView{}
+	result := te.runJSON(&view, "config", "validate")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.True(t, view.Valid)
+	assert.Equal(t, te.configPath, view.Path)
+
+	te = newTestEnv(t, "compression: lz4\n")
+	result = te.run("", "config", "validate")
+	assert.Equal(t, 1, result.code)
+	assert.Contains(t, result.stderr, "error:")
+
+	assert.NoError(t, os.WriteFile(te.configPath, []byte("port: [not a port\n"), 0640))
+	result = te.run("", "-format", "json", "config", "validate")
+	assert.Equal(t, 1, result.code)
+	assert.Contains(t, result.stdout, `"valid": false`)
+
+	// broken email templates are caught before the server starts
+	templates := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(templates, "verify-email.tmpl"), []byte(`{{define "subject"}}hi`), 0640))
+	te = newTestEnv(t, "smtp:\n  enabled: true\n  host: localhost\n  from: obsync@example.com\n  templates: "+templates+"\n")
+	result = te.run("", "config", "validate")
+	assert.Equal(t, 1, result.code)
+	assert.Contains(t, result.stderr, "verify-email")
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+)
+
+const (
+	ProblemMissing      = "missing"
+	ProblemEtagMismatch = "etag mismatch"
+	ProblemUntracked    = "untracked"
+	ProblemNoUser       = "no user"
+)
+
+type problemView struct {
+	Username string `json:"username,omitempty"`
+	Path     string `json:"path"`
+	Problem  string `json:"problem"`
+}
+
+// Check that the files in the database match the files in the file store:
+// every synced file has to exist with the same etag, and every file in a
+// user's folder has to be synced. It only reads, fixing problems is left to
+// whoever runs it.
+func runFsck(e *env, args []string) error {
+	flags := e.flags("fsck")
+	username := flags.String("user", "", "only check this user's files")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usageError("unexpected arguments")
+	}
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return err
+	}
+	db, err := e.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	fstore, err := newFileStore(cfg, false)
+	if err != nil {
+		return err
+	}
+
+	var users []*database.User
+	if len(*username) > 0 {
+		user, err := findUser(db, *username)
+		if err != nil {
+			return err
+		}
+		users = []*database.User{user}
+	} else if users, err = database.GetUsers(db, ""); err != nil {
+		return err
+	}
+
+	problems := []problemView{}
+	for _, user := range users {
+		userProblems, err := checkUserFiles(db, fstore, cfg.Root, user)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, userProblems...)
+	}
+	if len(*username) == 0 {
+		orphaned, err := checkUserFolders(cfg.Root, users)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, orphaned...)
+	}
+
+	rows := make([][]string, 0, len(problems))
+	for _, problem := range problems {
+		rows = append(rows, []string{problem.Username, problem.Path, problem.Problem})
+	}
+	if e.format == "json" || len(problems) > 0 {
+		if err := e.print(problems, []string{"USER", "PATH", "PROBLEM"}, rows); err != nil {
+			return err
+		}
+	} else {
+		e.printMessage(nil, "No problems found")
+	}
+	if len(problems) > 0 {
+		return ErrProblemsFound
+	}
+	return nil
+}
+
+func checkUserFiles(db *sql.DB, fstore filestore.FileStore, root string, user *database.User) ([]problemView, error) {
+	problems := []problemView{}
+	userId := strconv.FormatUint(user.Id, 10)
+	syncFiles, err := database.GetSyncFilesByUserId(db, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := make(map[string]bool, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		tracked[syncFile.Filepath] = true
+		etag, err := fstore.GetFileEtag(filepath.Join(userId, syncFile.Filepath))
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			problems = append(problems, problemView{user.Username, syncFile.Filepath, ProblemMissing})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if etag != syncFile.Etag {
+			problems = append(problems, problemView{user.Username, syncFile.Filepath, ProblemEtagMismatch})
+		}
+	}
+
+	userRoot := filepath.Join(root, userId)
+	err = filepath.WalkDir(userRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == userRoot {
+				return nil
+			}
+			return err
+		}
+		// temporary files are left behind when saving a file is interrupted
+		if entry.IsDir() || filestore.IsTempFile(entry.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(userRoot, path)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); !tracked[rel] {
+			problems = append(problems, problemView{user.Username, rel, ProblemUntracked})
+		}
+		return nil
+	})
+	return problems, err
+}
+
+// Find folders in the file store that are named like a user's folder, but
+// that don't belong to a user.
+func checkUserFolders(root string, users []*database.User) ([]problemView, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	userIds := make(map[string]bool, len(users))
+	for _, user := range users {
+		userIds[strconv.FormatUint(user.Id, 10)] = true
+	}
+	problems := []problemView{}
+	for _, entry := range entries {
+		if !entry.IsDir() || userIds[entry.Name()] {
+			continue
+		}
+		if _, err := strconv.ParseUint(entry.Name(), 10, 64); err == nil {
+			problems = append(problems, problemView{Path: entry.Name(), Problem: ProblemNoUser})
+		}
+	}
+	return problems, nil
+}
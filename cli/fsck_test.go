@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunFsck(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	db := te.db()
+	user, err := database.CreateUser(db, "test-cli-fsck", "test-cli-fsck@example.com", "not a password")
+	assert.NoError(t, err)
+	userRoot := filepath.Join(te.root, strconv.FormatUint(user.Id, 10))
+	writeFile := func(name, content string, synced bool) {
+		t.Helper()
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(userRoot, name)), 0777))
+		assert.NoError(t, os.WriteFile(filepath.Join(userRoot, name), []byte(content), 0640))
+		if synced {
+			_, err := database.CreateSyncFile(db, name, filestore.GetEtag([]byte(content)), int64(len(content)), user.Id, user.Id)
+			assert.NoError(t, err)
+		}
+	}
+	writeFile("a.md", "# A", true)
+	writeFile("notes/b.md", "# B", true)
+	writeFile("notes/.b.md.tmp-123", "# B", false)
+
+	result := te.run("", "fsck")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "No problems found")
+
+	writeFile("a.md", "# A changed", false)
+	writeFile("c.md", "# C", false)
+	writeFile("notes/.draft.tmp-2.md", "# Draft", false)
+	assert.NoError(t, os.Remove(filepath.Join(userRoot, "notes", "b.md")))
+	assert.NoError(t, os.MkdirAll(filepath.Join(te.root, "999"), 0777))
+
+	problems := []problemView{}
+	result = te.run("", "-format", "json", "fsck")
+	assert.Equal(t, 1, result.code)
+	assert.Contains(t, result.stderr, ErrProblemsFound.Error())
+	assert.NoError(t, decodeJSON(result.stdout, &problems))
+	assert.ElementsMatch(t, []problemView{
+		{"test-cli-fsck", "a.md", ProblemEtagMismatch},
+		{"test-cli-fsck", "notes/b.md", ProblemMissing},
+		{"test-cli-fsck", "c.md", ProblemUntracked},
+		{"test-cli-fsck", "notes/.draft.tmp-2.md", ProblemUntracked},
+		{"", "999", ProblemNoUser},
+	}, problems)
+
+	// folders without users are only looked for when checking every user
+	result = te.run("", "-format", "json", "fsck", "-user", "test-cli-fsck")
+	assert.Equal(t, 1, result.code)
+	assert.NoError(t, decodeJSON(result.stdout, &problems))
+	assert.Len(t, problems, 4)
+}
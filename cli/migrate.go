@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"strconv"
+
+	"github.com/raian621/obsync-server/database"
+)
+
+type migrationView struct {
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+	// SQLite compile option the migration is waiting for
+	MissingOption string `json:"missing_option,omitempty"`
+}
+
+func runMigrate(e *env, args []string) error {
+	name, args, err := subcommand(args)
+	if err != nil {
+		return err
+	}
+	if err := e.flags("migrate " + name).Parse(args); err != nil {
+		return err
+	}
+
+	// migrations are applied here, so the database is opened without
+	// checking them
+	db, err := database.NewDB(e.dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch name {
+	case "status":
+		statuses, err := database.GetMigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		views := make([]migrationView, 0, len(statuses))
+		rows := make([][]string, 0, len(statuses))
+		for _, status := range statuses {
+			views = append(views, migrationView(status))
+			note := ""
+			if len(status.MissingOption) > 0 {
+				note = missingOptionNote(status.MissingOption)
+			}
+			rows = append(rows, []string{status.Name, strconv.FormatBool(status.Applied), note})
+		}
+		return e.print(views, []string{"NAME", "APPLIED", "NOTE"}, rows)
+
+	case "up":
+		before, err := database.GetMigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		if err := database.ApplyMigrations(db); err != nil {
+			return err
+		}
+		after, err := database.GetMigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		applied := []string{}
+		for i, status := range after {
+			if status.Applied && !before[i].Applied {
+				applied = append(applied, status.Name)
+			}
+		}
+		if e.format == "json" {
+			return e.print(applied, nil, nil)
+		}
+		for _, name := range applied {
+			e.printMessage(nil, "Applied %s", name)
+		}
+		return e.printMessage(nil, "Applied %d migrations", len(applied))
+
+	default:
+		return usageError("unknown subcommand %q", name)
+	}
+}
+
+// Build tags that build SQLite with the compile options migrations need.
+var optionBuildTags = map[string]string{
+	"ENABLE_FTS5": "sqlite_fts5",
+}
+
+// Describe what SQLite needs to be built with for a migration waiting for a
+// compile option to be applied.
+func missingOptionNote(option string) string {
+	note := "needs SQLite built with " + option
+	if tag, ok := optionBuildTags[option]; ok {
+		note += " (build with -tags " + tag + ")"
+	}
+	return note
+}
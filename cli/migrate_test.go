@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunMigrate(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	statuses := []migrationView{}
+	result := te.runJSON(&statuses, "migrate", "status")
+	assert.Equal(t, 0, result.code, result.stderr)
+	if assert.NotEmpty(t, statuses) {
+		assert.False(t, statuses[0].Applied)
+	}
+
+	applied := []string{}
+	result = te.runJSON(&applied, "migrate", "up")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.NotEmpty(t, applied)
+	assert.Equal(t, statuses[0].Name, applied[0])
+
+	result = te.run("", "migrate", "up")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "Applied 0 migrations")
+
+	result = te.run("", "migrate", "status")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "NAME")
+	assert.Contains(t, result.stdout, applied[0])
+	result = te.runJSON(&statuses, "migrate", "status")
+	assert.Equal(t, 0, result.code, result.stderr)
+	for _, status := range statuses {
+		// migrations waiting for a SQLite option can't be applied
+		assert.True(t, status.Applied || len(status.MissingOption) > 0, status.Name)
+	}
+
+	result = te.run("", "migrate", "down")
+	assert.Equal(t, 2, result.code)
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"github.com/raian621/obsync-server/server"
+)
+
+type reindexView struct {
+	Indexed int `json:"indexed"`
+}
+
+// Rebuild the search index from the files in the file store.
+func runReindex(e *env, args []string) error {
+	if err := e.flags("reindex").Parse(args); err != nil {
+		return err
+	}
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return err
+	}
+	db, err := e.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	fstore, err := newFileStore(cfg, true)
+	if err != nil {
+		return err
+	}
+
+	indexed, err := server.RebuildSearchIndex(db, fstore)
+	if err != nil {
+		return err
+	}
+	return e.printMessage(reindexView{Indexed: indexed}, "Indexed %d files", indexed)
+}
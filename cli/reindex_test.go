@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunReindex(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	db := te.db()
+	user, err := database.CreateUser(db, "test-cli-reindex", "test-cli-reindex@example.com", "not a password")
+	assert.NoError(t, err)
+	userRoot := filepath.Join(te.root, strconv.FormatUint(user.Id, 10))
+	writeFile := func(name, content string) {
+		t.Helper()
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(userRoot, name)), 0777))
+		assert.NoError(t, os.WriteFile(filepath.Join(userRoot, name), []byte(content), 0640))
+		_, err := database.CreateSyncFile(db, name, filestore.GetEtag([]byte(content)), int64(len(content)), user.Id, user.Id)
+		assert.NoError(t, err)
+	}
+	writeFile("school/os/scheduling.md", "# Scheduling\n\nProcesses take turns with round robin scheduling.\n")
+	writeFile("todo.md", "- [ ] study for the exam\n")
+	// only notes are indexed
+	writeFile("attachments/diagram.png", "round robin")
+
+	available, err := database.SearchAvailable(db)
+	assert.NoError(t, err)
+	if !available {
+		result := te.run("", "reindex")
+		assert.Equal(t, 1, result.code)
+		assert.Contains(t, result.stderr, database.ErrSearchUnavailable.Error())
+		t.Skip("SQLite was built without FTS5")
+	}
+
+	var view reindexView
+	result := te.runJSON(&view, "reindex")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Equal(t, 2, view.Indexed)
+
+	results, err := database.SearchSyncFiles(db, user.Id, database.SearchQuery{Text: "round robin"})
+	if assert.NoError(t, err) && assert.Len(t, results, 1) {
+		assert.Equal(t, "school/os/scheduling.md", results[0].Filepath)
+	}
+
+	// reindexing again replaces the index instead of adding to it
+	result = te.run("", "reindex")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "Indexed 2 files")
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/log"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/backup"
+	"github.com/raian621/obsync-server/config"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/mailer"
+	"github.com/raian621/obsync-server/server"
+)
+
+func runServe(e *env, args []string) error {
+	if err := e.flags("serve").Parse(args); err != nil {
+		return err
+	}
+	cfg, err := e.loadConfig()
+	if err != nil {
+		return err
+	}
+	startServer(e.dbPath, cfg, context.Background())
+	return nil
+}
+
+// Warn about migrations that were skipped because SQLite wasn't built with the
+// compile option they need, like the search index without FTS5. The server
+// runs without them, but the features they add don't work.
+func warnSkippedMigrations(db *sql.DB, logger echo.Logger) error {
+	statuses, err := database.GetMigrationStatus(db)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if !status.Applied && len(status.MissingOption) > 0 {
+			logger.Warnf("Skipped migration `%s`, it %s", status.Name, missingOptionNote(status.MissingOption))
+		}
+	}
+	return nil
+}
+
+// Back up the database and file store every backup interval until ctx is
+// done.
+func scheduleBackups(ctx context.Context, db *sql.DB, srv *server.ObsyncServer, cfg *config.Config, logger echo.Logger) {
+	interval := cfg.Backup.Interval
+	if interval <= 0 {
+		interval = backup.DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			archivePath, err := backup.Create(db, cfg.Root, backupOptions(cfg, srv.FileLock()))
+			if err != nil {
+				logger.Error(err)
+				continue
+			}
+			logger.Infof("Backed up to %s", archivePath)
+		}
+	}
+}
+
+func startServer(connStr string, cfg *config.Config, serverCtx context.Context) {
+	e := echo.New()
+	e.Use(middleware.Logger())
+	e.Use(server.DecompressRequests(cfg.Import.MaxSize))
+	if cfg.ResponseCompression.Enabled {
+		e.Use(server.CompressResponses(server.CompressionConfig{
+			MinSize:      cfg.ResponseCompression.MinSize,
+			ContentTypes: cfg.ResponseCompression.ContentTypes,
+		}))
+	}
+	e.Logger.SetLevel(log.INFO)
+	ipExtractor, err := server.NewIPExtractor(cfg.TrustedProxies)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	e.IPExtractor = ipExtractor
+	db, err := database.NewDB(connStr)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	database.SetDB(db)
+	if err := database.ApplyMigrations(db); err != nil {
+		e.Logger.Fatal(err)
+	}
+	if err := warnSkippedMigrations(db, e.Logger); err != nil {
+		e.Logger.Fatal(err)
+	}
+	fstore, err := newFileStore(cfg, true)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	obsyncServer := server.NewServerWithFileStore(db, fstore)
+	e.Use(obsyncServer.RequireAdmin())
+	obsyncServer.SetImportLimits(server.ImportLimits{
+		MaxEntries: cfg.Import.MaxEntries,
+		MaxSize:    cfg.Import.MaxSize,
+	})
+	obsyncServer.SetRegistration(server.RegistrationPolicy{
+		Mode:                server.RegistrationMode(cfg.Registration.Mode),
+		AllowedEmailDomains: cfg.Registration.AllowedEmailDomains,
+		RateLimit:           cfg.Registration.RateLimit.Max,
+		RateWindow:          cfg.Registration.RateLimit.Window,
+	})
+	if cfg.SMTP.Enabled {
+		templates, err := mailer.LoadTemplates(cfg.SMTP.Templates)
+		if err != nil {
+			e.Logger.Fatal(err)
+		}
+		sender := mailer.NewSMTPSender(mailer.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+			TLS:      cfg.SMTP.TLS,
+		})
+		obsyncServer.SetMailer(mailer.New(sender, templates), cfg.SMTP.PublicURL)
+	}
+	api.RegisterHandlersWithBaseURL(e, obsyncServer, server.BaseURL)
+	if cfg.WebDAV.Enabled {
+		obsyncServer.RegisterWebDAV(e, cfg.WebDAV.Path)
+	}
+	backupCtx, stopBackups := context.WithCancel(context.Background())
+	var backups sync.WaitGroup
+	if cfg.Backup.Enabled {
+		backups.Add(1)
+		go func() {
+			defer backups.Done()
+			scheduleBackups(backupCtx, db, obsyncServer, cfg, e.Logger)
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(serverCtx, os.Interrupt)
+	go func() {
+		if err := e.Start(fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)); err != nil && err != http.ErrServerClosed {
+			e.Logger.Fatal("shutting down the server")
+		}
+	}()
+
+	// wait for the interrupt signal to gracefully shutdown the server after 5
+	// seconds
+	<-ctx.Done()
+	stop()
+	// thumbnails being generated and backups being made still need the
+	// database
+	stopBackups()
+	backups.Wait()
+	obsyncServer.WaitForThumbnails()
+	obsyncServer.WaitForEmails()
+	// commit the changes still waiting for the commit window to end
+	if history, ok := fstore.(filestore.HistoryFileStore); ok {
+		if err := history.Flush(); err != nil {
+			e.Logger.Error(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		e.Logger.Fatal(err)
+	}
+	e.Logger.Info("Shutting server down...")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		e.Logger.Fatal(err)
+	}
+}
+
+// Create the server's file store, wrapping it in a compressing file store if
+// compression is turned on, or a git file store if history is. Commands that
+// only read files, like fsck, leave out the git history so they don't commit
+// anything.
+func newFileStore(cfg *config.Config, history bool) (filestore.FileStore, error) {
+	fstore, err := filestore.NewFsFileStore(cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.History.Enabled {
+		if !history {
+			// the git history doesn't compress files
+			return fstore, nil
+		}
+		return filestore.NewGitFileStore(fstore, filestore.GitOptions{
+			CommitWindow: cfg.History.CommitWindow,
+			// thumbnails can be generated again, so they aren't kept
+			Exclude: []string{server.ThumbnailDir, server.SnapshotDir},
+		})
+	}
+
+	switch cfg.Compression {
+	case "gzip":
+		return filestore.NewCompressedFileStore(fstore, filestore.CodecGzip)
+	default:
+		return fstore, nil
+	}
+}
@@ -1,12 +1,15 @@
-package main
+package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
 	"testing"
 	"time"
 
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
 	"github.com/raian621/obsync-server/config"
 	"github.com/raian621/obsync-server/database"
 	"github.com/stretchr/testify/assert"
@@ -46,3 +49,29 @@ func TestStartServer(t *testing.T) {
 
 	database.SetDB(nil)
 }
+
+func TestWarnSkippedMigrations(t *testing.T) {
+	t.Parallel()
+
+	db, err := database.NewDB("test-warn-skipped-migrations.db?mode=memory")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	assert.NoError(t, database.ApplyMigrations(db))
+
+	var buf bytes.Buffer
+	logger := echo.New().Logger
+	logger.SetOutput(&buf)
+	logger.SetLevel(log.INFO)
+	assert.NoError(t, warnSkippedMigrations(db, logger))
+
+	available, err := database.SearchAvailable(db)
+	assert.NoError(t, err)
+	if available {
+		assert.Empty(t, buf.String())
+	} else {
+		assert.Contains(t, buf.String(), "CreateSearchIndex")
+		assert.Contains(t, buf.String(), "-tags sqlite_fts5")
+	}
+}
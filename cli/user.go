@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+type userView struct {
+	Id       uint64 `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Disabled bool   `json:"disabled"`
+	IsAdmin  bool   `json:"is_admin"`
+}
+
+func runUser(e *env, args []string) error {
+	name, args, err := subcommand(args)
+	if err != nil {
+		return err
+	}
+	flags := e.flags("user " + name)
+	var (
+		password string
+		search   string
+		yes      bool
+		files    bool
+		admin    bool
+	)
+	switch name {
+	case "create", "reset-password":
+		flags.StringVar(&password, "password", "", "the user's password, which is read from stdin when it's left out")
+		if name == "create" {
+			flags.BoolVar(&admin, "admin", false, "make the user an admin")
+		}
+	case "list":
+		flags.StringVar(&search, "search", "", "only list users whose username or email contains this")
+	case "delete":
+		flags.BoolVar(&yes, "yes", false, "confirm deleting the user")
+		flags.BoolVar(&files, "files", false, "also delete the user's files from the file store")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	db, err := e.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch name {
+	case "create":
+		if len(args) != 2 {
+			return usageError("expected a username and email")
+		}
+		if len(password) == 0 {
+			if password, err = e.readLine(); err != nil {
+				return err
+			}
+		}
+		user, err := database.CreateUser(db, args[0], args[1], password)
+		if err != nil {
+			return err
+		}
+		if admin {
+			if err := database.SetUserAdmin(db, user.Id, true); err != nil {
+				return err
+			}
+			user.IsAdmin = true
+		}
+		return e.printUsers([]*database.User{user})
+
+	case "list":
+		if len(args) != 0 {
+			return usageError("unexpected arguments")
+		}
+		users, err := database.GetUsers(db, search)
+		if err != nil {
+			return err
+		}
+		return e.printUsers(users)
+
+	case "delete":
+		user, err := userArg(db, args)
+		if err != nil {
+			return err
+		}
+		if !yes {
+			return fmt.Errorf("pass -yes to delete %s", user.Username)
+		}
+		// the user's folder in the file store is named after their id
+		userDir := ""
+		if files {
+			cfg, err := e.loadConfig()
+			if err != nil {
+				return err
+			}
+			fstore, err := filestore.NewFsFileStore(cfg.Root)
+			if err != nil {
+				return err
+			}
+			userDir, err = fstore.GetFilePath(strconv.FormatUint(user.Id, 10))
+			if err != nil && !errors.Is(err, filestore.ErrFileNotFound) {
+				return err
+			}
+		}
+		if err := database.DeleteUser(db, user.Id); err != nil {
+			return err
+		}
+		if len(userDir) > 0 {
+			if err := os.RemoveAll(userDir); err != nil {
+				return err
+			}
+		}
+		return e.printMessage(toUserView(user), "Deleted %s", user.Username)
+
+	case "reset-password":
+		user, err := userArg(db, args)
+		if err != nil {
+			return err
+		}
+		if len(password) == 0 {
+			if password, err = e.readLine(); err != nil {
+				return err
+			}
+		}
+		if err := database.UpdateUserPassword(db, user.Id, password); err != nil {
+			return err
+		}
+		// sessions made with the old password shouldn't keep working
+		if err := database.DeleteUserSessions(db, user.Id); err != nil {
+			return err
+		}
+		return e.printMessage(toUserView(user), "Reset the password of %s", user.Username)
+
+	case "disable", "enable":
+		user, err := userArg(db, args)
+		if err != nil {
+			return err
+		}
+		user.Disabled = name == "disable"
+		if err := database.SetUserDisabled(db, user.Id, user.Disabled); err != nil {
+			return err
+		}
+		message := "Enabled %s"
+		if user.Disabled {
+			message = "Disabled %s"
+		}
+		return e.printMessage(toUserView(user), message, user.Username)
+
+	// admins can only be made here, so the first admin of a server is made
+	// by whoever runs it
+	case "promote", "demote":
+		user, err := userArg(db, args)
+		if err != nil {
+			return err
+		}
+		user.IsAdmin = name == "promote"
+		if err := database.SetUserAdmin(db, user.Id, user.IsAdmin); err != nil {
+			return err
+		}
+		message := "%s is no longer an admin"
+		if user.IsAdmin {
+			message = "%s is now an admin"
+		}
+		return e.printMessage(toUserView(user), message, user.Username)
+
+	default:
+		return usageError("unknown subcommand %q", name)
+	}
+}
+
+// Get the user named by a command's only argument.
+func userArg(db *sql.DB, args []string) (*database.User, error) {
+	if len(args) != 1 {
+		return nil, usageError("expected a username")
+	}
+	return findUser(db, args[0])
+}
+
+func findUser(db *sql.DB, username string) (*database.User, error) {
+	user, err := database.GetUserByUsername(db, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, database.ErrUsernameFormat) {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (e *env) printUsers(users []*database.User) error {
+	views := make([]userView, 0, len(users))
+	rows := make([][]string, 0, len(users))
+	for _, user := range users {
+		views = append(views, toUserView(user))
+		rows = append(rows, []string{
+			strconv.FormatUint(user.Id, 10),
+			user.Username,
+			user.Email,
+			strconv.FormatBool(user.Disabled),
+			strconv.FormatBool(user.IsAdmin),
+		})
+	}
+	return e.print(views, []string{"ID", "USERNAME", "EMAIL", "DISABLED", "ADMIN"}, rows)
+}
+
+func toUserView(user *database.User) userView {
+	return userView{Id: user.Id, Username: user.Username, Email: user.Email, Disabled: user.Disabled, IsAdmin: user.IsAdmin}
+}
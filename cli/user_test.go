@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUser(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	db := te.db()
+
+	// the password is read from stdin when it isn't passed as a flag
+	result := te.run("not a password\n", "user", "create", "test-cli-user", "test-cli-user@example.com")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "test-cli-user@example.com")
+	_, err := database.LoginUser(db, "test-cli-user", "not a password")
+	assert.NoError(t, err)
+
+	result = te.run("", "user", "create", "-password", "another password", "test-cli-other", "test-cli-other@example.com")
+	assert.Equal(t, 0, result.code, result.stderr)
+	result = te.run("", "user", "create", "-password", "another password", "test-cli-other", "test-cli-other@example.com")
+	assert.Equal(t, 1, result.code)
+
+	users := []userView{}
+	result = te.runJSON(&users, "user", "list")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Len(t, users, 2)
+	result = te.runJSON(&users, "user", "list", "-search", "other")
+	assert.Equal(t, 0, result.code, result.stderr)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, "test-cli-other", users[0].Username)
+	}
+
+	result = te.run("", "user", "reset-password", "-password", "a new password", "test-cli-user")
+	assert.Equal(t, 0, result.code, result.stderr)
+	_, err = database.LoginUser(db, "test-cli-user", "not a password")
+	assert.ErrorIs(t, err, database.ErrIncorrectCredentials)
+	_, err = database.LoginUser(db, "test-cli-user", "a new password")
+	assert.NoError(t, err)
+
+	result = te.run("", "user", "disable", "test-cli-user")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "Disabled test-cli-user")
+	_, err = database.LoginUser(db, "test-cli-user", "a new password")
+	assert.ErrorIs(t, err, database.ErrUserDisabled)
+	result = te.run("", "user", "enable", "test-cli-user")
+	assert.Equal(t, 0, result.code, result.stderr)
+	_, err = database.LoginUser(db, "test-cli-user", "a new password")
+	assert.NoError(t, err)
+
+	result = te.run("", "user", "disable", "test-cli-nobody")
+	assert.Equal(t, 1, result.code)
+	assert.Contains(t, result.stderr, ErrUserNotFound.Error())
+}
+
+func TestRunUserAdmin(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	db := te.db()
+
+	users := []userView{}
+	result := te.runJSON(&users, "user", "create", "-admin", "-password", "not a password", "test-cli-admin", "test-cli-admin@example.com")
+	assert.Equal(t, 0, result.code, result.stderr)
+	if assert.Len(t, users, 1) {
+		assert.True(t, users[0].IsAdmin)
+	}
+	_, err := database.CreateUser(db, "test-cli-promoted", "test-cli-promoted@example.com", "not a password")
+	assert.NoError(t, err)
+
+	result = te.run("", "user", "promote", "test-cli-promoted")
+	assert.Equal(t, 0, result.code, result.stderr)
+	assert.Contains(t, result.stdout, "test-cli-promoted is now an admin")
+	user, err := database.GetUserByUsername(db, "test-cli-promoted")
+	if assert.NoError(t, err) {
+		assert.True(t, user.IsAdmin)
+	}
+
+	result = te.run("", "user", "demote", "test-cli-admin")
+	assert.Equal(t, 0, result.code, result.stderr)
+	user, err = database.GetUserByUsername(db, "test-cli-admin")
+	if assert.NoError(t, err) {
+		assert.False(t, user.IsAdmin)
+	}
+}
+
+func TestRunUserDelete(t *testing.T) {
+	t.Parallel()
+
+	te := newTestEnv(t, "")
+	db := te.db()
+	user, err := database.CreateUser(db, "test-cli-delete", "test-cli-delete@example.com", "not a password")
+	assert.NoError(t, err)
+	userRoot := filepath.Join(te.root, strconv.FormatUint(user.Id, 10))
+	assert.NoError(t, os.MkdirAll(userRoot, 0777))
+	assert.NoError(t, os.WriteFile(filepath.Join(userRoot, "a.md"), []byte("# A"), 0640))
+	_, err = database.CreateSyncFile(db, "a.md", "07fd4a5d1d7cb1dd2bd0e9b7b3d2d0f1", 3, user.Id, user.Id)
+	assert.NoError(t, err)
+	_, err = database.CreateApiKey(db, user.Id, "laptop", "not a real api key")
+	assert.NoError(t, err)
+	session, err := database.CreateSession(db, user.Id)
+	assert.NoError(t, err)
+
+	srv, err := server.NewServer(db, te.root)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	e := echo.New()
+	api.RegisterHandlersWithBaseURL(e, srv, server.BaseURL)
+	listFiles := func() int {
+		req := httptest.NewRequest(http.MethodGet, server.BaseURL+"/list-files", nil)
+		req.AddCookie(&http.Cookie{Name: "OBSYNC_SESSION_ID", Value: session.SessionKey})
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, listFiles())
+
+	// deleting has to be confirmed
+	result := te.run("", "user", "delete", "test-cli-delete")
+	assert.Equal(t, 1, result.code)
+	_, err = database.GetUserById(db, user.Id)
+	assert.NoError(t, err)
+
+	result = te.run("", "user", "delete", "-yes", "-files", "test-cli-delete")
+	assert.Equal(t, 0, result.code, result.stderr)
+	_, err = database.GetUserById(db, user.Id)
+	assert.Error(t, err)
+	_, err = os.Stat(userRoot)
+	assert.True(t, os.IsNotExist(err))
+
+	// the user's sessions stop working, and nothing of theirs is left behind
+	assert.Equal(t, http.StatusUnauthorized, listFiles())
+	apiKeys, err := database.GetApiKeys(db, user.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, apiKeys)
+	syncFiles, err := database.GetSyncFilesByUserId(db, user.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, syncFiles)
+}
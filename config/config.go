@@ -4,32 +4,135 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"net"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	ErrUnsupportedFileStoreType = errors.New("")
+	ErrUnsupportedFileStoreType = errors.New("unsupported file store type")
+	ErrUnsupportedCompression   = errors.New("unsupported file store compression codec")
+	ErrHistoryWithCompression   = errors.New("file history can't be kept for compressed file stores")
+	ErrBackupDirMissing         = errors.New("backups need a directory to be written to")
+	ErrUnsupportedRegistration  = errors.New("unsupported registration mode, use open, invite or closed")
+	ErrSMTPIncomplete           = errors.New("smtp needs a host and a from address")
+	ErrUnsupportedSMTPTLS       = errors.New("unsupported smtp tls mode, use starttls, tls or none")
+	ErrInvalidTrustedProxy      = errors.New("trusted proxies must be IP addresses or CIDR ranges")
 )
 
 type Config struct {
-	Type string `yaml:"type"`
-	Root string `yaml:"root"`
-	Host string `yaml:"host"`
-	Port uint16 `yaml:"port"`
+	Type                string                    `yaml:"type"`
+	Root                string                    `yaml:"root"`
+	Host                string                    `yaml:"host"`
+	Port                uint16                    `yaml:"port"`
+	TrustedProxies      []string                  `yaml:"trusted_proxies"`
+	Compression         string                    `yaml:"compression"`
+	ResponseCompression ResponseCompressionConfig `yaml:"response_compression"`
+	Import              ImportConfig              `yaml:"import"`
+	History             HistoryConfig             `yaml:"history"`
+	WebDAV              WebDAVConfig              `yaml:"webdav"`
+	Backup              BackupConfig              `yaml:"backup"`
+	Registration        RegistrationConfig        `yaml:"registration"`
+	SMTP                SMTPConfig                `yaml:"smtp"`
+}
+
+type ResponseCompressionConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	MinSize      int      `yaml:"min_size"`
+	ContentTypes []string `yaml:"content_types"`
+}
+
+type ImportConfig struct {
+	MaxEntries int   `yaml:"max_entries"`
+	MaxSize    int64 `yaml:"max_size"`
+}
+
+type HistoryConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	CommitWindow time.Duration `yaml:"commit_window"`
+}
+
+type WebDAVConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+}
+
+type BackupConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Dir      string        `yaml:"dir"`
+	Interval time.Duration `yaml:"interval"`
+	Keep     int           `yaml:"keep"`
+}
+
+type RegistrationConfig struct {
+	// open, invite or closed
+	Mode                string                `yaml:"mode"`
+	AllowedEmailDomains []string              `yaml:"allowed_email_domains"`
+	RateLimit           RegistrationRateLimit `yaml:"rate_limit"`
+}
+
+type RegistrationRateLimit struct {
+	Max    int           `yaml:"max"`
+	Window time.Duration `yaml:"window"`
+}
+
+type SMTPConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	// starttls, tls or none
+	TLS string `yaml:"tls"`
+	// Directory with templates replacing the default emails
+	Templates string `yaml:"templates"`
+	// URL the server is reached at, for links in emails
+	PublicURL string `yaml:"public_url"`
 }
 
 func ReadConfig(source io.Reader) (*Config, error) {
 	decoder := yaml.NewDecoder(source)
 	var config Config
 	if err := decoder.Decode(&config); err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	if config.Type != "FileSystem" {
 		return nil, ErrUnsupportedFileStoreType
 	}
+	switch config.Compression {
+	case "", "none", "gzip":
+	default:
+		return nil, ErrUnsupportedCompression
+	}
+	// git would keep the compressed files, which can't be diffed
+	if config.History.Enabled && config.Compression == "gzip" {
+		return nil, ErrHistoryWithCompression
+	}
+	if config.Backup.Enabled && len(config.Backup.Dir) == 0 {
+		return nil, ErrBackupDirMissing
+	}
+	switch config.Registration.Mode {
+	case "", "open", "invite", "closed":
+	default:
+		return nil, ErrUnsupportedRegistration
+	}
+	if config.SMTP.Enabled && (len(config.SMTP.Host) == 0 || len(config.SMTP.From) == 0) {
+		return nil, ErrSMTPIncomplete
+	}
+	switch config.SMTP.TLS {
+	case "", "starttls", "tls", "none":
+	default:
+		return nil, ErrUnsupportedSMTPTLS
+	}
+	for _, proxy := range config.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return nil, ErrInvalidTrustedProxy
+		}
+	}
 
 	return &config, nil
 }
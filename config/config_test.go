@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -38,6 +39,270 @@ host: localhost
 port: 8000`,
 			wantErr: ErrUnsupportedFileStoreType,
 		},
+		{
+			name: "load config with compression",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+compression: gzip`,
+			wantConfig: Config{
+				Type:        "FileSystem",
+				Root:        "/tmp/obsync-dev",
+				Host:        "localhost",
+				Port:        8000,
+				Compression: "gzip",
+			},
+		},
+		{
+			name: "load config with response compression",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+response_compression:
+  enabled: true
+  min_size: 512
+  content_types: [text/, application/json]`,
+			wantConfig: Config{
+				Type: "FileSystem",
+				Root: "/tmp/obsync-dev",
+				Host: "localhost",
+				Port: 8000,
+				ResponseCompression: ResponseCompressionConfig{
+					Enabled:      true,
+					MinSize:      512,
+					ContentTypes: []string{"text/", "application/json"},
+				},
+			},
+		},
+		{
+			name: "load config with import limits",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+import:
+  max_entries: 500
+  max_size: 1048576`,
+			wantConfig: Config{
+				Type: "FileSystem",
+				Root: "/tmp/obsync-dev",
+				Host: "localhost",
+				Port: 8000,
+				Import: ImportConfig{
+					MaxEntries: 500,
+					MaxSize:    1048576,
+				},
+			},
+		},
+		{
+			name: "load config with history",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+history:
+  enabled: true
+  commit_window: 30s`,
+			wantConfig: Config{
+				Type: "FileSystem",
+				Root: "/tmp/obsync-dev",
+				Host: "localhost",
+				Port: 8000,
+				History: HistoryConfig{
+					Enabled:      true,
+					CommitWindow: 30 * time.Second,
+				},
+			},
+		},
+		{
+			name: "load config with webdav",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+webdav:
+  enabled: true
+  path: /webdav`,
+			wantConfig: Config{
+				Type: "FileSystem",
+				Root: "/tmp/obsync-dev",
+				Host: "localhost",
+				Port: 8000,
+				WebDAV: WebDAVConfig{
+					Enabled: true,
+					Path:    "/webdav",
+				},
+			},
+		},
+		{
+			name: "load config with backups",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+backup:
+  enabled: true
+  dir: /tmp/obsync-backups
+  interval: 6h
+  keep: 4`,
+			wantConfig: Config{
+				Type: "FileSystem",
+				Root: "/tmp/obsync-dev",
+				Host: "localhost",
+				Port: 8000,
+				Backup: BackupConfig{
+					Enabled:  true,
+					Dir:      "/tmp/obsync-backups",
+					Interval: 6 * time.Hour,
+					Keep:     4,
+				},
+			},
+		},
+		{
+			name: "backups without a directory",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+backup:
+  enabled: true`,
+			wantErr: ErrBackupDirMissing,
+		},
+		{
+			name: "history with compression",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+compression: gzip
+history:
+  enabled: true`,
+			wantErr: ErrHistoryWithCompression,
+		},
+		{
+			name: "load config with registration",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+registration:
+  mode: invite
+  allowed_email_domains: [example.com]
+  rate_limit:
+    max: 3
+    window: 10m`,
+			wantConfig: Config{
+				Type: "FileSystem",
+				Root: "/tmp/obsync-dev",
+				Host: "localhost",
+				Port: 8000,
+				Registration: RegistrationConfig{
+					Mode:                "invite",
+					AllowedEmailDomains: []string{"example.com"},
+					RateLimit:           RegistrationRateLimit{Max: 3, Window: 10 * time.Minute},
+				},
+			},
+		},
+		{
+			name: "unsupported registration mode",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+registration:
+  mode: lottery`,
+			wantErr: ErrUnsupportedRegistration,
+		},
+		{
+			name: "load config with smtp",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+smtp:
+  enabled: true
+  host: smtp.example.com
+  port: 465
+  username: obsync
+  password: hunter2
+  from: Obsync <obsync@example.com>
+  tls: tls
+  templates: /etc/obsync/templates
+  public_url: https://obsync.example.com`,
+			wantConfig: Config{
+				Type: "FileSystem",
+				Root: "/tmp/obsync-dev",
+				Host: "localhost",
+				Port: 8000,
+				SMTP: SMTPConfig{
+					Enabled:   true,
+					Host:      "smtp.example.com",
+					Port:      465,
+					Username:  "obsync",
+					Password:  "hunter2",
+					From:      "Obsync <obsync@example.com>",
+					TLS:       "tls",
+					Templates: "/etc/obsync/templates",
+					PublicURL: "https://obsync.example.com",
+				},
+			},
+		},
+		{
+			name: "smtp without a from address",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+smtp:
+  enabled: true
+  host: smtp.example.com`,
+			wantErr: ErrSMTPIncomplete,
+		},
+		{
+			name: "unsupported smtp tls mode",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+smtp:
+  tls: ssl`,
+			wantErr: ErrUnsupportedSMTPTLS,
+		},
+		{
+			name: "load config with trusted proxies",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+trusted_proxies: [127.0.0.1, "10.0.0.0/8", "::1"]`,
+			wantConfig: Config{
+				Type:           "FileSystem",
+				Root:           "/tmp/obsync-dev",
+				Host:           "localhost",
+				Port:           8000,
+				TrustedProxies: []string{"127.0.0.1", "10.0.0.0/8", "::1"},
+			},
+		},
+		{
+			name: "invalid trusted proxy",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+trusted_proxies: [proxy.example.com]`,
+			wantErr: ErrInvalidTrustedProxy,
+		},
+		{
+			name: "unsupported compression",
+			configText: `type: FileSystem
+root: /tmp/obsync-dev
+host: localhost
+port: 8000
+compression: lzma`,
+			wantErr: ErrUnsupportedCompression,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -77,40 +77,55 @@ func DeleteApiKey(db *sql.DB, userId uint64, name string) error {
 	return err
 }
 
+// Activate or deactivate an API key, returning ErrNoResults if the user has no
+// key with the name.
 func SetApiKeyActivation(db *sql.DB, userId uint64, name string, active bool) error {
-	_, err := db.Exec(
+	res, err := db.Exec(
 		"UPDATE api_keys SET active=? WHERE user_id=? AND name=?",
 		active,
 		userId,
 		name,
 	)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrNoResults
+	}
 
-	return err
+	return nil
 }
 
 func GetApiKeys(db *sql.DB, userId uint64) ([]*ApiKey, error) {
 	rows, err := db.Query(
-		"SELECT id, user_id, name, hash, active, created_at"+
+		"SELECT id, user_id, name, hash, active, created_at "+
 			"FROM api_keys WHERE user_id=?",
 		userId,
 	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	var apiKeys []*ApiKey
 	for rows.Next() {
 		var apiKey ApiKey
+		var createdAt string
 		err := rows.Scan(
 			&apiKey.Id,
 			&apiKey.UserId,
 			&apiKey.Name,
 			&apiKey.Hash,
 			&apiKey.Active,
-			&apiKey.CreatedAt,
+			&createdAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if apiKey.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt); err != nil {
+			return nil, err
+		}
 		apiKeys = append(apiKeys, &apiKey)
 	}
 
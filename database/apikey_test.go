@@ -114,3 +114,24 @@ func TestSetApiKeyActivation(t *testing.T) {
 
 	tearDownApiKeyTest(t, testdb, user, []*ApiKey{apiKey})
 }
+
+func TestGetApiKeys(t *testing.T) {
+	t.Parallel()
+
+	testdb, user := setUpApiKeyTest(t, "test-get-api-keys.db")
+	key, err := generateKey(ApiKeyLength)
+	assert.NoError(t, err)
+	apiKey, err := CreateApiKey(testdb, user.Id, "test-get-name", key)
+	assert.NoError(t, err)
+
+	apiKeys, err := GetApiKeys(testdb, user.Id)
+	if assert.NoError(t, err) && assert.Len(t, apiKeys, 1) {
+		assert.Equal(t, apiKey.Id, apiKeys[0].Id)
+		assert.Equal(t, apiKey.Name, apiKeys[0].Name)
+		assert.True(t, apiKeys[0].Active)
+		assert.True(t, apiKey.CreatedAt.Equal(apiKeys[0].CreatedAt))
+		assert.NoError(t, ValidateHash(key, apiKeys[0].Hash))
+	}
+
+	tearDownApiKeyTest(t, testdb, user, []*ApiKey{apiKey})
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	ErrNotSQLite       = errors.New("database connection is not a SQLite connection")
+	ErrDatabaseCorrupt = errors.New("database failed its integrity check")
+)
+
+// Time to wait before trying to copy a database again while another
+// connection has it locked.
+const backupRetryDelay = 10 * time.Millisecond
+
+// Copy a live database to a new database file at destPath with SQLite's
+// online backup API. Unlike copying the database's file, the copy is always
+// consistent, even while other connections change the database.
+func Backup(db *sql.DB, destPath string) error {
+	ctx := context.Background()
+	destDB, err := sql.Open(SQL_PROVIDER, "file:"+destPath)
+	if err != nil {
+		return err
+	}
+	defer destDB.Close()
+
+	srcConn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	return destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			dest, ok := destRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return ErrNotSQLite
+			}
+			src, ok := srcRaw.(*sqlite3.SQLiteConn)
+			if !ok {
+				return ErrNotSQLite
+			}
+
+			backup, err := dest.Backup("main", src, "main")
+			if err != nil {
+				return err
+			}
+			// the whole database is copied in one step, which doesn't finish
+			// while another connection is writing to it
+			for {
+				done, err := backup.Step(-1)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					return backup.Finish()
+				}
+				time.Sleep(backupRetryDelay)
+			}
+		})
+	})
+}
+
+// Check a database for corruption with SQLite's integrity check.
+func CheckIntegrity(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	problems := []string{}
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return err
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %v", ErrDatabaseCorrupt, problems)
+	}
+
+	return nil
+}
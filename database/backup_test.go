@@ -0,0 +1,42 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackup(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	testdb, err := sql.Open(SQL_PROVIDER, "file:"+filepath.Join(dir, "live.db"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer testdb.Close()
+	assert.NoError(t, ApplyMigrations(testdb))
+	user, err := CreateUser(testdb, "test-backup", "test-backup@example.com", "not a secure password")
+	assert.NoError(t, err)
+
+	backupPath := filepath.Join(dir, "backup.db")
+	assert.NoError(t, Backup(testdb, backupPath))
+	// changes made after the backup aren't in it
+	_, err = CreateUser(testdb, "test-backup-later", "test-backup-later@example.com", "not a secure password")
+	assert.NoError(t, err)
+
+	backupdb, err := sql.Open(SQL_PROVIDER, "file:"+backupPath)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer backupdb.Close()
+	assert.NoError(t, CheckIntegrity(backupdb))
+	restored, err := GetUserByUsername(backupdb, "test-backup")
+	if assert.NoError(t, err) {
+		assert.Equal(t, user.Id, restored.Id)
+	}
+	_, err = GetUserByUsername(backupdb, "test-backup-later")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -57,5 +57,8 @@ func getAbsoluteDbPath(path string) (string, error) {
 	if err != nil {
 		return "", nil
 	}
+	if filepath.IsAbs(path) {
+		return fmt.Sprintf("file:%s", path), nil
+	}
 	return fmt.Sprintf("file:%s", filepath.Join(cwd, path)), nil
 }
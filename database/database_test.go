@@ -37,3 +37,27 @@ func TestGetDB(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, gotdb, testdb)
 }
+
+func TestGetMigrationStatus(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-migration-status.db?mode=memory")
+	assert.NoError(t, err)
+
+	statuses, err := GetMigrationStatus(testdb)
+	if assert.NoError(t, err) && assert.Len(t, statuses, len(migrations)) {
+		for _, status := range statuses {
+			assert.False(t, status.Applied)
+		}
+	}
+
+	assert.NoError(t, ApplyMigrations(testdb))
+	statuses, err = GetMigrationStatus(testdb)
+	if assert.NoError(t, err) {
+		for _, status := range statuses {
+			// migrations are only skipped when SQLite is missing an option
+			assert.True(t, status.Applied || len(status.MissingOption) > 0, status.Name)
+		}
+		assert.Equal(t, "CreateUsersTable", statuses[0].Name)
+	}
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// What an email token lets its holder do.
+type EmailTokenPurpose string
+
+const (
+	// Prove the user owns the email address the token was sent to
+	PurposeVerifyEmail EmailTokenPurpose = "verify_email"
+	// Set a new password without knowing the old one
+	PurposeResetPassword EmailTokenPurpose = "reset_password"
+)
+
+const EmailTokenBytes = 32
+
+var ErrTokenExpired = errors.New("token expired")
+
+// Single-use token sent to a user's email. Only a hash of the token is
+// stored, so tokens can't be read back out of the database.
+type EmailToken struct {
+	Id      uint64
+	UserId  uint64
+	Purpose EmailTokenPurpose
+	// Address the token was sent to
+	Email     string
+	Expires   time.Time
+	CreatedAt time.Time
+}
+
+// Create a token for sending to an email address, replacing the user's
+// earlier tokens for the same purpose. The token is returned so it can be
+// sent, since only its hash is stored.
+func CreateEmailToken(db *sql.DB, userId uint64, purpose EmailTokenPurpose, email string, lifetime time.Duration) (string, error) {
+	b, err := randomBytes(EmailTokenBytes)
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now().UTC()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"DELETE FROM email_tokens WHERE (user_id=? AND purpose=?) OR expires<datetime('now')",
+		userId,
+		purpose,
+	)
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO email_tokens (token_hash, purpose, email, expires, created_at, user_id)\n"+
+			"  VALUES (:token_hash, :purpose, :email, :expires, :created_at, :user_id)",
+		sql.Named("token_hash", hashEmailToken(token)),
+		sql.Named("purpose", purpose),
+		sql.Named("email", email),
+		sql.Named("expires", now.Add(lifetime)),
+		sql.Named("created_at", now),
+		sql.Named("user_id", userId),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return token, tx.Commit()
+}
+
+// Mark a user's email as verified with a token, using up the token. It
+// returns ErrNoResults if no token matches and ErrTokenExpired if the token
+// expired. Tokens sent to an address the user has since changed from are
+// treated as not matching.
+func VerifyEmailWithToken(db *sql.DB, token string) (*User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	emailToken, err := useEmailToken(tx, PurposeVerifyEmail, token)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.Exec(
+		"UPDATE users SET email_verified=TRUE WHERE id=? AND email=?",
+		emailToken.UserId,
+		emailToken.Email,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if count == 0 {
+		return nil, ErrNoResults
+	}
+	user, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id=?", emailToken.UserId))
+	if err != nil {
+		return nil, err
+	}
+
+	return user, tx.Commit()
+}
+
+// Set a user's password with a token, using up the token and logging the user
+// out everywhere. It returns ErrNoResults if no token matches,
+// ErrTokenExpired if the token expired, and ErrPasswordLength for short
+// passwords, which don't use up the token.
+func ResetPasswordWithToken(db *sql.DB, token, password string) (*User, error) {
+	if len(password) < 8 {
+		return nil, ErrPasswordLength
+	}
+	passhash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	emailToken, err := useEmailToken(tx, PurposeResetPassword, token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := scanUser(tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id=?", emailToken.UserId))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE users SET passhash=? WHERE id=?", passhash, user.Id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM sessions WHERE user_id=?", user.Id); err != nil {
+		return nil, err
+	}
+	user.Passhash = passhash
+
+	return user, tx.Commit()
+}
+
+// Delete the token matching token and purpose, returning it if it hasn't
+// expired. Expired tokens are deleted too, but only if the transaction is
+// committed.
+func useEmailToken(tx *sql.Tx, purpose EmailTokenPurpose, token string) (*EmailToken, error) {
+	emailToken, err := scanEmailToken(tx.QueryRow(
+		"SELECT id, user_id, purpose, email, expires, created_at FROM email_tokens\n"+
+			"  WHERE token_hash=? AND purpose=?",
+		hashEmailToken(token),
+		purpose,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("DELETE FROM email_tokens WHERE id=?", emailToken.Id); err != nil {
+		return nil, err
+	}
+	if emailToken.Expires.Before(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+	return emailToken, nil
+}
+
+// Tokens have enough entropy that a fast hash is as safe as a password hash,
+// and unlike a salted hash it lets tokens be looked up by their hash.
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func scanEmailToken(row Scannable) (*EmailToken, error) {
+	var (
+		token     EmailToken
+		expires   string
+		createdAt string
+	)
+
+	err := row.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Purpose,
+		&token.Email,
+		&expires,
+		&createdAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	if token.Expires, err = time.Parse(ISO_8601_FORMAT, expires); err != nil {
+		return nil, err
+	}
+	if token.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyEmailWithToken(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-verify-email.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-verify", "test-verify@example.com", "not a secure password")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.False(t, user.EmailVerified)
+
+	token, err := CreateEmailToken(testdb, user.Id, PurposeVerifyEmail, user.Email, time.Hour)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, token, 43)
+	var stored int
+	assert.NoError(t, testdb.QueryRow("SELECT COUNT(*) FROM email_tokens WHERE token_hash=?", token).Scan(&stored))
+	assert.Zero(t, stored, "tokens are only stored hashed")
+
+	// tokens only work for their purpose
+	_, err = ResetPasswordWithToken(testdb, token, "a new password")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	verified, err := VerifyEmailWithToken(testdb, token)
+	if assert.NoError(t, err) {
+		assert.True(t, verified.EmailVerified)
+	}
+	_, err = VerifyEmailWithToken(testdb, token)
+	assert.ErrorIs(t, err, ErrNoResults, "tokens can only be used once")
+
+	// changing the email address needs it verified again
+	assert.NoError(t, UpdateUserEmail(testdb, user.Id, "test-verify@example.com"))
+	found, _ := GetUserById(testdb, user.Id)
+	assert.True(t, found.EmailVerified)
+	assert.NoError(t, UpdateUserEmail(testdb, user.Id, "test-verify-2@example.com"))
+	found, _ = GetUserById(testdb, user.Id)
+	assert.False(t, found.EmailVerified)
+
+	// a token for the old address doesn't verify the new one
+	token, err = CreateEmailToken(testdb, user.Id, PurposeVerifyEmail, "test-verify@example.com", time.Hour)
+	assert.NoError(t, err)
+	_, err = VerifyEmailWithToken(testdb, token)
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	// a newer token replaces older ones
+	older, err := CreateEmailToken(testdb, user.Id, PurposeVerifyEmail, "test-verify-2@example.com", time.Hour)
+	assert.NoError(t, err)
+	newer, err := CreateEmailToken(testdb, user.Id, PurposeVerifyEmail, "test-verify-2@example.com", time.Hour)
+	assert.NoError(t, err)
+	_, err = VerifyEmailWithToken(testdb, older)
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	expired, err := CreateEmailToken(testdb, user.Id, PurposeVerifyEmail, "test-verify-2@example.com", -time.Minute)
+	assert.NoError(t, err)
+	_, err = VerifyEmailWithToken(testdb, expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+	_, err = VerifyEmailWithToken(testdb, newer)
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	// deleting users deletes their tokens
+	_, err = CreateEmailToken(testdb, user.Id, PurposeVerifyEmail, "test-verify-2@example.com", time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, DeleteUser(testdb, user.Id))
+	var count int
+	assert.NoError(t, testdb.QueryRow("SELECT COUNT(*) FROM email_tokens").Scan(&count))
+	assert.Zero(t, count)
+}
+
+func TestResetPasswordWithToken(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-reset-password.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-reset", "test-reset@example.com", "not a secure password")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := CreateSession(testdb, user.Id)
+	assert.NoError(t, err)
+
+	token, err := CreateEmailToken(testdb, user.Id, PurposeResetPassword, user.Email, time.Hour)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = ResetPasswordWithToken(testdb, token, "short")
+	assert.ErrorIs(t, err, ErrPasswordLength)
+
+	reset, err := ResetPasswordWithToken(testdb, token, "a new password")
+	if assert.NoError(t, err, "short passwords don't use up the token") {
+		assert.Equal(t, user.Id, reset.Id)
+	}
+	found, _ := GetUserById(testdb, user.Id)
+	assert.NoError(t, ValidateHash("a new password", found.Passhash))
+	_, err = GetSessionBySessionKey(testdb, session.SessionKey)
+	assert.Error(t, err, "resetting a password logs the user out")
+
+	_, err = ResetPasswordWithToken(testdb, token, "another password")
+	assert.ErrorIs(t, err, ErrNoResults)
+	_, err = ResetPasswordWithToken(testdb, "missing", "another password")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	expired, err := CreateEmailToken(testdb, user.Id, PurposeResetPassword, user.Email, -time.Minute)
+	assert.NoError(t, err)
+	_, err = ResetPasswordWithToken(testdb, expired, "another password")
+	assert.ErrorIs(t, err, ErrTokenExpired)
+	found, _ = GetUserById(testdb, user.Id)
+	assert.NoError(t, ValidateHash("a new password", found.Passhash))
+
+	assert.NoError(t, DeleteUser(testdb, user.Id))
+}
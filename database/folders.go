@@ -0,0 +1,126 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrFolderExists = errors.New("folder already exists")
+)
+
+// Folder that was created explicitly. Folders that contain sync files exist
+// without a row, so these are the folders that can be empty.
+type Folder struct {
+	Id     uint64
+	UserId uint64
+	// Path of the folder, without leading or trailing slashes
+	Path      string
+	CreatedAt time.Time
+}
+
+func CreateFolder(db *sql.DB, userId uint64, path string) (*Folder, error) {
+	createdAt := time.Now().UTC()
+	res, err := db.Exec(
+		"INSERT INTO folders (user_id, path, created_at) VALUES (:user_id, :path, :created_at)\n"+
+			"  ON CONFLICT (user_id, path) DO NOTHING",
+		sql.Named("user_id", userId),
+		sql.Named("path", path),
+		sql.Named("created_at", createdAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if count == 0 {
+		return nil, ErrFolderExists
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Folder{Id: uint64(id), UserId: userId, Path: path, CreatedAt: createdAt}, nil
+}
+
+func GetFolder(db *sql.DB, userId uint64, path string) (*Folder, error) {
+	row := db.QueryRow(
+		"SELECT id, user_id, path, created_at FROM folders WHERE user_id=? AND path=?",
+		userId,
+		path,
+	)
+
+	return scanFolder(row)
+}
+
+// Get a user's created folders inside a folder and its subfolders, sorted by
+// path. An empty parent gets every created folder.
+func GetFolders(db *sql.DB, userId uint64, parent string) ([]*Folder, error) {
+	where := "user_id = ?"
+	args := []any{userId}
+	if len(parent) > 0 {
+		where += " AND path > ?"
+		args = append(args, parent+"/")
+		if upper, ok := prefixUpperBound(parent + "/"); ok {
+			where += " AND path < ?"
+			args = append(args, upper)
+		}
+	}
+
+	rows, err := db.Query(
+		"SELECT id, user_id, path, created_at FROM folders WHERE "+where+" ORDER BY path",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []*Folder{}
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+
+	return folders, rows.Err()
+}
+
+func DeleteFolder(db *sql.DB, userId uint64, path string) error {
+	res, err := db.Exec("DELETE FROM folders WHERE user_id=? AND path=?", userId, path)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrNoResults
+	}
+
+	return nil
+}
+
+func scanFolder(row Scannable) (*Folder, error) {
+	var (
+		folder    Folder
+		createdAt string
+	)
+
+	if err := row.Scan(&folder.Id, &folder.UserId, &folder.Path, &createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	var err error
+	folder.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &folder, nil
+}
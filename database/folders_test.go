@@ -0,0 +1,60 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolders(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("folders.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-folders", "test-folders@example.com", "not a secure password")
+	assert.NoError(t, err)
+	other, err := CreateUser(testdb, "test-folders-other", "test-folders-other@example.com", "not a secure password")
+	assert.NoError(t, err)
+
+	for _, path := range []string{"notes", "notes/drafts", "notes/drafts/old", "notes2", "archive"} {
+		_, err := CreateFolder(testdb, user.Id, path)
+		assert.NoError(t, err, path)
+	}
+	_, err = CreateFolder(testdb, user.Id, "notes")
+	assert.ErrorIs(t, err, ErrFolderExists)
+	// folder paths are unique for each user
+	_, err = CreateFolder(testdb, other.Id, "notes")
+	assert.NoError(t, err)
+
+	folder, err := GetFolder(testdb, user.Id, "notes/drafts")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "notes/drafts", folder.Path)
+		assert.False(t, folder.CreatedAt.IsZero())
+	}
+	_, err = GetFolder(testdb, user.Id, "missing")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	paths := func(parent string) []string {
+		t.Helper()
+		folders, err := GetFolders(testdb, user.Id, parent)
+		assert.NoError(t, err)
+		paths := []string{}
+		for _, folder := range folders {
+			paths = append(paths, folder.Path)
+		}
+		return paths
+	}
+	assert.Equal(t, []string{"archive", "notes", "notes/drafts", "notes/drafts/old", "notes2"}, paths(""))
+	assert.Equal(t, []string{"notes/drafts", "notes/drafts/old"}, paths("notes"))
+	assert.Equal(t, []string{}, paths("notes/drafts/old"))
+
+	assert.NoError(t, DeleteFolder(testdb, user.Id, "notes/drafts/old"))
+	assert.ErrorIs(t, DeleteFolder(testdb, user.Id, "notes/drafts/old"), ErrNoResults)
+	assert.Equal(t, []string{"notes/drafts"}, paths("notes"))
+
+	assert.NoError(t, DeleteUser(testdb, user.Id))
+	assert.Equal(t, []string{}, paths(""))
+	assert.NoError(t, DeleteUser(testdb, other.Id))
+}
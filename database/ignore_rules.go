@@ -0,0 +1,56 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Files a vault doesn't sync, written in gitignore syntax.
+type IgnoreRules struct {
+	// Id of the user that owns the vault
+	UserId uint64
+	Rules  string
+	// When the rules were last changed, or the zero time for vaults that
+	// never had rules
+	UpdatedAt time.Time
+}
+
+// Get the ignore rules of a user's vault. Vaults without rules get empty
+// rules.
+func GetIgnoreRules(db *sql.DB, userId uint64) (*IgnoreRules, error) {
+	rules := IgnoreRules{UserId: userId}
+	var updatedAt string
+
+	row := db.QueryRow("SELECT rules, updated_at FROM ignore_rules WHERE user_id=?", userId)
+	if err := row.Scan(&rules.Rules, &updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &rules, nil
+		}
+		return nil, err
+	}
+	updated, err := time.Parse(ISO_8601_FORMAT, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rules.UpdatedAt = updated
+
+	return &rules, nil
+}
+
+// Replace the ignore rules of a user's vault.
+func SetIgnoreRules(db *sql.DB, userId uint64, rules string) (*IgnoreRules, error) {
+	updatedAt := time.Now().UTC()
+	_, err := db.Exec(
+		"INSERT INTO ignore_rules (user_id, rules, updated_at) VALUES (:user_id, :rules, :updated_at)\n"+
+			"  ON CONFLICT (user_id) DO UPDATE SET rules = excluded.rules, updated_at = excluded.updated_at",
+		sql.Named("user_id", userId),
+		sql.Named("rules", rules),
+		sql.Named("updated_at", updatedAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IgnoreRules{UserId: userId, Rules: rules, UpdatedAt: updatedAt}, nil
+}
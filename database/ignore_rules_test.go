@@ -0,0 +1,40 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("ignore_rules.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-ignore-rules", "test-ignore-rules@example.com", "not a secure password")
+	assert.NoError(t, err)
+
+	rules, err := GetIgnoreRules(testdb, user.Id)
+	if assert.NoError(t, err) {
+		assert.Empty(t, rules.Rules)
+		assert.True(t, rules.UpdatedAt.IsZero())
+	}
+
+	for _, want := range []string{".trash/\n*.mp4\n", ".obsidian/workspace.json\n"} {
+		set, err := SetIgnoreRules(testdb, user.Id, want)
+		assert.NoError(t, err)
+		rules, err = GetIgnoreRules(testdb, user.Id)
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, rules.Rules)
+			assert.True(t, set.UpdatedAt.Equal(rules.UpdatedAt))
+		}
+	}
+
+	assert.NoError(t, DeleteUser(testdb, user.Id))
+	rules, err = GetIgnoreRules(testdb, user.Id)
+	if assert.NoError(t, err) {
+		assert.Empty(t, rules.Rules)
+	}
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+const InviteCodeBytes = 12
+
+var (
+	ErrInviteExpired = errors.New("invite expired")
+	ErrInviteUsedUp  = errors.New("invite reached its use limit")
+)
+
+// Code that lets someone create an account when registration is invite-only.
+type Invite struct {
+	Id     uint64
+	Code   string
+	UserId uint64
+	// When the invite expires, or the zero time for invites that don't expire
+	Expires time.Time
+	// Number of accounts the invite can create, or 0 for no limit
+	MaxUses   int
+	Uses      int
+	CreatedAt time.Time
+}
+
+// Create an invite with a random code.
+func CreateInvite(db *sql.DB, invite *Invite) (*Invite, error) {
+	b, err := randomBytes(InviteCodeBytes)
+	if err != nil {
+		return nil, err
+	}
+	created := *invite
+	created.Code = base64.RawURLEncoding.EncodeToString(b)
+	created.CreatedAt = time.Now().UTC()
+	created.Uses = 0
+
+	res, err := db.Exec(
+		"INSERT INTO invites (code, expires, max_uses, created_at, user_id)\n"+
+			"  VALUES (:code, :expires, :max_uses, :created_at, :user_id)",
+		sql.Named("code", created.Code),
+		sql.Named("expires", sql.NullTime{Time: created.Expires.UTC(), Valid: !created.Expires.IsZero()}),
+		sql.Named("max_uses", sql.NullInt64{Int64: int64(created.MaxUses), Valid: created.MaxUses > 0}),
+		sql.Named("created_at", created.CreatedAt),
+		sql.Named("user_id", created.UserId),
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	created.Id = uint64(id)
+
+	return &created, nil
+}
+
+// Get a user's invites, newest first.
+func GetUserInvites(db *sql.DB, userId uint64) ([]*Invite, error) {
+	rows, err := db.Query(selectInvites+" WHERE user_id=? ORDER BY id DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []*Invite{}
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, rows.Err()
+}
+
+// Revoke one of a user's invites, returning ErrNoResults if the user doesn't
+// have an invite with the id.
+func DeleteInvite(db *sql.DB, userId, id uint64) error {
+	res, err := db.Exec("DELETE FROM invites WHERE id=? AND user_id=?", id, userId)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrNoResults
+	}
+	return nil
+}
+
+// Create a user with an invite code, using up one of the invite's uses. It
+// returns ErrNoResults if there's no invite with the code, ErrInviteExpired
+// or ErrInviteUsedUp if the invite can't be used anymore, and the errors of
+// CreateUser otherwise. The invite is only used up if the user is created.
+func CreateUserWithInvite(db *sql.DB, username, email, password, code string) (*User, error) {
+	user, err := newUser(username, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invite, err := scanInvite(tx.QueryRow(selectInvites+" WHERE code=?", code))
+	if err != nil {
+		return nil, err
+	}
+	if !invite.Expires.IsZero() && invite.Expires.Before(time.Now()) {
+		return nil, ErrInviteExpired
+	}
+	res, err := tx.Exec(
+		"UPDATE invites SET uses=uses+1 WHERE id=? AND (max_uses IS NULL OR uses < max_uses)",
+		invite.Id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if count == 0 {
+		return nil, ErrInviteUsedUp
+	}
+	if err := insertUser(tx, user); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+const selectInvites = "SELECT id, code, user_id, expires, max_uses, uses, created_at FROM invites"
+
+func scanInvite(row Scannable) (*Invite, error) {
+	var (
+		invite    Invite
+		expires   sql.NullString
+		maxUses   sql.NullInt64
+		createdAt string
+	)
+
+	err := row.Scan(
+		&invite.Id,
+		&invite.Code,
+		&invite.UserId,
+		&expires,
+		&maxUses,
+		&invite.Uses,
+		&createdAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	invite.MaxUses = int(maxUses.Int64)
+	if expires.Valid {
+		if invite.Expires, err = time.Parse(ISO_8601_FORMAT, expires.String); err != nil {
+			return nil, err
+		}
+	}
+	if invite.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt); err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
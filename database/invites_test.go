@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvites(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-invites.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-invites", "test-invites@example.com", "not a secure password")
+	assert.NoError(t, err)
+	once, err := CreateInvite(testdb, &Invite{UserId: user.Id, MaxUses: 1})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, once.Code, 16)
+	expired, err := CreateInvite(testdb, &Invite{UserId: user.Id, Expires: time.Now().Add(-time.Minute)})
+	assert.NoError(t, err)
+	unlimited, err := CreateInvite(testdb, &Invite{UserId: user.Id, Expires: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+
+	invites, err := GetUserInvites(testdb, user.Id)
+	if assert.NoError(t, err) && assert.Len(t, invites, 3) {
+		assert.Equal(t, unlimited.Id, invites[0].Id)
+		assert.Equal(t, once.Code, invites[2].Code)
+		assert.Equal(t, 1, invites[2].MaxUses)
+	}
+
+	invited, err := CreateUserWithInvite(testdb, "test-invited", "test-invited@example.com", "not a secure password", once.Code)
+	if assert.NoError(t, err) {
+		found, err := GetUserById(testdb, invited.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "test-invited", found.Username)
+	}
+	_, err = CreateUserWithInvite(testdb, "test-invited-2", "test-invited-2@example.com", "not a secure password", once.Code)
+	assert.ErrorIs(t, err, ErrInviteUsedUp)
+	_, err = CreateUserWithInvite(testdb, "test-invited-2", "test-invited-2@example.com", "not a secure password", expired.Code)
+	assert.ErrorIs(t, err, ErrInviteExpired)
+	_, err = CreateUserWithInvite(testdb, "test-invited-2", "test-invited-2@example.com", "not a secure password", "missing")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	// invites aren't used up by users that can't be created
+	_, err = CreateUserWithInvite(testdb, "test-invited", "test-invited@example.com", "not a secure password", unlimited.Code)
+	assert.Error(t, err)
+	_, err = CreateUserWithInvite(testdb, "test-invited-2", "test-invited-2@example.com", "short", unlimited.Code)
+	assert.ErrorIs(t, err, ErrPasswordLength)
+	for _, username := range []string{"test-invited-3", "test-invited-4"} {
+		_, err = CreateUserWithInvite(testdb, username, username+"@example.com", "not a secure password", unlimited.Code)
+		assert.NoError(t, err)
+	}
+	invites, err = GetUserInvites(testdb, user.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, invites[0].Uses)
+	}
+
+	assert.NoError(t, DeleteInvite(testdb, user.Id, once.Id))
+	assert.ErrorIs(t, DeleteInvite(testdb, user.Id, once.Id), ErrNoResults)
+	assert.ErrorIs(t, DeleteInvite(testdb, user.Id+1000, unlimited.Id), ErrNoResults)
+}
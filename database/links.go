@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+type Link struct {
+	Id       uint64
+	UserId   uint64
+	SourceId uint64
+	// id of the sync file the link resolves to, or 0 if it doesn't resolve to
+	// a file
+	TargetId uint64
+	// Path in the link, as written in the note
+	Target string
+	// Lowercase name of the file the link points to, used to find links that
+	// need to be resolved again when files are added, renamed or deleted
+	TargetName string
+	Subpath    string
+	Alias      string
+	Embed      bool
+	Markdown   bool
+
+	// Filepaths of the source and target, filled in when links are looked up
+	SourcePath string
+	TargetPath string
+}
+
+const selectLinks = "SELECT l.id, l.user_id, l.source_id, COALESCE(l.target_id, 0), l.target, l.target_name,\n" +
+	"  l.subpath, l.alias, l.embed, l.markdown, s.filepath, COALESCE(t.filepath, '')\n" +
+	"  FROM links l\n" +
+	"  JOIN file_syncs s ON s.id = l.source_id\n" +
+	"  LEFT JOIN file_syncs t ON t.id = l.target_id\n"
+
+// Replace the links in a note with a new set of links.
+func ReplaceLinks(db *sql.DB, sourceId, userId uint64, links []*Link) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM links WHERE source_id=?", sourceId); err != nil {
+		return err
+	}
+	for _, link := range links {
+		_, err := tx.Exec(
+			"INSERT INTO links (source_id, target, target_name, target_id, subpath, alias, embed, markdown, user_id)\n"+
+				"  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			sourceId,
+			link.Target,
+			link.TargetName,
+			nullId(link.TargetId),
+			link.Subpath,
+			link.Alias,
+			link.Embed,
+			link.Markdown,
+			userId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get the links in a note.
+func GetOutlinks(db *sql.DB, sourceId uint64) ([]*Link, error) {
+	return queryLinks(db, selectLinks+"  WHERE l.source_id=? ORDER BY l.id", sourceId)
+}
+
+// Get the links that resolve to a file.
+func GetBacklinks(db *sql.DB, targetId uint64) ([]*Link, error) {
+	return queryLinks(db, selectLinks+"  WHERE l.target_id=? ORDER BY s.filepath, l.id", targetId)
+}
+
+// Get every link in a user's notes.
+func GetUserLinks(db *sql.DB, userId uint64) ([]*Link, error) {
+	return queryLinks(db, selectLinks+"  WHERE l.user_id=? ORDER BY l.id", userId)
+}
+
+// Get the links in a user's notes that point to files with any of the given
+// names.
+func GetUserLinksByTargetName(db *sql.DB, userId uint64, targetNames ...string) ([]*Link, error) {
+	if len(targetNames) == 0 {
+		return []*Link{}, nil
+	}
+
+	args := []any{userId}
+	for _, name := range targetNames {
+		args = append(args, name)
+	}
+	placeholders := strings.Repeat(", ?", len(targetNames))[2:]
+	return queryLinks(
+		db,
+		selectLinks+"  WHERE l.user_id=? AND l.target_name IN ("+placeholders+") ORDER BY l.id",
+		args...,
+	)
+}
+
+// Change the file a link resolves to. A targetId of 0 means the link doesn't
+// resolve to a file.
+func SetLinkTarget(db *sql.DB, id, targetId uint64) error {
+	_, err := db.Exec("UPDATE links SET target_id=? WHERE id=?", nullId(targetId), id)
+	return err
+}
+
+func queryLinks(db *sql.DB, query string, args ...any) ([]*Link, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*Link{}
+	for rows.Next() {
+		var link Link
+		err := rows.Scan(
+			&link.Id,
+			&link.UserId,
+			&link.SourceId,
+			&link.TargetId,
+			&link.Target,
+			&link.TargetName,
+			&link.Subpath,
+			&link.Alias,
+			&link.Embed,
+			&link.Markdown,
+			&link.SourcePath,
+			&link.TargetPath,
+		)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, &link)
+	}
+
+	return links, rows.Err()
+}
+
+func nullId(id uint64) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinks(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("links.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-links-user", "test-links-user@example.com", "not a secure password")
+	assert.NoError(t, err)
+	note, err := CreateSyncFile(testdb, "Scheduling.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+	target, err := CreateSyncFile(testdb, "Round Robin.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ReplaceLinks(testdb, note.Id, user.Id, []*Link{
+		{Target: "Round Robin", TargetName: "round robin", TargetId: target.Id, Alias: "RR"},
+		{Target: "Lottery", TargetName: "lottery", Embed: true},
+	}))
+
+	outlinks, err := GetOutlinks(testdb, note.Id)
+	if assert.NoError(t, err) && assert.Len(t, outlinks, 2) {
+		assert.Equal(t, "Scheduling.md", outlinks[0].SourcePath)
+		assert.Equal(t, "Round Robin.md", outlinks[0].TargetPath)
+		assert.Equal(t, "RR", outlinks[0].Alias)
+		assert.Equal(t, uint64(0), outlinks[1].TargetId)
+		assert.True(t, outlinks[1].Embed)
+	}
+	backlinks, err := GetBacklinks(testdb, target.Id)
+	if assert.NoError(t, err) && assert.Len(t, backlinks, 1) {
+		assert.Equal(t, note.Id, backlinks[0].SourceId)
+	}
+	links, err := GetUserLinksByTargetName(testdb, user.Id, "lottery", "fifo")
+	if assert.NoError(t, err) && assert.Len(t, links, 1) {
+		assert.Equal(t, "Lottery", links[0].Target)
+		assert.NoError(t, SetLinkTarget(testdb, links[0].Id, target.Id))
+	}
+	backlinks, err = GetBacklinks(testdb, target.Id)
+	assert.NoError(t, err)
+	assert.Len(t, backlinks, 2)
+
+	// replacing links removes the old ones
+	assert.NoError(t, ReplaceLinks(testdb, note.Id, user.Id, []*Link{
+		{Target: "Round Robin", TargetName: "round robin", TargetId: target.Id},
+	}))
+	links, err = GetUserLinks(testdb, user.Id)
+	assert.NoError(t, err)
+	assert.Len(t, links, 1)
+
+	// deleting the target leaves the link unresolved, and deleting the note
+	// removes its links
+	assert.NoError(t, DeleteSyncFile(testdb, target.Id))
+	outlinks, err = GetOutlinks(testdb, note.Id)
+	if assert.NoError(t, err) && assert.Len(t, outlinks, 1) {
+		assert.Equal(t, uint64(0), outlinks[0].TargetId)
+		assert.Empty(t, outlinks[0].TargetPath)
+	}
+	assert.NoError(t, DeleteSyncFile(testdb, note.Id))
+	links, err = GetUserLinks(testdb, user.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, links)
+}
@@ -9,6 +9,9 @@ import (
 type migration struct {
 	name         string
 	sqlStatement string
+	// SQLite compile option the migration needs, like ENABLE_FTS5. Migrations
+	// are skipped until SQLite is built with the option they need.
+	compileOption string
 }
 
 var migrations = []migration{
@@ -81,6 +84,328 @@ var migrations = []migration{
 			"\n",
 		),
 	},
+	{
+		name:         "AddSizeToFileSyncs",
+		sqlStatement: "ALTER TABLE file_syncs ADD COLUMN size INTEGER NOT NULL DEFAULT 0;",
+	},
+	{
+		name: "CreateSearchIndex",
+		sqlStatement: strings.Join([]string{
+			"CREATE VIRTUAL TABLE search_index USING fts5(",
+			"  filepath,",
+			"  content,",
+			"  user_id UNINDEXED,",
+			"  tokenize = 'unicode61 remove_diacritics 2'",
+			");",
+			"CREATE TRIGGER file_syncs_search_index_rename AFTER UPDATE OF filepath ON file_syncs BEGIN",
+			"  UPDATE search_index SET filepath = new.filepath WHERE rowid = old.id;",
+			"END;",
+			"CREATE TRIGGER file_syncs_search_index_delete AFTER DELETE ON file_syncs BEGIN",
+			"  DELETE FROM search_index WHERE rowid = old.id;",
+			"END;"},
+			"\n",
+		),
+		compileOption: "ENABLE_FTS5",
+	},
+	{
+		name: "CreateLinksTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE links (",
+			"  id          INTEGER      PRIMARY KEY AUTOINCREMENT,",
+			"  source_id   INTEGER      NOT NULL REFERENCES file_syncs(id) ON DELETE CASCADE,",
+			"  target      VARCHAR(500) NOT NULL,",
+			"  target_name VARCHAR(500) NOT NULL,",
+			"  target_id   INTEGER      REFERENCES file_syncs(id) ON DELETE SET NULL,",
+			"  subpath     TEXT         NOT NULL DEFAULT '',",
+			"  alias       TEXT         NOT NULL DEFAULT '',",
+			"  embed       INTEGER      NOT NULL DEFAULT 0,",
+			"  markdown    INTEGER      NOT NULL DEFAULT 0,",
+			"  user_id     INTEGER      REFERENCES users(id) ON DELETE CASCADE",
+			");",
+			"CREATE INDEX links_source_id ON links(source_id);",
+			"CREATE INDEX links_target_id ON links(target_id);",
+			"CREATE INDEX links_user_id_target_name ON links(user_id, target_name);",
+			// foreign keys aren't enforced unless they're turned on for every
+			// connection, so clean up links with a trigger too
+			"CREATE TRIGGER file_syncs_links_delete AFTER DELETE ON file_syncs BEGIN",
+			"  DELETE FROM links WHERE source_id = old.id;",
+			"  UPDATE links SET target_id = NULL WHERE target_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateTagsTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE tags (",
+			"  id      INTEGER      PRIMARY KEY AUTOINCREMENT,",
+			"  file_id INTEGER      NOT NULL REFERENCES file_syncs(id) ON DELETE CASCADE,",
+			"  tag     VARCHAR(200) NOT NULL,",
+			"  name    VARCHAR(200) NOT NULL,",
+			"  user_id INTEGER      REFERENCES users(id) ON DELETE CASCADE",
+			");",
+			"CREATE INDEX tags_file_id ON tags(file_id);",
+			"CREATE INDEX tags_user_id_name ON tags(user_id, name);",
+			"CREATE TRIGGER file_syncs_tags_delete AFTER DELETE ON file_syncs BEGIN",
+			"  DELETE FROM tags WHERE file_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreatePropertiesTables",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE properties (",
+			"  id      INTEGER      PRIMARY KEY AUTOINCREMENT,",
+			"  file_id INTEGER      NOT NULL REFERENCES file_syncs(id) ON DELETE CASCADE,",
+			"  key     VARCHAR(200) NOT NULL,",
+			"  name    VARCHAR(200) NOT NULL,",
+			"  type    VARCHAR(20)  NOT NULL,",
+			"  value   TEXT         NOT NULL,",
+			"  user_id INTEGER      REFERENCES users(id) ON DELETE CASCADE",
+			");",
+			"CREATE INDEX properties_file_id_name ON properties(file_id, name);",
+			"CREATE INDEX properties_user_id_name ON properties(user_id, name);",
+			"CREATE TABLE property_errors (",
+			"  file_id INTEGER PRIMARY KEY REFERENCES file_syncs(id) ON DELETE CASCADE,",
+			"  message TEXT    NOT NULL,",
+			"  user_id INTEGER REFERENCES users(id) ON DELETE CASCADE",
+			");",
+			"CREATE INDEX property_errors_user_id ON property_errors(user_id);",
+			"CREATE TRIGGER file_syncs_properties_delete AFTER DELETE ON file_syncs BEGIN",
+			"  DELETE FROM properties WHERE file_id = old.id;",
+			"  DELETE FROM property_errors WHERE file_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateSharesTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE shares (",
+			"  id         INTEGER       PRIMARY KEY AUTOINCREMENT,",
+			"  token      VARCHAR(64)   UNIQUE NOT NULL,",
+			"  file_id    INTEGER       REFERENCES file_syncs(id) ON DELETE CASCADE,",
+			"  folder     VARCHAR(1024),",
+			"  passhash   VARCHAR(1024),",
+			"  expires    TEXT,",
+			"  max_views  INTEGER,",
+			"  views      INTEGER       NOT NULL DEFAULT 0,",
+			"  created_at TEXT          NOT NULL,",
+			"  user_id    INTEGER       REFERENCES users(id) ON DELETE CASCADE",
+			");",
+			"CREATE INDEX shares_user_id ON shares(user_id);",
+			"CREATE TRIGGER file_syncs_shares_delete AFTER DELETE ON file_syncs BEGIN",
+			"  DELETE FROM shares WHERE file_id = old.id;",
+			"END;",
+			"CREATE TRIGGER users_shares_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM shares WHERE user_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "AddUpdatedByToFileSyncs",
+		sqlStatement: strings.Join([]string{
+			"ALTER TABLE file_syncs ADD COLUMN updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL;",
+			// files were only ever changed by their owners before vaults could be shared
+			"UPDATE file_syncs SET updated_by = user_id;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateVaultMembersTables",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE vault_members (",
+			"  id         INTEGER     PRIMARY KEY AUTOINCREMENT,",
+			"  vault_id   INTEGER     NOT NULL REFERENCES users(id) ON DELETE CASCADE,",
+			"  user_id    INTEGER     NOT NULL REFERENCES users(id) ON DELETE CASCADE,",
+			"  role       VARCHAR(20) NOT NULL,",
+			"  created_at TEXT        NOT NULL,",
+			"  UNIQUE (vault_id, user_id)",
+			");",
+			"CREATE INDEX vault_members_user_id ON vault_members(user_id);",
+			"CREATE TABLE vault_folder_roles (",
+			"  id       INTEGER      PRIMARY KEY AUTOINCREMENT,",
+			"  vault_id INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,",
+			"  user_id  INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,",
+			"  folder   VARCHAR(500) NOT NULL,",
+			"  role     VARCHAR(20)  NOT NULL,",
+			"  UNIQUE (vault_id, user_id, folder)",
+			");",
+			"CREATE TRIGGER vault_members_delete AFTER DELETE ON vault_members BEGIN",
+			"  DELETE FROM vault_folder_roles WHERE vault_id = old.vault_id AND user_id = old.user_id;",
+			"END;",
+			"CREATE TRIGGER users_vault_members_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM vault_members WHERE vault_id = old.id OR user_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateIgnoreRulesTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE ignore_rules (",
+			"  user_id    INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,",
+			"  rules      TEXT    NOT NULL,",
+			"  updated_at TEXT    NOT NULL",
+			");",
+			"CREATE TRIGGER users_ignore_rules_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM ignore_rules WHERE user_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateFileSyncsListIndexes",
+		sqlStatement: strings.Join([]string{
+			"CREATE INDEX file_syncs_user_id_filepath ON file_syncs(user_id, filepath);",
+			"CREATE INDEX file_syncs_user_id_updated_at ON file_syncs(user_id, updated_at, id);"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateFoldersTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE folders (",
+			"  id         INTEGER PRIMARY KEY AUTOINCREMENT,",
+			"  user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,",
+			"  path       TEXT    NOT NULL,",
+			"  created_at TEXT    NOT NULL,",
+			"  UNIQUE (user_id, path)",
+			");",
+			"CREATE TRIGGER users_folders_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM folders WHERE user_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateThumbnailsTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE thumbnails (",
+			"  file_id    INTEGER NOT NULL REFERENCES file_syncs(id) ON DELETE CASCADE,",
+			"  size       INTEGER NOT NULL,",
+			"  etag       TEXT    NOT NULL,",
+			"  created_at TEXT    NOT NULL,",
+			"  UNIQUE (file_id, size)",
+			");",
+			"CREATE TRIGGER file_syncs_thumbnails_delete AFTER DELETE ON file_syncs BEGIN",
+			"  DELETE FROM thumbnails WHERE file_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name: "CreateSnapshotsTables",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE snapshots (",
+			"  id         INTEGER PRIMARY KEY AUTOINCREMENT,",
+			"  user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,",
+			"  name       TEXT    NOT NULL,",
+			"  created_by INTEGER NOT NULL,",
+			"  created_at TEXT    NOT NULL,",
+			"  UNIQUE (user_id, name)",
+			");",
+			"CREATE TABLE snapshot_files (",
+			"  snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,",
+			"  filepath    TEXT    NOT NULL,",
+			"  etag        TEXT    NOT NULL,",
+			"  size        INTEGER NOT NULL,",
+			"  updated_at  TEXT    NOT NULL,",
+			"  PRIMARY KEY (snapshot_id, filepath)",
+			");",
+			"CREATE INDEX snapshot_files_etag ON snapshot_files (etag);",
+			"CREATE TRIGGER users_snapshots_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM snapshots WHERE user_id = old.id;",
+			"END;",
+			"CREATE TRIGGER snapshots_files_delete AFTER DELETE ON snapshots BEGIN",
+			"  DELETE FROM snapshot_files WHERE snapshot_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name:         "AddUsersDisabled",
+		sqlStatement: "ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;",
+	},
+	{
+		name:         "AddUsersIsAdmin",
+		sqlStatement: "ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;",
+	},
+	{
+		name: "CreateInvitesTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE invites (",
+			"  id         INTEGER     PRIMARY KEY AUTOINCREMENT,",
+			"  code       VARCHAR(64) UNIQUE NOT NULL,",
+			"  expires    TEXT,",
+			"  max_uses   INTEGER,",
+			"  uses       INTEGER     NOT NULL DEFAULT 0,",
+			"  created_at TEXT        NOT NULL,",
+			"  user_id    INTEGER     REFERENCES users(id) ON DELETE CASCADE",
+			");",
+			"CREATE INDEX invites_user_id ON invites(user_id);",
+			"CREATE TRIGGER users_invites_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM invites WHERE user_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		name:         "AddUsersEmailVerified",
+		sqlStatement: "ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;",
+	},
+	{
+		name: "CreateEmailTokensTable",
+		sqlStatement: strings.Join([]string{
+			"CREATE TABLE email_tokens (",
+			"  id         INTEGER      PRIMARY KEY AUTOINCREMENT,",
+			"  token_hash VARCHAR(64)  UNIQUE NOT NULL,",
+			"  purpose    VARCHAR(32)  NOT NULL,",
+			"  email      VARCHAR(200) NOT NULL,",
+			"  expires    TEXT         NOT NULL,",
+			"  created_at TEXT         NOT NULL,",
+			"  user_id    INTEGER      REFERENCES users(id) ON DELETE CASCADE",
+			");",
+			"CREATE INDEX email_tokens_user_id ON email_tokens(user_id);",
+			"CREATE TRIGGER users_email_tokens_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM email_tokens WHERE user_id = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+	{
+		// foreign keys aren't enforced, so the sessions, API keys and files of
+		// deleted users were left behind and their sessions kept working
+		name: "DeleteUserSessionsKeysAndFiles",
+		sqlStatement: strings.Join([]string{
+			"DELETE FROM sessions WHERE user_id NOT IN (SELECT id FROM users);",
+			"DELETE FROM api_keys WHERE user_id NOT IN (SELECT id FROM users);",
+			"DELETE FROM file_syncs WHERE user_id NOT IN (SELECT id FROM users);",
+			"UPDATE file_syncs SET updated_by = NULL WHERE updated_by NOT IN (SELECT id FROM users);",
+			"CREATE TRIGGER users_sessions_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM sessions WHERE user_id = old.id;",
+			"END;",
+			"CREATE TRIGGER users_api_keys_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM api_keys WHERE user_id = old.id;",
+			"END;",
+			"CREATE TRIGGER users_file_syncs_delete AFTER DELETE ON users BEGIN",
+			"  DELETE FROM file_syncs WHERE user_id = old.id;",
+			"  UPDATE file_syncs SET updated_by = NULL WHERE updated_by = old.id;",
+			"END;"},
+			"\n",
+		),
+	},
+}
+
+// Whether a migration has been applied to a database.
+type MigrationStatus struct {
+	Name    string
+	Applied bool
+	// SQLite compile option the migration needs that SQLite wasn't built
+	// with, if any
+	MissingOption string
 }
 
 func CreateMigrationsTable(db *sql.DB) error {
@@ -112,6 +437,17 @@ func (m *migration) Apply(db *sql.DB, echo bool) error {
 		log.Println("Migration already applied...")
 		return nil
 	}
+	if len(m.compileOption) > 0 {
+		row := db.QueryRow("SELECT sqlite_compileoption_used(?)", m.compileOption)
+		var used bool
+		if err := row.Scan(&used); err != nil {
+			return err
+		}
+		if !used {
+			log.Printf("Skipping migration `%s`, SQLite wasn't built with %s...\n", m.name, m.compileOption)
+			return nil
+		}
+	}
 
 	// apply migration
 	tx, err := db.Begin()
@@ -142,3 +478,44 @@ func ApplyMigrations(db *sql.DB) error {
 	}
 	return nil
 }
+
+// Get whether each migration has been applied to a database, in the order
+// they're applied in.
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	if err := CreateMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT name FROM migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		applied[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Name: m.name, Applied: applied[m.name]}
+		if !status.Applied && len(m.compileOption) > 0 {
+			var used bool
+			row := db.QueryRow("SELECT sqlite_compileoption_used(?)", m.compileOption)
+			if err := row.Scan(&used); err != nil {
+				return nil, err
+			}
+			if !used {
+				status.MissingOption = m.compileOption
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Property struct {
+	FileId uint64
+	// Key as it's written in the frontmatter
+	Key string
+	// Lowercase key that properties are matched by
+	Name string
+	Type string
+	// Value encoded as JSON
+	Value string
+}
+
+type PropertyError struct {
+	FileId   uint64
+	Filepath string
+	Message  string
+}
+
+type PropertyOperator string
+
+const (
+	PropertyExists         PropertyOperator = "exists"
+	PropertyMissing        PropertyOperator = "missing"
+	PropertyEquals         PropertyOperator = "="
+	PropertyNotEquals      PropertyOperator = "!="
+	PropertyLess           PropertyOperator = "<"
+	PropertyLessOrEqual    PropertyOperator = "<="
+	PropertyGreater        PropertyOperator = ">"
+	PropertyGreaterOrEqual PropertyOperator = ">="
+)
+
+// Condition on a property, like status=open. Lists match when any of their
+// items match, so tags=book matches files with book in their tags.
+type PropertyCondition struct {
+	Name     string
+	Operator PropertyOperator
+	Value    string
+}
+
+type PropertySort struct {
+	Name       string
+	Descending bool
+}
+
+type PropertyQuery struct {
+	// Conditions that all have to match
+	Conditions []PropertyCondition
+	// Properties to sort by. Files without a property are sorted after files
+	// with it, and files are sorted by their path last.
+	Sort  []PropertySort
+	Limit int
+}
+
+// Replace the properties of a sync file with a new set of properties.
+func ReplaceProperties(db *sql.DB, fileId, userId uint64, properties []*Property) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM properties WHERE file_id=?", fileId); err != nil {
+		return err
+	}
+	for _, property := range properties {
+		_, err := tx.Exec(
+			"INSERT INTO properties (file_id, key, name, type, value, user_id) VALUES (?, ?, ?, ?, ?, ?)",
+			fileId,
+			property.Key,
+			property.Name,
+			property.Type,
+			property.Value,
+			userId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get the properties of a sync file in the order they were added.
+func GetSyncFileProperties(db *sql.DB, fileId uint64) ([]*Property, error) {
+	rows, err := db.Query(
+		"SELECT file_id, key, name, type, value FROM properties WHERE file_id=? ORDER BY id",
+		fileId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	properties := []*Property{}
+	for rows.Next() {
+		var property Property
+		if err := rows.Scan(
+			&property.FileId,
+			&property.Key,
+			&property.Name,
+			&property.Type,
+			&property.Value,
+		); err != nil {
+			return nil, err
+		}
+		properties = append(properties, &property)
+	}
+
+	return properties, rows.Err()
+}
+
+// Record why a sync file's frontmatter couldn't be parsed, or clear the
+// error if the message is empty.
+func SetPropertyError(db *sql.DB, fileId, userId uint64, message string) error {
+	if len(message) == 0 {
+		_, err := db.Exec("DELETE FROM property_errors WHERE file_id=?", fileId)
+		return err
+	}
+	_, err := db.Exec(
+		"INSERT INTO property_errors (file_id, message, user_id) VALUES (?, ?, ?)\n"+
+			"  ON CONFLICT(file_id) DO UPDATE SET message=excluded.message",
+		fileId,
+		message,
+		userId,
+	)
+	return err
+}
+
+// Get why a sync file's frontmatter couldn't be parsed. Files with valid
+// frontmatter get an empty message.
+func GetPropertyError(db *sql.DB, fileId uint64) (string, error) {
+	row := db.QueryRow("SELECT message FROM property_errors WHERE file_id=?", fileId)
+	var message string
+	if err := row.Scan(&message); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return message, nil
+}
+
+// Get the user's files with frontmatter that couldn't be parsed.
+func GetPropertyErrors(db *sql.DB, userId uint64) ([]*PropertyError, error) {
+	rows, err := db.Query(
+		"SELECT e.file_id, f.filepath, e.message FROM property_errors e\n"+
+			"  JOIN file_syncs f ON f.id = e.file_id\n"+
+			"  WHERE e.user_id=? ORDER BY f.filepath",
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	propertyErrors := []*PropertyError{}
+	for rows.Next() {
+		var propertyError PropertyError
+		if err := rows.Scan(&propertyError.FileId, &propertyError.Filepath, &propertyError.Message); err != nil {
+			return nil, err
+		}
+		propertyErrors = append(propertyErrors, &propertyError)
+	}
+
+	return propertyErrors, rows.Err()
+}
+
+// Get a user's sync files with properties that match a query. Only files that
+// have properties are matched.
+func QueryProperties(db *sql.DB, userId uint64, query PropertyQuery) ([]*SyncFile, error) {
+	var sb strings.Builder
+	args := []any{userId}
+	sb.WriteString(
+		"SELECT f.id, f.user_id, f.filepath, f.etag, f.size, f.created_at, f.updated_at, f.updated_by\n" +
+			"  FROM file_syncs f\n" +
+			"  WHERE f.user_id=? AND EXISTS (SELECT 1 FROM properties p WHERE p.file_id = f.id)",
+	)
+	for _, condition := range query.Conditions {
+		expression, conditionArgs, err := propertyConditionExpression(condition)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString("\n  AND " + expression)
+		args = append(args, conditionArgs...)
+	}
+
+	sb.WriteString("\n  ORDER BY ")
+	const sortValue = "(SELECT json_extract(s.value, '$') FROM properties s WHERE s.file_id = f.id AND s.name=? ORDER BY s.id LIMIT 1)"
+	for _, sort := range query.Sort {
+		direction := "ASC"
+		if sort.Descending {
+			direction = "DESC"
+		}
+		fmt.Fprintf(&sb, "%s IS NULL, %s %s, ", sortValue, sortValue, direction)
+		args = append(args, sort.Name, sort.Name)
+	}
+	sb.WriteString("f.filepath")
+	if query.Limit > 0 {
+		sb.WriteString(" LIMIT ?")
+		args = append(args, query.Limit)
+	}
+
+	rows, err := db.Query(sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	syncFiles := []*SyncFile{}
+	for rows.Next() {
+		syncFile, err := scanSyncFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		syncFiles = append(syncFiles, syncFile)
+	}
+
+	return syncFiles, rows.Err()
+}
+
+// Build the SQL expression for a property condition. Values are compared as
+// numbers when the condition's value is a number, and as text otherwise, so
+// dates like 2024-05-01 compare in order.
+func propertyConditionExpression(condition PropertyCondition) (string, []any, error) {
+	const (
+		hasProperty = "EXISTS (SELECT 1 FROM properties p WHERE p.file_id = f.id AND p.name=?)"
+		hasValue    = "EXISTS (SELECT 1 FROM properties p, json_each(p.value) j WHERE p.file_id = f.id AND p.name=? AND (%s))"
+	)
+	args := []any{condition.Name}
+	number, numberErr := strconv.ParseFloat(condition.Value, 64)
+	isNumber := numberErr == nil
+
+	switch condition.Operator {
+	case PropertyExists:
+		return hasProperty, args, nil
+	case PropertyMissing:
+		return "NOT " + hasProperty, args, nil
+	case PropertyEquals, PropertyNotEquals:
+		var match string
+		switch {
+		case len(condition.Value) == 0:
+			match = "j.type = 'null' OR (j.type = 'text' AND j.value = '')"
+		case condition.Value == "true" || condition.Value == "false":
+			match = "j.type = ? OR (j.type = 'text' AND j.value = ? COLLATE NOCASE)"
+			args = append(args, condition.Value, condition.Value)
+		case isNumber:
+			match = "(j.type IN ('integer', 'real') AND j.value = ?) OR (j.type = 'text' AND j.value = ?)"
+			args = append(args, number, condition.Value)
+		default:
+			match = "j.type = 'text' AND j.value = ? COLLATE NOCASE"
+			args = append(args, condition.Value)
+		}
+		expression := fmt.Sprintf(hasValue, match)
+		if condition.Operator == PropertyNotEquals {
+			expression = "NOT " + expression
+		}
+		return expression, args, nil
+	case PropertyLess, PropertyLessOrEqual, PropertyGreater, PropertyGreaterOrEqual:
+		if isNumber {
+			args = append(args, number)
+			return fmt.Sprintf(hasValue, "j.type IN ('integer', 'real') AND j.value "+string(condition.Operator)+" ?"), args, nil
+		}
+		args = append(args, condition.Value)
+		return fmt.Sprintf(hasValue, "j.type = 'text' AND j.value "+string(condition.Operator)+" ?"), args, nil
+	}
+
+	return "", nil, fmt.Errorf("unknown property operator %q", condition.Operator)
+}
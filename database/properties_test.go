@@ -0,0 +1,142 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProperties(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("properties.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-properties-user", "test-properties-user@example.com", "not a secure password")
+	assert.NoError(t, err)
+	files := map[string][]*Property{
+		"alpha.md": {
+			{Key: "Status", Name: "status", Type: "text", Value: `"open"`},
+			{Key: "due", Name: "due", Type: "date", Value: `"2024-05-01"`},
+			{Key: "priority", Name: "priority", Type: "number", Value: `2`},
+			{Key: "tags", Name: "tags", Type: "list", Value: `["book","reading"]`},
+		},
+		"beta.md": {
+			{Key: "status", Name: "status", Type: "text", Value: `"done"`},
+			{Key: "due", Name: "due", Type: "date", Value: `"2024-04-01"`},
+			{Key: "priority", Name: "priority", Type: "number", Value: `10`},
+			{Key: "archived", Name: "archived", Type: "checkbox", Value: `true`},
+		},
+		"gamma.md": {
+			{Key: "status", Name: "status", Type: "text", Value: `"open"`},
+			{Key: "owner", Name: "owner", Type: "empty", Value: `null`},
+		},
+	}
+	for filepath, properties := range files {
+		syncFile, err := CreateSyncFile(testdb, filepath, "etag", 10, user.Id, user.Id)
+		assert.NoError(t, err)
+		assert.NoError(t, ReplaceProperties(testdb, syncFile.Id, user.Id, properties))
+	}
+	// files without properties are never matched
+	_, err = CreateSyncFile(testdb, "plain.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		query PropertyQuery
+		want  []string
+	}{
+		{
+			name:  "no conditions",
+			query: PropertyQuery{},
+			want:  []string{"alpha.md", "beta.md", "gamma.md"},
+		},
+		{
+			name:  "text is case-insensitive",
+			query: PropertyQuery{Conditions: []PropertyCondition{{Name: "status", Operator: PropertyEquals, Value: "OPEN"}}},
+			want:  []string{"alpha.md", "gamma.md"},
+		},
+		{
+			name:  "not equal includes files without the property",
+			query: PropertyQuery{Conditions: []PropertyCondition{{Name: "priority", Operator: PropertyNotEquals, Value: "2"}}},
+			want:  []string{"beta.md", "gamma.md"},
+		},
+		{
+			name:  "numbers compare as numbers",
+			query: PropertyQuery{Conditions: []PropertyCondition{{Name: "priority", Operator: PropertyGreater, Value: "3"}}},
+			want:  []string{"beta.md"},
+		},
+		{
+			name:  "dates compare in order",
+			query: PropertyQuery{Conditions: []PropertyCondition{{Name: "due", Operator: PropertyLess, Value: "2024-04-15"}}},
+			want:  []string{"beta.md"},
+		},
+		{
+			name:  "list items",
+			query: PropertyQuery{Conditions: []PropertyCondition{{Name: "tags", Operator: PropertyEquals, Value: "book"}}},
+			want:  []string{"alpha.md"},
+		},
+		{
+			name:  "checkbox",
+			query: PropertyQuery{Conditions: []PropertyCondition{{Name: "archived", Operator: PropertyEquals, Value: "true"}}},
+			want:  []string{"beta.md"},
+		},
+		{
+			name:  "empty value",
+			query: PropertyQuery{Conditions: []PropertyCondition{{Name: "owner", Operator: PropertyEquals}}},
+			want:  []string{"gamma.md"},
+		},
+		{
+			name: "exists and missing",
+			query: PropertyQuery{Conditions: []PropertyCondition{
+				{Name: "due", Operator: PropertyExists},
+				{Name: "archived", Operator: PropertyMissing},
+			}},
+			want: []string{"alpha.md"},
+		},
+		{
+			name:  "sort with missing values last",
+			query: PropertyQuery{Sort: []PropertySort{{Name: "due"}}},
+			want:  []string{"beta.md", "alpha.md", "gamma.md"},
+		},
+		{
+			name:  "sort descending with a limit",
+			query: PropertyQuery{Sort: []PropertySort{{Name: "priority", Descending: true}}, Limit: 2},
+			want:  []string{"beta.md", "alpha.md"},
+		},
+	}
+
+	for _, tc := range testCases {
+		syncFiles, err := QueryProperties(testdb, user.Id, tc.query)
+		if assert.NoError(t, err, tc.name) {
+			filepaths := []string{}
+			for _, syncFile := range syncFiles {
+				filepaths = append(filepaths, syncFile.Filepath)
+			}
+			assert.Equal(t, tc.want, filepaths, tc.name)
+		}
+	}
+
+	// frontmatter errors are recorded, cleared and removed with their file
+	syncFile, err := CreateSyncFile(testdb, "broken.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+	assert.NoError(t, SetPropertyError(testdb, syncFile.Id, user.Id, "yaml: line 1: did not find expected node content"))
+	assert.NoError(t, SetPropertyError(testdb, syncFile.Id, user.Id, "yaml: line 2: mapping values are not allowed in this context"))
+	propertyErrors, err := GetPropertyErrors(testdb, user.Id)
+	if assert.NoError(t, err) && assert.Len(t, propertyErrors, 1) {
+		assert.Equal(t, "broken.md", propertyErrors[0].Filepath)
+		assert.Equal(t, "yaml: line 2: mapping values are not allowed in this context", propertyErrors[0].Message)
+	}
+	assert.NoError(t, SetPropertyError(testdb, syncFile.Id, user.Id, ""))
+	message, err := GetPropertyError(testdb, syncFile.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, message)
+
+	alpha, err := GetUserSyncFileByFilepath(testdb, user.Id, "alpha.md")
+	assert.NoError(t, err)
+	assert.NoError(t, DeleteSyncFile(testdb, alpha.Id))
+	properties, err := GetSyncFileProperties(testdb, alpha.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, properties)
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+var ErrSearchUnavailable = errors.New("full-text search needs SQLite to be built with FTS5")
+
+// Markers put around matching terms in search snippets. They're control
+// characters so they can't be confused with anything in a note.
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
+
+type SearchQuery struct {
+	// Text to search for
+	Text string
+	// Match the text as a phrase instead of as separate terms
+	Phrase bool
+	// Match terms that start with the last term in the text
+	Prefix bool
+	// Only search files in this folder
+	Folder string
+	// Maximum number of results
+	Limit int
+}
+
+type SearchResult struct {
+	FileId   uint64
+	Filepath string
+	// Part of the file's contents around the matching terms. Matching terms are
+	// surrounded by SnippetMatchStart and SnippetMatchEnd.
+	Snippet string
+	// bm25 rank of the result, lower ranks are better matches
+	Rank float64
+}
+
+// Check whether the search index was created, which only happens when SQLite
+// is built with FTS5.
+func SearchAvailable(db *sql.DB) (bool, error) {
+	row := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='search_index'")
+	var count int
+	if err := row.Scan(&count); err != nil {
+		return false, err
+	}
+	return count == 1, nil
+}
+
+// Add a sync file's contents to the search index, replacing what was indexed
+// for the file before.
+func IndexSyncFile(db *sql.DB, syncFile *SyncFile, content string) error {
+	if available, err := SearchAvailable(db); err != nil {
+		return err
+	} else if !available {
+		return ErrSearchUnavailable
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM search_index WHERE rowid=?", syncFile.Id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO search_index (rowid, filepath, content, user_id) VALUES (?, ?, ?, ?)",
+		syncFile.Id,
+		syncFile.Filepath,
+		content,
+		syncFile.UserId,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Remove every file from the search index.
+func ClearSearchIndex(db *sql.DB) error {
+	if available, err := SearchAvailable(db); err != nil {
+		return err
+	} else if !available {
+		return ErrSearchUnavailable
+	}
+
+	_, err := db.Exec("DELETE FROM search_index")
+	return err
+}
+
+// Search a user's files, returning the best matches first. Filepaths are
+// weighted higher than file contents.
+func SearchSyncFiles(db *sql.DB, userId uint64, query SearchQuery) ([]*SearchResult, error) {
+	if available, err := SearchAvailable(db); err != nil {
+		return nil, err
+	} else if !available {
+		return nil, ErrSearchUnavailable
+	}
+
+	match := searchMatchExpression(query)
+	if len(match) == 0 {
+		return []*SearchResult{}, nil
+	}
+
+	statement := "SELECT rowid, filepath, snippet(search_index, 1, ?, ?, '…', 16), bm25(search_index, 5.0, 1.0) AS rank\n" +
+		"  FROM search_index WHERE search_index MATCH ? AND user_id=?"
+	args := []any{SnippetMatchStart, SnippetMatchEnd, match, userId}
+	if folder := strings.Trim(query.Folder, "/"); len(folder) > 0 {
+		inFolder, folderArgs := prefixCondition("filepath", folder+"/")
+		statement += " AND (filepath=? OR " + inFolder + ")"
+		args = append(append(args, folder), folderArgs...)
+	}
+	statement += "\n  ORDER BY rank LIMIT ?"
+	limit := query.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit)
+
+	rows, err := db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*SearchResult{}
+	for rows.Next() {
+		var result SearchResult
+		if err := rows.Scan(&result.FileId, &result.Filepath, &result.Snippet, &result.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+
+	return results, rows.Err()
+}
+
+// Build an FTS5 match expression for a query. Every term is quoted, so FTS5
+// operators in the query's text are searched for like any other text.
+func searchMatchExpression(query SearchQuery) string {
+	var terms []string
+	if query.Phrase {
+		if text := strings.TrimSpace(query.Text); len(text) > 0 {
+			terms = []string{text}
+		}
+	} else {
+		terms = strings.Fields(query.Text)
+	}
+	if len(terms) == 0 {
+		return ""
+	}
+
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	if query.Prefix {
+		terms[len(terms)-1] += "*"
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchSyncFiles(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("search-sync-files.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+	available, err := SearchAvailable(testdb)
+	assert.NoError(t, err)
+	if !available {
+		_, err := SearchSyncFiles(testdb, 1, SearchQuery{Text: "round robin"})
+		assert.ErrorIs(t, err, ErrSearchUnavailable)
+		t.Skip("SQLite was built without FTS5")
+	}
+
+	user, err := CreateUser(testdb, "test-search-user", "test-search-user@example.com", "not a secure password")
+	assert.NoError(t, err)
+	otherUser, err := CreateUser(testdb, "test-search-other", "test-search-other@example.com", "not a secure password")
+	assert.NoError(t, err)
+
+	files := []struct {
+		filepath string
+		content  string
+		userId   uint64
+	}{
+		{"school/os/scheduling.md", "Processes take turns with round robin scheduling.", user.Id},
+		{"school/os/paging.md", "Pages are evicted in a robin-like round of the clock.", user.Id},
+		{"school/networks.md", "Round trip time and scheduler queues.", user.Id},
+		{"schoolwork/essay.md", "Robin Hood and the round table.", user.Id},
+		{"école/dictée.md", "Une dictée, round two.", user.Id},
+		{"recipes.md", "Round robin pot luck.", otherUser.Id},
+	}
+	syncFiles := make(map[string]*SyncFile)
+	for _, file := range files {
+		syncFile, err := CreateSyncFile(testdb, file.filepath, "etag", int64(len(file.content)), file.userId, file.userId)
+		if !assert.NoError(t, err) || !assert.NoError(t, IndexSyncFile(testdb, syncFile, file.content)) {
+			t.FailNow()
+		}
+		syncFiles[file.filepath] = syncFile
+	}
+
+	testCases := []struct {
+		name      string
+		query     SearchQuery
+		wantFiles []string
+	}{
+		{
+			name:      "every term has to match",
+			query:     SearchQuery{Text: "round robin"},
+			wantFiles: []string{"school/os/scheduling.md", "school/os/paging.md", "schoolwork/essay.md"},
+		},
+		{
+			name:      "phrase",
+			query:     SearchQuery{Text: "round robin", Phrase: true},
+			wantFiles: []string{"school/os/scheduling.md"},
+		},
+		{
+			name:      "prefix",
+			query:     SearchQuery{Text: "sched", Prefix: true},
+			wantFiles: []string{"school/os/scheduling.md", "school/networks.md"},
+		},
+		{
+			name:      "folder",
+			query:     SearchQuery{Text: "round", Folder: "/school/"},
+			wantFiles: []string{"school/os/scheduling.md", "school/os/paging.md", "school/networks.md"},
+		},
+		{
+			name:      "folder with non-ASCII characters",
+			query:     SearchQuery{Text: "round", Folder: "école"},
+			wantFiles: []string{"école/dictée.md"},
+		},
+		{
+			name:      "filenames are searched",
+			query:     SearchQuery{Text: "essay"},
+			wantFiles: []string{"schoolwork/essay.md"},
+		},
+		{
+			name:      "query syntax is searched as text",
+			query:     SearchQuery{Text: `round OR "robin`},
+			wantFiles: []string{},
+		},
+		{
+			name:      "limit",
+			query:     SearchQuery{Text: "round robin", Phrase: true, Limit: 1},
+			wantFiles: []string{"school/os/scheduling.md"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := SearchSyncFiles(testdb, user.Id, tc.query)
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			gotFiles := make([]string, 0, len(results))
+			for _, result := range results {
+				gotFiles = append(gotFiles, result.Filepath)
+				assert.Equal(t, syncFiles[result.Filepath].Id, result.FileId)
+			}
+			assert.ElementsMatch(t, tc.wantFiles, gotFiles)
+		})
+	}
+
+	// the best match comes first, and matches are marked in the snippet
+	results, err := SearchSyncFiles(testdb, user.Id, SearchQuery{Text: "scheduling"})
+	if assert.NoError(t, err) && assert.Len(t, results, 1) {
+		assert.Contains(t, results[0].Snippet, SnippetMatchStart+"scheduling"+SnippetMatchEnd)
+	}
+
+	// renamed and deleted files stay in sync with the index
+	assert.NoError(t, UpdateSyncFileFilepath(testdb, "school/os/scheduling.md", "school/os/cpu.md"))
+	results, err = SearchSyncFiles(testdb, user.Id, SearchQuery{Text: "round robin", Phrase: true})
+	if assert.NoError(t, err) && assert.Len(t, results, 1) {
+		assert.Equal(t, "school/os/cpu.md", results[0].Filepath)
+	}
+	assert.NoError(t, DeleteSyncFile(testdb, syncFiles["school/os/scheduling.md"].Id))
+	results, err = SearchSyncFiles(testdb, user.Id, SearchQuery{Text: "round robin", Phrase: true})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	// the index can be cleared to rebuild it
+	assert.NoError(t, ClearSearchIndex(testdb))
+	results, err = SearchSyncFiles(testdb, user.Id, SearchQuery{Text: "round"})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestSearchMatchExpression(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `"round" "robin"`, searchMatchExpression(SearchQuery{Text: " round  robin "}))
+	assert.Equal(t, `"round robin"`, searchMatchExpression(SearchQuery{Text: "round robin", Phrase: true}))
+	assert.Equal(t, `"round" "rob"*`, searchMatchExpression(SearchQuery{Text: "round rob", Prefix: true}))
+	assert.Equal(t, `"round rob"*`, searchMatchExpression(SearchQuery{Text: "round rob", Phrase: true, Prefix: true}))
+	assert.Equal(t, `"NEAR(a" """b"")"`, searchMatchExpression(SearchQuery{Text: `NEAR(a "b")`}))
+	assert.Equal(t, "", searchMatchExpression(SearchQuery{Text: "  ", Phrase: true}))
+}
@@ -24,10 +24,11 @@ func LoginUser(db *sql.DB, username, password string) (*Session, error) {
 	var (
 		userId   uint64
 		passhash string
+		disabled bool
 	)
 
-	row := db.QueryRow("SELECT id, passhash FROM users WHERE username=?", username)
-	if err := row.Scan(&userId, &passhash); err != nil {
+	row := db.QueryRow("SELECT id, passhash, disabled FROM users WHERE username=?", username)
+	if err := row.Scan(&userId, &passhash, &disabled); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrIncorrectCredentials
 		}
@@ -39,6 +40,9 @@ func LoginUser(db *sql.DB, username, password string) (*Session, error) {
 		}
 		return nil, err
 	}
+	if disabled {
+		return nil, ErrUserDisabled
+	}
 
 	return CreateSession(db, userId)
 }
@@ -102,6 +106,12 @@ func DeleteSession(db *sql.DB, sessionKey string) error {
 	return err
 }
 
+// Log a user out everywhere.
+func DeleteUserSessions(db *sql.DB, userId uint64) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE user_id=?", userId)
+	return err
+}
+
 func DeleteExpiredSessions(db *sql.DB) error {
 	_, err := db.Exec("DELETE FROM sessions WHERE expires<datetime('now')")
 	return err
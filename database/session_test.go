@@ -51,11 +51,12 @@ func TestCreateSyncFile(t *testing.T) {
 	filepath := "/cool/filepath"
 	etag := "ff3e4b618b07a1f9b2ab04c201ae6613"
 
-	syncFile, err := CreateSyncFile(testdb, filepath, etag, user.Id)
+	syncFile, err := CreateSyncFile(testdb, filepath, etag, 42, user.Id, user.Id)
 	assert.NoError(t, err)
 	assert.Equal(t, syncFile.UserId, user.Id)
 	assert.Equal(t, syncFile.Filepath, filepath)
 	assert.Equal(t, syncFile.Etag, etag)
+	assert.Equal(t, syncFile.Size, int64(42))
 
 	assert.NoError(t, testdb.Close())
 }
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+const ShareTokenBytes = 24
+
+var (
+	ErrShareExpired   = errors.New("share expired")
+	ErrShareViewLimit = errors.New("share reached its view limit")
+)
+
+// Link that lets anyone with its token view a file or the files in a folder
+// without an account.
+type Share struct {
+	Id     uint64
+	Token  string
+	UserId uint64
+	// Shared file, or 0 for folder shares
+	FileId uint64
+	// Current path of the shared file, which follows the file when it's renamed
+	Filepath string
+	// Shared folder, or empty for file shares
+	Folder string
+	// Hash of the share's password, or empty for shares without a password
+	Passhash string
+	// When the share expires, or the zero time for shares that don't expire
+	Expires time.Time
+	// Number of times the share can be viewed, or 0 for no limit
+	MaxViews  int
+	Views     int
+	CreatedAt time.Time
+}
+
+// Create a share with a random token. Shares without a password get an
+// empty password.
+func CreateShare(db *sql.DB, share *Share, password string) (*Share, error) {
+	b, err := randomBytes(ShareTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	created := *share
+	created.Token = base64.RawURLEncoding.EncodeToString(b)
+	created.CreatedAt = time.Now().UTC()
+	created.Views = 0
+	if len(password) > 0 {
+		if created.Passhash, err = HashPassword(password); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := db.Exec(
+		"INSERT INTO shares (token, file_id, folder, passhash, expires, max_views, created_at, user_id)\n"+
+			"  VALUES (:token, :file_id, :folder, :passhash, :expires, :max_views, :created_at, :user_id)",
+		sql.Named("token", created.Token),
+		sql.Named("file_id", nullId(created.FileId)),
+		sql.Named("folder", nullString(created.Folder)),
+		sql.Named("passhash", nullString(created.Passhash)),
+		sql.Named("expires", sql.NullTime{Time: created.Expires.UTC(), Valid: !created.Expires.IsZero()}),
+		sql.Named("max_views", sql.NullInt64{Int64: int64(created.MaxViews), Valid: created.MaxViews > 0}),
+		sql.Named("created_at", created.CreatedAt),
+		sql.Named("user_id", created.UserId),
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	created.Id = uint64(id)
+
+	return &created, nil
+}
+
+// Get a share by its token. Expired shares are returned along with
+// ErrShareExpired.
+func GetShareByToken(db *sql.DB, token string) (*Share, error) {
+	row := db.QueryRow(selectShares+" WHERE s.token=?", token)
+	share, err := scanShare(row)
+	if err != nil {
+		return nil, err
+	}
+	if !share.Expires.IsZero() && share.Expires.Before(time.Now()) {
+		return share, ErrShareExpired
+	}
+	return share, nil
+}
+
+// Get a user's shares, newest first.
+func GetUserShares(db *sql.DB, userId uint64) ([]*Share, error) {
+	rows, err := db.Query(selectShares+" WHERE s.user_id=? ORDER BY s.id DESC", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*Share{}
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+// Revoke one of a user's shares, returning ErrNoResults if the user doesn't
+// have a share with the id.
+func DeleteShare(db *sql.DB, userId, id uint64) error {
+	res, err := db.Exec("DELETE FROM shares WHERE id=? AND user_id=?", id, userId)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrNoResults
+	}
+	return nil
+}
+
+// Count a view of a share, returning ErrShareViewLimit if the share was
+// already viewed as many times as it can be.
+func AddShareView(db *sql.DB, id uint64) error {
+	res, err := db.Exec(
+		"UPDATE shares SET views=views+1 WHERE id=? AND (max_views IS NULL OR views < max_views)",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrShareViewLimit
+	}
+	return nil
+}
+
+const selectShares = "SELECT s.id, s.token, s.user_id, s.file_id, f.filepath, s.folder, s.passhash,\n" +
+	"  s.expires, s.max_views, s.views, s.created_at\n" +
+	"  FROM shares s LEFT JOIN file_syncs f ON f.id = s.file_id"
+
+func scanShare(row Scannable) (*Share, error) {
+	var (
+		share     Share
+		fileId    sql.NullInt64
+		filepath  sql.NullString
+		folder    sql.NullString
+		passhash  sql.NullString
+		expires   sql.NullString
+		maxViews  sql.NullInt64
+		createdAt string
+	)
+
+	err := row.Scan(
+		&share.Id,
+		&share.Token,
+		&share.UserId,
+		&fileId,
+		&filepath,
+		&folder,
+		&passhash,
+		&expires,
+		&maxViews,
+		&share.Views,
+		&createdAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	share.FileId = uint64(fileId.Int64)
+	share.Filepath = filepath.String
+	share.Folder = folder.String
+	share.Passhash = passhash.String
+	share.MaxViews = int(maxViews.Int64)
+	if expires.Valid {
+		if share.Expires, err = time.Parse(ISO_8601_FORMAT, expires.String); err != nil {
+			return nil, err
+		}
+	}
+	if share.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt); err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: len(s) > 0}
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShares(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("shares.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-shares-user", "test-shares-user@example.com", "not a secure password")
+	assert.NoError(t, err)
+	note, err := CreateSyncFile(testdb, "notes/note.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+
+	fileShare, err := CreateShare(testdb, &Share{UserId: user.Id, FileId: note.Id, MaxViews: 2}, "hunter22")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Len(t, fileShare.Token, 32)
+	assert.NoError(t, ValidateHash("hunter22", fileShare.Passhash))
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	folderShare, err := CreateShare(testdb, &Share{UserId: user.Id, Folder: "notes", Expires: expires}, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NotEqual(t, fileShare.Token, folderShare.Token)
+
+	share, err := GetShareByToken(testdb, fileShare.Token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, note.Id, share.FileId)
+		assert.Equal(t, "notes/note.md", share.Filepath)
+		assert.Equal(t, 2, share.MaxViews)
+		assert.True(t, share.Expires.IsZero())
+	}
+	share, err = GetShareByToken(testdb, folderShare.Token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "notes", share.Folder)
+		assert.Empty(t, share.Passhash)
+		assert.True(t, expires.Equal(share.Expires))
+	}
+	_, err = GetShareByToken(testdb, "missing")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	// shares follow renamed files
+	assert.NoError(t, RenameSyncFile(testdb, note.Id, "archive/note.md", user.Id))
+	share, err = GetShareByToken(testdb, fileShare.Token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "archive/note.md", share.Filepath)
+	}
+
+	// views are limited
+	assert.NoError(t, AddShareView(testdb, fileShare.Id))
+	assert.NoError(t, AddShareView(testdb, fileShare.Id))
+	assert.ErrorIs(t, AddShareView(testdb, fileShare.Id), ErrShareViewLimit)
+	assert.NoError(t, AddShareView(testdb, folderShare.Id))
+
+	// expired shares
+	expiredShare, err := CreateShare(testdb, &Share{UserId: user.Id, Folder: "notes", Expires: time.Now().Add(-time.Minute)}, "")
+	assert.NoError(t, err)
+	_, err = GetShareByToken(testdb, expiredShare.Token)
+	assert.ErrorIs(t, err, ErrShareExpired)
+
+	shares, err := GetUserShares(testdb, user.Id)
+	if assert.NoError(t, err) && assert.Len(t, shares, 3) {
+		assert.Equal(t, expiredShare.Id, shares[0].Id)
+		assert.Equal(t, 2, shares[2].Views)
+	}
+
+	// revoking shares and deleting shared files
+	assert.NoError(t, DeleteShare(testdb, user.Id, expiredShare.Id))
+	assert.ErrorIs(t, DeleteShare(testdb, user.Id, expiredShare.Id), ErrNoResults)
+	assert.ErrorIs(t, DeleteShare(testdb, user.Id+1, folderShare.Id), ErrNoResults)
+	assert.NoError(t, DeleteSyncFile(testdb, note.Id))
+	_, err = GetShareByToken(testdb, fileShare.Token)
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	// deleting the user deletes their shares
+	assert.NoError(t, DeleteUser(testdb, user.Id))
+	_, err = GetShareByToken(testdb, folderShare.Token)
+	assert.ErrorIs(t, err, ErrNoResults)
+}
@@ -0,0 +1,288 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSnapshotExists = errors.New("snapshot already exists")
+)
+
+// Named copy of every file in a vault at a point in time. Snapshots only
+// record the etag of each file, and the content is kept elsewhere once for
+// every etag.
+type Snapshot struct {
+	Id     uint64
+	UserId uint64
+	Name   string
+	// User that took the snapshot, which can be a member of the owner's
+	// vault
+	CreatedBy uint64
+	CreatedAt time.Time
+	FileCount int
+	TotalSize int64
+}
+
+// File as it was when a snapshot was taken.
+type SnapshotFile struct {
+	SnapshotId uint64
+	Filepath   string
+	Etag       string
+	Size       int64
+	UpdatedAt  time.Time
+}
+
+const snapshotColumns = "s.id, s.user_id, s.name, s.created_by, s.created_at, " +
+	"COUNT(f.filepath), COALESCE(SUM(f.size), 0) " +
+	"FROM snapshots s LEFT JOIN snapshot_files f ON f.snapshot_id = s.id"
+
+// Take a snapshot of a user's sync files.
+func CreateSnapshot(db *sql.DB, userId uint64, name string, createdBy uint64, syncFiles []*SyncFile) (*Snapshot, error) {
+	createdAt := time.Now().UTC()
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		"INSERT INTO snapshots (user_id, name, created_by, created_at)\n"+
+			"  VALUES (:user_id, :name, :created_by, :created_at)\n"+
+			"  ON CONFLICT (user_id, name) DO NOTHING",
+		sql.Named("user_id", userId),
+		sql.Named("name", name),
+		sql.Named("created_by", createdBy),
+		sql.Named("created_at", createdAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if count == 0 {
+		return nil, ErrSnapshotExists
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Id:        uint64(id),
+		UserId:    userId,
+		Name:      name,
+		CreatedBy: createdBy,
+		CreatedAt: createdAt,
+	}
+	for _, syncFile := range syncFiles {
+		_, err := tx.Exec(
+			"INSERT INTO snapshot_files (snapshot_id, filepath, etag, size, updated_at) VALUES (?, ?, ?, ?, ?)",
+			id,
+			syncFile.Filepath,
+			syncFile.Etag,
+			syncFile.Size,
+			syncFile.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.FileCount++
+		snapshot.TotalSize += syncFile.Size
+	}
+
+	return snapshot, tx.Commit()
+}
+
+func GetSnapshot(db *sql.DB, userId uint64, name string) (*Snapshot, error) {
+	row := db.QueryRow(
+		"SELECT "+snapshotColumns+" WHERE s.user_id=? AND s.name=? GROUP BY s.id",
+		userId,
+		name,
+	)
+
+	return scanSnapshot(row)
+}
+
+// Get a user's snapshots, newest first.
+func GetSnapshots(db *sql.DB, userId uint64) ([]*Snapshot, error) {
+	rows, err := db.Query(
+		"SELECT "+snapshotColumns+" WHERE s.user_id=? GROUP BY s.id ORDER BY s.created_at DESC, s.id DESC",
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []*Snapshot{}
+	for rows.Next() {
+		snapshot, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// Get the files in a snapshot inside a folder and its subfolders, sorted by
+// path. An empty folder gets every file in the snapshot.
+func GetSnapshotFiles(db *sql.DB, snapshotId uint64, folder string) ([]*SnapshotFile, error) {
+	where := "snapshot_id = ?"
+	args := []any{snapshotId}
+	if len(folder) > 0 {
+		where += " AND filepath > ?"
+		args = append(args, folder+"/")
+		if upper, ok := prefixUpperBound(folder + "/"); ok {
+			where += " AND filepath < ?"
+			args = append(args, upper)
+		}
+	}
+
+	rows, err := db.Query(
+		"SELECT snapshot_id, filepath, etag, size, updated_at FROM snapshot_files WHERE "+where+" ORDER BY filepath",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []*SnapshotFile{}
+	for rows.Next() {
+		file, err := scanSnapshotFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+func GetSnapshotFile(db *sql.DB, snapshotId uint64, filepath string) (*SnapshotFile, error) {
+	row := db.QueryRow(
+		"SELECT snapshot_id, filepath, etag, size, updated_at FROM snapshot_files WHERE snapshot_id=? AND filepath=?",
+		snapshotId,
+		filepath,
+	)
+
+	return scanSnapshotFile(row)
+}
+
+// Check whether any of a user's snapshots has a file with the etag.
+func SnapshotEtagExists(db *sql.DB, userId uint64, etag string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(
+		"SELECT EXISTS (\n"+
+			"  SELECT 1 FROM snapshot_files f JOIN snapshots s ON s.id = f.snapshot_id\n"+
+			"  WHERE s.user_id=? AND f.etag=?\n"+
+			")",
+		userId,
+		etag,
+	).Scan(&exists)
+	return exists, err
+}
+
+// Delete a snapshot, returning the etags of its files that no other snapshot
+// of the user has, whose content isn't needed anymore.
+func DeleteSnapshot(db *sql.DB, userId uint64, name string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id uint64
+	err = tx.QueryRow("SELECT id FROM snapshots WHERE user_id=? AND name=?", userId, name).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+
+	rows, err := tx.Query(
+		"SELECT DISTINCT f.etag FROM snapshot_files f WHERE f.snapshot_id = :id AND NOT EXISTS (\n"+
+			"  SELECT 1 FROM snapshot_files other JOIN snapshots s ON s.id = other.snapshot_id\n"+
+			"  WHERE s.user_id = :user_id AND other.snapshot_id != :id AND other.etag = f.etag\n"+
+			")",
+		sql.Named("id", id),
+		sql.Named("user_id", userId),
+	)
+	if err != nil {
+		return nil, err
+	}
+	unused := []string{}
+	for rows.Next() {
+		var etag string
+		if err := rows.Scan(&etag); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		unused = append(unused, etag)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM snapshots WHERE id=?", id); err != nil {
+		return nil, err
+	}
+
+	return unused, tx.Commit()
+}
+
+func scanSnapshot(row Scannable) (*Snapshot, error) {
+	var (
+		snapshot  Snapshot
+		createdAt string
+	)
+
+	err := row.Scan(
+		&snapshot.Id,
+		&snapshot.UserId,
+		&snapshot.Name,
+		&snapshot.CreatedBy,
+		&createdAt,
+		&snapshot.FileCount,
+		&snapshot.TotalSize,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	snapshot.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+func scanSnapshotFile(row Scannable) (*SnapshotFile, error) {
+	var (
+		file      SnapshotFile
+		updatedAt string
+	)
+
+	err := row.Scan(&file.SnapshotId, &file.Filepath, &file.Etag, &file.Size, &updatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	file.UpdatedAt, err = time.Parse(ISO_8601_FORMAT, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
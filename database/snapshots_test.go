@@ -0,0 +1,97 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshots(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("snapshots.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-snapshots", "test-snapshots@example.com", "not a secure password")
+	assert.NoError(t, err)
+	files := []*SyncFile{}
+	for _, file := range []struct {
+		filepath, etag string
+		size           int64
+	}{
+		{"a.md", "etag-a", 10},
+		{"notes/b.md", "etag-b", 20},
+		{"notes/deep/c.md", "etag-shared", 30},
+		{"notesx.md", "etag-shared", 30},
+	} {
+		syncFile, err := CreateSyncFile(testdb, file.filepath, file.etag, file.size, user.Id, user.Id)
+		assert.NoError(t, err)
+		files = append(files, syncFile)
+	}
+
+	first, err := CreateSnapshot(testdb, user.Id, "first", user.Id, files)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, first.FileCount)
+		assert.Equal(t, int64(90), first.TotalSize)
+	}
+	_, err = CreateSnapshot(testdb, user.Id, "first", user.Id, nil)
+	assert.ErrorIs(t, err, ErrSnapshotExists)
+	_, err = CreateSnapshot(testdb, user.Id, "second", user.Id, files[:2])
+	assert.NoError(t, err)
+
+	snapshot, err := GetSnapshot(testdb, user.Id, "first")
+	if assert.NoError(t, err) {
+		assert.Equal(t, first.Id, snapshot.Id)
+		assert.Equal(t, 4, snapshot.FileCount)
+		assert.Equal(t, int64(90), snapshot.TotalSize)
+	}
+	_, err = GetSnapshot(testdb, user.Id, "missing")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	snapshots, err := GetSnapshots(testdb, user.Id)
+	if assert.NoError(t, err) && assert.Len(t, snapshots, 2) {
+		assert.Equal(t, "second", snapshots[0].Name)
+		assert.Equal(t, "first", snapshots[1].Name)
+	}
+
+	snapshotFiles, err := GetSnapshotFiles(testdb, first.Id, "notes")
+	if assert.NoError(t, err) && assert.Len(t, snapshotFiles, 2) {
+		assert.Equal(t, "notes/b.md", snapshotFiles[0].Filepath)
+		assert.Equal(t, "notes/deep/c.md", snapshotFiles[1].Filepath)
+	}
+	snapshotFiles, err = GetSnapshotFiles(testdb, first.Id, "")
+	if assert.NoError(t, err) {
+		assert.Len(t, snapshotFiles, 4)
+	}
+	snapshotFile, err := GetSnapshotFile(testdb, first.Id, "a.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "etag-a", snapshotFile.Etag)
+		assert.Equal(t, int64(10), snapshotFile.Size)
+	}
+	_, err = GetSnapshotFile(testdb, first.Id, "missing.md")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	exists, err := SnapshotEtagExists(testdb, user.Id, "etag-shared")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	// only the content no other snapshot has can be removed
+	unused, err := DeleteSnapshot(testdb, user.Id, "first")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"etag-shared"}, unused)
+	_, err = GetSnapshot(testdb, user.Id, "first")
+	assert.ErrorIs(t, err, ErrNoResults)
+	_, err = DeleteSnapshot(testdb, user.Id, "first")
+	assert.ErrorIs(t, err, ErrNoResults)
+	exists, err = SnapshotEtagExists(testdb, user.Id, "etag-shared")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// snapshots are deleted with their user
+	assert.NoError(t, DeleteUser(testdb, user.Id))
+	snapshots, err = GetSnapshots(testdb, user.Id)
+	if assert.NoError(t, err) {
+		assert.Empty(t, snapshots)
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import "database/sql"
+
+// Storage a user's files take up.
+type StorageUsage struct {
+	UserId uint64
+	// Number of synced files and the sum of their sizes
+	FileCount int64
+	FileSize  int64
+	// Size of the content kept for the user's snapshots, counting content
+	// that's in several snapshots once
+	SnapshotSize int64
+}
+
+const storageUsageQuery = "SELECT users.id, COALESCE(files.count, 0), COALESCE(files.size, 0), COALESCE(snapshots.size, 0)\n" +
+	"  FROM users\n" +
+	"  LEFT JOIN (\n" +
+	"    SELECT user_id, COUNT(*) AS count, SUM(size) AS size FROM file_syncs GROUP BY user_id\n" +
+	"  ) AS files ON files.user_id=users.id\n" +
+	"  LEFT JOIN (\n" +
+	"    SELECT user_id, SUM(size) AS size FROM (\n" +
+	"      SELECT DISTINCT snapshots.user_id, snapshot_files.etag, snapshot_files.size\n" +
+	"        FROM snapshot_files JOIN snapshots ON snapshots.id=snapshot_files.snapshot_id\n" +
+	"    ) GROUP BY user_id\n" +
+	"  ) AS snapshots ON snapshots.user_id=users.id\n"
+
+// Get the storage every user's files take up, by the user's id.
+func GetStorageUsage(db *sql.DB) (map[uint64]*StorageUsage, error) {
+	rows, err := db.Query(storageUsageQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := map[uint64]*StorageUsage{}
+	for rows.Next() {
+		usage, err := scanStorageUsage(rows)
+		if err != nil {
+			return nil, err
+		}
+		usages[usage.UserId] = usage
+	}
+
+	return usages, rows.Err()
+}
+
+// Get the storage a user's files take up, returning ErrNoResults if there's no
+// user with the id.
+func GetUserStorageUsage(db *sql.DB, userId uint64) (*StorageUsage, error) {
+	row := db.QueryRow(storageUsageQuery+"  WHERE users.id=?", userId)
+	usage, err := scanStorageUsage(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoResults
+	}
+	return usage, err
+}
+
+func scanStorageUsage(row Scannable) (*StorageUsage, error) {
+	var usage StorageUsage
+	err := row.Scan(&usage.UserId, &usage.FileCount, &usage.FileSize, &usage.SnapshotSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
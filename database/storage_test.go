@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStorageUsage(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-storage-usage.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-storage", "test-storage@example.com", "not a secure password")
+	assert.NoError(t, err)
+	empty, err := CreateUser(testdb, "test-storage-empty", "test-storage-empty@example.com", "not a secure password")
+	assert.NoError(t, err)
+	files := []*SyncFile{}
+	for _, file := range []struct {
+		filepath, etag string
+		size           int64
+	}{
+		{"a.md", "etag-a", 10},
+		{"b.md", "etag-b", 20},
+		{"copy-of-b.md", "etag-b", 20},
+	} {
+		syncFile, err := CreateSyncFile(testdb, file.filepath, file.etag, file.size, user.Id, user.Id)
+		assert.NoError(t, err)
+		files = append(files, syncFile)
+	}
+	_, err = CreateSnapshot(testdb, user.Id, "first", user.Id, files)
+	assert.NoError(t, err)
+	_, err = CreateSnapshot(testdb, user.Id, "second", user.Id, files[:1])
+	assert.NoError(t, err)
+
+	usages, err := GetStorageUsage(testdb)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &StorageUsage{UserId: user.Id, FileCount: 3, FileSize: 50, SnapshotSize: 30}, usages[user.Id])
+		assert.Equal(t, &StorageUsage{UserId: empty.Id}, usages[empty.Id])
+	}
+
+	usage, err := GetUserStorageUsage(testdb, user.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, usages[user.Id], usage)
+	}
+	_, err = GetUserStorageUsage(testdb, user.Id+1000)
+	assert.ErrorIs(t, err, ErrNoResults)
+}
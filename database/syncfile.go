@@ -3,7 +3,8 @@ package database
 import (
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -16,46 +17,67 @@ type SyncFile struct {
 	UserId    uint64
 	Filepath  string
 	Etag      string
+	Size      int64
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// User that last created, changed or renamed the file, which can be a
+	// member of the owner's vault, or 0 if the user was deleted
+	UpdatedBy uint64
 }
 
 func CreateSyncFile(
 	db *sql.DB,
 	filepath, etag string,
-	userId uint64,
+	size int64,
+	userId, updatedBy uint64,
+) (*SyncFile, error) {
+	return createSyncFile(db, filepath, etag, size, userId, updatedBy)
+}
+
+func createSyncFile(
+	db execQuerier,
+	filepath, etag string,
+	size int64,
+	userId, updatedBy uint64,
 ) (*SyncFile, error) {
 	var syncFile SyncFile
 
 	createdAt := time.Now().UTC()
-	_, err := db.Exec(
-		"INSERT INTO file_syncs (filepath, etag, created_at, updated_at, user_id)\n"+
-			"  VALUES (:filepath, :etag, :created_at, :updated_at, :user_id)",
+	res, err := db.Exec(
+		"INSERT INTO file_syncs (filepath, etag, size, created_at, updated_at, updated_by, user_id)\n"+
+			"  VALUES (:filepath, :etag, :size, :created_at, :updated_at, :updated_by, :user_id)",
 		sql.Named("filepath", filepath),
 		sql.Named("etag", etag),
+		sql.Named("size", size),
 		sql.Named("created_at", createdAt),
 		sql.Named("updated_at", createdAt),
+		sql.Named("updated_by", updatedBy),
 		sql.Named("user_id", userId),
 	)
 	if err != nil {
 		return nil, err
 	}
-	row := db.QueryRow("SELECT id FROM file_syncs WHERE filepath=?", filepath)
-	if err := row.Scan(&syncFile.Id); err != nil {
+	// several users can sync files with the same filepath, so look the row up
+	// by its rowid instead of its filepath
+	id, err := res.LastInsertId()
+	if err != nil {
 		return nil, err
 	}
+	syncFile.Id = uint64(id)
 	syncFile.Filepath = filepath
 	syncFile.UserId = userId
 	syncFile.Etag = etag
+	syncFile.Size = size
 	syncFile.CreatedAt = createdAt
 	syncFile.UpdatedAt = createdAt
+	syncFile.UpdatedBy = updatedBy
 
 	return &syncFile, nil
 }
 
 func GetSyncFileById(db *sql.DB, id uint64) (*SyncFile, error) {
 	row := db.QueryRow(
-		"SELECT id, user_id, filepath, etag, created_at, updated_at "+
+		"SELECT id, user_id, filepath, etag, size, created_at, updated_at, updated_by "+
 			"FROM file_syncs WHERE id=?",
 		id,
 	)
@@ -65,7 +87,7 @@ func GetSyncFileById(db *sql.DB, id uint64) (*SyncFile, error) {
 
 func GetSyncFileByFilepath(db *sql.DB, filepath string) (*SyncFile, error) {
 	row := db.QueryRow(
-		"SELECT id, user_id, filepath, etag, created_at, updated_at "+
+		"SELECT id, user_id, filepath, etag, size, created_at, updated_at, updated_by "+
 			"FROM file_syncs WHERE filepath=?",
 		filepath,
 	)
@@ -73,30 +95,44 @@ func GetSyncFileByFilepath(db *sql.DB, filepath string) (*SyncFile, error) {
 	return scanSyncFile(row)
 }
 
+func GetUserSyncFileByFilepath(db *sql.DB, userId uint64, filepath string) (*SyncFile, error) {
+	row := db.QueryRow(
+		"SELECT id, user_id, filepath, etag, size, created_at, updated_at, updated_by "+
+			"FROM file_syncs WHERE user_id=? AND filepath=?",
+		userId,
+		filepath,
+	)
+
+	return scanSyncFile(row)
+}
+
+// Get every sync file of a user.
 func GetSyncFilesByUserId(db *sql.DB, userId uint64) ([]*SyncFile, error) {
-	var syncfiles []*SyncFile
+	syncFiles, _, err := ListSyncFiles(db, userId, SyncFileQuery{})
+	return syncFiles, err
+}
 
+// Get every user's sync files.
+func GetAllSyncFiles(db *sql.DB) ([]*SyncFile, error) {
 	rows, err := db.Query(
-		"SELECT id, user_id, filepath, etag, created_at, updated_at "+
-			"FROM file_syncs WHERE user_id=?",
-		userId,
+		"SELECT id, user_id, filepath, etag, size, created_at, updated_at, updated_by " +
+			"FROM file_syncs ORDER BY id",
 	)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var syncFiles []*SyncFile
 	for rows.Next() {
-		syncfile, err := scanSyncFile(rows)
-		log.Println(syncfile)
+		syncFile, err := scanSyncFile(rows)
 		if err != nil {
 			return nil, err
 		}
-		syncfiles = append(syncfiles, syncfile)
-	}
-	if len(syncfiles) == 0 {
-		return nil, ErrNoResults
+		syncFiles = append(syncFiles, syncFile)
 	}
 
-	return syncfiles, nil
+	return syncFiles, rows.Err()
 }
 
 func UpdateSyncFileFilepath(db *sql.DB, currFilepath, newFilepath string) error {
@@ -120,6 +156,19 @@ func UpdateSyncFileFilepath(db *sql.DB, currFilepath, newFilepath string) error
 	return nil
 }
 
+// Change the filepath of a sync file.
+func RenameSyncFile(db *sql.DB, id uint64, filepath string, updatedBy uint64) error {
+	_, err := db.Exec(
+		"UPDATE file_syncs SET filepath=?, updated_at=?, updated_by=? WHERE id=?",
+		filepath,
+		time.Now().UTC(),
+		updatedBy,
+		id,
+	)
+
+	return err
+}
+
 func UpdateSyncFileEtag(db *sql.DB, filepath, etag string) error {
 	_, err := db.Exec("UPDATE file_syncs SET etag=? WHERE filepath=?", etag, filepath)
 	if err != nil {
@@ -129,11 +178,78 @@ func UpdateSyncFileEtag(db *sql.DB, filepath, etag string) error {
 	return nil
 }
 
+// Update the etag and size of a sync file after its contents change.
+func UpdateSyncFileContent(db *sql.DB, id uint64, etag string, size int64, updatedBy uint64) error {
+	return updateSyncFileContent(db, id, etag, size, updatedBy)
+}
+
+func updateSyncFileContent(db execQuerier, id uint64, etag string, size int64, updatedBy uint64) error {
+	_, err := db.Exec(
+		"UPDATE file_syncs SET etag=?, size=?, updated_at=?, updated_by=? WHERE id=?",
+		etag,
+		size,
+		time.Now().UTC(),
+		updatedBy,
+		id,
+	)
+
+	return err
+}
+
+func DeleteSyncFile(db *sql.DB, id uint64) error {
+	_, err := db.Exec("DELETE FROM file_syncs WHERE id=?", id)
+	return err
+}
+
+// Change to a user's sync files made by ApplySyncFileChanges.
+type SyncFileChange struct {
+	Filepath string
+	Etag     string
+	Size     int64
+	// Sync file to change or delete, or nil to create one
+	Existing *SyncFile
+	Delete   bool
+}
+
+// Make several changes to a user's sync files in one transaction, so either
+// all of them are made or none are. Returns the sync file each change created
+// or updated, or nil for deleted files.
+func ApplySyncFileChanges(db *sql.DB, userId, updatedBy uint64, changes []SyncFileChange) ([]*SyncFile, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	syncFiles := make([]*SyncFile, len(changes))
+	for i, change := range changes {
+		switch {
+		case change.Existing == nil:
+			syncFiles[i], err = createSyncFile(tx, change.Filepath, change.Etag, change.Size, userId, updatedBy)
+		case change.Delete:
+			_, err = tx.Exec("DELETE FROM file_syncs WHERE id=?", change.Existing.Id)
+		default:
+			err = updateSyncFileContent(tx, change.Existing.Id, change.Etag, change.Size, updatedBy)
+			if err == nil {
+				updated := *change.Existing
+				updated.Etag, updated.Size, updated.UpdatedBy = change.Etag, change.Size, updatedBy
+				syncFiles[i] = &updated
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return syncFiles, tx.Commit()
+}
+
 func scanSyncFile(row Scannable) (*SyncFile, error) {
 	var (
 		syncfile  SyncFile
 		createdAt string
 		updatedAt string
+		updatedBy sql.NullInt64
 	)
 
 	err := row.Scan(
@@ -141,8 +257,10 @@ func scanSyncFile(row Scannable) (*SyncFile, error) {
 		&syncfile.UserId,
 		&syncfile.Filepath,
 		&syncfile.Etag,
+		&syncfile.Size,
 		&createdAt,
 		&updatedAt,
+		&updatedBy,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -150,6 +268,7 @@ func scanSyncFile(row Scannable) (*SyncFile, error) {
 		}
 		return nil, err
 	}
+	syncfile.UpdatedBy = uint64(updatedBy.Int64)
 	syncfile.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt)
 	if err != nil {
 		return nil, err
@@ -161,3 +280,170 @@ func scanSyncFile(row Scannable) (*SyncFile, error) {
 
 	return &syncfile, nil
 }
+
+// Fields sync files can be sorted by.
+type SyncFileSort string
+
+const (
+	SortByFilepath  SyncFileSort = "filepath"
+	SortByUpdatedAt SyncFileSort = "updated_at"
+)
+
+// Position in a list of sync files, which the next page of the list starts
+// after.
+type SyncFileCursor struct {
+	Filepath  string
+	UpdatedAt time.Time
+	Id        uint64
+}
+
+// Filters, sort and page of a list of a user's sync files. Zero values don't
+// filter anything.
+type SyncFileQuery struct {
+	// Only list files with paths that start with the prefix
+	Prefix string
+	// Only list files updated at or after the time
+	ModifiedSince time.Time
+	// Only list files with one of the extensions, like "md" or ".md"
+	Extensions []string
+	MinSize    *int64
+	MaxSize    *int64
+	// Field to sort by, which is the filepath by default
+	Sort       SyncFileSort
+	Descending bool
+	// Maximum number of files to list, or 0 for every file
+	Limit int
+	// Start the list after the file at the cursor
+	After *SyncFileCursor
+}
+
+// List a user's sync files one page at a time using keyset pagination. The
+// returned cursor points to the last file in the page, and is nil if there
+// are no more files to list.
+func ListSyncFiles(db *sql.DB, userId uint64, query SyncFileQuery) ([]*SyncFile, *SyncFileCursor, error) {
+	where := []string{"user_id = ?"}
+	args := []any{userId}
+
+	if len(query.Prefix) > 0 {
+		// compare with a range instead of LIKE, so the index on filepath is
+		// used and prefixes are case-sensitive like filepaths
+		where = append(where, "filepath >= ?")
+		args = append(args, query.Prefix)
+		if upper, ok := prefixUpperBound(query.Prefix); ok {
+			where = append(where, "filepath < ?")
+			args = append(args, upper)
+		}
+	}
+	if !query.ModifiedSince.IsZero() {
+		where = append(where, "updated_at >= ?")
+		args = append(args, query.ModifiedSince.UTC())
+	}
+	if len(query.Extensions) > 0 {
+		extensions := make([]string, 0, len(query.Extensions))
+		for _, extension := range query.Extensions {
+			extensions = append(extensions, `filepath LIKE ? ESCAPE '\'`)
+			args = append(args, "%."+escapeLike(strings.TrimPrefix(extension, ".")))
+		}
+		where = append(where, "("+strings.Join(extensions, " OR ")+")")
+	}
+	if query.MinSize != nil {
+		where = append(where, "size >= ?")
+		args = append(args, *query.MinSize)
+	}
+	if query.MaxSize != nil {
+		where = append(where, "size <= ?")
+		args = append(args, *query.MaxSize)
+	}
+
+	comparison, direction := ">", "ASC"
+	if query.Descending {
+		comparison, direction = "<", "DESC"
+	}
+	var orderBy string
+	switch query.Sort {
+	case SortByUpdatedAt:
+		if query.After != nil {
+			where = append(where, "(updated_at, id) "+comparison+" (?, ?)")
+			args = append(args, query.After.UpdatedAt.UTC(), query.After.Id)
+		}
+		orderBy = "updated_at " + direction + ", id " + direction
+	case SortByFilepath, "":
+		// filepaths are unique for each user, so they're enough to find
+		// the cursor's position
+		if query.After != nil {
+			where = append(where, "filepath "+comparison+" ?")
+			args = append(args, query.After.Filepath)
+		}
+		orderBy = "filepath " + direction
+	default:
+		return nil, nil, fmt.Errorf("can't sort sync files by %q", query.Sort)
+	}
+
+	statement := "SELECT id, user_id, filepath, etag, size, created_at, updated_at, updated_by\n" +
+		"  FROM file_syncs WHERE " + strings.Join(where, " AND ") + "\n" +
+		"  ORDER BY " + orderBy
+	if query.Limit > 0 {
+		// get one more file than the limit to check whether there's
+		// another page
+		statement += " LIMIT ?"
+		args = append(args, query.Limit+1)
+	}
+
+	rows, err := db.Query(statement, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	syncFiles := []*SyncFile{}
+	for rows.Next() {
+		syncFile, err := scanSyncFile(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		syncFiles = append(syncFiles, syncFile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if query.Limit > 0 && len(syncFiles) > query.Limit {
+		syncFiles = syncFiles[:query.Limit]
+		last := syncFiles[len(syncFiles)-1]
+		return syncFiles, &SyncFileCursor{
+			Filepath:  last.Filepath,
+			UpdatedAt: last.UpdatedAt,
+			Id:        last.Id,
+		}, nil
+	}
+	return syncFiles, nil, nil
+}
+
+// Get the smallest string that's greater than every string starting with the
+// prefix, comparing strings byte by byte like SQLite does. Prefixes made of
+// 0xff bytes don't have one.
+func prefixUpperBound(prefix string) (string, bool) {
+	upper := []byte(prefix)
+	for i := len(upper) - 1; i >= 0; i-- {
+		if upper[i] < 0xff {
+			upper[i]++
+			return string(upper[:i+1]), true
+		}
+	}
+	return "", false
+}
+
+// Get an SQL condition matching values of a column that start with the
+// prefix, along with its arguments. Comparing with the prefix's bounds works
+// for any prefix, where substr() counts characters and not bytes.
+func prefixCondition(column, prefix string) (string, []any) {
+	if upper, ok := prefixUpperBound(prefix); ok {
+		return "(" + column + " >= ? AND " + column + " < ?)", []any{prefix, upper}
+	}
+	return column + " >= ?", []any{prefix}
+}
+
+// Escape the wildcards in a LIKE pattern, using \ as the escape character.
+func escapeLike(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(pattern)
+}
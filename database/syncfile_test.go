@@ -3,6 +3,7 @@ package database
 import (
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -17,12 +18,12 @@ func TestGetSyncFileBy(t *testing.T) {
 	user, err := CreateUser(testdb, "test-user", "test-user@example.com", "not a secure password")
 	assert.NoError(t, err)
 	syncfiles := []*SyncFile{
-		{Filepath: "/folder/file1.md", Etag: "f0f9ef0cbb7e0d836aea4a4c6fe6420a"},
-		{Filepath: "/folder/file2.md", Etag: "8c42bf48c4b5d8553ad3ab5b30b484df"},
-		{Filepath: "/folder/file3.md", Etag: "37e904b58a2a5e61babc827ded3a828d"},
+		{Filepath: "/folder/file1.md", Etag: "f0f9ef0cbb7e0d836aea4a4c6fe6420a", Size: 120},
+		{Filepath: "/folder/file2.md", Etag: "8c42bf48c4b5d8553ad3ab5b30b484df", Size: 4096},
+		{Filepath: "/folder/file3.md", Etag: "37e904b58a2a5e61babc827ded3a828d", Size: 7},
 	}
 	for i, syncfile := range syncfiles {
-		syncfiles[i], err = CreateSyncFile(testdb, syncfile.Filepath, syncfile.Etag, user.Id)
+		syncfiles[i], err = CreateSyncFile(testdb, syncfile.Filepath, syncfile.Etag, syncfile.Size, user.Id, user.Id)
 		assert.NoError(t, err)
 	}
 
@@ -87,9 +88,10 @@ func TestGetSyncFileBy(t *testing.T) {
 	})
 	assert.ElementsMatch(t, syncfiles, dbSyncfiles)
 
-	// sync files for user are not found
+	// users without sync files get an empty list
 	dbSyncfiles, err = GetSyncFilesByUserId(testdb, user.Id+1)
-	assert.ErrorIs(t, err, ErrNoResults)
+	assert.NoError(t, err)
+	assert.NotNil(t, dbSyncfiles)
 	assert.Empty(t, dbSyncfiles)
 
 	for _, syncfile := range syncfiles {
@@ -114,7 +116,7 @@ func TestUpdateFileSyncFilepath(t *testing.T) {
 		{Filepath: "/folder/file3.md", Etag: "37e904b58a2a5e61babc827ded3a828d"},
 	}
 	for i, syncfile := range syncfiles {
-		syncfiles[i], err = CreateSyncFile(testdb, syncfile.Filepath, syncfile.Etag, user.Id)
+		syncfiles[i], err = CreateSyncFile(testdb, syncfile.Filepath, syncfile.Etag, syncfile.Size, user.Id, user.Id)
 		assert.NoError(t, err)
 	}
 
@@ -171,7 +173,7 @@ func TestUpdateFileSyncEtag(t *testing.T) {
 		{Filepath: "/folder/file3.md", Etag: "37e904b58a2a5e61babc827ded3a828d"},
 	}
 	for i, syncfile := range syncfiles {
-		syncfiles[i], err = CreateSyncFile(testdb, syncfile.Filepath, syncfile.Etag, user.Id)
+		syncfiles[i], err = CreateSyncFile(testdb, syncfile.Filepath, syncfile.Etag, syncfile.Size, user.Id, user.Id)
 		assert.NoError(t, err)
 	}
 
@@ -184,3 +186,187 @@ func TestUpdateFileSyncEtag(t *testing.T) {
 	assert.NoError(t, row.Scan(&count))
 	assert.Equal(t, 1, count)
 }
+
+func TestUserSyncFiles(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-user-sync-files.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+	user1, err := CreateUser(testdb, "test-user-1", "test-user-1@example.com", "not a password")
+	assert.NoError(t, err)
+	user2, err := CreateUser(testdb, "test-user-2", "test-user-2@example.com", "not a password")
+	assert.NoError(t, err)
+
+	// two users can sync files with the same filepath
+	syncfile1, err := CreateSyncFile(testdb, "notes/todo.md", "f0f9ef0cbb7e0d836aea4a4c6fe6420a", 10, user1.Id, user1.Id)
+	assert.NoError(t, err)
+	syncfile2, err := CreateSyncFile(testdb, "notes/todo.md", "8c42bf48c4b5d8553ad3ab5b30b484df", 20, user2.Id, user2.Id)
+	assert.NoError(t, err)
+	assert.NotEqual(t, syncfile1.Id, syncfile2.Id)
+
+	found, err := GetUserSyncFileByFilepath(testdb, user2.Id, "notes/todo.md")
+	assert.NoError(t, err)
+	assert.Equal(t, *syncfile2, *found)
+	_, err = GetUserSyncFileByFilepath(testdb, user2.Id, "notes/done.md")
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	// update the contents of the file, as a member of user1's vault
+	assert.NoError(t, UpdateSyncFileContent(testdb, syncfile1.Id, "37e904b58a2a5e61babc827ded3a828d", 30, user2.Id))
+	found, err = GetSyncFileById(testdb, syncfile1.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "37e904b58a2a5e61babc827ded3a828d", found.Etag)
+	assert.Equal(t, int64(30), found.Size)
+	assert.False(t, found.UpdatedAt.Before(syncfile1.UpdatedAt))
+	assert.Equal(t, user1.Id, found.UserId)
+	assert.Equal(t, user2.Id, found.UpdatedBy)
+
+	// delete the file
+	assert.NoError(t, DeleteSyncFile(testdb, syncfile1.Id))
+	_, err = GetSyncFileById(testdb, syncfile1.Id)
+	assert.ErrorIs(t, err, ErrNoResults)
+	_, err = GetSyncFileById(testdb, syncfile2.Id)
+	assert.NoError(t, err)
+}
+
+func TestListSyncFiles(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("list_syncfiles.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+	user, err := CreateUser(testdb, "test-list-user", "test-list-user@example.com", "not a password")
+	assert.NoError(t, err)
+	other, err := CreateUser(testdb, "test-list-other", "test-list-other@example.com", "not a password")
+	assert.NoError(t, err)
+
+	filepaths := []string{"b.md", "notes/a.md", "notes/b.MD", "notes/c.png", "notes_old/d.md", "z_100%.txt", "zettel.md"}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, filepath := range filepaths {
+		syncFile, err := CreateSyncFile(testdb, filepath, "etag", int64(i*10), user.Id, user.Id)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		// files are changed in reverse order, with two changed at once
+		updatedAt := start.Add(time.Duration(len(filepaths)-i) * time.Second / 2)
+		_, err = testdb.Exec("UPDATE file_syncs SET updated_at=? WHERE id=?", updatedAt.Truncate(time.Second), syncFile.Id)
+		assert.NoError(t, err)
+	}
+	_, err = CreateSyncFile(testdb, "notes/other.md", "etag", 10, other.Id, other.Id)
+	assert.NoError(t, err)
+
+	size := func(size int64) *int64 { return &size }
+	testCases := []struct {
+		name  string
+		query SyncFileQuery
+		want  []string
+	}{
+		{"every file", SyncFileQuery{}, filepaths},
+		{"prefix", SyncFileQuery{Prefix: "notes/"}, []string{"notes/a.md", "notes/b.MD", "notes/c.png"}},
+		{"prefix without slash", SyncFileQuery{Prefix: "notes"}, []string{"notes/a.md", "notes/b.MD", "notes/c.png", "notes_old/d.md"}},
+		{"extensions", SyncFileQuery{Extensions: []string{"md", ".txt"}}, []string{"b.md", "notes/a.md", "notes/b.MD", "notes_old/d.md", "z_100%.txt", "zettel.md"}},
+		{"extension wildcards", SyncFileQuery{Extensions: []string{"%"}}, []string{}},
+		{"size", SyncFileQuery{MinSize: size(20), MaxSize: size(40)}, []string{"notes/b.MD", "notes/c.png", "notes_old/d.md"}},
+		{"modified since", SyncFileQuery{ModifiedSince: start.Add(2 * time.Second)}, []string{"b.md", "notes/a.md", "notes/b.MD", "notes/c.png"}},
+		{"descending", SyncFileQuery{Prefix: "notes/", Descending: true}, []string{"notes/c.png", "notes/b.MD", "notes/a.md"}},
+		{"updated at", SyncFileQuery{Sort: SortByUpdatedAt, Prefix: "notes"}, []string{"notes_old/d.md", "notes/b.MD", "notes/c.png", "notes/a.md"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			syncFiles, cursor, err := ListSyncFiles(testdb, user.Id, tc.query)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Nil(t, cursor)
+			got := []string{}
+			for _, syncFile := range syncFiles {
+				got = append(got, syncFile.Filepath)
+			}
+			assert.Equal(t, tc.want, got)
+
+			// paging through the files gets the same files in the same order
+			paged := []string{}
+			query := tc.query
+			query.Limit = 2
+			for {
+				syncFiles, cursor, err := ListSyncFiles(testdb, user.Id, query)
+				if !assert.NoError(t, err) || !assert.LessOrEqual(t, len(syncFiles), 2) {
+					return
+				}
+				for _, syncFile := range syncFiles {
+					paged = append(paged, syncFile.Filepath)
+				}
+				if cursor == nil {
+					break
+				}
+				query.After = cursor
+			}
+			assert.Equal(t, tc.want, paged)
+		})
+	}
+
+	_, _, err = ListSyncFiles(testdb, user.Id, SyncFileQuery{Sort: "size"})
+	assert.Error(t, err)
+}
+
+func TestApplySyncFileChanges(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-apply-sync-file-changes.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+	user, err := CreateUser(testdb, "test-user", "test-user@example.com", "not a password")
+	assert.NoError(t, err)
+	changed, err := CreateSyncFile(testdb, "changed.md", "f0f9ef0cbb7e0d836aea4a4c6fe6420a", 5, user.Id, user.Id)
+	assert.NoError(t, err)
+	deleted, err := CreateSyncFile(testdb, "deleted.md", "8c42bf48c4b5d8553ad3ab5b30b484df", 7, user.Id, user.Id)
+	assert.NoError(t, err)
+
+	filepaths := func() []string {
+		t.Helper()
+		syncFiles, err := GetSyncFilesByUserId(testdb, user.Id)
+		assert.NoError(t, err)
+		filepaths := []string{}
+		for _, syncFile := range syncFiles {
+			filepaths = append(filepaths, syncFile.Filepath+":"+syncFile.Etag)
+		}
+		slices.Sort(filepaths)
+		return filepaths
+	}
+	before := filepaths()
+
+	// none of the changes are made when one of them fails
+	_, err = testdb.Exec(
+		"CREATE TRIGGER fail_insert BEFORE INSERT ON file_syncs WHEN NEW.filepath='fail.md'\n" +
+			"BEGIN SELECT RAISE(ABORT, 'insert failed'); END",
+	)
+	assert.NoError(t, err)
+	_, err = ApplySyncFileChanges(testdb, user.Id, user.Id, []SyncFileChange{
+		{Filepath: "changed.md", Etag: "37e904b58a2a5e61babc827ded3a828d", Size: 9, Existing: changed},
+		{Existing: deleted, Delete: true},
+		{Filepath: "created.md", Etag: "d41d8cd98f00b204e9800998ecf8427e"},
+		{Filepath: "fail.md", Etag: "d41d8cd98f00b204e9800998ecf8427e"},
+	})
+	assert.ErrorContains(t, err, "insert failed")
+	assert.Equal(t, before, filepaths())
+
+	syncFiles, err := ApplySyncFileChanges(testdb, user.Id, user.Id, []SyncFileChange{
+		{Filepath: "changed.md", Etag: "37e904b58a2a5e61babc827ded3a828d", Size: 9, Existing: changed},
+		{Existing: deleted, Delete: true},
+		{Filepath: "created.md", Etag: "d41d8cd98f00b204e9800998ecf8427e"},
+	})
+	if assert.NoError(t, err) && assert.Len(t, syncFiles, 3) {
+		assert.Equal(t, changed.Id, syncFiles[0].Id)
+		assert.Equal(t, int64(9), syncFiles[0].Size)
+		assert.Nil(t, syncFiles[1])
+		assert.Equal(t, "created.md", syncFiles[2].Filepath)
+	}
+	assert.Equal(t, []string{
+		"changed.md:37e904b58a2a5e61babc827ded3a828d",
+		"created.md:d41d8cd98f00b204e9800998ecf8427e",
+	}, filepaths())
+}
@@ -0,0 +1,148 @@
+package database
+
+import (
+	"database/sql"
+)
+
+type Tag struct {
+	// Tag as it's written in the note, without the #
+	Tag string
+	// Lowercase tag that tags are matched by
+	Name string
+}
+
+type TagCount struct {
+	Tag  string
+	Name string
+	// Number of files with the tag
+	Count int
+}
+
+// How tags are matched when looking up files by tag.
+type TagMatch int
+
+const (
+	// Only match the tag itself
+	TagMatchExact TagMatch = iota
+	// Match the tag and tags nested in it, so project matches project/alpha
+	TagMatchNested
+	// Match every tag that starts with the name, so proj matches project
+	TagMatchPrefix
+)
+
+// Replace the tags of a sync file with a new set of tags.
+func ReplaceTags(db *sql.DB, fileId, userId uint64, tags []*Tag) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM tags WHERE file_id=?", fileId); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		_, err := tx.Exec(
+			"INSERT INTO tags (file_id, tag, name, user_id) VALUES (?, ?, ?, ?)",
+			fileId,
+			tag.Tag,
+			tag.Name,
+			userId,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get the tags of a sync file.
+func GetSyncFileTags(db *sql.DB, fileId uint64) ([]*Tag, error) {
+	rows, err := db.Query("SELECT tag, name FROM tags WHERE file_id=? ORDER BY id", fileId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Tag, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// Get every tag a user's files have along with the number of files with each
+// tag, optionally only getting tags that start with a prefix. Tags written
+// with different cases are counted together.
+func GetTagCounts(db *sql.DB, userId uint64, prefix string) ([]*TagCount, error) {
+	condition, args := prefixCondition("name", prefix)
+	rows, err := db.Query(
+		"SELECT MIN(tag), name, COUNT(DISTINCT file_id) FROM tags\n"+
+			"  WHERE user_id=? AND "+condition+"\n"+
+			"  GROUP BY name ORDER BY name",
+		append([]any{userId}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*TagCount{}
+	for rows.Next() {
+		var count TagCount
+		if err := rows.Scan(&count.Tag, &count.Name, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+
+	return counts, rows.Err()
+}
+
+// Get a user's sync files that have a tag.
+func GetSyncFilesByTag(db *sql.DB, userId uint64, name string, match TagMatch) ([]*SyncFile, error) {
+	var condition string
+	args := []any{userId}
+	switch match {
+	case TagMatchNested:
+		nested, nestedArgs := prefixCondition("t.name", name+"/")
+		condition = "(t.name=? OR " + nested + ")"
+		args = append(append(args, name), nestedArgs...)
+	case TagMatchPrefix:
+		prefix, prefixArgs := prefixCondition("t.name", name)
+		condition = prefix
+		args = append(args, prefixArgs...)
+	default:
+		condition = "t.name=?"
+		args = append(args, name)
+	}
+
+	rows, err := db.Query(
+		"SELECT f.id, f.user_id, f.filepath, f.etag, f.size, f.created_at, f.updated_at, f.updated_by\n"+
+			"  FROM file_syncs f WHERE f.id IN (\n"+
+			"    SELECT t.file_id FROM tags t WHERE t.user_id=? AND "+condition+"\n"+
+			"  ) ORDER BY f.filepath",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	syncFiles := []*SyncFile{}
+	for rows.Next() {
+		syncFile, err := scanSyncFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		syncFiles = append(syncFiles, syncFile)
+	}
+
+	return syncFiles, rows.Err()
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("tags.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-tags-user", "test-tags-user@example.com", "not a secure password")
+	assert.NoError(t, err)
+	alpha, err := CreateSyncFile(testdb, "alpha.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+	beta, err := CreateSyncFile(testdb, "beta.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ReplaceTags(testdb, alpha.Id, user.Id, []*Tag{
+		{Tag: "Project", Name: "project"},
+		{Tag: "project/alpha", Name: "project/alpha"},
+	}))
+	assert.NoError(t, ReplaceTags(testdb, beta.Id, user.Id, []*Tag{
+		{Tag: "project", Name: "project"},
+		{Tag: "projects", Name: "projects"},
+	}))
+
+	counts, err := GetTagCounts(testdb, user.Id, "")
+	if assert.NoError(t, err) && assert.Len(t, counts, 3) {
+		assert.Equal(t, TagCount{Tag: "Project", Name: "project", Count: 2}, *counts[0])
+		assert.Equal(t, "project/alpha", counts[1].Name)
+		assert.Equal(t, 1, counts[1].Count)
+	}
+	counts, err = GetTagCounts(testdb, user.Id, "project/")
+	if assert.NoError(t, err) && assert.Len(t, counts, 1) {
+		assert.Equal(t, "project/alpha", counts[0].Tag)
+	}
+
+	testCases := []struct {
+		name  string
+		tag   string
+		match TagMatch
+		want  []string
+	}{
+		{name: "exact", tag: "project/alpha", match: TagMatchExact, want: []string{"alpha.md"}},
+		{name: "nested", tag: "project", match: TagMatchNested, want: []string{"alpha.md", "beta.md"}},
+		{name: "nested doesn't match other tags with the prefix", tag: "project/alpha", match: TagMatchNested, want: []string{"alpha.md"}},
+		{name: "prefix", tag: "projects", match: TagMatchPrefix, want: []string{"beta.md"}},
+		{name: "missing tag", tag: "archive", match: TagMatchPrefix, want: []string{}},
+	}
+	for _, tc := range testCases {
+		syncFiles, err := GetSyncFilesByTag(testdb, user.Id, tc.tag, tc.match)
+		if assert.NoError(t, err, tc.name) {
+			filepaths := []string{}
+			for _, syncFile := range syncFiles {
+				filepaths = append(filepaths, syncFile.Filepath)
+			}
+			assert.Equal(t, tc.want, filepaths, tc.name)
+		}
+	}
+
+	// prefixes are compared by bytes, so tags with multi-byte characters work
+	gamma, err := CreateSyncFile(testdb, "gamma.md", "etag", 10, user.Id, user.Id)
+	assert.NoError(t, err)
+	assert.NoError(t, ReplaceTags(testdb, gamma.Id, user.Id, []*Tag{
+		{Tag: "café/menu", Name: "café/menu"},
+		{Tag: "cafeteria", Name: "cafeteria"},
+	}))
+	counts, err = GetTagCounts(testdb, user.Id, "café/")
+	if assert.NoError(t, err) && assert.Len(t, counts, 1) {
+		assert.Equal(t, "café/menu", counts[0].Name)
+	}
+	counts, err = GetTagCounts(testdb, user.Id, "café")
+	if assert.NoError(t, err) {
+		assert.Len(t, counts, 1)
+	}
+	for _, match := range []TagMatch{TagMatchNested, TagMatchPrefix} {
+		syncFiles, err := GetSyncFilesByTag(testdb, user.Id, "café", match)
+		if assert.NoError(t, err) && assert.Len(t, syncFiles, 1, match) {
+			assert.Equal(t, "gamma.md", syncFiles[0].Filepath)
+		}
+	}
+
+	// replacing tags removes the old ones, and deleting a file removes its tags
+	assert.NoError(t, ReplaceTags(testdb, alpha.Id, user.Id, []*Tag{{Tag: "done", Name: "done"}}))
+	tags, err := GetSyncFileTags(testdb, alpha.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Tag{{Tag: "done", Name: "done"}}, tags)
+	assert.NoError(t, DeleteSyncFile(testdb, alpha.Id))
+	tags, err = GetSyncFileTags(testdb, alpha.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+}
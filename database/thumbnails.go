@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Cached thumbnail of an image file. Thumbnails are generated from the
+// version of the file with the etag, so they're stale once the etag changes.
+type Thumbnail struct {
+	FileId uint64
+	// Width and height of the square the thumbnail fits in
+	Size      int
+	Etag      string
+	CreatedAt time.Time
+}
+
+func GetThumbnail(db *sql.DB, fileId uint64, size int) (*Thumbnail, error) {
+	row := db.QueryRow(
+		"SELECT file_id, size, etag, created_at FROM thumbnails WHERE file_id=? AND size=?",
+		fileId,
+		size,
+	)
+
+	return scanThumbnail(row)
+}
+
+// Get every cached thumbnail of a file.
+func GetThumbnails(db *sql.DB, fileId uint64) ([]*Thumbnail, error) {
+	rows, err := db.Query(
+		"SELECT file_id, size, etag, created_at FROM thumbnails WHERE file_id=? ORDER BY size",
+		fileId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	thumbnails := []*Thumbnail{}
+	for rows.Next() {
+		thumbnail, err := scanThumbnail(rows)
+		if err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	return thumbnails, rows.Err()
+}
+
+// Record the etag a file's thumbnail of a size was generated from, replacing
+// the thumbnail of that size that was cached before.
+func SetThumbnail(db *sql.DB, fileId uint64, size int, etag string) (*Thumbnail, error) {
+	createdAt := time.Now().UTC()
+	_, err := db.Exec(
+		"INSERT INTO thumbnails (file_id, size, etag, created_at) VALUES (:file_id, :size, :etag, :created_at)\n"+
+			"  ON CONFLICT (file_id, size) DO UPDATE SET etag = excluded.etag, created_at = excluded.created_at",
+		sql.Named("file_id", fileId),
+		sql.Named("size", size),
+		sql.Named("etag", etag),
+		sql.Named("created_at", createdAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Thumbnail{FileId: fileId, Size: size, Etag: etag, CreatedAt: createdAt}, nil
+}
+
+func scanThumbnail(row Scannable) (*Thumbnail, error) {
+	var (
+		thumbnail Thumbnail
+		createdAt string
+	)
+
+	if err := row.Scan(&thumbnail.FileId, &thumbnail.Size, &thumbnail.Etag, &createdAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	var err error
+	thumbnail.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &thumbnail, nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbnails(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("thumbnails.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-thumbnails", "test-thumbnails@example.com", "not a secure password")
+	assert.NoError(t, err)
+	syncFile, err := CreateSyncFile(testdb, "photos/cat.png", "etag-1", 1024, user.Id, user.Id)
+	assert.NoError(t, err)
+
+	_, err = GetThumbnail(testdb, syncFile.Id, 256)
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	for _, size := range []int{256, 128} {
+		_, err = SetThumbnail(testdb, syncFile.Id, size, "etag-1")
+		assert.NoError(t, err)
+	}
+	_, err = SetThumbnail(testdb, syncFile.Id, 256, "etag-2")
+	assert.NoError(t, err)
+
+	thumbnail, err := GetThumbnail(testdb, syncFile.Id, 256)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "etag-2", thumbnail.Etag)
+		assert.False(t, thumbnail.CreatedAt.IsZero())
+	}
+	thumbnails, err := GetThumbnails(testdb, syncFile.Id)
+	if assert.NoError(t, err) && assert.Len(t, thumbnails, 2) {
+		assert.Equal(t, 128, thumbnails[0].Size)
+		assert.Equal(t, "etag-1", thumbnails[0].Etag)
+	}
+
+	// thumbnails are deleted with their file
+	assert.NoError(t, DeleteSyncFile(testdb, syncFile.Id))
+	thumbnails, err = GetThumbnails(testdb, syncFile.Id)
+	if assert.NoError(t, err) {
+		assert.Empty(t, thumbnails)
+	}
+
+	assert.NoError(t, DeleteUser(testdb, user.Id))
+}
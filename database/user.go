@@ -4,12 +4,16 @@ import (
 	"database/sql"
 	"errors"
 	"strings"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 var (
 	ErrUsernameFormat = errors.New("username too short or too long")
 	ErrEmailFormat    = errors.New("email format invalid")
 	ErrPasswordLength = errors.New("password is too short (must be at least 8 characters)")
+	ErrUserDisabled   = errors.New("user is disabled")
+	ErrUserExists     = errors.New("a user with the username or email already exists")
 )
 
 type User struct {
@@ -17,9 +21,29 @@ type User struct {
 	Username string
 	Passhash string
 	Email    string
+	// Disabled users can't log in
+	Disabled bool
+	// Admins can manage other users
+	IsAdmin bool
+	// Whether the user proved they own their email address
+	EmailVerified bool
 }
 
+const userColumns = "id, username, email, passhash, disabled, is_admin, email_verified"
+
 func CreateUser(db *sql.DB, username string, email string, password string) (*User, error) {
+	user, err := newUser(username, email, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertUser(db, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Validate a new user's details and hash their password.
+func newUser(username, email, password string) (*User, error) {
 	if len(username) == 0 || len(username) > 100 {
 		return nil, ErrUsernameFormat
 	}
@@ -34,34 +58,36 @@ func CreateUser(db *sql.DB, username string, email string, password string) (*Us
 	if err != nil {
 		return nil, err
 	}
-	user := User{
+	return &User{
 		Username: username,
 		Email:    email,
 		Passhash: passhash,
-	}
+	}, nil
+}
 
-	_, err = db.Exec(
+func insertUser(db execQuerier, user *User) error {
+	_, err := db.Exec(
 		strings.Join([]string{
 			"INSERT INTO users (username, email, passhash)",
 			"  VALUES (:username, :email, :passhash)"},
 			"\n",
 		),
-		sql.Named("username", username),
-		sql.Named("email", email),
-		sql.Named("passhash", passhash),
+		sql.Named("username", user.Username),
+		sql.Named("email", user.Email),
+		sql.Named("passhash", user.Passhash),
 	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrUserExists
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 	row := db.QueryRow(
 		"SELECT id FROM users WHERE username=:username",
-		sql.Named("username", username),
+		sql.Named("username", user.Username),
 	)
-	if err := row.Scan(&user.Id); err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	return row.Scan(&user.Id)
 }
 
 func GetUserByUsername(db *sql.DB, username string) (*User, error) {
@@ -69,14 +95,8 @@ func GetUserByUsername(db *sql.DB, username string) (*User, error) {
 		return nil, ErrUsernameFormat
 	}
 
-	row := db.QueryRow("SELECT id, username, email, passhash FROM users WHERE username=?", username)
-	user := User{}
-	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Passhash)
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	row := db.QueryRow("SELECT "+userColumns+" FROM users WHERE username=?", username)
+	return scanUser(row)
 }
 
 func GetUserByEmail(db *sql.DB, email string) (*User, error) {
@@ -84,25 +104,13 @@ func GetUserByEmail(db *sql.DB, email string) (*User, error) {
 		return nil, ErrEmailFormat
 	}
 
-	row := db.QueryRow("SELECT id, username, email, passhash FROM users WHERE email=?", email)
-	user := User{}
-	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Passhash)
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	row := db.QueryRow("SELECT "+userColumns+" FROM users WHERE email=?", email)
+	return scanUser(row)
 }
 
 func GetUserById(db *sql.DB, id uint64) (*User, error) {
-	row := db.QueryRow("SELECT id, username, email, passhash FROM users WHERE id=?", id)
-	user := User{}
-	err := row.Scan(&user.Id, &user.Username, &user.Email, &user.Passhash)
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	row := db.QueryRow("SELECT "+userColumns+" FROM users WHERE id=?", id)
+	return scanUser(row)
 }
 
 func DeleteUser(db *sql.DB, id uint64) error {
@@ -110,11 +118,17 @@ func DeleteUser(db *sql.DB, id uint64) error {
 	return err
 }
 
+// Change a user's email. The new address has to be verified again unless it's
+// the same as the old one.
 func UpdateUserEmail(db *sql.DB, id uint64, email string) error {
 	if !validEmail(email) || len(email) > 200 {
 		return ErrEmailFormat
 	}
-	_, err := db.Exec("UPDATE users SET email=? WHERE id=?", email, id)
+	_, err := db.Exec(
+		"UPDATE users SET email_verified=(email_verified AND email=:email), email=:email WHERE id=:id",
+		sql.Named("email", email),
+		sql.Named("id", id),
+	)
 	return err
 }
 
@@ -137,3 +151,120 @@ func UpdateUserUsername(db *sql.DB, id uint64, username string) error {
 	_, err := db.Exec("UPDATE users SET username=? WHERE id=?", username, id)
 	return err
 }
+
+// Get the users whose username or email contains search, sorted by username.
+// An empty search gets every user.
+func GetUsers(db *sql.DB, search string) ([]*User, error) {
+	pattern := "%" + escapeLike(search) + "%"
+	rows, err := db.Query(
+		"SELECT "+userColumns+" FROM users\n"+
+			"  WHERE username LIKE :pattern ESCAPE '\\' OR email LIKE :pattern ESCAPE '\\'\n"+
+			"  ORDER BY username",
+		sql.Named("pattern", pattern),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// Disable or enable a user, returning ErrNoResults if there's no user with
+// the id. Disabling a user logs them out everywhere.
+func SetUserDisabled(db *sql.DB, id uint64, disabled bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE users SET disabled=? WHERE id=?", disabled, id)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrNoResults
+	}
+	if disabled {
+		if _, err := tx.Exec("DELETE FROM sessions WHERE user_id=?", id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Make a user an admin or take away their admin role, returning ErrNoResults
+// if there's no user with the id.
+func SetUserAdmin(db *sql.DB, id uint64, admin bool) error {
+	res, err := db.Exec("UPDATE users SET is_admin=? WHERE id=?", admin, id)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrNoResults
+	}
+
+	return nil
+}
+
+// Log a user out everywhere and deactivate all of their API keys, returning
+// the number of sessions deleted and API keys deactivated.
+func RevokeUserCredentials(db *sql.DB, id uint64) (int64, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM sessions WHERE user_id=?", id)
+	if err != nil {
+		return 0, 0, err
+	}
+	sessions, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+	res, err = tx.Exec("UPDATE api_keys SET active=FALSE WHERE user_id=? AND active", id)
+	if err != nil {
+		return 0, 0, err
+	}
+	apiKeys, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return sessions, apiKeys, tx.Commit()
+}
+
+func scanUser(row Scannable) (*User, error) {
+	var user User
+	err := row.Scan(
+		&user.Id,
+		&user.Username,
+		&user.Email,
+		&user.Passhash,
+		&user.Disabled,
+		&user.IsAdmin,
+		&user.EmailVerified,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -213,3 +213,120 @@ func TestGetUserBy(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUsers(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-get-users.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	for _, username := range []string{"carol", "alice", "bob_smith"} {
+		_, err := CreateUser(testdb, username, username+"@example.com", "not a secure password")
+		assert.NoError(t, err)
+	}
+
+	users, err := GetUsers(testdb, "")
+	if assert.NoError(t, err) && assert.Len(t, users, 3) {
+		assert.Equal(t, "alice", users[0].Username)
+		assert.Equal(t, "carol", users[2].Username)
+	}
+	users, err = GetUsers(testdb, "b_")
+	if assert.NoError(t, err) && assert.Len(t, users, 1) {
+		assert.Equal(t, "bob_smith", users[0].Username)
+	}
+	users, err = GetUsers(testdb, "carol@")
+	if assert.NoError(t, err) {
+		assert.Len(t, users, 1)
+	}
+}
+
+func TestSetUserDisabled(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-set-user-disabled.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-disabled", "test-disabled@example.com", "not a secure password")
+	assert.NoError(t, err)
+	session, err := LoginUser(testdb, user.Username, "not a secure password")
+	assert.NoError(t, err)
+
+	// disabled users are logged out and can't log in again
+	assert.NoError(t, SetUserDisabled(testdb, user.Id, true))
+	_, err = GetSessionBySessionKey(testdb, session.SessionKey)
+	assert.Error(t, err)
+	_, err = LoginUser(testdb, user.Username, "not a secure password")
+	assert.ErrorIs(t, err, ErrUserDisabled)
+	found, err := GetUserById(testdb, user.Id)
+	if assert.NoError(t, err) {
+		assert.True(t, found.Disabled)
+	}
+
+	assert.NoError(t, SetUserDisabled(testdb, user.Id, false))
+	_, err = LoginUser(testdb, user.Username, "not a secure password")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, SetUserDisabled(testdb, user.Id+1000, true), ErrNoResults)
+}
+
+func TestSetUserAdmin(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-set-user-admin.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-admin", "test-admin@example.com", "not a secure password")
+	assert.NoError(t, err)
+	assert.False(t, user.IsAdmin)
+
+	assert.NoError(t, SetUserAdmin(testdb, user.Id, true))
+	found, err := GetUserByUsername(testdb, user.Username)
+	if assert.NoError(t, err) {
+		assert.True(t, found.IsAdmin)
+	}
+	assert.NoError(t, SetUserAdmin(testdb, user.Id, false))
+	found, err = GetUserById(testdb, user.Id)
+	if assert.NoError(t, err) {
+		assert.False(t, found.IsAdmin)
+	}
+	assert.ErrorIs(t, SetUserAdmin(testdb, user.Id+1000, true), ErrNoResults)
+}
+
+func TestRevokeUserCredentials(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("test-revoke-user-credentials.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	user, err := CreateUser(testdb, "test-revoke", "test-revoke@example.com", "not a secure password")
+	assert.NoError(t, err)
+	other, err := CreateUser(testdb, "test-revoke-other", "test-revoke-other@example.com", "not a secure password")
+	assert.NoError(t, err)
+	for range 2 {
+		_, err = LoginUser(testdb, user.Username, "not a secure password")
+		assert.NoError(t, err)
+	}
+	otherSession, err := LoginUser(testdb, other.Username, "not a secure password")
+	assert.NoError(t, err)
+	_, err = CreateApiKey(testdb, user.Id, "test-revoke-laptop", "not a key")
+	assert.NoError(t, err)
+	_, err = CreateApiKey(testdb, user.Id, "test-revoke-phone", "not a key")
+	assert.NoError(t, err)
+	assert.NoError(t, SetApiKeyActivation(testdb, user.Id, "test-revoke-phone", false))
+
+	sessions, apiKeys, err := RevokeUserCredentials(testdb, user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), sessions)
+	assert.Equal(t, int64(1), apiKeys)
+	keys, err := GetApiKeys(testdb, user.Id)
+	assert.NoError(t, err)
+	for _, key := range keys {
+		assert.False(t, key.Active)
+	}
+	// other users stay logged in
+	_, err = GetSessionBySessionKey(testdb, otherSession.SessionKey)
+	assert.NoError(t, err)
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -20,6 +21,12 @@ type Scannable interface {
 	Scan(dest ...any) error
 }
 
+// Database or transaction to run statements on.
+type execQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 type Argon2idParams struct {
 	hash        []byte
 	salt        []byte
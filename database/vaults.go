@@ -0,0 +1,248 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Role a user has in a vault. Every user owns the vault with their own files,
+// and can make other users members of it.
+type VaultRole string
+
+const (
+	// Can change files and manage the vault's members
+	VaultOwner VaultRole = "owner"
+	// Can create, change, rename and delete files
+	VaultEditor VaultRole = "editor"
+	// Can only read files
+	VaultViewer VaultRole = "viewer"
+)
+
+var ErrInvalidVaultRole = errors.New("invalid vault role")
+
+func (r VaultRole) Valid() bool {
+	return r == VaultOwner || r == VaultEditor || r == VaultViewer
+}
+
+// Check whether the role lets users change files.
+func (r VaultRole) CanWrite() bool {
+	return r == VaultOwner || r == VaultEditor
+}
+
+// Member of another user's vault.
+type VaultMember struct {
+	// Id of the user that owns the vault
+	VaultId  uint64
+	UserId   uint64
+	Username string
+	Role     VaultRole
+	// Roles that override the member's role for files in a folder and its
+	// subfolders. Overrides can only make a member an editor or viewer.
+	Folders   map[string]VaultRole
+	CreatedAt time.Time
+}
+
+// Vault a user can access, along with their role in it.
+type Vault struct {
+	OwnerId       uint64
+	OwnerUsername string
+	Role          VaultRole
+}
+
+// Add a user to a vault, or change the role of a user that's already a member.
+// The member's folder overrides are replaced with the given ones.
+func SetVaultMember(db *sql.DB, vaultId, userId uint64, role VaultRole, folders map[string]VaultRole) error {
+	if !role.Valid() {
+		return ErrInvalidVaultRole
+	}
+	for _, folderRole := range folders {
+		if folderRole != VaultEditor && folderRole != VaultViewer {
+			return ErrInvalidVaultRole
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"INSERT INTO vault_members (vault_id, user_id, role, created_at)\n"+
+			"  VALUES (:vault_id, :user_id, :role, :created_at)\n"+
+			"  ON CONFLICT (vault_id, user_id) DO UPDATE SET role = excluded.role",
+		sql.Named("vault_id", vaultId),
+		sql.Named("user_id", userId),
+		sql.Named("role", role),
+		sql.Named("created_at", time.Now().UTC()),
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM vault_folder_roles WHERE vault_id=? AND user_id=?",
+		vaultId,
+		userId,
+	); err != nil {
+		return err
+	}
+	for folder, folderRole := range folders {
+		if _, err := tx.Exec(
+			"INSERT INTO vault_folder_roles (vault_id, user_id, folder, role) VALUES (?, ?, ?, ?)",
+			vaultId,
+			userId,
+			folder,
+			folderRole,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get a member of a vault, returning ErrNoResults if the user isn't a member.
+func GetVaultMember(db *sql.DB, vaultId, userId uint64) (*VaultMember, error) {
+	row := db.QueryRow(selectVaultMembers+" WHERE m.vault_id=? AND m.user_id=?", vaultId, userId)
+	member, err := scanVaultMember(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := getVaultFolderRoles(db, []*VaultMember{member}); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// Get the members of a vault, sorted by username.
+func GetVaultMembers(db *sql.DB, vaultId uint64) ([]*VaultMember, error) {
+	rows, err := db.Query(selectVaultMembers+" WHERE m.vault_id=? ORDER BY u.username", vaultId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*VaultMember{}
+	for rows.Next() {
+		member, err := scanVaultMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, getVaultFolderRoles(db, members)
+}
+
+// Remove a user from a vault, returning ErrNoResults if the user isn't a
+// member.
+func DeleteVaultMember(db *sql.DB, vaultId, userId uint64) error {
+	res, err := db.Exec("DELETE FROM vault_members WHERE vault_id=? AND user_id=?", vaultId, userId)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil {
+		return err
+	} else if count == 0 {
+		return ErrNoResults
+	}
+	return nil
+}
+
+// Get the vaults a user can access, starting with the user's own vault and
+// followed by the vaults they're a member of sorted by their owner's username.
+func GetUserVaults(db *sql.DB, userId uint64) ([]*Vault, error) {
+	rows, err := db.Query(
+		"SELECT u.id, u.username, ?, 0 AS member FROM users u WHERE u.id=?\n"+
+			"UNION ALL\n"+
+			"SELECT u.id, u.username, m.role, 1 AS member FROM vault_members m\n"+
+			"  JOIN users u ON u.id = m.vault_id\n"+
+			"  WHERE m.user_id=?\n"+
+			"ORDER BY member, username",
+		VaultOwner,
+		userId,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vaults := []*Vault{}
+	for rows.Next() {
+		var (
+			vault  Vault
+			member bool
+		)
+		if err := rows.Scan(&vault.OwnerId, &vault.OwnerUsername, &vault.Role, &member); err != nil {
+			return nil, err
+		}
+		vaults = append(vaults, &vault)
+	}
+
+	return vaults, rows.Err()
+}
+
+const selectVaultMembers = "SELECT m.vault_id, m.user_id, u.username, m.role, m.created_at\n" +
+	"  FROM vault_members m JOIN users u ON u.id = m.user_id"
+
+func scanVaultMember(row Scannable) (*VaultMember, error) {
+	var (
+		member    VaultMember
+		createdAt string
+	)
+
+	err := row.Scan(&member.VaultId, &member.UserId, &member.Username, &member.Role, &createdAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoResults
+		}
+		return nil, err
+	}
+	if member.CreatedAt, err = time.Parse(ISO_8601_FORMAT, createdAt); err != nil {
+		return nil, err
+	}
+	member.Folders = map[string]VaultRole{}
+
+	return &member, nil
+}
+
+// Fill in the folder overrides of members of the same vault.
+func getVaultFolderRoles(db *sql.DB, members []*VaultMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+	byUserId := make(map[uint64]*VaultMember, len(members))
+	for _, member := range members {
+		byUserId[member.UserId] = member
+	}
+
+	rows, err := db.Query(
+		"SELECT user_id, folder, role FROM vault_folder_roles WHERE vault_id=?",
+		members[0].VaultId,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			userId uint64
+			folder string
+			role   VaultRole
+		)
+		if err := rows.Scan(&userId, &folder, &role); err != nil {
+			return err
+		}
+		if member, ok := byUserId[userId]; ok {
+			member.Folders[folder] = role
+		}
+	}
+
+	return rows.Err()
+}
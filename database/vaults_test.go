@@ -0,0 +1,69 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultMembers(t *testing.T) {
+	t.Parallel()
+
+	testdb, err := NewDB("vaults.db?mode=memory")
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyMigrations(testdb))
+
+	owner, err := CreateUser(testdb, "test-vault-owner", "test-vault-owner@example.com", "not a secure password")
+	assert.NoError(t, err)
+	editor, err := CreateUser(testdb, "test-vault-editor", "test-vault-editor@example.com", "not a secure password")
+	assert.NoError(t, err)
+	viewer, err := CreateUser(testdb, "test-vault-viewer", "test-vault-viewer@example.com", "not a secure password")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, SetVaultMember(testdb, owner.Id, editor.Id, "admin", nil), ErrInvalidVaultRole)
+	assert.ErrorIs(t, SetVaultMember(testdb, owner.Id, editor.Id, VaultEditor, map[string]VaultRole{"notes": VaultOwner}), ErrInvalidVaultRole)
+	assert.NoError(t, SetVaultMember(testdb, owner.Id, editor.Id, VaultViewer, map[string]VaultRole{"notes": VaultEditor}))
+	assert.NoError(t, SetVaultMember(testdb, owner.Id, viewer.Id, VaultViewer, nil))
+	assert.NoError(t, SetVaultMember(testdb, editor.Id, owner.Id, VaultOwner, nil))
+
+	// changing a member's role replaces their folder overrides
+	assert.NoError(t, SetVaultMember(testdb, owner.Id, editor.Id, VaultEditor, map[string]VaultRole{"archive": VaultViewer}))
+	member, err := GetVaultMember(testdb, owner.Id, editor.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test-vault-editor", member.Username)
+		assert.Equal(t, VaultEditor, member.Role)
+		assert.Equal(t, map[string]VaultRole{"archive": VaultViewer}, member.Folders)
+	}
+	_, err = GetVaultMember(testdb, viewer.Id, owner.Id)
+	assert.ErrorIs(t, err, ErrNoResults)
+
+	members, err := GetVaultMembers(testdb, owner.Id)
+	if assert.NoError(t, err) && assert.Len(t, members, 2) {
+		assert.Equal(t, editor.Id, members[0].UserId)
+		assert.Equal(t, viewer.Id, members[1].UserId)
+		assert.Empty(t, members[1].Folders)
+	}
+
+	vaults, err := GetUserVaults(testdb, owner.Id)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*Vault{
+			{OwnerId: owner.Id, OwnerUsername: "test-vault-owner", Role: VaultOwner},
+			{OwnerId: editor.Id, OwnerUsername: "test-vault-editor", Role: VaultOwner},
+		}, vaults)
+	}
+
+	assert.NoError(t, DeleteVaultMember(testdb, owner.Id, viewer.Id))
+	assert.ErrorIs(t, DeleteVaultMember(testdb, owner.Id, viewer.Id), ErrNoResults)
+
+	// deleting a user removes their vault's members and their memberships
+	assert.NoError(t, DeleteUser(testdb, editor.Id))
+	members, err = GetVaultMembers(testdb, owner.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+	vaults, err = GetUserVaults(testdb, owner.Id)
+	assert.NoError(t, err)
+	assert.Len(t, vaults, 1)
+	var count int
+	assert.NoError(t, testdb.QueryRow("SELECT COUNT(*) FROM vault_folder_roles").Scan(&count))
+	assert.Zero(t, count)
+}
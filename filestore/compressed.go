@@ -0,0 +1,321 @@
+package filestore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+type Codec string
+
+const (
+	CodecIdentity Codec = "identity"
+	CodecGzip     Codec = "gzip"
+)
+
+var (
+	ErrUnsupportedCodec = errors.New("unsupported compression codec")
+	ErrCorruptHeader    = errors.New("compressed file header is corrupt")
+)
+
+// Every file written by a CompressedFileStore starts with this header, followed
+// by a single byte identifying the codec and the md5 hash of the original
+// bytes. Files without the header are treated as uncompressed, so compression
+// can be turned on for a file store that already has files in it.
+var compressedFileMagic = []byte("\x89OBZ")
+
+const compressedHeaderLength = 4 + 1 + md5.Size
+
+var codecIds = map[Codec]byte{
+	CodecIdentity: 0,
+	CodecGzip:     1,
+}
+
+// Extensions of formats that are already compressed, so compressing them again
+// would only waste CPU time.
+var incompressibleExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".webp": true,
+	".avif": true,
+	".heic": true,
+	".pdf":  true,
+	".zip":  true,
+	".gz":   true,
+	".tgz":  true,
+	".bz2":  true,
+	".xz":   true,
+	".zst":  true,
+	".7z":   true,
+	".rar":  true,
+	".mp3":  true,
+	".m4a":  true,
+	".ogg":  true,
+	".flac": true,
+	".mp4":  true,
+	".m4v":  true,
+	".mov":  true,
+	".webm": true,
+	".mkv":  true,
+	".docx": true,
+	".xlsx": true,
+	".pptx": true,
+}
+
+// Extensions of text formats commonly found in Obsidian vaults.
+var textExtensions = map[string]bool{
+	".md":     true,
+	".txt":    true,
+	".canvas": true,
+	".json":   true,
+	".css":    true,
+	".js":     true,
+	".html":   true,
+	".svg":    true,
+	".csv":    true,
+	".yaml":   true,
+	".yml":    true,
+	".xml":    true,
+}
+
+// An EncodedFileStore can hand out a file's bytes exactly as they're stored,
+// so they can be sent to clients that accept the file's encoding without
+// decompressing them first.
+type EncodedFileStore interface {
+	FileStore
+	LoadEncodedFile(filePath string) ([]byte, Codec, error)
+}
+
+var _ EncodedFileStore = &CompressedFileStore{}
+var _ FileOpener = &CompressedFileStore{}
+
+// CompressedFileStore wraps another FileStore and transparently compresses
+// text-like files before they are saved. Etags always describe the original,
+// uncompressed bytes.
+type CompressedFileStore struct {
+	store FileStore
+	codec Codec
+}
+
+func NewCompressedFileStore(store FileStore, codec Codec) (*CompressedFileStore, error) {
+	if _, ok := codecIds[codec]; !ok {
+		return nil, ErrUnsupportedCodec
+	}
+
+	return &CompressedFileStore{
+		store: store,
+		codec: codec,
+	}, nil
+}
+
+func (c *CompressedFileStore) SaveFile(filePath string, data []byte) error {
+	codec := CodecIdentity
+	payload := data
+	if c.codec != CodecIdentity && compressible(filePath, data) {
+		compressed, err := compress(c.codec, data)
+		if err != nil {
+			return err
+		}
+		// only keep the compressed bytes if compressing actually saved space
+		if len(compressed) < len(data) {
+			codec = c.codec
+			payload = compressed
+		}
+	}
+
+	hash := md5.Sum(data)
+	encoded := make([]byte, 0, compressedHeaderLength+len(payload))
+	encoded = append(encoded, compressedFileMagic...)
+	encoded = append(encoded, codecIds[codec])
+	encoded = append(encoded, hash[:]...)
+	encoded = append(encoded, payload...)
+
+	return c.store.SaveFile(filePath, encoded)
+}
+
+func (c *CompressedFileStore) LoadFile(filePath string) ([]byte, error) {
+	data, codec, err := c.LoadEncodedFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return decompress(codec, data)
+}
+
+// Load a file without decompressing it, returning the codec its bytes are
+// encoded with.
+func (c *CompressedFileStore) LoadEncodedFile(filePath string) ([]byte, Codec, error) {
+	data, err := c.store.LoadFile(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	codec, _, payload, err := parseCompressedFile(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return payload, codec, nil
+}
+
+// Open a file for reading, decompressing it as it's read. The returned reader
+// can't seek.
+func (c *CompressedFileStore) OpenFile(filePath string) (io.ReadCloser, error) {
+	file, err := OpenReadSeeker(c.store, filePath, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, compressedHeaderLength)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		file.Close()
+		return nil, err
+	}
+	codec, _, payload, err := parseCompressedFile(header[:n])
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	reader := io.MultiReader(bytes.NewReader(payload), file)
+	switch codec {
+	case CodecIdentity:
+		return readCloser{reader, file.Close}, nil
+	case CodecGzip:
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return readCloser{gzipReader, func() error {
+			gzipReader.Close()
+			return file.Close()
+		}}, nil
+	default:
+		file.Close()
+		return nil, ErrUnsupportedCodec
+	}
+}
+
+func (c *CompressedFileStore) RenameFile(filePath, newFilePath string) error {
+	return c.store.RenameFile(filePath, newFilePath)
+}
+
+func (c *CompressedFileStore) DeleteFile(filePath string) error {
+	return c.store.DeleteFile(filePath)
+}
+
+func (c *CompressedFileStore) GetFileEtag(filePath string) (string, error) {
+	data, err := c.store.LoadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	_, etag, payload, err := parseCompressedFile(data)
+	if err != nil {
+		return "", err
+	}
+	if len(etag) == 0 {
+		return GetEtag(payload), nil
+	}
+
+	return etag, nil
+}
+
+// Get the path of the file in the wrapped file store. The file at the path
+// holds the encoded bytes, not the original ones.
+func (c *CompressedFileStore) GetFilePath(filePath string) (string, error) {
+	return c.store.GetFilePath(filePath)
+}
+
+// Split a stored file into its codec, the etag of the original bytes and the
+// encoded payload. The etag is empty for files stored without a header.
+func parseCompressedFile(data []byte) (Codec, string, []byte, error) {
+	if !bytes.HasPrefix(data, compressedFileMagic) {
+		return CodecIdentity, "", data, nil
+	}
+	if len(data) < compressedHeaderLength {
+		return "", "", nil, ErrCorruptHeader
+	}
+
+	var codec Codec
+	for name, id := range codecIds {
+		if id == data[len(compressedFileMagic)] {
+			codec = name
+		}
+	}
+	if len(codec) == 0 {
+		return "", "", nil, ErrUnsupportedCodec
+	}
+	hashStart := len(compressedFileMagic) + 1
+	etag := hex.EncodeToString(data[hashStart:compressedHeaderLength])
+
+	return codec, etag, data[compressedHeaderLength:], nil
+}
+
+// Check whether a file is worth compressing, going by its extension first and
+// by sniffing its content type otherwise.
+func compressible(filePath string, data []byte) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if incompressibleExtensions[ext] {
+		return false
+	}
+	if textExtensions[ext] {
+		return true
+	}
+
+	return strings.HasPrefix(http.DetectContentType(data), "text/")
+}
+
+func compress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecIdentity:
+		return data, nil
+	case CodecGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+}
+
+func decompress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecIdentity:
+		return data, nil
+	case CodecGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error {
+	return r.close()
+}
@@ -0,0 +1,137 @@
+package filestore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCompressedFileStore(t *testing.T) {
+	fstore, err := NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	cstore, err := NewCompressedFileStore(fstore, CodecGzip)
+	assert.NoError(t, err)
+	assert.NotNil(t, cstore)
+
+	cstore, err = NewCompressedFileStore(fstore, Codec("lzma"))
+	assert.ErrorIs(t, err, ErrUnsupportedCodec)
+	assert.Nil(t, cstore)
+}
+
+func TestCompressedFileStoreSaveAndLoadFile(t *testing.T) {
+	fstore, err := NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cstore, err := NewCompressedFileStore(fstore, CodecGzip)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	markdown := []byte(strings.Repeat("# Process Scheduling\n\nRound robin, FIFO, SJF.\n", 100))
+	// fake PNG: starts with the PNG signature, but is highly compressible
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 4096)...)
+
+	testCases := []struct {
+		name      string
+		path      string
+		data      []byte
+		wantCodec Codec
+	}{
+		{
+			name:      "markdown is compressed",
+			path:      "notes/scheduling.md",
+			data:      markdown,
+			wantCodec: CodecGzip,
+		},
+		{
+			name:      "png is not compressed",
+			path:      "attachments/image.png",
+			data:      png,
+			wantCodec: CodecIdentity,
+		},
+		{
+			name:      "unknown extension with text content is compressed",
+			path:      "notes/scheduling",
+			data:      markdown,
+			wantCodec: CodecGzip,
+		},
+		{
+			name:      "tiny file that grows when compressed is not compressed",
+			path:      "notes/tiny.md",
+			data:      []byte("a"),
+			wantCodec: CodecIdentity,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if !assert.NoError(t, cstore.SaveFile(tc.path, tc.data)) {
+				t.FailNow()
+			}
+
+			// the original bytes are loaded back
+			data, err := cstore.LoadFile(tc.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.data, data)
+
+			// the codec is recorded per file
+			_, codec, err := cstore.LoadEncodedFile(tc.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantCodec, codec)
+
+			// etags describe the original bytes
+			etag, err := cstore.GetFileEtag(tc.path)
+			assert.NoError(t, err)
+			assert.Equal(t, GetEtag(tc.data), etag)
+
+			if tc.wantCodec == CodecGzip {
+				stored, err := os.ReadFile(filepath.Join(fstore.rootDir, tc.path))
+				assert.NoError(t, err)
+				assert.Less(t, len(stored), len(tc.data))
+			}
+		})
+	}
+}
+
+func TestCompressedFileStoreUncompressedFiles(t *testing.T) {
+	fstore, err := NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cstore, err := NewCompressedFileStore(fstore, CodecGzip)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// files saved before compression was turned on can still be read
+	data := []byte("# Existing note\n")
+	if !assert.NoError(t, fstore.SaveFile("existing.md", data)) {
+		t.FailNow()
+	}
+	loaded, err := cstore.LoadFile("existing.md")
+	assert.NoError(t, err)
+	assert.Equal(t, data, loaded)
+	etag, err := cstore.GetFileEtag("existing.md")
+	assert.NoError(t, err)
+	assert.Equal(t, GetEtag(data), etag)
+
+	// corrupt header
+	if !assert.NoError(t, fstore.SaveFile("corrupt.md", compressedFileMagic)) {
+		t.FailNow()
+	}
+	_, err = cstore.LoadFile("corrupt.md")
+	assert.ErrorIs(t, err, ErrCorruptHeader)
+
+	// missing file
+	_, err = cstore.LoadFile("missing.md")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
@@ -1,12 +1,15 @@
 package filestore
 
 import (
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var _ FileStore = &FsFileStore{}
+var _ FileOpener = &FsFileStore{}
 
 type FsFileStore struct {
 	rootDir string
@@ -65,7 +68,7 @@ func (f *FsFileStore) GetFileEtag(filePath string) (string, error) {
 		return "", err
 	}
 
-	return getEtag(data), nil
+	return GetEtag(data), nil
 }
 
 func (f *FsFileStore) LoadFile(filePath string) ([]byte, error) {
@@ -77,8 +80,57 @@ func (f *FsFileStore) LoadFile(filePath string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
-func (f *FsFileStore) RenameFile(filePath string) error {
-	panic("unimplemented")
+func (f *FsFileStore) OpenFile(filePath string) (io.ReadCloser, error) {
+	path, err := f.GetFilePath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+func (f *FsFileStore) RenameFile(filePath, newFilePath string) error {
+	path, err := f.GetFilePath(filePath)
+	if err != nil {
+		return err
+	}
+	newPath := filepath.Join(f.rootDir, newFilePath)
+	if !pathInRootDir(f.rootDir, newPath) {
+		return ErrFileNotFound
+	}
+
+	baseDir := filepath.Dir(newPath)
+	if !pathExists(baseDir) {
+		if err := os.MkdirAll(baseDir, 0777); err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(path, newPath)
+}
+
+// Files are written to a temporary file named like ".note.md.tmp-123456"
+// before being moved into place.
+const tempFileInfix = ".tmp-"
+
+// Check whether a file is a temporary file left behind by SaveFile.
+func IsTempFile(name string) bool {
+	i := strings.LastIndex(name, tempFileInfix)
+	// the file's own name comes between the dot and the infix
+	if i < 2 || name[0] != '.' {
+		return false
+	}
+	// os.CreateTemp replaces the * in the pattern with a random number
+	digits := name[i+len(tempFileInfix):]
+	if len(digits) == 0 {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func (f *FsFileStore) SaveFile(filePath string, data []byte) error {
@@ -94,21 +146,31 @@ func (f *FsFileStore) SaveFile(filePath string, data []byte) error {
 		}
 	}
 
-	file, err := os.Create(path)
+	// write the file next to where it goes and move it into place, so files
+	// are never left half written and links to the old file, like the ones
+	// backups make, keep the old content
+	file, err := os.CreateTemp(baseDir, "."+filepath.Base(path)+tempFileInfix+"*")
 	if err != nil {
 		return err
 	}
-	if err := file.Chmod(0640); err != nil {
-		return err
-	}
 	defer func() {
-		if err := file.Close(); err != nil {
+		if err := os.Remove(file.Name()); err != nil && !os.IsNotExist(err) {
 			log.Println("Unexpected error:", err)
 		}
 	}()
-	_, err = file.Write(data)
+	if err := file.Chmod(0640); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
 
-	return err
+	return os.Rename(file.Name(), path)
 }
 
 func (f *FsFileStore) GetFilePath(filePath string) (string, error) {
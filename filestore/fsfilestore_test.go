@@ -72,6 +72,29 @@ func TestFsFileStoreSaveFile(t *testing.T) {
 	}
 }
 
+func TestIsTempFile(t *testing.T) {
+	t.Parallel()
+
+	// names made the same way SaveFile makes them
+	file, err := os.CreateTemp(t.TempDir(), ".note.md"+tempFileInfix+"*")
+	if assert.NoError(t, err) {
+		file.Close()
+		assert.True(t, IsTempFile(filepath.Base(file.Name())))
+	}
+
+	for name, want := range map[string]bool{
+		".note.md.tmp-123":      true,
+		".a.tmp-b.md.tmp-42":    true,
+		"note.md.tmp-123":       false,
+		".tmp-123":              false,
+		".note.md.tmp-":         false,
+		".note.md.tmp-draft.md": false,
+		".draft.tmp-2.md":       false,
+	} {
+		assert.Equal(t, want, IsTempFile(name), name)
+	}
+}
+
 func TestFsFileStoreCheckFilepath(t *testing.T) {
 	rootDir := filepath.Join("tmp", "directory")
 	filePath := filepath.Join("tmp", "directory", "file")
@@ -121,7 +144,7 @@ func TestFsFileGetFileEtag(t *testing.T) {
 			} else if err != nil {
 				return
 			}
-			wantEtag := getEtag(tc.data)
+			wantEtag := GetEtag(tc.data)
 			assert.Equal(t, wantEtag, etag)
 			os.Remove(filepath.Join(fstore.rootDir, tc.path))
 		})
@@ -160,6 +183,35 @@ func TestFsFileDeleteFile(t *testing.T) {
 	assert.ErrorIs(t, ErrFileNotFound, fstore.DeleteFile("alphabet.txt"))
 }
 
+func TestFsFileStoreRenameFile(t *testing.T) {
+	rootDir := t.TempDir()
+	fstore, err := NewFsFileStore(rootDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	if !assert.NoError(t, fstore.SaveFile("alphabet.txt", []byte("abcdefg"))) {
+		t.FailNow()
+	}
+
+	// discard sneaky paths
+	assert.ErrorIs(t, ErrFileNotFound, fstore.RenameFile("alphabet.txt", "../alphabet.txt"))
+	assert.ErrorIs(t, ErrFileNotFound, fstore.RenameFile("../alphabet.txt", "alphabet.txt"))
+
+	// rename into a folder that doesn't exist yet
+	if !assert.NoError(t, fstore.RenameFile("alphabet.txt", "letters/alphabet.txt")) {
+		t.FailNow()
+	}
+	data, err := fstore.LoadFile("letters/alphabet.txt")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte("abcdefg"), data)
+	}
+	assert.False(t, pathExists(filepath.Join(fstore.rootDir, "alphabet.txt")))
+
+	// file doesn't exist
+	assert.ErrorIs(t, ErrFileNotFound, fstore.RenameFile("alphabet.txt", "letters.txt"))
+}
+
 func TestFsFileStoreLoadFile(t *testing.T) {
 	rootDir := t.TempDir()
 	fstore, err := NewFsFileStore(rootDir)
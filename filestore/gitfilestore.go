@@ -0,0 +1,502 @@
+package filestore
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Person that changed files in a HistoryFileStore, and the device they made
+// the change from.
+type Author struct {
+	Name  string
+	Email string
+	// Device the change was made from, if the client named it
+	Device string
+}
+
+// Author of changes made through a HistoryFileStore without one, like
+// changes made by the server itself.
+var DefaultAuthor = Author{Name: "Obsync", Email: "obsync@localhost"}
+
+// Change to the files in a HistoryFileStore.
+type Commit struct {
+	Hash    string
+	Author  Author
+	Message string
+	Time    time.Time
+}
+
+// A HistoryFileStore keeps every version of its files, along with who changed
+// them.
+type HistoryFileStore interface {
+	FileStore
+	// Get a view of the file store that records the changes made through it
+	// as made by the author.
+	WithAuthor(author Author) FileStore
+	// Get the commits that changed a file, or the files in a folder, newest
+	// first. A limit of 0 gets every commit.
+	Log(filePath string, limit int) ([]Commit, error)
+	// Load a file as it was after a commit.
+	LoadFileAt(filePath, commit string) ([]byte, error)
+	// Commit the changes that are waiting for their commit window to end.
+	Flush() error
+}
+
+var _ HistoryFileStore = &GitFileStore{}
+var _ FileOpener = &GitFileStore{}
+
+type GitOptions struct {
+	// Changes made within this long of the first uncommitted change are
+	// committed together. Every change is committed right away if it's 0.
+	CommitWindow time.Duration
+	// Folders in the file store that aren't committed, like caches
+	Exclude []string
+}
+
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{4,64}$`)
+
+// GitFileStore keeps the files of a FsFileStore in a local git repository,
+// committing every change with the author that made it. Changes to different
+// top level folders are committed separately. Nothing is ever pushed
+// anywhere.
+type GitFileStore struct {
+	store   *FsFileStore
+	options GitOptions
+
+	// changes waiting to be committed, grouped by author and top level
+	// folder in the order they were first made
+	mu      sync.Mutex
+	pending []*pendingCommit
+	timer   *time.Timer
+
+	// git commands that change the index run one at a time
+	gitMu sync.Mutex
+}
+
+type pendingCommit struct {
+	author Author
+	// top level folder the changes were made in, so changes to separate
+	// vaults aren't mixed in one commit
+	folder  string
+	changes []fileChange
+}
+
+type fileChange struct {
+	action  string
+	path    string
+	newPath string
+}
+
+// Create a git file store in the root directory of a FsFileStore. The
+// repository is created if it doesn't exist yet, and files that are already
+// in the file store are committed.
+func NewGitFileStore(store *FsFileStore, options GitOptions) (*GitFileStore, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, err
+	}
+	g := &GitFileStore{store: store, options: options}
+
+	if !pathExists(filepath.Join(store.rootDir, ".git")) {
+		if _, err := g.git("init", "--quiet"); err != nil {
+			return nil, err
+		}
+	}
+	exclude := make([]string, 0, len(options.Exclude))
+	for _, dir := range options.Exclude {
+		exclude = append(exclude, "/"+strings.Trim(filepath.ToSlash(dir), "/")+"/")
+	}
+	excludePath := filepath.Join(store.rootDir, ".git", "info", "exclude")
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0777); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(excludePath, []byte(strings.Join(exclude, "\n")+"\n"), 0640); err != nil {
+		return nil, err
+	}
+
+	if !g.hasCommits() {
+		if _, err := g.git("add", "--all"); err != nil {
+			return nil, err
+		}
+		if err := g.commitIndex(DefaultAuthor, "Add existing files"); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+func (g *GitFileStore) WithAuthor(author Author) FileStore {
+	return &authoredGitFileStore{git: g, author: author}
+}
+
+func (g *GitFileStore) SaveFile(filePath string, data []byte) error {
+	return g.WithAuthor(DefaultAuthor).SaveFile(filePath, data)
+}
+
+func (g *GitFileStore) LoadFile(filePath string) ([]byte, error) {
+	return g.store.LoadFile(filePath)
+}
+
+func (g *GitFileStore) OpenFile(filePath string) (io.ReadCloser, error) {
+	return g.store.OpenFile(filePath)
+}
+
+func (g *GitFileStore) RenameFile(filePath, newFilePath string) error {
+	return g.WithAuthor(DefaultAuthor).RenameFile(filePath, newFilePath)
+}
+
+func (g *GitFileStore) DeleteFile(filePath string) error {
+	return g.WithAuthor(DefaultAuthor).DeleteFile(filePath)
+}
+
+func (g *GitFileStore) GetFileEtag(filePath string) (string, error) {
+	return g.store.GetFileEtag(filePath)
+}
+
+func (g *GitFileStore) GetFilePath(filePath string) (string, error) {
+	return g.store.GetFilePath(filePath)
+}
+
+func (g *GitFileStore) Log(filePath string, limit int) ([]Commit, error) {
+	if err := g.Flush(); err != nil {
+		return nil, err
+	}
+	path, err := g.repoPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !g.hasCommits() {
+		return []Commit{}, nil
+	}
+
+	// fields are separated by unit separators and commits by record
+	// separators, which don't show up in names or subjects
+	args := []string{
+		"log",
+		"--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%s%x1f%(trailers:key=Device,valueonly,separator=%x2C)%x1e",
+	}
+	if limit > 0 {
+		args = append(args, "-n", strconv.Itoa(limit))
+	}
+	if info, err := os.Stat(filepath.Join(g.store.rootDir, path)); err == nil && info.Mode().IsRegular() {
+		// follow files across renames, which only works for single files
+		args = append(args, "--follow")
+	}
+	if len(path) > 0 {
+		args = append(args, "--", path)
+	}
+	out, err := g.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []Commit{}
+	for _, record := range strings.Split(string(out), "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 6 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, Commit{
+			Hash: fields[0],
+			Author: Author{
+				Name:   fields[1],
+				Email:  fields[2],
+				Device: strings.TrimSpace(fields[5]),
+			},
+			Time:    date,
+			Message: fields[4],
+		})
+	}
+	return commits, nil
+}
+
+func (g *GitFileStore) LoadFileAt(filePath, commit string) ([]byte, error) {
+	if !commitHashPattern.MatchString(commit) {
+		return nil, ErrInvalidCommit
+	}
+	if err := g.Flush(); err != nil {
+		return nil, err
+	}
+	path, err := g.repoPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	object := commit + ":" + path
+	if _, err := g.git("cat-file", "-e", object); err != nil {
+		return nil, ErrFileNotFound
+	}
+	return g.git("cat-file", "blob", object)
+}
+
+func (g *GitFileStore) Flush() error {
+	g.mu.Lock()
+	pending := g.pending
+	g.pending = nil
+	if g.timer != nil {
+		g.timer.Stop()
+		g.timer = nil
+	}
+	g.mu.Unlock()
+
+	g.gitMu.Lock()
+	defer g.gitMu.Unlock()
+	var errs []error
+	for _, commit := range pending {
+		errs = append(errs, g.commit(commit))
+	}
+	return errors.Join(errs...)
+}
+
+// Record a change to be committed once the commit window ends.
+func (g *GitFileStore) record(author Author, change fileChange) error {
+	if g.excluded(change.path) && (len(change.newPath) == 0 || g.excluded(change.newPath)) {
+		return nil
+	}
+
+	folder, _, _ := strings.Cut(change.path, "/")
+	g.mu.Lock()
+	var pending *pendingCommit
+	for _, commit := range g.pending {
+		if commit.author == author && commit.folder == folder {
+			pending = commit
+		}
+	}
+	if pending == nil {
+		pending = &pendingCommit{author: author, folder: folder}
+		g.pending = append(g.pending, pending)
+	}
+	pending.changes = append(pending.changes, change)
+	if g.options.CommitWindow > 0 && g.timer == nil {
+		g.timer = time.AfterFunc(g.options.CommitWindow, func() {
+			if err := g.Flush(); err != nil {
+				log.Println("Unexpected error:", err)
+			}
+		})
+	}
+	g.mu.Unlock()
+
+	if g.options.CommitWindow <= 0 {
+		return g.Flush()
+	}
+	return nil
+}
+
+// Stage the files an author changed and commit them.
+func (g *GitFileStore) commit(pending *pendingCommit) error {
+	var existing, missing []string
+	seen := map[string]bool{}
+	for _, change := range pending.changes {
+		for _, path := range []string{change.path, change.newPath} {
+			if len(path) == 0 || seen[path] || g.excluded(path) {
+				continue
+			}
+			seen[path] = true
+			if pathExists(filepath.Join(g.store.rootDir, path)) {
+				existing = append(existing, path)
+			} else {
+				missing = append(missing, path)
+			}
+		}
+	}
+
+	// files are added even if a .gitignore in the vault ignores them, so the
+	// history has every file that was synced
+	if len(existing) > 0 {
+		if _, err := g.git(append([]string{"add", "--force", "--"}, existing...)...); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		args := append([]string{"rm", "-r", "--quiet", "--cached", "--ignore-unmatch", "--"}, missing...)
+		if _, err := g.git(args...); err != nil {
+			return err
+		}
+	}
+	return g.commitIndex(pending.author, commitMessage(pending))
+}
+
+// Commit the staged changes, if there are any.
+func (g *GitFileStore) commitIndex(author Author, message string) error {
+	// diff exits with 1 when there are staged changes
+	if _, err := g.git("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	_, err := g.git(
+		"commit",
+		"--quiet",
+		"--no-verify",
+		"--author", fmt.Sprintf("%s <%s>", author.Name, author.Email),
+		"--message", message,
+	)
+	return err
+}
+
+func (g *GitFileStore) hasCommits() bool {
+	_, err := g.git("rev-parse", "--verify", "--quiet", "HEAD")
+	return err == nil
+}
+
+func (g *GitFileStore) excluded(path string) bool {
+	// git refuses to track anything inside a .git folder
+	for _, part := range strings.Split(path, "/") {
+		if strings.EqualFold(part, ".git") {
+			return true
+		}
+	}
+	for _, dir := range g.options.Exclude {
+		dir = strings.Trim(filepath.ToSlash(dir), "/")
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the path of a file relative to the root of the repository, with
+// forward slashes like git uses.
+func (g *GitFileStore) repoPath(filePath string) (string, error) {
+	path := filepath.Join(g.store.rootDir, filePath)
+	if !pathInRootDir(g.store.rootDir, path) {
+		return "", ErrFileNotFound
+	}
+	rel, err := filepath.Rel(g.store.rootDir, path)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Run a git command in the repository. Pathspecs are taken literally, and
+// the user's git config is left out so it can't change how commits are made.
+func (g *GitFileStore) git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{
+		"--literal-pathspecs",
+		"-c", "commit.gpgsign=false",
+		"-c", "core.quotePath=false",
+	}, args...)...)
+	cmd.Dir = g.store.rootDir
+	cmd.Env = append(
+		os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_COMMITTER_NAME="+DefaultAuthor.Name,
+		"GIT_COMMITTER_EMAIL="+DefaultAuthor.Email,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Describe the changes in a commit. The device the changes were made from is
+// added as a trailer.
+func commitMessage(pending *pendingCommit) string {
+	// saving a file several times in a commit window is one change, described
+	// by what happened to the file first unless it was moved or deleted later
+	var changes []string
+	index := map[string]int{}
+	for _, change := range pending.changes {
+		line := change.action + " " + change.path
+		if len(change.newPath) > 0 {
+			line += " to " + change.newPath
+		}
+		i, seen := index[change.path]
+		if !seen {
+			index[change.path] = len(changes)
+			changes = append(changes, line)
+		} else if change.action != "Update" {
+			changes[i] = line
+		}
+	}
+
+	message := changes[0]
+	if len(changes) > 1 {
+		message = fmt.Sprintf("Update %d files\n\n%s", len(changes), strings.Join(changes, "\n"))
+	}
+	if len(pending.author.Device) > 0 {
+		message += "\n\nDevice: " + pending.author.Device
+	}
+	return message
+}
+
+// View of a GitFileStore that commits changes as made by an author.
+type authoredGitFileStore struct {
+	git    *GitFileStore
+	author Author
+}
+
+func (a *authoredGitFileStore) SaveFile(filePath string, data []byte) error {
+	path, err := a.git.repoPath(filePath)
+	if err != nil {
+		return err
+	}
+	action := "Update"
+	if !pathExists(filepath.Join(a.git.store.rootDir, path)) {
+		action = "Add"
+	}
+	if err := a.git.store.SaveFile(filePath, data); err != nil {
+		return err
+	}
+	return a.git.record(a.author, fileChange{action: action, path: path})
+}
+
+func (a *authoredGitFileStore) LoadFile(filePath string) ([]byte, error) {
+	return a.git.LoadFile(filePath)
+}
+
+func (a *authoredGitFileStore) RenameFile(filePath, newFilePath string) error {
+	path, err := a.git.repoPath(filePath)
+	if err != nil {
+		return err
+	}
+	newPath, err := a.git.repoPath(newFilePath)
+	if err != nil {
+		return err
+	}
+	if err := a.git.store.RenameFile(filePath, newFilePath); err != nil {
+		return err
+	}
+	return a.git.record(a.author, fileChange{action: "Rename", path: path, newPath: newPath})
+}
+
+func (a *authoredGitFileStore) DeleteFile(filePath string) error {
+	path, err := a.git.repoPath(filePath)
+	if err != nil {
+		return err
+	}
+	if err := a.git.store.DeleteFile(filePath); err != nil {
+		return err
+	}
+	return a.git.record(a.author, fileChange{action: "Delete", path: path})
+}
+
+func (a *authoredGitFileStore) GetFileEtag(filePath string) (string, error) {
+	return a.git.GetFileEtag(filePath)
+}
+
+func (a *authoredGitFileStore) GetFilePath(filePath string) (string, error) {
+	return a.git.GetFilePath(filePath)
+}
@@ -0,0 +1,153 @@
+package filestore
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGitFileStore(t *testing.T, rootDir string, options GitOptions) *GitFileStore {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	fstore, err := NewFsFileStore(rootDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	gstore, err := NewGitFileStore(fstore, options)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return gstore
+}
+
+func commitMessages(commits []Commit) []string {
+	messages := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		messages = append(messages, commit.Message)
+	}
+	return messages
+}
+
+func TestNewGitFileStore(t *testing.T) {
+	rootDir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(rootDir, "1", "notes"), 0777))
+	assert.NoError(t, os.WriteFile(filepath.Join(rootDir, "1", "notes", "a.md"), []byte("a"), 0640))
+
+	// files that are already there are committed
+	gstore := newTestGitFileStore(t, rootDir, GitOptions{})
+	commits, err := gstore.Log("", 0)
+	if assert.NoError(t, err) && assert.Len(t, commits, 1) {
+		assert.Equal(t, "Add existing files", commits[0].Message)
+		assert.Equal(t, DefaultAuthor, commits[0].Author)
+	}
+
+	// opening an existing repository doesn't commit anything
+	gstore = newTestGitFileStore(t, rootDir, GitOptions{})
+	commits, err = gstore.Log("", 0)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 1)
+}
+
+func TestGitFileStoreCommits(t *testing.T) {
+	gstore := newTestGitFileStore(t, t.TempDir(), GitOptions{Exclude: []string{".cache"}})
+	alice := gstore.WithAuthor(Author{Name: "alice", Email: "alice@example.com", Device: "laptop"})
+	bob := gstore.WithAuthor(Author{Name: "bob", Email: "bob@example.com"})
+
+	assert.NoError(t, alice.SaveFile("1/notes/a.md", []byte("first")))
+	assert.NoError(t, bob.SaveFile("1/notes/a.md", []byte("second")))
+	assert.NoError(t, alice.RenameFile("1/notes/a.md", "1/notes/b.md"))
+	assert.NoError(t, bob.SaveFile("1/other.md", []byte("other")))
+	assert.NoError(t, bob.DeleteFile("1/other.md"))
+	assert.NoError(t, alice.SaveFile(".cache/1/thumbnail", []byte("cached")))
+	assert.NoError(t, alice.SaveFile("1/.git/config", []byte("[core]")))
+
+	commits, err := gstore.Log("", 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{
+			"Delete 1/other.md",
+			"Add 1/other.md",
+			"Rename 1/notes/a.md to 1/notes/b.md",
+			"Update 1/notes/a.md",
+			"Add 1/notes/a.md",
+		}, commitMessages(commits))
+		assert.Equal(t, "alice", commits[2].Author.Name)
+		assert.Equal(t, "alice@example.com", commits[2].Author.Email)
+		assert.Equal(t, "laptop", commits[2].Author.Device)
+		assert.Equal(t, "bob", commits[3].Author.Name)
+		assert.Empty(t, commits[3].Author.Device)
+		assert.WithinDuration(t, time.Now(), commits[0].Time, time.Minute)
+	}
+
+	// the log of a file follows it across renames
+	commits, err = gstore.Log("1/notes/b.md", 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{
+			"Rename 1/notes/a.md to 1/notes/b.md",
+			"Update 1/notes/a.md",
+			"Add 1/notes/a.md",
+		}, commitMessages(commits))
+
+		data, err := gstore.LoadFileAt("1/notes/a.md", commits[2].Hash)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", string(data))
+		data, err = gstore.LoadFileAt("1/notes/a.md", commits[1].Hash)
+		assert.NoError(t, err)
+		assert.Equal(t, "second", string(data))
+		_, err = gstore.LoadFileAt("1/notes/a.md", commits[0].Hash)
+		assert.ErrorIs(t, err, ErrFileNotFound)
+	}
+
+	commits, err = gstore.Log("1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, commits, 2)
+	commits, err = gstore.Log(".cache", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, commits)
+
+	_, err = gstore.LoadFileAt("1/notes/b.md", "HEAD~1")
+	assert.ErrorIs(t, err, ErrInvalidCommit)
+	_, err = gstore.LoadFileAt("1/notes/b.md", "--output=x")
+	assert.ErrorIs(t, err, ErrInvalidCommit)
+	_, err = gstore.Log("../outside", 0)
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestGitFileStoreCommitWindow(t *testing.T) {
+	gstore := newTestGitFileStore(t, t.TempDir(), GitOptions{CommitWindow: time.Hour})
+	alice := gstore.WithAuthor(Author{Name: "alice", Email: "alice@example.com", Device: "phone"})
+	bob := gstore.WithAuthor(Author{Name: "bob", Email: "bob@example.com"})
+
+	assert.NoError(t, alice.SaveFile("1/a.md", []byte("a")))
+	assert.NoError(t, alice.SaveFile("1/a.md", []byte("aa")))
+	assert.NoError(t, alice.SaveFile("1/b.md", []byte("b")))
+	assert.NoError(t, bob.SaveFile("1/c.md", []byte("c")))
+	assert.NoError(t, bob.SaveFile("2/d.md", []byte("d")))
+
+	// changes in the window are written right away, and committed together
+	// for each author and top level folder once the window ends
+	data, err := gstore.LoadFile("1/a.md")
+	assert.NoError(t, err)
+	assert.Equal(t, "aa", string(data))
+
+	commits, err := gstore.Log("", 0)
+	if assert.NoError(t, err) && assert.Len(t, commits, 3) {
+		assert.Equal(t, "Add 2/d.md", commits[0].Message)
+		assert.Equal(t, "Add 1/c.md", commits[1].Message)
+		assert.Equal(t, "bob", commits[1].Author.Name)
+		assert.Equal(t, "Update 2 files", commits[2].Message)
+		assert.Equal(t, "phone", commits[2].Author.Device)
+	}
+
+	gstore = newTestGitFileStore(t, t.TempDir(), GitOptions{CommitWindow: 10 * time.Millisecond})
+	assert.NoError(t, gstore.SaveFile("1/a.md", []byte("a")))
+	assert.Eventually(t, func() bool {
+		out, err := gstore.git("log", "--oneline")
+		return err == nil && len(out) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,158 @@
+package filestore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var ErrInvalidSeek = errors.New("seek to a negative position")
+
+// A FileOpener can open files for reading without loading the whole file into
+// memory first.
+type FileOpener interface {
+	OpenFile(filePath string) (io.ReadCloser, error)
+}
+
+// Open a file in a file store as an io.ReadSeekCloser. Files are streamed when
+// the file store supports it, and files from file stores that can't seek
+// natively are wrapped so seeking still works. size is the size of the file in
+// bytes, or -1 if it's unknown. Sizes of 0 are treated as unknown, since files
+// synced before sizes were recorded have a size of 0.
+func OpenReadSeeker(store FileStore, filePath string, size int64) (io.ReadSeekCloser, error) {
+	if size == 0 {
+		size = -1
+	}
+	opener, ok := store.(FileOpener)
+	if !ok {
+		data, err := store.LoadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		return nopReadSeekCloser{bytes.NewReader(data)}, nil
+	}
+
+	file, err := opener.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if seeker, ok := file.(io.ReadSeekCloser); ok {
+		return seeker, nil
+	}
+
+	return &sequentialReadSeeker{
+		open:   func() (io.ReadCloser, error) { return opener.OpenFile(filePath) },
+		reader: file,
+		size:   size,
+	}, nil
+}
+
+type nopReadSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopReadSeekCloser) Close() error {
+	return nil
+}
+
+// sequentialReadSeeker makes a reader that can only be read from start to end
+// seekable. Seeking forwards discards bytes, and seeking backwards reopens the
+// file and starts reading from the beginning again.
+type sequentialReadSeeker struct {
+	open   func() (io.ReadCloser, error)
+	reader io.ReadCloser
+	// position of the underlying reader
+	pos int64
+	// position that the next read should start at
+	offset int64
+	size   int64
+}
+
+func (s *sequentialReadSeeker) Read(p []byte) (int, error) {
+	if err := s.moveTo(s.offset); err != nil {
+		return 0, err
+	}
+	if s.pos < s.offset {
+		return 0, io.EOF
+	}
+
+	n, err := s.reader.Read(p)
+	s.pos += int64(n)
+	s.offset = s.pos
+	return n, err
+}
+
+func (s *sequentialReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.offset
+	case io.SeekEnd:
+		size, err := s.getSize()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, ErrInvalidSeek
+	}
+
+	s.offset = offset
+	return offset, nil
+}
+
+func (s *sequentialReadSeeker) Close() error {
+	if s.reader == nil {
+		return nil
+	}
+	err := s.reader.Close()
+	s.reader = nil
+	return err
+}
+
+// Move the underlying reader to an offset, reopening the file if the offset is
+// behind the reader.
+func (s *sequentialReadSeeker) moveTo(offset int64) error {
+	if s.reader == nil || offset < s.pos {
+		if err := s.Close(); err != nil {
+			return err
+		}
+		reader, err := s.open()
+		if err != nil {
+			return err
+		}
+		s.reader = reader
+		s.pos = 0
+	}
+
+	skipped, err := io.CopyN(io.Discard, s.reader, offset-s.pos)
+	s.pos += skipped
+	if err == io.EOF {
+		// seeking past the end of a file is allowed, reads just return io.EOF
+		return nil
+	}
+	return err
+}
+
+// Get the size of the file, reading through the whole file if the size isn't
+// known yet.
+func (s *sequentialReadSeeker) getSize() (int64, error) {
+	if s.size >= 0 {
+		return s.size, nil
+	}
+
+	reader, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	size, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return 0, err
+	}
+	s.size = size
+	return size, nil
+}
@@ -0,0 +1,169 @@
+package filestore
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streamingFileStore is a FileStore that can only stream files from start to
+// end, like a file store backed by an object storage service.
+type streamingFileStore struct {
+	FileStore
+	opens int
+}
+
+func (s *streamingFileStore) OpenFile(filePath string) (io.ReadCloser, error) {
+	data, err := s.LoadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	s.opens++
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestOpenReadSeeker(t *testing.T) {
+	fstore, err := NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data := []byte("abcdefghijklmnopqrstuvwxyz")
+	if !assert.NoError(t, fstore.SaveFile("alphabet.txt", data)) {
+		t.FailNow()
+	}
+
+	// files in the file system are seekable without any help
+	file, err := OpenReadSeeker(fstore, "alphabet.txt", int64(len(data)))
+	if assert.NoError(t, err) {
+		assert.IsType(t, &os.File{}, file)
+		assert.NoError(t, file.Close())
+	}
+
+	// file stores that can only load whole files
+	file, err = OpenReadSeeker(struct{ FileStore }{fstore}, "alphabet.txt", int64(len(data)))
+	if assert.NoError(t, err) {
+		assertSeekable(t, file, data)
+		assert.NoError(t, file.Close())
+	}
+
+	// file stores that can only stream files, with known and unknown sizes.
+	// files synced before sizes were recorded have a size of 0
+	for _, size := range []int64{int64(len(data)), -1, 0} {
+		sstore := &streamingFileStore{FileStore: fstore}
+		file, err = OpenReadSeeker(sstore, "alphabet.txt", size)
+		if assert.NoError(t, err) {
+			assert.IsType(t, &sequentialReadSeeker{}, file)
+			assertSeekable(t, file, data)
+			assert.NoError(t, file.Close())
+		}
+	}
+
+	// missing files
+	_, err = OpenReadSeeker(&streamingFileStore{FileStore: fstore}, "missing.txt", -1)
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestSequentialReadSeekerOnlyReopensToSeekBackwards(t *testing.T) {
+	fstore, err := NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data := []byte("abcdefghijklmnopqrstuvwxyz")
+	if !assert.NoError(t, fstore.SaveFile("alphabet.txt", data)) {
+		t.FailNow()
+	}
+	sstore := &streamingFileStore{FileStore: fstore}
+	file, err := OpenReadSeeker(sstore, "alphabet.txt", int64(len(data)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	buf := make([]byte, 3)
+	_, err = file.Seek(5, io.SeekStart)
+	assert.NoError(t, err)
+	_, err = io.ReadFull(file, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "fgh", string(buf))
+	_, err = file.Seek(10, io.SeekStart)
+	assert.NoError(t, err)
+	_, err = io.ReadFull(file, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "klm", string(buf))
+	assert.Equal(t, 1, sstore.opens)
+
+	_, err = file.Seek(0, io.SeekStart)
+	assert.NoError(t, err)
+	_, err = io.ReadFull(file, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", string(buf))
+	assert.Equal(t, 2, sstore.opens)
+}
+
+func TestCompressedFileStoreOpenFile(t *testing.T) {
+	fstore, err := NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cstore, err := NewCompressedFileStore(fstore, CodecGzip)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	compressed := []byte(strings.Repeat("# Heading\n\nSome text.\n", 50))
+	uncompressed := []byte("a")
+	legacy := []byte("# Saved before compression was turned on\n")
+	assert.NoError(t, cstore.SaveFile("compressed.md", compressed))
+	assert.NoError(t, cstore.SaveFile("uncompressed.md", uncompressed))
+	assert.NoError(t, fstore.SaveFile("legacy.md", legacy))
+
+	for path, want := range map[string][]byte{
+		"compressed.md":   compressed,
+		"uncompressed.md": uncompressed,
+		"legacy.md":       legacy,
+	} {
+		file, err := cstore.OpenFile(path)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		data, err := io.ReadAll(file)
+		assert.NoError(t, err)
+		assert.Equal(t, want, data)
+		assert.NoError(t, file.Close())
+
+		seeker, err := OpenReadSeeker(cstore, path, -1)
+		if assert.NoError(t, err) {
+			assertSeekable(t, seeker, want)
+			assert.NoError(t, seeker.Close())
+		}
+	}
+}
+
+// Check that seeking around a file works like seeking in an in-memory copy of
+// the file.
+func assertSeekable(t *testing.T, file io.ReadSeeker, data []byte) {
+	t.Helper()
+
+	size, err := file.Seek(0, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), size)
+
+	for _, offset := range []int64{size / 2, 0, size - 1, 1, size, size + 10} {
+		pos, err := file.Seek(offset, io.SeekStart)
+		assert.NoError(t, err)
+		assert.Equal(t, offset, pos)
+		rest, err := io.ReadAll(file)
+		assert.NoError(t, err)
+		if offset < size {
+			assert.Equal(t, data[offset:], rest)
+		} else {
+			assert.Empty(t, rest)
+		}
+	}
+
+	_, err = file.Seek(-1, io.SeekStart)
+	assert.Error(t, err)
+}
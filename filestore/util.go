@@ -5,7 +5,8 @@ import (
 	"encoding/hex"
 )
 
-func getEtag(data []byte) string {
+// Get the etag (hex encoded md5 hash) of a file's contents.
+func GetEtag(data []byte) string {
 	hash := md5.Sum(data)
 	return hex.EncodeToString(hash[:])
 }
@@ -0,0 +1,189 @@
+package ignore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule parsed from a line of a gitignore file.
+type Rule struct {
+	Pattern string
+	// Rule re-includes paths that earlier rules ignored
+	Negate bool
+	// Rule only matches directories, and the files inside them
+	DirOnly bool
+	re      *regexp.Regexp
+}
+
+// Matcher checks paths against a list of rules written in gitignore syntax.
+type Matcher struct {
+	rules []Rule
+}
+
+// Parse rules written in gitignore syntax. Blank lines and lines starting
+// with # are skipped. Patterns that can't be parsed are returned as an error
+// with their line number.
+func Parse(rules string) (*Matcher, error) {
+	m := &Matcher{}
+	for i, line := range strings.Split(rules, "\n") {
+		rule, ok, err := parseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+	return m, nil
+}
+
+// Get the rules, in the order they're applied.
+func (m *Matcher) Rules() []Rule {
+	return m.rules
+}
+
+// Check whether a file is ignored. Like git, files in an ignored directory
+// stay ignored even if a later rule re-includes them.
+func (m *Matcher) Match(filename string) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	return m.matchPath(filename, false)
+}
+
+// Check whether a directory is ignored.
+func (m *Matcher) MatchDir(dir string) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	return m.matchPath(dir, true)
+}
+
+// Check a path and each of its parent directories.
+func (m *Matcher) matchPath(path string, isDir bool) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), isDir || i < len(parts)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check a path against every rule. The last rule that matches the path
+// decides whether it's ignored.
+func (m *Matcher) match(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.DirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(path) {
+			ignored = !rule.Negate
+		}
+	}
+	return ignored
+}
+
+func parseRule(line string) (Rule, bool, error) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return Rule{}, false, nil
+	}
+
+	rule := Rule{Pattern: line}
+	if strings.HasPrefix(line, "!") {
+		rule.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") && !strings.HasSuffix(line, `\/`) {
+		rule.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if len(line) == 0 {
+		return Rule{}, false, nil
+	}
+
+	// patterns with a slash anywhere but the end are relative to the root,
+	// and other patterns match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/") && (i == 0 || line[i-1] == '/'):
+			// leading and middle **/ match any number of directories
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**") && i+2 == len(line) && (i == 0 || line[i-1] == '/'):
+			// trailing ** matches everything inside
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := classEnd(line, i)
+			if end == -1 {
+				return Rule{}, false, fmt.Errorf("unterminated character class in %q", rule.Pattern)
+			}
+			class := line[i+1 : end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		case c == '\\' && i+1 < len(line):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(line[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return Rule{}, false, fmt.Errorf("invalid pattern %q: %w", rule.Pattern, err)
+	}
+	rule.re = re
+	return rule, true, nil
+}
+
+// Get the index of the ] that closes the character class starting at i, or
+// -1 if the class isn't closed. A ] right after the [ or [! is part of the
+// class.
+func classEnd(pattern string, i int) int {
+	j := i + 1
+	if j < len(pattern) && pattern[j] == '!' {
+		j++
+	}
+	if j < len(pattern) && pattern[j] == ']' {
+		j++
+	}
+	for ; j < len(pattern); j++ {
+		if pattern[j] == ']' {
+			return j
+		}
+	}
+	return -1
+}
+
+// Trim trailing spaces, unless they're escaped with a backslash.
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
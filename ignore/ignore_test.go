@@ -0,0 +1,112 @@
+package ignore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		rules   string
+		ignored []string
+		synced  []string
+	}{
+		{
+			name:    "file names match at any depth",
+			rules:   "workspace.json\n*.mp4",
+			ignored: []string{"workspace.json", ".obsidian/workspace.json", "Videos/Lecture 1.mp4"},
+			synced:  []string{"workspace.json.bak", "notes/mp4.md"},
+		},
+		{
+			name:    "patterns with a slash are relative to the root",
+			rules:   "/todo.md\n.obsidian/workspace.json",
+			ignored: []string{"todo.md", ".obsidian/workspace.json"},
+			synced:  []string{"notes/todo.md", "vault/.obsidian/workspace.json"},
+		},
+		{
+			name:    "directories",
+			rules:   ".trash/\nVideos",
+			ignored: []string{".trash/note.md", "archive/.trash/old/note.md", "Videos/a.mp4", "Videos"},
+			synced:  []string{".trash", "notes/.trash.md"},
+		},
+		{
+			name:    "double asterisks",
+			rules:   "**/drafts/*.md\nattachments/**\nnotes/**/private.md",
+			ignored: []string{"drafts/a.md", "blog/drafts/a.md", "attachments/a/b.png", "notes/private.md", "notes/a/b/private.md"},
+			synced:  []string{"drafts/a/b.md", "attachments", "private.md"},
+		},
+		{
+			name:    "wildcards and character classes",
+			rules:   "draft-?.md\nlog[0-9].txt\nfile[!a].md\n*.tmp",
+			ignored: []string{"draft-1.md", "log3.txt", "fileb.md", "a/b.tmp"},
+			synced:  []string{"draft-10.md", "logs.txt", "filea.md", "a.tmpl"},
+		},
+		{
+			name:    "negation",
+			rules:   "*.json\n!data.json\n.obsidian/\n!.obsidian/app.json",
+			ignored: []string{"workspace.json", ".obsidian/app.json"},
+			synced:  []string{"data.json", "notes/data.json"},
+		},
+		{
+			name:    "comments, blank lines and escapes",
+			rules:   "# comment\n\n\\#hash.md\n\\!bang.md\ntrailing.md   \nspace\\ ",
+			ignored: []string{"#hash.md", "!bang.md", "trailing.md", "space "},
+			synced:  []string{"comment", "space"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := Parse(tc.rules)
+			if !assert.NoError(t, err) {
+				return
+			}
+			for _, filename := range tc.ignored {
+				assert.True(t, m.Match(filename), filename)
+			}
+			for _, filename := range tc.synced {
+				assert.False(t, m.Match(filename), filename)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	m, err := Parse("# comment\n.trash/\n!keep.md\n")
+	if assert.NoError(t, err) && assert.Len(t, m.Rules(), 2) {
+		assert.Equal(t, ".trash/", m.Rules()[0].Pattern)
+		assert.True(t, m.Rules()[0].DirOnly)
+		assert.True(t, m.Rules()[1].Negate)
+	}
+
+	_, err = Parse("*.md\nlog[0-9.txt")
+	assert.ErrorContains(t, err, "line 2")
+
+	var empty *Matcher
+	assert.False(t, empty.Match("note.md"))
+	assert.False(t, empty.MatchDir("notes"))
+}
+
+func TestMatchDir(t *testing.T) {
+	t.Parallel()
+
+	m, err := Parse(".trash/\n*.md\nVideos/**\n")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, m.MatchDir(".trash"))
+	assert.True(t, m.MatchDir("archive/.trash/old"))
+	assert.True(t, m.MatchDir("Videos/2024"))
+	assert.True(t, m.MatchDir("notes.md"))
+	assert.False(t, m.MatchDir("notes"))
+	assert.False(t, m.MatchDir("Videos"))
+}
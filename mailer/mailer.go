@@ -0,0 +1,238 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How the connection to the SMTP server is secured.
+const (
+	// Upgrade the connection with STARTTLS, failing if the server can't
+	TLSStartTLS = "starttls"
+	// Connect with TLS from the start, usually on port 465
+	TLSImplicit = "tls"
+	// Send everything in plain text, only for servers on a trusted network
+	TLSNone = "none"
+)
+
+const (
+	DefaultPort    = 587
+	DefaultTimeout = 30 * time.Second
+)
+
+var (
+	ErrStartTLSUnsupported = errors.New("smtp server doesn't support STARTTLS")
+	ErrUnsupportedTLS      = errors.New("unsupported smtp tls mode")
+)
+
+// Plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sends emails.
+type Sender interface {
+	Send(msg *Message) error
+}
+
+type SMTPConfig struct {
+	Host string
+	// Port of the SMTP server, or zero for DefaultPort
+	Port     int
+	Username string
+	Password string
+	// Address emails are sent from, like "Obsync <obsync@example.com>"
+	From string
+	// One of TLSStartTLS, TLSImplicit or TLSNone, or empty for TLSStartTLS
+	TLS string
+	// Timeout for connecting to the server, or zero for DefaultTimeout
+	Timeout time.Duration
+}
+
+// Sends emails through an SMTP server, connecting for each email.
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	if config.Port == 0 {
+		config.Port = DefaultPort
+	}
+	if len(config.TLS) == 0 {
+		config.TLS = TLSStartTLS
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return &SMTPSender{config: config}
+}
+
+func (s *SMTPSender) Send(msg *Message) error {
+	from, err := mail.ParseAddress(s.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid to address: %w", err)
+	}
+	data, err := formatMessage(from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if len(s.config.Username) > 0 {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Connect to the SMTP server, securing the connection as configured.
+func (s *SMTPSender) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+	dialer := &net.Dialer{Timeout: s.config.Timeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch s.config.TLS {
+	case TLSImplicit:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	case TLSStartTLS, TLSNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, ErrUnsupportedTLS
+	}
+	if err != nil {
+		return nil, err
+	}
+	// don't let a stalled server hold up the request sending the email
+	conn.SetDeadline(time.Now().Add(s.config.Timeout))
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if s.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, ErrStartTLSUnsupported
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// Format an email with its headers, encoding the body as quoted-printable so
+// long lines and non-ASCII text survive any server.
+func formatMessage(from, to *mail.Address, msg *Message, date time.Time) ([]byte, error) {
+	id, err := messageId(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", id},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header[0], header[1])
+	}
+	buf.WriteString("\r\n")
+
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	body = strings.ReplaceAll(body, "\n", "\r\n")
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Make a unique Message-ID in the domain of the from address.
+func messageId(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = from[at+1:]
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
+
+// Renders emails from templates and sends them.
+type Mailer struct {
+	sender    Sender
+	templates *Templates
+}
+
+func New(sender Sender, templates *Templates) *Mailer {
+	return &Mailer{sender: sender, templates: templates}
+}
+
+// Render the named template with data and send it to an address.
+func (m *Mailer) Send(to, template string, data any) error {
+	msg, err := m.templates.Render(template, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return m.sender.Send(msg)
+}
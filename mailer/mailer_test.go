@@ -0,0 +1,161 @@
+package mailer
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raian621/obsync-server/mailer/smtptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func startSMTPServer(t *testing.T) *smtptest.Server {
+	t.Helper()
+	server, err := smtptest.NewServer()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func testSender(server *smtptest.Server) *SMTPSender {
+	return NewSMTPSender(SMTPConfig{
+		Host: server.Host(),
+		Port: server.Port(),
+		From: "Obsync <obsync@example.com>",
+		TLS:  TLSNone,
+	})
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	t.Parallel()
+
+	server := startSMTPServer(t)
+	longLine := strings.Repeat("long line ", 20)
+	err := testSender(server).Send(&Message{
+		To:      "Ünïcode User <user@example.com>",
+		Subject: "Grüße from Obsync",
+		Body:    "Hello there,\n" + longLine + "\n",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	messages := server.Messages()
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+	msg := messages[0]
+	assert.Equal(t, "obsync@example.com", msg.From)
+	assert.Equal(t, []string{"user@example.com"}, msg.To)
+
+	parsed, err := msg.Parse()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `"Obsync" <obsync@example.com>`, parsed.Header.Get("From"))
+	to, err := mail.ParseAddress(parsed.Header.Get("To"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Ünïcode User", to.Name)
+	}
+	assert.True(t, strings.HasSuffix(parsed.Header.Get("Message-ID"), "@example.com>"))
+	date, err := parsed.Header.Date()
+	if assert.NoError(t, err) {
+		assert.WithinDuration(t, time.Now(), date, time.Minute)
+	}
+
+	subject, err := msg.Subject()
+	assert.NoError(t, err)
+	assert.Equal(t, "Grüße from Obsync", subject)
+	body, err := msg.Body()
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello there,\n"+longLine+"\n", body)
+	// quoted-printable keeps lines short
+	for _, line := range strings.Split(string(msg.Data), "\n") {
+		assert.LessOrEqual(t, len(line), 78)
+	}
+}
+
+func TestSMTPSenderAuth(t *testing.T) {
+	t.Parallel()
+
+	server := startSMTPServer(t)
+	server.RequireAuth("obsync", "hunter2")
+	msg := &Message{To: "user@example.com", Subject: "hi", Body: "hi"}
+
+	sender := testSender(server)
+	assert.Error(t, sender.Send(msg), "server requires a login")
+
+	sender.config.Username = "obsync"
+	sender.config.Password = "wrong"
+	assert.Error(t, sender.Send(msg))
+
+	sender.config.Password = "hunter2"
+	assert.NoError(t, sender.Send(msg))
+	assert.Len(t, server.Messages(), 1)
+}
+
+func TestSMTPSenderErrors(t *testing.T) {
+	t.Parallel()
+
+	server := startSMTPServer(t)
+	msg := &Message{To: "user@example.com", Subject: "hi", Body: "hi"}
+
+	sender := testSender(server)
+	sender.config.TLS = TLSStartTLS
+	assert.ErrorIs(t, sender.Send(msg), ErrStartTLSUnsupported)
+
+	sender.config.TLS = "ssl"
+	assert.ErrorIs(t, sender.Send(msg), ErrUnsupportedTLS)
+
+	sender = testSender(server)
+	assert.Error(t, sender.Send(&Message{To: "not an address"}))
+	sender.config.From = "not an address"
+	assert.Error(t, sender.Send(msg))
+
+	assert.Empty(t, server.Messages())
+}
+
+func TestNewSMTPSenderDefaults(t *testing.T) {
+	t.Parallel()
+
+	sender := NewSMTPSender(SMTPConfig{Host: "smtp.example.com"})
+	assert.Equal(t, DefaultPort, sender.config.Port)
+	assert.Equal(t, TLSStartTLS, sender.config.TLS)
+	assert.Equal(t, DefaultTimeout, sender.config.Timeout)
+}
+
+func TestMailerSend(t *testing.T) {
+	t.Parallel()
+
+	server := startSMTPServer(t)
+	templates, err := LoadTemplates("")
+	if !assert.NoError(t, err) {
+		return
+	}
+	m := New(testSender(server), templates)
+
+	err = m.Send("user@example.com", TemplateResetPassword, map[string]any{
+		"Username": "user",
+		"Token":    "secret-token",
+		"Lifetime": "1 hour",
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	messages := server.Messages()
+	if !assert.Len(t, messages, 1) {
+		return
+	}
+	assert.Equal(t, []string{"user@example.com"}, messages[0].To)
+	subject, _ := messages[0].Subject()
+	assert.Equal(t, "Reset your Obsync password", subject)
+	body, _ := messages[0].Body()
+	assert.Contains(t, body, "Hi user,")
+	assert.Contains(t, body, "secret-token")
+
+	assert.ErrorIs(t, m.Send("user@example.com", "welcome", nil), ErrUnknownTemplate)
+	assert.Len(t, server.Messages(), 1)
+}
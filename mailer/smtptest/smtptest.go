@@ -0,0 +1,243 @@
+// Package smtptest runs an in-process SMTP server that captures the emails
+// sent to it, for testing code that sends email.
+package smtptest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Email the server received.
+type Message struct {
+	From string
+	To   []string
+	// Message with its headers, with line endings as "\n"
+	Data []byte
+}
+
+// Parse the message's headers and body.
+func (m Message) Parse() (*mail.Message, error) {
+	return mail.ReadMessage(bytes.NewReader(m.Data))
+}
+
+// Get the decoded subject of the message.
+func (m Message) Subject() (string, error) {
+	msg, err := m.Parse()
+	if err != nil {
+		return "", err
+	}
+	return new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+}
+
+// Get the body of the message, decoding quoted-printable bodies.
+func (m Message) Body() (string, error) {
+	msg, err := m.Parse()
+	if err != nil {
+		return "", err
+	}
+	body := msg.Body
+	if strings.EqualFold(msg.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	b, err := io.ReadAll(body)
+	return strings.ReplaceAll(string(b), "\r\n", "\n"), err
+}
+
+// SMTP server listening on a loopback address. It speaks just enough SMTP
+// for net/smtp clients, without STARTTLS, so senders have to use plain
+// connections.
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []Message
+	username string
+	password string
+}
+
+// Start a server. Close it when done.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Host the server listens on.
+func (s *Server) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port the server listens on.
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Make clients log in with AUTH PLAIN using the credentials before sending
+// mail. Servers accept any credentials, or clients that don't log in, until
+// this is called.
+func (s *Server) RequireAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// Get the messages received so far, oldest first.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message{}, s.messages...)
+}
+
+// Stop listening and wait for open connections to finish.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (s *Server) handle(conn *textproto.Conn) {
+	s.mu.Lock()
+	username, password := s.username, s.password
+	s.mu.Unlock()
+	var (
+		msg       Message
+		loggedIn  bool
+		needsAuth = len(username) > 0 || len(password) > 0
+	)
+	reply := func(code int, text string) error {
+		return conn.PrintfLine("%d %s", code, text)
+	}
+
+	if reply(220, "smtptest ESMTP") != nil {
+		return
+	}
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			err = reply(250, "smtptest")
+		case "EHLO":
+			err = conn.PrintfLine("250-smtptest\r\n250-8BITMIME\r\n250 AUTH PLAIN")
+		case "AUTH":
+			mechanism, response, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				err = reply(504, "unrecognized authentication type")
+				break
+			}
+			if len(response) == 0 {
+				if err = conn.PrintfLine("334 "); err != nil {
+					return
+				}
+				if response, err = conn.ReadLine(); err != nil {
+					return
+				}
+			}
+			if validCredentials(response, username, password) {
+				loggedIn = true
+				err = reply(235, "authentication successful")
+			} else {
+				err = reply(535, "authentication failed")
+			}
+		case "MAIL":
+			if needsAuth && !loggedIn {
+				err = reply(530, "authentication required")
+				break
+			}
+			msg = Message{From: trimPath(arg, "FROM:")}
+			err = reply(250, "ok")
+		case "RCPT":
+			msg.To = append(msg.To, trimPath(arg, "TO:"))
+			err = reply(250, "ok")
+		case "DATA":
+			if len(msg.To) == 0 {
+				err = reply(503, "need RCPT first")
+				break
+			}
+			if err = reply(354, "end data with <CR><LF>.<CR><LF>"); err != nil {
+				return
+			}
+			if msg.Data, err = io.ReadAll(conn.DotReader()); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{}
+			err = reply(250, "ok: queued as "+strconv.Itoa(len(s.Messages())))
+		case "RSET":
+			msg = Message{}
+			err = reply(250, "ok")
+		case "NOOP":
+			err = reply(250, "ok")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			err = reply(502, "command not implemented")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Check an AUTH PLAIN response against the server's credentials.
+func validCredentials(response, username, password string) bool {
+	b, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(b), "\x00")
+	if len(parts) != 3 {
+		return false
+	}
+	if len(username) == 0 && len(password) == 0 {
+		return true
+	}
+	return parts[1] == username && parts[2] == password
+}
+
+// Get the address from a MAIL or RCPT argument like "FROM:<a@example.com>".
+func trimPath(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	// drop parameters like BODY=8BITMIME
+	arg, _, _ = strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.TrimSuffix(strings.TrimPrefix(arg, "<"), ">")
+}
@@ -0,0 +1,97 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Names of the templates the server sends.
+const (
+	TemplateVerifyEmail   = "verify-email"
+	TemplateResetPassword = "reset-password"
+)
+
+const templateExt = ".tmpl"
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Email templates. Each template defines a "subject" and a "body" template
+// that are rendered with the same data.
+type Templates struct {
+	templates map[string]*template.Template
+}
+
+// Load the default templates, replacing any of them that have a file with
+// the same name, like "verify-email.tmpl", in dir. Leave dir empty to only
+// use the defaults.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{templates: map[string]*template.Template{}}
+
+	entries, err := defaultTemplates.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), templateExt)
+		text, err := defaultTemplates.ReadFile("templates/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(dir) > 0 {
+			custom, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+			if err == nil {
+				text = custom
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+		if err := t.parse(name, string(text)); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (t *Templates) parse(name, text string) error {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return err
+	}
+	for _, part := range []string{"subject", "body"} {
+		if tmpl.Lookup(part) == nil {
+			return fmt.Errorf("email template %q doesn't define %q", name, part)
+		}
+	}
+	t.templates[name] = tmpl
+	return nil
+}
+
+// Render the subject and body of a template. The message has no recipient.
+func (t *Templates) Render(name string, data any) (*Message, error) {
+	tmpl, ok := t.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, err
+	}
+	return &Message{
+		// headers can't span lines
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Body:    strings.TrimSpace(body.String()) + "\n",
+	}, nil
+}
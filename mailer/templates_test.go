@@ -0,0 +1,95 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTemplatesDefaults(t *testing.T) {
+	t.Parallel()
+
+	templates, err := LoadTemplates("")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	msg, err := templates.Render(TemplateVerifyEmail, map[string]any{
+		"Username": "user",
+		"Email":    "user@example.com",
+		"Token":    "secret-token",
+		"Link":     "https://obsync.example.com/verify?token=secret-token",
+		"Lifetime": "24 hours",
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Verify your Obsync email address", msg.Subject)
+		assert.Contains(t, msg.Body, "user@example.com")
+		assert.Contains(t, msg.Body, "https://obsync.example.com/verify?token=secret-token")
+		assert.Contains(t, msg.Body, "24 hours")
+		assert.Empty(t, msg.To)
+	}
+
+	// without a link, the token is in the email instead
+	msg, err = templates.Render(TemplateVerifyEmail, map[string]any{
+		"Username": "user",
+		"Email":    "user@example.com",
+		"Token":    "secret-token",
+		"Link":     "",
+		"Lifetime": "24 hours",
+	})
+	if assert.NoError(t, err) {
+		assert.Contains(t, msg.Body, "secret-token")
+		assert.NotContains(t, msg.Body, "https://")
+	}
+
+	_, err = templates.Render(TemplateResetPassword, map[string]any{"Username": "user"})
+	assert.Error(t, err, "missing data is an error")
+	_, err = templates.Render("welcome", nil)
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+}
+
+func TestLoadTemplatesOverride(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	custom := `{{define "subject"}}
+  Password reset
+  for {{.Username}}
+{{end}}{{define "body"}}Token: {{.Token}}{{end}}`
+	err := os.WriteFile(filepath.Join(dir, TemplateResetPassword+".tmpl"), []byte(custom), 0o644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	templates, err := LoadTemplates(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	msg, err := templates.Render(TemplateResetPassword, map[string]any{"Username": "user", "Token": "abc"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Password reset for user", msg.Subject)
+		assert.Equal(t, "Token: abc\n", msg.Body)
+	}
+	// templates without a file in the directory keep the default
+	msg, err = templates.Render(TemplateVerifyEmail, map[string]any{
+		"Username": "user", "Email": "user@example.com", "Token": "abc", "Link": "", "Lifetime": "1 hour",
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Verify your Obsync email address", msg.Subject)
+	}
+
+	// templates need a subject and a body
+	dir = t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, TemplateVerifyEmail+".tmpl"), []byte(`{{define "body"}}hi{{end}}`), 0o644)
+	if assert.NoError(t, err) {
+		_, err = LoadTemplates(dir)
+		assert.Error(t, err)
+	}
+	err = os.WriteFile(filepath.Join(dir, TemplateVerifyEmail+".tmpl"), []byte(`{{define "subject"}}`), 0o644)
+	if assert.NoError(t, err) {
+		_, err = LoadTemplates(dir)
+		assert.Error(t, err)
+	}
+}
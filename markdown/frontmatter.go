@@ -0,0 +1,36 @@
+package markdown
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Split a note into its YAML frontmatter and the rest of the note. Notes
+// without frontmatter get a nil map. If the frontmatter isn't valid YAML, the
+// rest of the note is still returned along with the error.
+func ParseFrontmatter(content string) (map[string]any, string, error) {
+	firstLine, rest, found := strings.Cut(content, "\n")
+	if !found || strings.TrimRight(firstLine, "\r") != "---" {
+		return nil, content, nil
+	}
+
+	offset := len(firstLine) + 1
+	for len(rest) > 0 {
+		line, next, _ := strings.Cut(rest, "\n")
+		if trimmed := strings.TrimRight(line, "\r \t"); trimmed == "---" || trimmed == "..." {
+			frontmatter := content[len(firstLine)+1 : offset]
+			body := next
+			properties := map[string]any{}
+			if err := yaml.Unmarshal([]byte(frontmatter), &properties); err != nil {
+				return nil, body, err
+			}
+			return properties, body, nil
+		}
+		offset += len(line) + 1
+		rest = next
+	}
+
+	// the frontmatter was never closed, so it's just part of the note
+	return nil, content, nil
+}
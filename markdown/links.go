@@ -0,0 +1,183 @@
+// Package markdown parses the parts of Obsidian flavored markdown that the
+// server needs to understand.
+package markdown
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Link struct {
+	// Path the link points to, without a heading, block reference or alias
+	Target string
+	// Heading or block reference in the target, like "#Heading" or "#^block"
+	Subpath string
+	// Text the link is displayed as, if it has any
+	Alias string
+	// Whether the link embeds its target, like ![[image.png]]
+	Embed bool
+	// Whether the link is a markdown link instead of a wikilink. Markdown links
+	// can be relative to the note they're in.
+	Markdown bool
+}
+
+var (
+	inlineCodePattern   = regexp.MustCompile("`[^`\n]*`")
+	commentPattern      = regexp.MustCompile(`(?s)%%.*?%%`)
+	wikilinkPattern     = regexp.MustCompile(`(!?)\[\[([^\[\]\n]+?)\]\]`)
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[([^\]\n]*)\]\((<[^>\n]+>|[^)\s]+)(?:\s+"[^"\n]*")?\)`)
+)
+
+// Find the wikilinks, embeds and markdown links to other files in a note, in
+// the order they appear. Links inside code and comments are ignored, and so
+// are links to websites and to headings in the same note.
+func ParseLinks(content string) []Link {
+	content = stripCodeBlocks(content)
+	content = inlineCodePattern.ReplaceAllString(content, "")
+	content = commentPattern.ReplaceAllString(content, "")
+
+	type match struct {
+		index int
+		link  Link
+	}
+	var matches []match
+
+	for _, m := range wikilinkPattern.FindAllStringSubmatchIndex(content, -1) {
+		target, alias, _ := strings.Cut(content[m[4]:m[5]], "|")
+		link := Link{Alias: strings.TrimSpace(alias), Embed: m[3] > m[2]}
+		link.Target, link.Subpath = splitSubpath(strings.TrimSpace(target))
+		if len(link.Target) > 0 {
+			matches = append(matches, match{m[0], link})
+		}
+	}
+
+	for _, m := range markdownLinkPattern.FindAllStringSubmatchIndex(content, -1) {
+		target := strings.Trim(content[m[6]:m[7]], "<>")
+		if parsed, err := url.Parse(target); err == nil && len(parsed.Scheme) > 0 {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		link := Link{Alias: content[m[4]:m[5]], Embed: m[3] > m[2], Markdown: true}
+		link.Target, link.Subpath = splitSubpath(target)
+		if len(link.Target) > 0 {
+			matches = append(matches, match{m[0], link})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].index < matches[j].index })
+	links := make([]Link, 0, len(matches))
+	for _, m := range matches {
+		links = append(links, m.link)
+	}
+	return links
+}
+
+// Remove fenced code blocks from a note, keeping the lines around them.
+func stripCodeBlocks(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	fence := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if len(fence) > 0 {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func splitSubpath(target string) (string, string) {
+	if i := strings.Index(target, "#"); i >= 0 {
+		return strings.TrimSpace(target[:i]), target[i:]
+	}
+	return target, ""
+}
+
+// Get the lowercase name of the file a link points to, without its folder or
+// a .md extension. Links can only resolve to files with the same name.
+func LinkName(target string) string {
+	return FileLinkName(path.Base(strings.TrimSuffix(target, "/")))
+}
+
+// Get the name links to a file use, the lowercase filename without its
+// folder or a .md extension.
+func FileLinkName(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	return strings.TrimSuffix(name, ".md")
+}
+
+// Resolve a link in the note at sourcePath to one of files, the same way
+// Obsidian does:
+//
+//   - markdown links starting with ./ or ../ are relative to the note's folder,
+//     and only match the file at that exact path
+//   - links without a .md extension can point to markdown files
+//   - a link can be any part of the end of a file's path, like [[Note]] or
+//     [[Folder/Note]] for Folder/Note.md
+//   - when several files match, a file with the exact path wins, then files in
+//     the note's own folder, then the file with the shortest path
+//
+// Matching ignores case. ok is false if the link doesn't point to any file.
+func ResolveLink(link Link, sourcePath string, files []string) (resolved string, ok bool) {
+	target := link.Target
+	relative := link.Markdown && (strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../"))
+	if relative {
+		target = path.Join(path.Dir(sourcePath), target)
+		if strings.HasPrefix(target, "../") {
+			return "", false
+		}
+	}
+	target = strings.ToLower(strings.TrimPrefix(path.Clean("/"+target), "/"))
+	if len(target) == 0 {
+		return "", false
+	}
+
+	names := []string{target}
+	if !strings.HasSuffix(target, ".md") {
+		names = append(names, target+".md")
+	}
+
+	sourceDir := path.Dir(sourcePath)
+	var candidates []string
+	for _, file := range files {
+		lower := strings.ToLower(file)
+		for _, name := range names {
+			if lower == name {
+				return file, true
+			}
+			if !relative && strings.HasSuffix(lower, "/"+name) {
+				candidates = append(candidates, file)
+				break
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		iLocal, jLocal := path.Dir(candidates[i]) == sourceDir, path.Dir(candidates[j]) == sourceDir
+		if iLocal != jLocal {
+			return iLocal
+		}
+		iDepth, jDepth := strings.Count(candidates[i], "/"), strings.Count(candidates[j], "/")
+		if iDepth != jDepth {
+			return iDepth < jDepth
+		}
+		return candidates[i] < candidates[j]
+	})
+	return candidates[0], true
+}
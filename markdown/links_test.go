@@ -0,0 +1,139 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLinks(t *testing.T) {
+	t.Parallel()
+
+	note := "# Process Scheduling\n" +
+		"\n" +
+		"See [[Round Robin]] and [[CSCE4600/Paging|paging]] or [[Round Robin#Time quantum]].\n" +
+		"![[diagram.png]] ![diagram](<attachments/my diagram.png>)\n" +
+		"[FIFO](./FIFO%20Queue.md#Example \"FIFO\") and [docs](https://example.com/notes.md).\n" +
+		"[[#Local heading]] [top](#top)\n" +
+		"`[[Not a link]]`\n" +
+		"%% [[Commented out]] %%\n" +
+		"```md\n" +
+		"[[Code block]]\n" +
+		"```\n" +
+		"[[Last]]\n"
+
+	assert.Equal(t, []Link{
+		{Target: "Round Robin"},
+		{Target: "CSCE4600/Paging", Alias: "paging"},
+		{Target: "Round Robin", Subpath: "#Time quantum"},
+		{Target: "diagram.png", Embed: true},
+		{Target: "attachments/my diagram.png", Alias: "diagram", Embed: true, Markdown: true},
+		{Target: "./FIFO Queue.md", Subpath: "#Example", Alias: "FIFO", Markdown: true},
+		{Target: "Last"},
+	}, ParseLinks(note))
+}
+
+func TestResolveLink(t *testing.T) {
+	t.Parallel()
+
+	files := []string{
+		"Round Robin.md",
+		"school/os/Round Robin.md",
+		"school/os/Paging.md",
+		"school/networks/Paging.md",
+		"school/Paging.md",
+		"attachments/diagram.png",
+		"school/os/FIFO Queue.md",
+		"v1.2.md",
+	}
+
+	testCases := []struct {
+		name   string
+		link   Link
+		source string
+		want   string
+	}{
+		{
+			name:   "exact path wins",
+			link:   Link{Target: "Round Robin"},
+			source: "school/os/Scheduling.md",
+			want:   "Round Robin.md",
+		},
+		{
+			name:   "file in the same folder",
+			link:   Link{Target: "Paging"},
+			source: "school/networks/TCP.md",
+			want:   "school/networks/Paging.md",
+		},
+		{
+			name:   "shortest path",
+			link:   Link{Target: "paging"},
+			source: "todo.md",
+			want:   "school/Paging.md",
+		},
+		{
+			name:   "partial path",
+			link:   Link{Target: "os/Paging"},
+			source: "todo.md",
+			want:   "school/os/Paging.md",
+		},
+		{
+			name:   "attachment",
+			link:   Link{Target: "diagram.png", Embed: true},
+			source: "school/os/Scheduling.md",
+			want:   "attachments/diagram.png",
+		},
+		{
+			name:   "link with a .md extension",
+			link:   Link{Target: "FIFO Queue.md"},
+			source: "todo.md",
+			want:   "school/os/FIFO Queue.md",
+		},
+		{
+			name:   "relative markdown link",
+			link:   Link{Target: "../Paging.md", Markdown: true},
+			source: "school/os/Scheduling.md",
+			want:   "school/Paging.md",
+		},
+		{
+			name:   "dots in the filename",
+			link:   Link{Target: "v1.2"},
+			source: "todo.md",
+			want:   "v1.2.md",
+		},
+		{
+			name:   "missing file",
+			link:   Link{Target: "Lottery Scheduling"},
+			source: "todo.md",
+		},
+		{
+			name:   "relative links only match exact paths",
+			link:   Link{Target: "./Paging.md", Markdown: true},
+			source: "Scheduling.md",
+		},
+		{
+			name:   "relative link outside of the vault",
+			link:   Link{Target: "../../Paging.md", Markdown: true},
+			source: "school/Scheduling.md",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			resolved, ok := ResolveLink(tc.link, tc.source, files)
+			assert.Equal(t, tc.want, resolved)
+			assert.Equal(t, len(tc.want) > 0, ok)
+		})
+	}
+}
+
+func TestLinkName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "round robin", LinkName("school/os/Round Robin"))
+	assert.Equal(t, "round robin", LinkName("../Round Robin.md"))
+	assert.Equal(t, "diagram.png", LinkName("diagram.png"))
+	assert.Equal(t, "round robin", FileLinkName("school/os/Round Robin.md"))
+	assert.Equal(t, "diagram.png", FileLinkName("attachments/diagram.png"))
+}
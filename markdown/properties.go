@@ -0,0 +1,124 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Property types, named after the types Obsidian gives properties.
+type PropertyType string
+
+const (
+	PropertyText     PropertyType = "text"
+	PropertyList     PropertyType = "list"
+	PropertyNumber   PropertyType = "number"
+	PropertyCheckbox PropertyType = "checkbox"
+	PropertyDate     PropertyType = "date"
+	PropertyDateTime PropertyType = "datetime"
+	PropertyObject   PropertyType = "object"
+	PropertyEmpty    PropertyType = "empty"
+)
+
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02T15:04:05"
+)
+
+// Property in a note's frontmatter.
+type Property struct {
+	Key  string
+	Type PropertyType
+	// Value that can be encoded as JSON. Dates are formatted as strings like
+	// 2024-05-01 and 2024-05-01T10:00:00, so they can be compared as text.
+	Value any
+}
+
+// Get the typed properties in a note's frontmatter, sorted by key. Notes
+// without frontmatter have no properties, and frontmatter that isn't valid
+// YAML is returned as an error.
+func ParseProperties(content string) ([]Property, error) {
+	frontmatter, _, err := ParseFrontmatter(content)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := make([]Property, 0, len(frontmatter))
+	for key, value := range frontmatter {
+		value = normalizeValue(value)
+		properties = append(properties, Property{
+			Key:   key,
+			Type:  propertyType(value),
+			Value: value,
+		})
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].Key < properties[j].Key
+	})
+
+	return properties, nil
+}
+
+func propertyType(value any) PropertyType {
+	switch value := value.(type) {
+	case nil:
+		return PropertyEmpty
+	case bool:
+		return PropertyCheckbox
+	case int, int64, uint64, float64:
+		return PropertyNumber
+	case []any:
+		return PropertyList
+	case map[string]any:
+		return PropertyObject
+	case string:
+		if _, err := time.Parse(dateLayout, value); err == nil {
+			return PropertyDate
+		}
+		// Obsidian writes datetimes without seconds
+		for _, layout := range []string{dateTimeLayout, "2006-01-02T15:04", time.RFC3339} {
+			if _, err := time.Parse(layout, value); err == nil {
+				return PropertyDateTime
+			}
+		}
+	}
+	return PropertyText
+}
+
+// YAML decodes unquoted dates as times, which are turned back into strings
+// here so they keep the precision they were written with.
+func normalizeValue(value any) any {
+	switch value := value.(type) {
+	case time.Time:
+		if value.Location() == time.UTC {
+			if value.Equal(value.Truncate(24 * time.Hour)) {
+				return value.Format(dateLayout)
+			}
+			return value.Format(dateTimeLayout)
+		}
+		return value.Format(time.RFC3339)
+	case []any:
+		for i, item := range value {
+			value[i] = normalizeValue(item)
+		}
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalizeValue(item)
+		}
+	case map[any]any:
+		// mappings with keys that aren't strings can't be encoded as JSON
+		converted := make(map[string]any, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return converted
+	}
+	return value
+}
+
+// Get the name properties are matched by. Property names are
+// case-insensitive.
+func PropertyName(key string) string {
+	return strings.ToLower(key)
+}
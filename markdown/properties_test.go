@@ -0,0 +1,49 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProperties(t *testing.T) {
+	t.Parallel()
+
+	note := "---\n" +
+		"status: open\n" +
+		"priority: 2\n" +
+		"estimate: 1.5\n" +
+		"done: false\n" +
+		"due: 2024-05-01\n" +
+		"reminder: 2024-04-30T09:30\n" +
+		"started: 2024-04-01 08:00:00\n" +
+		"owner:\n" +
+		"aliases: [Alpha, 1]\n" +
+		"links: {docs: 2024-01-02, 1: one}\n" +
+		"---\n" +
+		"# Project Alpha\n"
+
+	properties, err := ParseProperties(note)
+	assert.NoError(t, err)
+	assert.Equal(t, []Property{
+		{Key: "aliases", Type: PropertyList, Value: []any{"Alpha", 1}},
+		{Key: "done", Type: PropertyCheckbox, Value: false},
+		{Key: "due", Type: PropertyDate, Value: "2024-05-01"},
+		{Key: "estimate", Type: PropertyNumber, Value: 1.5},
+		{Key: "links", Type: PropertyObject, Value: map[string]any{"docs": "2024-01-02", "1": "one"}},
+		{Key: "owner", Type: PropertyEmpty, Value: nil},
+		{Key: "priority", Type: PropertyNumber, Value: 2},
+		{Key: "reminder", Type: PropertyDateTime, Value: "2024-04-30T09:30"},
+		{Key: "started", Type: PropertyDateTime, Value: "2024-04-01T08:00:00"},
+		{Key: "status", Type: PropertyText, Value: "open"},
+	}, properties)
+
+	properties, err = ParseProperties("# No frontmatter\nstatus: open\n")
+	assert.NoError(t, err)
+	assert.Empty(t, properties)
+
+	_, err = ParseProperties("---\nstatus: [open\n---\n")
+	assert.Error(t, err)
+	_, err = ParseProperties("---\n- a list\n---\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,492 @@
+package markdown
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Resolves a link in a rendered note to the URL of the file it points to. ok
+// is false for links to files that don't exist.
+type LinkResolver func(link Link) (url string, ok bool)
+
+var (
+	calloutPattern     = regexp.MustCompile(`^\[!([\w-]+)\]([+-]?)\s*(.*)$`)
+	imageSizePattern   = regexp.MustCompile(`^(\d+)(?:x(\d+))?$`)
+	imageExtensions    = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".svg": true, ".webp": true, ".avif": true}
+	kindWikilink       = ast.NewNodeKind("Wikilink")
+	kindHighlight      = ast.NewNodeKind("Highlight")
+	kindCallout        = ast.NewNodeKind("Callout")
+	highlightDelimiter = &highlightDelimiterProcessor{}
+)
+
+// Only HTML that can't run scripts or load anything but images is kept in
+// rendered notes, since notes can contain any HTML.
+var renderPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\- ]+$`)).Globally()
+	policy.AllowAttrs("data-callout").Matching(regexp.MustCompile(`^[\w-]+$`)).OnElements("div", "details")
+	policy.AllowElements("mark", "details", "summary")
+	policy.AllowAttrs("open").OnElements("details")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	return policy
+}()
+
+// Render a note as sanitized HTML. Besides CommonMark and GitHub flavored
+// markdown, Obsidian's wikilinks, embeds, callouts, ==highlights== and
+// %%comments%% are supported. Links to other files are turned into URLs by
+// resolve, and the frontmatter isn't rendered.
+func Render(content string, resolve LinkResolver) ([]byte, error) {
+	_, body, _ := ParseFrontmatter(content)
+	body = stripComments(body)
+
+	md := goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			&obsidianExtension{resolve: resolve},
+		),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// raw HTML is allowed in notes, and cleaned up by the sanitizer instead
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(body), &buf); err != nil {
+		return nil, err
+	}
+	return renderPolicy.SanitizeBytes(buf.Bytes()), nil
+}
+
+// Remove %%comments%% from a note, except for the ones in code blocks.
+func stripComments(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := make([]string, 0, len(lines))
+	var prose []string
+	flush := func() {
+		if len(prose) > 0 {
+			kept = append(kept, commentPattern.ReplaceAllString(strings.Join(prose, "\n"), ""))
+			prose = prose[:0]
+		}
+	}
+
+	fence := ""
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case len(fence) > 0:
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			kept = append(kept, line)
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence = trimmed[:3]
+			kept = append(kept, line)
+		default:
+			prose = append(prose, line)
+		}
+	}
+	flush()
+	return strings.Join(kept, "\n")
+}
+
+// Get the anchor goldmark gives a heading in a link's subpath, like
+// #time-quantum for [[Round Robin#Time quantum]]. Block references don't have
+// anchors.
+func headingAnchor(subpath string) string {
+	heading := strings.TrimSpace(subpath[strings.LastIndex(subpath, "#")+1:])
+	if len(heading) == 0 || strings.HasPrefix(heading, "^") {
+		return ""
+	}
+	return "#" + string(parser.NewContext().IDs().Generate([]byte(heading), ast.KindHeading))
+}
+
+func isImage(filename string) bool {
+	return imageExtensions[strings.ToLower(path.Ext(filename))]
+}
+
+type obsidianExtension struct {
+	resolve LinkResolver
+}
+
+func (e *obsidianExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithInlineParsers(
+			// wikilinks have to be parsed before regular links
+			util.Prioritized(&wikilinkParser{}, 199),
+			util.Prioritized(&highlightParser{}, 500),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(&obsidianTransformer{resolve: e.resolve}, 100),
+		),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&obsidianRenderer{}, 100),
+	))
+}
+
+type wikilinkNode struct {
+	ast.BaseInline
+	link     Link
+	url      string
+	resolved bool
+}
+
+func (n *wikilinkNode) Kind() ast.NodeKind {
+	return kindWikilink
+}
+
+func (n *wikilinkNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Target": n.link.Target, "URL": n.url}, nil)
+}
+
+// Text a wikilink is displayed as, like "Round Robin > Time quantum" for
+// [[Round Robin#Time quantum]].
+func (n *wikilinkNode) text() string {
+	if len(n.link.Alias) > 0 {
+		return n.link.Alias
+	}
+	subpath := strings.TrimSpace(strings.ReplaceAll(strings.TrimPrefix(n.link.Subpath, "#"), "#", " > "))
+	if len(n.link.Target) == 0 {
+		return subpath
+	}
+	if len(subpath) > 0 {
+		return n.link.Target + " > " + subpath
+	}
+	return n.link.Target
+}
+
+type highlightNode struct {
+	ast.BaseInline
+}
+
+func (n *highlightNode) Kind() ast.NodeKind {
+	return kindHighlight
+}
+
+func (n *highlightNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+type calloutNode struct {
+	ast.BaseBlock
+	calloutType string
+	title       string
+	// "+" for callouts that can be folded and start open, "-" for ones that
+	// start folded, and empty for ones that can't be folded
+	fold string
+}
+
+func (n *calloutNode) Kind() ast.NodeKind {
+	return kindCallout
+}
+
+func (n *calloutNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Type": n.calloutType, "Title": n.title}, nil)
+}
+
+// Parses [[wikilinks]] and ![[embeds]].
+type wikilinkParser struct{}
+
+func (p *wikilinkParser) Trigger() []byte {
+	return []byte{'[', '!'}
+}
+
+func (p *wikilinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	start := 0
+	if len(line) > 0 && line[0] == '!' {
+		start = 1
+	}
+	if !bytes.HasPrefix(line[start:], []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line[start+2:], []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := string(line[start+2 : start+2+end])
+	if strings.ContainsAny(inner, "[]") {
+		return nil
+	}
+
+	target, alias, _ := strings.Cut(inner, "|")
+	// pipes in wikilinks in tables are escaped
+	target = strings.TrimSuffix(strings.TrimSpace(target), `\`)
+	link := Link{Alias: strings.TrimSpace(alias), Embed: start == 1}
+	link.Target, link.Subpath = splitSubpath(target)
+	if len(link.Target) == 0 && len(link.Subpath) == 0 {
+		return nil
+	}
+
+	block.Advance(start + 2 + end + 2)
+	return &wikilinkNode{link: link}
+}
+
+// Parses ==highlights== the same way goldmark parses ~~strikethroughs~~.
+type highlightParser struct{}
+
+func (p *highlightParser) Trigger() []byte {
+	return []byte{'='}
+}
+
+func (p *highlightParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 2, highlightDelimiter)
+	if node == nil || node.OriginalLength != 2 || before == '=' {
+		return nil
+	}
+
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
+
+func (p *highlightParser) CloseBlock(parent ast.Node, pc parser.Context) {}
+
+type highlightDelimiterProcessor struct{}
+
+func (p *highlightDelimiterProcessor) IsDelimiter(b byte) bool {
+	return b == '='
+}
+
+func (p *highlightDelimiterProcessor) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char
+}
+
+func (p *highlightDelimiterProcessor) OnMatch(consumes int) ast.Node {
+	return &highlightNode{}
+}
+
+// Resolves links to other files and turns blockquotes starting with [!type]
+// into callouts.
+type obsidianTransformer struct {
+	resolve LinkResolver
+}
+
+func (t *obsidianTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var blockquotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *wikilinkNode:
+			t.resolveWikilink(n)
+		case *ast.Link:
+			if destination, ok := t.resolveMarkdownLink(n.Destination, false); ok {
+				n.Destination = destination
+			}
+		case *ast.Image:
+			if destination, ok := t.resolveMarkdownLink(n.Destination, true); ok {
+				n.Destination = destination
+			}
+		case *ast.Blockquote:
+			blockquotes = append(blockquotes, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, blockquote := range blockquotes {
+		replaceCallout(blockquote, reader.Source())
+	}
+}
+
+func (t *obsidianTransformer) resolveWikilink(n *wikilinkNode) {
+	if len(n.link.Target) == 0 {
+		// a link to a heading in the same note
+		n.url, n.resolved = headingAnchor(n.link.Subpath), true
+		return
+	}
+	if t.resolve == nil {
+		return
+	}
+	if url, ok := t.resolve(n.link); ok {
+		n.url, n.resolved = url+headingAnchor(n.link.Subpath), true
+	}
+}
+
+// Resolve a markdown link or image to another file in the vault. Links to
+// websites and to headings in the same note are left alone.
+func (t *obsidianTransformer) resolveMarkdownLink(destination []byte, embed bool) ([]byte, bool) {
+	target := string(destination)
+	if t.resolve == nil || len(target) == 0 || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "//") {
+		return nil, false
+	}
+	if parsed, err := url.Parse(target); err != nil || len(parsed.Scheme) > 0 {
+		return nil, false
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	link := Link{Embed: embed, Markdown: true}
+	link.Target, link.Subpath = splitSubpath(target)
+	resolved, ok := t.resolve(link)
+	if !ok {
+		return nil, false
+	}
+	return []byte(resolved + headingAnchor(link.Subpath)), true
+}
+
+// Replace a blockquote with a callout if its first line is a callout marker
+// like [!warning] Title.
+func replaceCallout(blockquote *ast.Blockquote, source []byte) {
+	paragraph, ok := blockquote.FirstChild().(*ast.Paragraph)
+	if !ok || paragraph.Lines().Len() == 0 {
+		return
+	}
+	firstLine := paragraph.Lines().At(0)
+	match := calloutPattern.FindSubmatch(bytes.TrimRight(firstLine.Value(source), "\r\n"))
+	if match == nil {
+		return
+	}
+
+	callout := &calloutNode{
+		calloutType: strings.ToLower(string(match[1])),
+		title:       strings.TrimSpace(string(match[3])),
+		fold:        string(match[2]),
+	}
+	if len(callout.title) == 0 {
+		callout.title = strings.ToUpper(callout.calloutType[:1]) + callout.calloutType[1:]
+	}
+
+	// the marker and title are on the first line, and the rest of the
+	// blockquote is the callout's content
+	for child := paragraph.FirstChild(); child != nil; {
+		if inlineStart(child) >= firstLine.Stop {
+			break
+		}
+		next := child.NextSibling()
+		paragraph.RemoveChild(paragraph, child)
+		if t, ok := child.(*ast.Text); ok && (t.SoftLineBreak() || t.HardLineBreak()) {
+			break
+		}
+		child = next
+	}
+	if !paragraph.HasChildren() {
+		blockquote.RemoveChild(blockquote, paragraph)
+	}
+	for child := blockquote.FirstChild(); child != nil; {
+		next := child.NextSibling()
+		callout.AppendChild(callout, child)
+		child = next
+	}
+	blockquote.Parent().ReplaceChild(blockquote.Parent(), blockquote, callout)
+}
+
+// Get the position in the source an inline node starts at, or -1 for nodes
+// without any text.
+func inlineStart(n ast.Node) int {
+	if t, ok := n.(*ast.Text); ok {
+		return t.Segment.Start
+	}
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if start := inlineStart(child); start >= 0 {
+			return start
+		}
+	}
+	return -1
+}
+
+// Renders the nodes the Obsidian extension adds the way Obsidian does, using
+// the same class names so Obsidian themes can style them.
+type obsidianRenderer struct{}
+
+func (r *obsidianRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindWikilink, r.renderWikilink)
+	reg.Register(kindHighlight, r.renderHighlight)
+	reg.Register(kindCallout, r.renderCallout)
+}
+
+func (r *obsidianRenderer) renderWikilink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*wikilinkNode)
+	href := util.EscapeHTML(util.URLEscape([]byte(n.url), false))
+
+	switch {
+	case !n.resolved:
+		_, _ = w.WriteString(`<span class="internal-link is-unresolved">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.text())))
+		_, _ = w.WriteString(`</span>`)
+	case n.link.Embed && isImage(n.link.Target):
+		_, _ = w.WriteString(`<img class="internal-embed" src="`)
+		_, _ = w.Write(href)
+		_, _ = w.WriteString(`"`)
+		// the alias of an embedded image is its size, like ![[image.png|100x50]]
+		if size := imageSizePattern.FindStringSubmatch(n.link.Alias); size != nil {
+			_, _ = w.WriteString(` width="` + size[1] + `"`)
+			if len(size[2]) > 0 {
+				_, _ = w.WriteString(` height="` + size[2] + `"`)
+			}
+			_, _ = w.WriteString(` alt="`)
+			_, _ = w.Write(util.EscapeHTML([]byte(path.Base(n.link.Target))))
+		} else {
+			_, _ = w.WriteString(` alt="`)
+			_, _ = w.Write(util.EscapeHTML([]byte(n.text())))
+		}
+		_, _ = w.WriteString(`">`)
+	default:
+		class := "internal-link"
+		if n.link.Embed {
+			class = "internal-embed"
+		}
+		_, _ = w.WriteString(`<a class="` + class + `" href="`)
+		_, _ = w.Write(href)
+		_, _ = w.WriteString(`">`)
+		_, _ = w.Write(util.EscapeHTML([]byte(n.text())))
+		_, _ = w.WriteString(`</a>`)
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *obsidianRenderer) renderHighlight(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString("<mark>")
+	} else {
+		_, _ = w.WriteString("</mark>")
+	}
+	return ast.WalkContinue, nil
+}
+
+// Callouts that can be folded are rendered as <details> elements, so they can
+// be folded without any scripts.
+func (r *obsidianRenderer) renderCallout(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*calloutNode)
+	element, titleElement := "div", "div"
+	if len(n.fold) > 0 {
+		element, titleElement = "details", "summary"
+	}
+
+	if !entering {
+		_, _ = w.WriteString("</div>\n</" + element + ">\n")
+		return ast.WalkContinue, nil
+	}
+	_, _ = w.WriteString("<" + element + ` class="callout" data-callout="`)
+	_, _ = w.Write(util.EscapeHTML([]byte(n.calloutType)))
+	_, _ = w.WriteString(`"`)
+	if n.fold == "+" {
+		_, _ = w.WriteString(" open")
+	}
+	_, _ = w.WriteString(">\n<" + titleElement + ` class="callout-title">`)
+	_, _ = w.Write(util.EscapeHTML([]byte(n.title)))
+	_, _ = w.WriteString("</" + titleElement + ">\n" + `<div class="callout-content">` + "\n")
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,108 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	resolve := func(link Link) (string, bool) {
+		if link.Target == "Missing" {
+			return "", false
+		}
+		return "/files/" + link.Target, true
+	}
+
+	testCases := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "frontmatter isn't rendered",
+			content: "---\nstatus: open\n---\n# Title",
+			want:    "<h1 id=\"title\">Title</h1>\n",
+		},
+		{
+			name:    "wikilinks",
+			content: "[[Round Robin#Time quantum]] [[Round Robin|RR]] [[Missing]] [[#Title]]",
+			want: `<p><a class="internal-link" href="/files/Round%20Robin#time-quantum" rel="nofollow">Round Robin &gt; Time quantum</a> ` +
+				`<a class="internal-link" href="/files/Round%20Robin" rel="nofollow">RR</a> ` +
+				`<span class="internal-link is-unresolved">Missing</span> ` +
+				`<a class="internal-link" href="#title" rel="nofollow">Title</a></p>` + "\n",
+		},
+		{
+			name:    "embeds",
+			content: "![[diagram.png|100x50]] ![[photo.jpg]] ![[Other Note]]",
+			want: `<p><img class="internal-embed" src="/files/diagram.png" width="100" height="50" alt="diagram.png"> ` +
+				`<img class="internal-embed" src="/files/photo.jpg" alt="photo.jpg"> ` +
+				`<a class="internal-embed" href="/files/Other%20Note" rel="nofollow">Other Note</a></p>` + "\n",
+		},
+		{
+			name:    "markdown links",
+			content: "![diagram](<attachments/my diagram.png>) [FIFO](FIFO%20Queue.md#Example) [site](https://example.com) [top](#top)",
+			want: `<p><img src="/files/attachments/my%20diagram.png" alt="diagram"> ` +
+				`<a href="/files/FIFO%20Queue.md#example" rel="nofollow">FIFO</a> ` +
+				`<a href="https://example.com" rel="nofollow">site</a> ` +
+				`<a href="#top" rel="nofollow">top</a></p>` + "\n",
+		},
+		{
+			name:    "highlights",
+			content: "==important **text**== and a == b and ===not===",
+			want:    "<p><mark>important <strong>text</strong></mark> and a == b and ===not===</p>\n",
+		},
+		{
+			name:    "callout",
+			content: "> [!Warning] Be careful\n> Don't [[Missing|forget]].\n> Really.",
+			want: `<div class="callout" data-callout="warning">` + "\n" +
+				`<div class="callout-title">Be careful</div>` + "\n" +
+				`<div class="callout-content">` + "\n" +
+				`<p>Don&#39;t <span class="internal-link is-unresolved">forget</span>.` + "\nReally.</p>\n" +
+				"</div>\n</div>\n",
+		},
+		{
+			name:    "folded callout without a title",
+			content: "> [!faq]-\n> Answer",
+			want: `<details class="callout" data-callout="faq">` + "\n" +
+				`<summary class="callout-title">Faq</summary>` + "\n" +
+				`<div class="callout-content">` + "\n" +
+				"<p>Answer</p>\n" +
+				"</div>\n</details>\n",
+		},
+		{
+			name:    "blockquote",
+			content: "> [not a callout]",
+			want:    "<blockquote>\n<p>[not a callout]</p>\n</blockquote>\n",
+		},
+		{
+			name:    "task list",
+			content: "- [ ] todo\n- [x] done",
+			want: "<ul>\n" +
+				`<li><input disabled="" type="checkbox"> todo</li>` + "\n" +
+				`<li><input checked="" disabled="" type="checkbox"> done</li>` + "\n" +
+				"</ul>\n",
+		},
+		{
+			name:    "comments",
+			content: "visible %%hidden%%\n\n%%\nhidden block\n%%\n```\n%% kept in code %%\n```",
+			want:    "<p>visible</p>\n<pre><code>%% kept in code %%\n</code></pre>\n",
+		},
+		{
+			name:    "unsafe html",
+			content: "<script>alert(1)</script>\n\n<a href=\"javascript:alert(1)\" onclick=\"alert(1)\">click</a> <img src=x onerror=\"alert(1)\">",
+			want:    "\n<p>click <img src=\"x\"></p>\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			html, err := Render(tc.content, resolve)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, string(html))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var tagPattern = regexp.MustCompile(`(^|\s)#([\p{L}\p{N}\p{M}_/-]+)`)
+
+// Find the tags in a note, both inline tags like #project/alpha and tags in
+// the frontmatter's tags property. Tags are returned without the # in the
+// order they first appear, and tags that only differ by case are only
+// returned once. Tags inside code, comments and the frontmatter's other
+// properties are ignored.
+func ParseTags(content string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	addTag := func(tag string) {
+		tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "#"), "/")
+		if !validTag(tag) || seen[TagName(tag)] {
+			return
+		}
+		seen[TagName(tag)] = true
+		tags = append(tags, tag)
+	}
+
+	properties, body, _ := ParseFrontmatter(content)
+	for _, key := range []string{"tags", "tag"} {
+		switch value := properties[key].(type) {
+		case string:
+			for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+				addTag(tag)
+			}
+		case []any:
+			for _, item := range value {
+				if tag, ok := item.(string); ok {
+					addTag(tag)
+				}
+			}
+		}
+	}
+
+	body = stripCodeBlocks(body)
+	body = inlineCodePattern.ReplaceAllString(body, "")
+	body = commentPattern.ReplaceAllString(body, "")
+	for _, match := range tagPattern.FindAllStringSubmatch(body, -1) {
+		addTag(match[2])
+	}
+
+	return tags
+}
+
+// Get the name tags are matched by. Tags are case-insensitive.
+func TagName(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+// Tags can't be empty or only made of numbers, so #123 isn't a tag.
+func validTag(tag string) bool {
+	if len(tag) == 0 || strings.ContainsFunc(tag, unicode.IsSpace) {
+		return false
+	}
+	for _, r := range tag {
+		if !unicode.IsDigit(r) && r != '/' {
+			return true
+		}
+	}
+	return false
+}
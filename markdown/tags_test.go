@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		content  string
+		wantTags []string
+	}{
+		{
+			name:     "inline tags",
+			content:  "#project/alpha is due, see #todo and #TODO.\n#a-b_c #café",
+			wantTags: []string{"project/alpha", "todo", "a-b_c", "café"},
+		},
+		{
+			name:     "headings aren't tags",
+			content:  "# Heading\n## Another heading #inline\n###### Small",
+			wantTags: []string{"inline"},
+		},
+		{
+			name:     "tags in code and comments",
+			content:  "```sh\n# comment\necho #notatag\n```\n`#code` %% #hidden %% #real\n~~~\n#fenced\n~~~",
+			wantTags: []string{"real"},
+		},
+		{
+			name:     "things that look like tags",
+			content:  "#123 #2024/10 page.html#anchor &#35; [[Note#Heading]] #/ color: #fff",
+			wantTags: []string{"fff"},
+		},
+		{
+			name:     "frontmatter list",
+			content:  "---\ntags:\n  - '#project/beta'\n  - reading\naliases: [\"#notatag\"]\n# yaml comment\n---\n#reading #inline",
+			wantTags: []string{"project/beta", "reading", "inline"},
+		},
+		{
+			name:     "frontmatter string",
+			content:  "---\ntags: reading, books writing\n---\n",
+			wantTags: []string{"reading", "books", "writing"},
+		},
+		{
+			name:     "unclosed frontmatter",
+			content:  "---\ntags: reading\n#inline",
+			wantTags: []string{"inline"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantTags, ParseTags(tc.content))
+		})
+	}
+}
+
+func TestParseFrontmatter(t *testing.T) {
+	t.Parallel()
+
+	properties, body, err := ParseFrontmatter("---\ntitle: Scheduling\ntags: [os]\n---\n# Scheduling\n")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"title": "Scheduling", "tags": []any{"os"}}, properties)
+	assert.Equal(t, "# Scheduling\n", body)
+
+	properties, body, err = ParseFrontmatter("---\r\ntitle: Scheduling\r\n---\r\nbody")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"title": "Scheduling"}, properties)
+	assert.Equal(t, "body", body)
+
+	properties, body, err = ParseFrontmatter("# Scheduling\n---\n")
+	assert.NoError(t, err)
+	assert.Nil(t, properties)
+	assert.Equal(t, "# Scheduling\n---\n", body)
+
+	_, body, err = ParseFrontmatter("---\ntitle: [unclosed\n---\nbody")
+	assert.Error(t, err)
+	assert.Equal(t, "body", body)
+}
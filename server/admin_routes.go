@@ -0,0 +1,253 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+)
+
+// Path of the endpoints only admins can use.
+const AdminPath = BaseURL + "/admin"
+
+// Key of the admin making a request in the request's context.
+const adminContextKey = "obsync.admin"
+
+// Middleware that only lets admins use the endpoints under AdminPath. The
+// admin making the request is kept in the request's context, and the admin
+// endpoints refuse requests that didn't go through the middleware.
+func (o *ObsyncServer) RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if ctx.Path() != AdminPath && !strings.HasPrefix(ctx.Path(), AdminPath+"/") {
+				return next(ctx)
+			}
+
+			userId, err := o.authenticate(ctx)
+			if err != nil {
+				return sendAuthError(ctx, err)
+			}
+			user, err := database.GetUserById(o.db, userId)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return sendAuthError(ctx, ErrNotAuthenticated)
+				}
+				ctx.Logger().Print(err)
+				return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+			}
+			if !user.IsAdmin || user.Disabled {
+				return sendAdminOnly(ctx)
+			}
+			ctx.Set(adminContextKey, user)
+			return next(ctx)
+		}
+	}
+}
+
+// Get the admin making the request, which RequireAdmin puts in the context.
+func adminUser(ctx echo.Context) (*database.User, bool) {
+	admin, ok := ctx.Get(adminContextKey).(*database.User)
+	return admin, ok
+}
+
+func sendAdminOnly(ctx echo.Context) error {
+	return sendApiMessage(ctx, http.StatusForbidden, "only admins can manage users")
+}
+
+// List users
+// (GET /admin/users)
+func (o *ObsyncServer) GetAdminUsers(ctx echo.Context, params api.GetAdminUsersParams) error {
+	if _, ok := adminUser(ctx); !ok {
+		return sendAdminOnly(ctx)
+	}
+
+	search := ""
+	if params.Search != nil {
+		search = *params.Search
+	}
+	users, err := database.GetUsers(o.db, search)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	usages, err := database.GetStorageUsage(o.db)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiUsers := make([]api.AdminUser, 0, len(users))
+	for _, user := range users {
+		usage, ok := usages[user.Id]
+		if !ok {
+			// the user was created after their storage was counted
+			usage = &database.StorageUsage{UserId: user.Id}
+		}
+		apiUsers = append(apiUsers, toApiAdminUser(user, usage))
+	}
+	return ctx.JSON(http.StatusOK, apiUsers)
+}
+
+// Create a user
+// (POST /admin/users)
+func (o *ObsyncServer) PostAdminUsers(ctx echo.Context) error {
+	if _, ok := adminUser(ctx); !ok {
+		return sendAdminOnly(ctx)
+	}
+
+	var body api.User
+	if err := ctx.Bind(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	user, err := database.CreateUser(o.db, body.Username, string(body.Email), body.Password)
+	if err != nil {
+		return sendCreateUserError(ctx, err)
+	}
+	return ctx.JSON(http.StatusCreated, toApiAdminUser(user, &database.StorageUsage{UserId: user.Id}))
+}
+
+// Get a user
+// (GET /admin/users/{username})
+func (o *ObsyncServer) GetAdminUsersUsername(ctx echo.Context, username string) error {
+	if _, ok := adminUser(ctx); !ok {
+		return sendAdminOnly(ctx)
+	}
+
+	user, err := database.GetUserByUsername(o.db, username)
+	if err != nil {
+		return sendUserError(ctx, err)
+	}
+	return o.sendAdminUser(ctx, user)
+}
+
+// Disable a user
+// (POST /admin/users/{username}/disable)
+func (o *ObsyncServer) PostAdminUsersUsernameDisable(ctx echo.Context, username string) error {
+	admin, ok := adminUser(ctx)
+	if !ok {
+		return sendAdminOnly(ctx)
+	}
+
+	user, err := database.GetUserByUsername(o.db, username)
+	if err != nil {
+		return sendUserError(ctx, err)
+	}
+	// an admin disabling themselves could leave no one to enable them again
+	if user.Id == admin.Id {
+		return sendApiMessage(ctx, http.StatusBadRequest, "admins can't disable themselves")
+	}
+	if err := database.SetUserDisabled(o.db, user.Id, true); err != nil {
+		return sendUserError(ctx, err)
+	}
+	o.davLogins.forget(user.Id)
+	user.Disabled = true
+	return o.sendAdminUser(ctx, user)
+}
+
+// Enable a user
+// (POST /admin/users/{username}/enable)
+func (o *ObsyncServer) PostAdminUsersUsernameEnable(ctx echo.Context, username string) error {
+	if _, ok := adminUser(ctx); !ok {
+		return sendAdminOnly(ctx)
+	}
+
+	user, err := database.GetUserByUsername(o.db, username)
+	if err != nil {
+		return sendUserError(ctx, err)
+	}
+	if err := database.SetUserDisabled(o.db, user.Id, false); err != nil {
+		return sendUserError(ctx, err)
+	}
+	user.Disabled = false
+	return o.sendAdminUser(ctx, user)
+}
+
+// Reset a user's password
+// (POST /admin/users/{username}/password)
+func (o *ObsyncServer) PostAdminUsersUsernamePassword(ctx echo.Context, username string) error {
+	if _, ok := adminUser(ctx); !ok {
+		return sendAdminOnly(ctx)
+	}
+
+	var body api.AdminPasswordReset
+	if err := ctx.Bind(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	user, err := database.GetUserByUsername(o.db, username)
+	if err != nil {
+		return sendUserError(ctx, err)
+	}
+	if err := database.UpdateUserPassword(o.db, user.Id, body.Password); err != nil {
+		if errors.Is(err, database.ErrPasswordLength) {
+			return sendApiMessage(ctx, http.StatusBadRequest, "password too short")
+		}
+		return sendUserError(ctx, err)
+	}
+	// sessions made with the old password shouldn't keep working
+	if err := database.DeleteUserSessions(o.db, user.Id); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	o.davLogins.forget(user.Id)
+
+	return sendApiMessage(ctx, http.StatusOK, "password reset")
+}
+
+// Revoke a user's sessions and API keys
+// (POST /admin/users/{username}/revoke)
+func (o *ObsyncServer) PostAdminUsersUsernameRevoke(ctx echo.Context, username string) error {
+	if _, ok := adminUser(ctx); !ok {
+		return sendAdminOnly(ctx)
+	}
+
+	user, err := database.GetUserByUsername(o.db, username)
+	if err != nil {
+		return sendUserError(ctx, err)
+	}
+	sessions, apiKeys, err := database.RevokeUserCredentials(o.db, user.Id)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	o.davLogins.forget(user.Id)
+
+	return ctx.JSON(http.StatusOK, api.CredentialRevocation{Sessions: sessions, ApiKeys: apiKeys})
+}
+
+func (o *ObsyncServer) sendAdminUser(ctx echo.Context, user *database.User) error {
+	usage, err := database.GetUserStorageUsage(o.db, user.Id)
+	if err != nil {
+		return sendUserError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, toApiAdminUser(user, usage))
+}
+
+// Send the response for an error from getting or changing a user by their
+// username.
+func sendUserError(ctx echo.Context, err error) error {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, database.ErrNoResults) || errors.Is(err, database.ErrUsernameFormat) {
+		return sendApiMessage(ctx, http.StatusNotFound, "user not found")
+	}
+	ctx.Logger().Print(err)
+	return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+}
+
+func toApiAdminUser(user *database.User, usage *database.StorageUsage) api.AdminUser {
+	return api.AdminUser{
+		Id:            int64(user.Id),
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		IsAdmin:       user.IsAdmin,
+		Disabled:      user.Disabled,
+		Storage: api.StorageUsage{
+			FileCount:    usage.FileCount,
+			FileSize:     usage.FileSize,
+			SnapshotSize: usage.SnapshotSize,
+		},
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	db := createTestDB(t)
+	admin, adminCookie := createTestSession(t, db, "test-admin-routes-admin")
+	user, userCookie := createTestSession(t, db, "test-admin-routes-user")
+	assert.NoError(t, database.SetUserAdmin(db, admin.Id, true))
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = database.CreateSyncFile(db, "a.md", "etag-a", 100, user.Id, user.Id)
+	assert.NoError(t, err)
+	_, err = database.CreateApiKey(db, user.Id, "test-admin-routes-key", "not a key")
+	assert.NoError(t, err)
+
+	// the routes are served like the server serves them, so requests go
+	// through the middleware
+	e := echo.New()
+	e.Use(srv.RequireAdmin())
+	api.RegisterHandlersWithBaseURL(e, srv, BaseURL)
+	request := func(cookie *http.Cookie, method, path string, body io.Reader) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, BaseURL+path, body)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("only admins", func(t *testing.T) {
+		for _, tc := range []struct {
+			method, path string
+		}{
+			{http.MethodGet, "/admin/users"},
+			{http.MethodGet, "/admin/users/" + admin.Username},
+			{http.MethodPost, "/admin/users/" + admin.Username + "/disable"},
+			{http.MethodPost, "/admin/users/" + admin.Username + "/enable"},
+			{http.MethodPost, "/admin/users/" + admin.Username + "/password"},
+			{http.MethodPost, "/admin/users/" + admin.Username + "/revoke"},
+		} {
+			rec := request(nil, tc.method, tc.path, nil)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code, tc.path)
+			rec = request(userCookie, tc.method, tc.path, strings.NewReader(`{"password": "a new password"}`))
+			assert.Equal(t, http.StatusForbidden, rec.Code, tc.path)
+		}
+		// other routes don't need an admin
+		rec := request(userCookie, http.MethodGet, "/vaults", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// handlers refuse requests that didn't go through the middleware
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(adminCookie)
+		rec = httptest.NewRecorder()
+		assert.NoError(t, srv.GetAdminUsers(e.NewContext(req, rec), api.GetAdminUsersParams{}))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("list users", func(t *testing.T) {
+		rec := request(adminCookie, http.MethodGet, "/admin/users?search=test-admin-routes", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		users := []api.AdminUser{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &users))
+		if assert.Len(t, users, 2) {
+			assert.Equal(t, admin.Username, users[0].Username)
+			assert.True(t, users[0].IsAdmin)
+			assert.Equal(t, user.Username, users[1].Username)
+			assert.False(t, users[1].IsAdmin)
+			assert.Equal(t, api.StorageUsage{FileCount: 1, FileSize: 100}, users[1].Storage)
+		}
+
+		rec = request(adminCookie, http.MethodGet, "/admin/users/"+user.Username, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		found := api.AdminUser{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &found))
+		assert.Equal(t, int64(user.Id), found.Id)
+		assert.Equal(t, int64(100), found.Storage.FileSize)
+
+		rec = request(adminCookie, http.MethodGet, "/admin/users/test-admin-routes-missing", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		rec := request(adminCookie, http.MethodPost, "/admin/users/"+admin.Username+"/disable", nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = request(adminCookie, http.MethodPost, "/admin/users/"+user.Username+"/disable", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		found := api.AdminUser{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &found))
+		assert.True(t, found.Disabled)
+		// disabled users are logged out and can't log in
+		rec = request(userCookie, http.MethodGet, "/vaults", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		_, err := database.LoginUser(db, user.Username, "not a password")
+		assert.ErrorIs(t, err, database.ErrUserDisabled)
+
+		rec = request(adminCookie, http.MethodPost, "/admin/users/"+user.Username+"/enable", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		session, err := database.LoginUser(db, user.Username, "not a password")
+		if assert.NoError(t, err) {
+			userCookie.Value = session.SessionKey
+		}
+	})
+
+	t.Run("reset password", func(t *testing.T) {
+		path := "/admin/users/" + user.Username + "/password"
+		rec := request(adminCookie, http.MethodPost, path, strings.NewReader(`{"password": "short"}`))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = request(adminCookie, http.MethodPost, path, strings.NewReader(`{"password": "a new password"}`))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = request(userCookie, http.MethodGet, "/vaults", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		_, err := database.LoginUser(db, user.Username, "not a password")
+		assert.ErrorIs(t, err, database.ErrIncorrectCredentials)
+		session, err := database.LoginUser(db, user.Username, "a new password")
+		if assert.NoError(t, err) {
+			userCookie.Value = session.SessionKey
+		}
+	})
+
+	t.Run("revoke credentials", func(t *testing.T) {
+		rec := request(adminCookie, http.MethodPost, "/admin/users/"+user.Username+"/revoke", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		revocation := api.CredentialRevocation{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &revocation))
+		assert.Equal(t, api.CredentialRevocation{Sessions: 1, ApiKeys: 1}, revocation)
+		rec = request(userCookie, http.MethodGet, "/vaults", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		apiKeys, err := database.GetApiKeys(db, user.Id)
+		if assert.NoError(t, err) && assert.Len(t, apiKeys, 1) {
+			assert.False(t, apiKeys[0].Active)
+		}
+	})
+
+	t.Run("create users", func(t *testing.T) {
+		// admins create users however registration is set up
+		srv.SetRegistration(RegistrationPolicy{Mode: RegistrationClosed, AllowedEmailDomains: []string{"example.org"}})
+		body := `{"username": "test-admin-routes-created", "email": "test-admin-routes-created@example.com", "password": "not a password"}`
+		rec := request(adminCookie, http.MethodPost, "/admin/users", strings.NewReader(body))
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		created := api.AdminUser{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+		assert.Equal(t, "test-admin-routes-created", created.Username)
+		rec = request(adminCookie, http.MethodPost, "/admin/users", strings.NewReader(body))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.NoError(t, database.DeleteUser(db, uint64(created.Id)))
+	})
+
+	t.Run("demoted admins", func(t *testing.T) {
+		assert.NoError(t, database.SetUserAdmin(db, admin.Id, false))
+		rec := request(adminCookie, http.MethodGet, "/admin/users", nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	for _, user := range []*database.User{admin, user} {
+		assert.NoError(t, database.DeleteUser(db, user.Id))
+	}
+}
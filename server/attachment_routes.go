@@ -0,0 +1,267 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/markdown"
+)
+
+const (
+	// Folder Obsidian moves deleted files to
+	trashDir = ".trash"
+	// Folder Obsidian keeps the vault's settings in
+	configDir = ".obsidian"
+)
+
+// Find attachments that no note references
+// (GET /attachments/orphans)
+func (o *ObsyncServer) GetAttachmentsOrphans(ctx echo.Context, params api.GetAttachmentsOrphansParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	refs, err := o.findAttachmentRefs(ctx, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	orphans := refs.orphans()
+	if params.Folder != nil {
+		orphans = filterFolder(orphans, *params.Folder)
+	}
+
+	report := api.OrphanReport{Orphans: make([]api.File, 0, len(orphans))}
+	for _, orphan := range orphans {
+		report.Orphans = append(report.Orphans, toApiFile(orphan))
+		report.TotalSize += orphan.Size
+	}
+	report.Count = len(report.Orphans)
+
+	return ctx.JSON(http.StatusOK, report)
+}
+
+// Move orphaned attachments to the trash
+// (POST /attachments/orphans/trash)
+func (o *ObsyncServer) PostAttachmentsOrphansTrash(ctx echo.Context) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	var body api.OrphanTrashRequest
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	if len(body.Filenames) == 0 {
+		return sendApiMessage(ctx, http.StatusBadRequest, "no files selected")
+	}
+	filenames := make([]string, 0, len(body.Filenames))
+	seen := make(map[string]bool, len(body.Filenames))
+	for _, filename := range body.Filenames {
+		filename, err := cleanFilename(filename)
+		if err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+		}
+		// check every file before moving any, like imports do
+		if !vault.canWrite(filename) || !vault.canWrite(path.Join(trashDir, filename)) {
+			return sendForbidden(ctx)
+		}
+		if !seen[filename] {
+			seen[filename] = true
+			filenames = append(filenames, filename)
+		}
+	}
+	dryRun := body.DryRun != nil && *body.DryRun
+
+	// check the files are still orphans, since notes might have changed since
+	// the client found them
+	refs, err := o.findAttachmentRefs(ctx, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	report := api.OrphanTrashReport{
+		DryRun:  dryRun,
+		Trashed: []api.TrashResult{},
+		Skipped: []api.TrashResult{},
+	}
+	skip := func(filename, message string) {
+		report.Skipped = append(report.Skipped, api.TrashResult{Filename: filename, Message: &message})
+	}
+	for _, filename := range filenames {
+		syncFile, ok := refs.files[filename]
+		if !ok {
+			skip(filename, "file not found")
+			continue
+		}
+		if !isAttachment(filename) {
+			skip(filename, "file is not an attachment")
+			continue
+		}
+		if refs.referenced[filename] {
+			skip(filename, "file is referenced by a note")
+			continue
+		}
+
+		trashed, err := o.trashFilename(vault.OwnerId, filename)
+		if err != nil {
+			ctx.Logger().Print(err)
+			skip(filename, "unexpected error occurred")
+			continue
+		}
+		if !dryRun {
+			if err := o.moveFile(ctx, vault, syncFile, trashed); err != nil {
+				ctx.Logger().Print(err)
+				skip(filename, "file couldn't be moved")
+				continue
+			}
+		}
+		report.Trashed = append(report.Trashed, api.TrashResult{Filename: filename, TrashedAs: &trashed})
+		report.TotalSize += syncFile.Size
+	}
+
+	return ctx.JSON(http.StatusOK, report)
+}
+
+// Files in a vault, and the files that its notes and canvases link to or
+// embed.
+type attachmentRefs struct {
+	files      map[string]*database.SyncFile
+	referenced map[string]bool
+}
+
+// Parse every note and canvas in a vault to find the files they reference.
+// Notes are parsed again instead of using the link index, so links in notes
+// synced before they were indexed count too.
+func (o *ObsyncServer) findAttachmentRefs(ctx echo.Context, ownerId uint64) (*attachmentRefs, error) {
+	syncFiles, err := database.GetSyncFilesByUserId(o.db, ownerId)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := o.ignoreMatcher(ownerId)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := &attachmentRefs{
+		files:      make(map[string]*database.SyncFile, len(syncFiles)),
+		referenced: map[string]bool{},
+	}
+	filepaths := make([]string, 0, len(syncFiles))
+	// Obsidian doesn't link to or from files in the trash
+	for _, syncFile := range filterIgnored(syncFiles, matcher) {
+		if !inFolder(syncFile.Filepath, trashDir) {
+			refs.files[syncFile.Filepath] = syncFile
+			filepaths = append(filepaths, syncFile.Filepath)
+		}
+	}
+
+	addLinks := func(content, sourcePath string) {
+		for _, link := range markdown.ParseLinks(content) {
+			if resolved, ok := markdown.ResolveLink(link, sourcePath, filepaths); ok {
+				refs.referenced[resolved] = true
+			}
+		}
+	}
+	for _, filename := range filepaths {
+		if !isMarkdownFile(filename) && !isCanvasFile(filename) {
+			continue
+		}
+		filePath, err := userFilePath(ownerId, filename)
+		if err != nil {
+			return nil, err
+		}
+		data, err := o.fstore.LoadFile(filePath)
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			// the note is missing from the file store, so it can't
+			// reference anything
+			ctx.Logger().Printf("file %q is missing from the file store", filename)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if isMarkdownFile(filename) {
+			addLinks(string(data), filename)
+			continue
+		}
+
+		// canvases embed files by their path, and text cards are markdown
+		var canvas struct {
+			Nodes []struct {
+				Type string `json:"type"`
+				File string `json:"file"`
+				Text string `json:"text"`
+			} `json:"nodes"`
+		}
+		if err := json.Unmarshal(data, &canvas); err != nil {
+			// Obsidian can't open broken canvases either, so they don't
+			// reference anything
+			continue
+		}
+		for _, node := range canvas.Nodes {
+			switch node.Type {
+			case "file":
+				refs.referenced[node.File] = true
+			case "text":
+				addLinks(node.Text, filename)
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// Get the attachments that nothing references, sorted by path.
+func (r *attachmentRefs) orphans() []*database.SyncFile {
+	orphans := []*database.SyncFile{}
+	for filename, syncFile := range r.files {
+		if isAttachment(filename) && !r.referenced[filename] {
+			orphans = append(orphans, syncFile)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Filepath < orphans[j].Filepath })
+	return orphans
+}
+
+// Get the path in the trash a file would be moved to, adding a number to the
+// end of it if a file is already there.
+func (o *ObsyncServer) trashFilename(ownerId uint64, filename string) (string, error) {
+	trashed := path.Join(trashDir, filename)
+	_, err := database.GetUserSyncFileByFilepath(o.db, ownerId, trashed)
+	if errors.Is(err, database.ErrNoResults) {
+		return trashed, nil
+	} else if err != nil {
+		return "", err
+	}
+	return o.availableFilename(ownerId, trashed, nil)
+}
+
+// Check whether a file is an attachment, which is any file that isn't a note,
+// a canvas or a part of the vault's settings or trash.
+func isAttachment(filename string) bool {
+	return !isMarkdownFile(filename) &&
+		!isCanvasFile(filename) &&
+		!inFolder(filename, configDir) &&
+		!inFolder(filename, trashDir)
+}
+
+func isCanvasFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".canvas")
+}
+
+// Check whether a file is inside a folder or its subfolders.
+func inFolder(filename, folder string) bool {
+	return strings.HasPrefix(filename, folder+"/")
+}
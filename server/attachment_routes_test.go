@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachmentRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-attachment-routes")
+	viewer, viewerCookie := createTestSession(t, db, "test-attachment-routes-viewer")
+	assert.NoError(t, database.SetVaultMember(db, user.Id, viewer.Id, database.VaultViewer, nil))
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	request := func(cookie *http.Cookie, body string, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(body))
+		req.AddCookie(cookie)
+		req.Header.Set(VaultHeader, user.Username)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	getOrphans := func(folder *string) []string {
+		t.Helper()
+		rec := request(cookie, "", func(ctx echo.Context) error {
+			return srv.GetAttachmentsOrphans(ctx, api.GetAttachmentsOrphansParams{Folder: folder})
+		})
+		var report api.OrphanReport
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		}
+		filenames := []string{}
+		for _, orphan := range report.Orphans {
+			filenames = append(filenames, *orphan.Filename)
+		}
+		assert.Equal(t, len(filenames), report.Count)
+		return filenames
+	}
+	trash := func(cookie *http.Cookie, filenames []string, dryRun bool) (api.OrphanTrashReport, int) {
+		t.Helper()
+		body, _ := json.Marshal(api.OrphanTrashRequest{Filenames: filenames, DryRun: &dryRun})
+		rec := request(cookie, string(body), srv.PostAttachmentsOrphansTrash)
+		var report api.OrphanTrashReport
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		}
+		return report, rec.Code
+	}
+	filenames := func(results []api.TrashResult) map[string]string {
+		byFilename := map[string]string{}
+		for _, result := range results {
+			if result.TrashedAs != nil {
+				byFilename[result.Filename] = *result.TrashedAs
+			} else {
+				byFilename[result.Filename] = *result.Message
+			}
+		}
+		return byFilename
+	}
+
+	canvas := `{"nodes": [
+		{"id": "1", "type": "file", "file": "images/diagram.png"},
+		{"id": "2", "type": "text", "text": "![[photo.jpg]]"}
+	]}`
+	for filename, data := range map[string]string{
+		"notes/a.md":                "![[logo.png]] and [the spec](../docs/spec.pdf)",
+		"notes/b.md":                "no links, `![[unused.png]]` is code",
+		"board.canvas":              canvas,
+		"attachments/logo.png":      "logo",
+		"docs/spec.pdf":             "spec",
+		"images/diagram.png":        "diagram",
+		"images/photo.jpg":          "photo",
+		"attachments/unused.png":    "unused",
+		"old/unused.pdf":            "old unused",
+		".obsidian/app.json":        "{}",
+		".trash/old/unused.pdf":     "already trashed",
+		".trash/attachments/a.png":  "already trashed",
+		"attachments/ignored.cache": "ignored",
+	} {
+		rec := request(cookie, data, func(ctx echo.Context) error {
+			return srv.PostFilesFilename(ctx, filename)
+		})
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+	}
+	body, _ := json.Marshal(api.IgnoreRules{Rules: "*.cache\n"})
+	assert.Equal(t, http.StatusOK, request(cookie, string(body), srv.PutIgnoreRules).Code)
+
+	rec := request(cookie, "", func(ctx echo.Context) error {
+		return srv.GetAttachmentsOrphans(ctx, api.GetAttachmentsOrphansParams{})
+	})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		var report api.OrphanReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, 2, report.Count)
+		assert.Equal(t, int64(len("unused")+len("old unused")), report.TotalSize)
+	}
+	assert.Equal(t, []string{"attachments/unused.png", "old/unused.pdf"}, getOrphans(nil))
+	folder := "old"
+	assert.Equal(t, []string{"old/unused.pdf"}, getOrphans(&folder))
+
+	// viewers can't move files, and nothing is moved if any file can't be
+	_, code := trash(viewerCookie, []string{"attachments/unused.png"}, false)
+	assert.Equal(t, http.StatusForbidden, code)
+	_, code = trash(cookie, []string{}, false)
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = trash(cookie, []string{"../unused.png"}, false)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	selected := []string{"attachments/unused.png", "old/unused.pdf", "attachments/logo.png", "notes/a.md", "missing.png"}
+	wantTrashed := map[string]string{
+		"attachments/unused.png": ".trash/attachments/unused.png",
+		"old/unused.pdf":         ".trash/old/unused (1).pdf",
+	}
+	wantSkipped := map[string]string{
+		"attachments/logo.png": "file is referenced by a note",
+		"notes/a.md":           "file is not an attachment",
+		"missing.png":          "file not found",
+	}
+
+	report, code := trash(cookie, selected, true)
+	if assert.Equal(t, http.StatusOK, code) {
+		assert.True(t, report.DryRun)
+		assert.Equal(t, wantTrashed, filenames(report.Trashed))
+		assert.Equal(t, wantSkipped, filenames(report.Skipped))
+		assert.Equal(t, int64(len("unused")+len("old unused")), report.TotalSize)
+	}
+	assert.Equal(t, []string{"attachments/unused.png", "old/unused.pdf"}, getOrphans(nil))
+
+	report, code = trash(cookie, selected, false)
+	if assert.Equal(t, http.StatusOK, code) {
+		assert.False(t, report.DryRun)
+		assert.Equal(t, wantTrashed, filenames(report.Trashed))
+		assert.Equal(t, wantSkipped, filenames(report.Skipped))
+	}
+	assert.Equal(t, []string{}, getOrphans(nil))
+	for filename, trashed := range wantTrashed {
+		_, err := database.GetUserSyncFileByFilepath(db, user.Id, filename)
+		assert.ErrorIs(t, err, database.ErrNoResults)
+		syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, trashed)
+		if assert.NoError(t, err) {
+			data, err := srv.fstore.LoadFile(testFilePath(t, user.Id, syncFile.Filepath))
+			assert.NoError(t, err)
+			assert.Equal(t, syncFile.Size, int64(len(data)))
+		}
+	}
+
+	// notes missing from the file store don't reference anything, and don't
+	// stop the rest of the vault from being checked
+	assert.NoError(t, srv.fstore.DeleteFile(testFilePath(t, user.Id, "notes/a.md")))
+	assert.Equal(t, []string{"attachments/logo.png", "docs/spec.pdf"}, getOrphans(nil))
+	_, code = trash(cookie, []string{"docs/spec.pdf"}, true)
+	assert.Equal(t, http.StatusOK, code)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+	assert.NoError(t, database.DeleteUser(db, viewer.Id))
+}
@@ -0,0 +1,504 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	encodingGzip     = "gzip"
+	encodingZstd     = "zstd"
+	encodingIdentity = "identity"
+)
+
+// Content codings the server can compress responses with, in order of
+// preference.
+var responseEncodings = []string{encodingZstd, encodingGzip}
+
+// Encoders are reused between responses, since each one allocates its
+// buffers when it's created. zstd encoders only use one goroutine, which is
+// plenty for a single response.
+var (
+	zstdEncoders = sync.Pool{New: func() any {
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			panic(err)
+		}
+		return encoder
+	}}
+	gzipEncoders = sync.Pool{New: func() any {
+		return gzip.NewWriter(nil)
+	}}
+)
+
+// Responses smaller than this many bytes aren't compressed by default, since
+// compressing them barely saves anything.
+const DefaultCompressionMinSize = 1024
+
+// Content types that are compressed by default. Types ending in a slash match
+// every subtype.
+var DefaultCompressionContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/yaml",
+	"image/svg+xml",
+}
+
+type CompressionConfig struct {
+	// Minimum size of a response body before it's compressed
+	MinSize int
+	// Content types that can be compressed
+	ContentTypes []string
+}
+
+// Middleware that compresses responses with gzip or zstd, depending on the
+// request's Accept-Encoding header. Only responses with an allowed content
+// type that are at least config.MinSize bytes long are compressed, and
+// responses that are already encoded or are partial are sent as-is.
+func CompressResponses(config CompressionConfig) echo.MiddlewareFunc {
+	if config.MinSize <= 0 {
+		config.MinSize = DefaultCompressionMinSize
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = DefaultCompressionContentTypes
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			encoding := negotiateEncoding(req.Header.Get(echo.HeaderAcceptEncoding), responseEncodings)
+			if req.Method == http.MethodHead || encoding == encodingIdentity {
+				return next(ctx)
+			}
+
+			res := ctx.Response()
+			writer := &compressWriter{
+				ResponseWriter: res.Writer,
+				config:         &config,
+				encoding:       encoding,
+			}
+			res.Writer = writer
+			defer func() {
+				if err := writer.finish(); err != nil {
+					ctx.Logger().Print(err)
+				}
+				res.Writer = writer.ResponseWriter
+			}()
+
+			return next(ctx)
+		}
+	}
+}
+
+// Largest window zstd request bodies can be decoded with. Encoders only use
+// bigger windows when asked to, and each window is held in memory.
+const zstdMaxWindow = 32 << 20
+
+var ErrRequestTooLarge = errors.New("request body is too large once decompressed")
+
+// Middleware that decodes request bodies sent with a gzip or zstd
+// Content-Encoding, so handlers always see (and compute etags for) the
+// original bytes. Bodies that decode to more than maxSize bytes get a 413
+// response, so small bodies can't decompress into huge ones. maxSize
+// defaults to the default import size limit, since import archives are the
+// largest uploads.
+func DecompressRequests(maxSize int64) echo.MiddlewareFunc {
+	if maxSize <= 0 {
+		maxSize = DefaultImportMaxSize
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			encoding := strings.ToLower(strings.TrimSpace(req.Header.Get(echo.HeaderContentEncoding)))
+
+			var body io.ReadCloser
+			switch encoding {
+			case "", encodingIdentity:
+				return next(ctx)
+			case encodingGzip:
+				reader, err := gzip.NewReader(req.Body)
+				if err != nil {
+					ctx.Logger().Print(err)
+					return sendApiMessage(ctx, http.StatusBadRequest, "invalid gzip request body")
+				}
+				body = reader
+			case encodingZstd:
+				reader, err := zstd.NewReader(
+					req.Body,
+					// the window has to fit in memory, even when the limit is
+					// smaller than it
+					zstd.WithDecoderMaxMemory(max(uint64(maxSize), zstdMaxWindow)),
+					zstd.WithDecoderMaxWindow(zstdMaxWindow),
+				)
+				if err != nil {
+					ctx.Logger().Print(err)
+					return sendApiMessage(ctx, http.StatusBadRequest, "invalid zstd request body")
+				}
+				body = reader.IOReadCloser()
+			default:
+				return sendApiMessage(ctx, http.StatusUnsupportedMediaType, "unsupported content encoding")
+			}
+
+			limited := &limitedBody{ReadCloser: body, remaining: maxSize}
+			original := req.Body
+			req.Body = limited
+			req.Header.Del(echo.HeaderContentEncoding)
+			req.Header.Del(echo.HeaderContentLength)
+			req.ContentLength = -1
+			defer func() {
+				body.Close()
+				req.Body = original
+			}()
+
+			// handlers answer bodies that fail to read in their own way, so
+			// their response is dropped for a 413 when the body was too large
+			res := ctx.Response()
+			writer := &tooLargeWriter{ResponseWriter: res.Writer, body: limited}
+			res.Writer = writer
+			err := next(ctx)
+			res.Writer = writer.ResponseWriter
+			if writer.sent || !limited.exceeded {
+				return err
+			}
+			if err != nil {
+				ctx.Logger().Print(err)
+			}
+			res.Committed = false
+			res.Size = 0
+			for _, header := range []string{echo.HeaderContentEncoding, echo.HeaderContentLength, "ETag"} {
+				res.Header().Del(header)
+			}
+			return sendApiMessage(ctx, http.StatusRequestEntityTooLarge, "request body too large")
+		}
+	}
+}
+
+// Decoded request body that fails with ErrRequestTooLarge after remaining
+// bytes.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(data []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrRequestTooLarge
+	}
+	// read a byte past the limit to tell bodies that end at it apart from
+	// bodies that go past it
+	if int64(len(data)) > b.remaining+1 {
+		data = data[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(data)
+	// zstd frames can say how big they decode to, so zstd stops early
+	if int64(n) > b.remaining || errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		n = int(b.remaining)
+		b.remaining = 0
+		b.exceeded = true
+		return n, ErrRequestTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// tooLargeWriter drops the handler's response when the request body turned
+// out to be too large before the handler started responding.
+type tooLargeWriter struct {
+	http.ResponseWriter
+	body *limitedBody
+	// whether the handler's response is being sent or dropped, decided when
+	// the handler starts responding
+	sent, discarded bool
+}
+
+func (w *tooLargeWriter) discarding() bool {
+	if !w.sent && !w.discarded {
+		w.discarded = w.body.exceeded
+		w.sent = !w.discarded
+	}
+	return w.discarded
+}
+
+func (w *tooLargeWriter) WriteHeader(code int) {
+	if !w.discarding() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *tooLargeWriter) Write(data []byte) (int, error) {
+	if w.discarding() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *tooLargeWriter) Flush() {
+	if w.discarding() {
+		return
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *tooLargeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// response should be compressed: responses are compressed once they reach the
+// minimum size, or when they're flushed.
+type compressWriter struct {
+	http.ResponseWriter
+	config   *CompressionConfig
+	encoding string
+	status   int
+	buf      []byte
+	// whether the response has been checked for compression yet
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			if err := w.passthrough(); err != nil {
+				return 0, err
+			}
+			return w.ResponseWriter.Write(data)
+		}
+
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.config.MinSize {
+			return len(data), nil
+		}
+		if err := w.startCompressing(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		var err error
+		if w.compressible() {
+			err = w.startCompressing()
+		} else {
+			err = w.passthrough()
+		}
+		if err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, errors.New("response writer can't be hijacked")
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Check whether the response can be compressed based on its status code and
+// headers.
+func (w *compressWriter) compressible() bool {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := w.Header()
+	if status != http.StatusOK ||
+		len(header.Get(echo.HeaderContentEncoding)) > 0 ||
+		len(header.Get("Content-Range")) > 0 {
+		return false
+	}
+
+	contentType := header.Get(echo.HeaderContentType)
+	if len(contentType) == 0 && len(w.buf) > 0 {
+		contentType = http.DetectContentType(w.buf)
+	}
+	return compressibleContentType(contentType, w.config.ContentTypes)
+}
+
+// Send the buffered response without compressing it.
+func (w *compressWriter) passthrough() error {
+	w.decided = true
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *compressWriter) startCompressing() error {
+	w.decided = true
+	header := w.Header()
+	header.Set(echo.HeaderContentEncoding, w.encoding)
+	header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+	header.Del(echo.HeaderContentLength)
+	header.Del("Accept-Ranges")
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	switch w.encoding {
+	case encodingZstd:
+		encoder := zstdEncoders.Get().(*zstd.Encoder)
+		encoder.Reset(w.ResponseWriter)
+		w.encoder = encoder
+	default:
+		encoder := gzipEncoders.Get().(*gzip.Writer)
+		encoder.Reset(w.ResponseWriter)
+		w.encoder = encoder
+	}
+
+	_, err := w.encoder.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// Finish the response once the handler is done with it.
+func (w *compressWriter) finish() error {
+	if !w.decided {
+		// the whole response was smaller than the minimum size, but a larger
+		// response from the same URL could be compressed
+		if w.compressible() && len(w.buf) > 0 {
+			w.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+		}
+		return w.passthrough()
+	}
+	if w.encoder != nil {
+		err := w.encoder.Close()
+		w.releaseEncoder()
+		return err
+	}
+	return nil
+}
+
+// Put the encoder back in its pool once the response is done with it.
+func (w *compressWriter) releaseEncoder() {
+	switch encoder := w.encoder.(type) {
+	case *zstd.Encoder:
+		encoder.Reset(nil)
+		zstdEncoders.Put(encoder)
+	case *gzip.Writer:
+		encoder.Reset(nil)
+		gzipEncoders.Put(encoder)
+	}
+	w.encoder = nil
+}
+
+func compressibleContentType(contentType string, allowed []string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if len(mediaType) == 0 {
+		return false
+	}
+
+	for _, allowedType := range allowed {
+		if strings.HasSuffix(allowedType, "/") && strings.HasPrefix(mediaType, allowedType) {
+			return true
+		}
+		if mediaType == allowedType {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse an Accept-Encoding header value into the quality value of each content
+// coding in it.
+func parseAcceptEncoding(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+	for _, value := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(value), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) == 0 {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		qualities[name] = quality
+	}
+	return qualities
+}
+
+// Get the quality value an Accept-Encoding header gives a content coding. An
+// explicit entry for the coding takes precedence over a wildcard.
+func encodingQuality(qualities map[string]float64, coding string) float64 {
+	if quality, ok := qualities[coding]; ok {
+		return quality
+	}
+	if quality, ok := qualities["*"]; ok {
+		return quality
+	}
+	return 0
+}
+
+// Check whether an Accept-Encoding header value allows a content coding.
+func acceptsEncoding(header, coding string) bool {
+	return encodingQuality(parseAcceptEncoding(header), coding) > 0
+}
+
+// Pick the content coding with the highest quality value out of the supported
+// codings, which are listed in order of preference. identity is returned if
+// the client doesn't accept any of them.
+func negotiateEncoding(header string, supported []string) string {
+	qualities := parseAcceptEncoding(header)
+	best, bestQuality := encodingIdentity, 0.0
+	for _, coding := range supported {
+		if quality := encodingQuality(qualities, coding); quality > bestQuality {
+			best, bestQuality = coding, quality
+		}
+	}
+	return best
+}
@@ -0,0 +1,419 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressResponses(t *testing.T) {
+	t.Parallel()
+
+	largeText := strings.Repeat("compress me please\n", 200)
+	e := echo.New()
+	e.Use(CompressResponses(CompressionConfig{MinSize: 256}))
+	e.GET("/large-json", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, map[string]string{"text": largeText})
+	})
+	e.GET("/small-json", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, map[string]string{"text": "tiny"})
+	})
+	e.GET("/image", func(ctx echo.Context) error {
+		return ctx.Blob(http.StatusOK, "image/png", []byte(largeText))
+	})
+	e.GET("/not-found", func(ctx echo.Context) error {
+		return ctx.String(http.StatusNotFound, largeText)
+	})
+	e.GET("/partial", func(ctx echo.Context) error {
+		http.ServeContent(ctx.Response(), ctx.Request(), "file.txt", time.Now(), strings.NewReader(largeText))
+		return nil
+	})
+
+	testCases := []struct {
+		name         string
+		path         string
+		headers      map[string]string
+		wantEncoding string
+		wantCode     int
+	}{
+		{
+			name:         "zstd is preferred",
+			path:         "/large-json",
+			headers:      map[string]string{"Accept-Encoding": "gzip, zstd"},
+			wantEncoding: "zstd",
+		},
+		{
+			name:         "client prefers gzip",
+			path:         "/large-json",
+			headers:      map[string]string{"Accept-Encoding": "gzip;q=1, zstd;q=0.5"},
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "client only accepts gzip",
+			path:         "/large-json",
+			headers:      map[string]string{"Accept-Encoding": "gzip"},
+			wantEncoding: "gzip",
+		},
+		{
+			name:    "client doesn't accept compression",
+			path:    "/large-json",
+			headers: map[string]string{"Accept-Encoding": "br"},
+		},
+		{
+			name:    "response is smaller than the threshold",
+			path:    "/small-json",
+			headers: map[string]string{"Accept-Encoding": "gzip, zstd"},
+		},
+		{
+			name:    "content type isn't allowed",
+			path:    "/image",
+			headers: map[string]string{"Accept-Encoding": "gzip, zstd"},
+		},
+		{
+			name:     "error responses",
+			path:     "/not-found",
+			headers:  map[string]string{"Accept-Encoding": "gzip, zstd"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "partial responses",
+			path:     "/partial",
+			headers:  map[string]string{"Accept-Encoding": "gzip, zstd", "Range": "bytes=0-99"},
+			wantCode: http.StatusPartialContent,
+		},
+		{
+			name:         "whole file responses",
+			path:         "/partial",
+			headers:      map[string]string{"Accept-Encoding": "gzip, zstd"},
+			wantEncoding: "zstd",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			wantCode := tc.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			assert.Equal(t, wantCode, rec.Code)
+			assert.Equal(t, tc.wantEncoding, rec.Header().Get(echo.HeaderContentEncoding))
+			if len(tc.wantEncoding) > 0 {
+				assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+				assert.Empty(t, rec.Header().Get(echo.HeaderContentLength))
+			}
+
+			body := decodeBody(t, tc.wantEncoding, rec.Body.Bytes())
+			switch tc.path {
+			case "/large-json":
+				var data map[string]string
+				assert.NoError(t, json.Unmarshal(body, &data))
+				assert.Equal(t, largeText, data["text"])
+			case "/partial":
+				if wantCode == http.StatusPartialContent {
+					assert.Equal(t, largeText[:100], string(body))
+				} else {
+					assert.Equal(t, largeText, string(body))
+				}
+			}
+		})
+	}
+}
+
+func TestCompressResponsesReusesEncoders(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	e.Use(CompressResponses(CompressionConfig{MinSize: 256}))
+	e.GET("/text/:id", func(ctx echo.Context) error {
+		return ctx.String(http.StatusOK, strings.Repeat("response "+ctx.Param("id")+"\n", 100))
+	})
+
+	// encoders handed from one response to the next don't mix their contents
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		encoding := []string{"zstd", "gzip"}[i%2]
+		id := strconv.Itoa(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/text/"+id, nil)
+			req.Header.Set(echo.HeaderAcceptEncoding, encoding)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, encoding, rec.Header().Get(echo.HeaderContentEncoding))
+			assert.Equal(t, strings.Repeat("response "+id+"\n", 100), string(decodeBody(t, encoding, rec.Body.Bytes())))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDecompressRequests(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	e.Use(DecompressRequests(0))
+	e.POST("/echo", func(ctx echo.Context) error {
+		data, err := io.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return err
+		}
+		return ctx.Blob(http.StatusOK, echo.MIMEOctetStream, data)
+	})
+
+	data := []byte(strings.Repeat("decompress me please\n", 100))
+	testCases := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantCode int
+	}{
+		{
+			name: "uncompressed body",
+			body: data,
+		},
+		{
+			name:     "gzip body",
+			encoding: "gzip",
+			body:     encodeBody(t, "gzip", data),
+		},
+		{
+			name:     "zstd body",
+			encoding: "zstd",
+			body:     encodeBody(t, "zstd", data),
+		},
+		{
+			name:     "invalid gzip body",
+			encoding: "gzip",
+			body:     data,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unsupported encoding",
+			encoding: "br",
+			body:     data,
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewBuffer(tc.body))
+			if len(tc.encoding) > 0 {
+				req.Header.Set(echo.HeaderContentEncoding, tc.encoding)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if tc.wantCode != 0 {
+				assert.Equal(t, tc.wantCode, rec.Code)
+				return
+			}
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, data, rec.Body.Bytes())
+		})
+	}
+}
+
+func TestDecompressRequestsLimit(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	e.Use(DecompressRequests(1000))
+	e.Use(CompressResponses(CompressionConfig{MinSize: 1}))
+	e.POST("/echo", func(ctx echo.Context) error {
+		data, err := io.ReadAll(ctx.Request().Body)
+		if err != nil {
+			return ctx.String(http.StatusBadRequest, err.Error())
+		}
+		return ctx.Blob(http.StatusOK, echo.MIMEOctetStream, data)
+	})
+
+	atLimit := bytes.Repeat([]byte("a"), 1000)
+	overLimit := bytes.Repeat([]byte("a"), 1001)
+	bomb := make([]byte, 10<<20)
+	testCases := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantCode int
+	}{
+		{"gzip body at the limit", "gzip", encodeBody(t, "gzip", atLimit), http.StatusOK},
+		{"zstd body at the limit", "zstd", encodeBody(t, "zstd", atLimit), http.StatusOK},
+		{"gzip body over the limit", "gzip", encodeBody(t, "gzip", overLimit), http.StatusRequestEntityTooLarge},
+		{"gzip bomb", "gzip", encodeBody(t, "gzip", bomb), http.StatusRequestEntityTooLarge},
+		{"zstd bomb", "zstd", encodeBody(t, "zstd", bomb), http.StatusRequestEntityTooLarge},
+		// only decompressed bodies are limited
+		{"uncompressed body", "", overLimit, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewBuffer(tc.body))
+			if len(tc.encoding) > 0 {
+				req.Header.Set(echo.HeaderContentEncoding, tc.encoding)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tc.wantCode, rec.Code, rec.Body.String())
+			if tc.wantCode == http.StatusRequestEntityTooLarge {
+				// the handler's own response is replaced
+				assert.Contains(t, rec.Body.String(), "request body too large")
+				assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+			}
+		})
+	}
+}
+
+func TestCompressedUploadsAndDownloads(t *testing.T) {
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-compressed-uploads")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	e := echo.New()
+	e.Use(DecompressRequests(0))
+	e.Use(CompressResponses(CompressionConfig{}))
+	api.RegisterHandlersWithBaseURL(e, srv, "/api/v1")
+
+	note := []byte(strings.Repeat("## Week 1\n\n- [x] read chapter 1\n", 100))
+	for _, encoding := range []string{"gzip", "zstd"} {
+		filename := "notes/" + encoding + ".md"
+		// uploads are decoded before the etag is computed
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBuffer(encodeBody(t, encoding, note)))
+		req.Header.Set(echo.HeaderContentEncoding, encoding)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, filename)
+		if assert.NoError(t, err) {
+			assert.Equal(t, filestore.GetEtag(note), syncFile.Etag)
+			assert.Equal(t, int64(len(note)), syncFile.Size)
+		}
+
+		// downloads are compressed on the fly
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/files/"+url.PathEscape(filename), nil)
+		req.Header.Set(echo.HeaderAcceptEncoding, encoding)
+		req.AddCookie(cookie)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, encoding, rec.Header().Get(echo.HeaderContentEncoding))
+		assert.Equal(t, `"`+filestore.GetEtag(note)+`"`, rec.Header().Get("ETag"))
+		assert.Equal(t, note, decodeBody(t, encoding, rec.Body.Bytes()))
+	}
+
+	// listings this small aren't worth compressing
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/list-files", nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentEncoding))
+	assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+	var files api.FileList
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files))
+	assert.Len(t, files, 2)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "zstd", negotiateEncoding("gzip, zstd", responseEncodings))
+	assert.Equal(t, "zstd", negotiateEncoding("*", responseEncodings))
+	assert.Equal(t, "gzip", negotiateEncoding("gzip", responseEncodings))
+	assert.Equal(t, "gzip", negotiateEncoding("zstd;q=0.1, gzip;q=0.9", responseEncodings))
+	assert.Equal(t, "gzip", negotiateEncoding("*, zstd;q=0", responseEncodings))
+	assert.Equal(t, "identity", negotiateEncoding("", responseEncodings))
+	assert.Equal(t, "identity", negotiateEncoding("br, deflate", responseEncodings))
+	assert.Equal(t, "identity", negotiateEncoding("*;q=0", responseEncodings))
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, acceptsEncoding("gzip", "gzip"))
+	assert.True(t, acceptsEncoding("deflate, gzip;q=0.5", "gzip"))
+	assert.True(t, acceptsEncoding("*", "gzip"))
+	assert.False(t, acceptsEncoding("", "gzip"))
+	assert.False(t, acceptsEncoding("deflate, br", "gzip"))
+	assert.False(t, acceptsEncoding("gzip;q=0", "gzip"))
+	assert.False(t, acceptsEncoding("*, gzip;q=0", "gzip"))
+	assert.False(t, acceptsEncoding("*;q=0", "gzip"))
+}
+
+func encodeBody(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "zstd":
+		encoder, err := zstd.NewWriter(&buf)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		writer = encoder
+	}
+	_, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func decodeBody(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+
+	var reader io.Reader
+	switch encoding {
+	case "":
+		return data
+	case "gzip":
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		reader = gzipReader
+	case "zstd":
+		decoder, err := zstd.NewReader(bytes.NewReader(data))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer decoder.Close()
+		reader = decoder
+	}
+	decoded, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return decoded
+}
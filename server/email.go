@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/mailer"
+)
+
+const (
+	VerifyEmailTokenLifetime   = 24 * time.Hour
+	ResetPasswordTokenLifetime = time.Hour
+	// Emails of each kind a user can be sent in EmailRateWindow, so the
+	// server can't be used to flood someone's inbox
+	MaxEmailsPerWindow = 3
+	EmailRateWindow    = time.Hour
+)
+
+var (
+	ErrEmailDisabled = errors.New("server isn't set up to send email")
+	ErrTooManyEmails = errors.New("too many emails sent to the user recently")
+)
+
+// Data email templates are rendered with.
+type emailData struct {
+	Username string
+	// Address the email is sent to
+	Email string
+	Token string
+	// Link that uses the token, when the server knows its public URL and the
+	// token can be used from a browser
+	Link string
+	// How long the token lasts, like "24 hours"
+	Lifetime string
+}
+
+// Set the mailer verification and password reset emails are sent with, and
+// the URL the server is reached at for links in them. Servers without a
+// mailer don't send emails.
+func (o *ObsyncServer) SetMailer(m *mailer.Mailer, publicURL string) {
+	o.mailer = m
+	o.publicURL = strings.TrimSuffix(publicURL, "/")
+}
+
+// Send a user a token for verifying their current email address.
+func (o *ObsyncServer) sendVerificationEmail(user *database.User) error {
+	return o.sendEmailToken(user, database.PurposeVerifyEmail, mailer.TemplateVerifyEmail, VerifyEmailTokenLifetime)
+}
+
+// Send a user a token for resetting their password.
+func (o *ObsyncServer) sendPasswordResetEmail(user *database.User) error {
+	return o.sendEmailToken(user, database.PurposeResetPassword, mailer.TemplateResetPassword, ResetPasswordTokenLifetime)
+}
+
+func (o *ObsyncServer) sendEmailToken(
+	user *database.User,
+	purpose database.EmailTokenPurpose,
+	template string,
+	lifetime time.Duration,
+) error {
+	if o.mailer == nil {
+		return ErrEmailDisabled
+	}
+	client := fmt.Sprintf("%d:%s", user.Id, purpose)
+	if !o.emails.allow(client, MaxEmailsPerWindow, EmailRateWindow, time.Now()) {
+		return ErrTooManyEmails
+	}
+
+	token, err := database.CreateEmailToken(o.db, user.Id, purpose, user.Email, lifetime)
+	if err != nil {
+		return err
+	}
+	data := emailData{
+		Username: user.Username,
+		Email:    user.Email,
+		Token:    token,
+		Lifetime: formatLifetime(lifetime),
+	}
+	if purpose == database.PurposeVerifyEmail && len(o.publicURL) > 0 {
+		data.Link = o.publicURL + BaseURL + "/user/email/verify?token=" + url.QueryEscape(token)
+	}
+	return o.mailer.Send(user.Email, template, data)
+}
+
+// Send an email in the background, so the request doesn't wait on the mail
+// server and takes as long whether or not an email is sent.
+func (o *ObsyncServer) queueEmail(ctx echo.Context, send func() error) {
+	if o.mailer == nil {
+		return
+	}
+
+	logger := ctx.Logger()
+	o.emailJobs.Add(1)
+	go func() {
+		defer o.emailJobs.Done()
+		if err := send(); err != nil && !errors.Is(err, ErrTooManyEmails) {
+			logger.Print(err)
+		}
+	}()
+}
+
+// Wait for the emails being sent in the background.
+func (o *ObsyncServer) WaitForEmails() {
+	o.emailJobs.Wait()
+}
+
+// Describe how long a token lasts in whole hours, or minutes for tokens that
+// last less than an hour.
+func formatLifetime(d time.Duration) string {
+	count, unit := int(d/time.Hour), "hour"
+	if d < time.Hour {
+		count, unit = int(d/time.Minute), "minute"
+	}
+	if count == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", count, unit)
+}
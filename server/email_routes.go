@@ -0,0 +1,114 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+)
+
+// Send an email to verify the user's email address
+// (POST /user/email/verification)
+func (o *ObsyncServer) PostUserEmailVerification(ctx echo.Context) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	if o.mailer == nil {
+		return sendEmailDisabled(ctx)
+	}
+	user, err := database.GetUserById(o.db, userId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if user.EmailVerified {
+		return sendApiMessage(ctx, http.StatusConflict, "email already verified")
+	}
+
+	if err := o.sendVerificationEmail(user); err != nil {
+		if errors.Is(err, ErrTooManyEmails) {
+			return sendApiMessage(ctx, http.StatusTooManyRequests, "too many emails sent, try again later")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "verification email sent")
+}
+
+// Verify the user's email address
+// (GET /user/email/verify)
+func (o *ObsyncServer) GetUserEmailVerify(ctx echo.Context, params api.GetUserEmailVerifyParams) error {
+	if _, err := database.VerifyEmailWithToken(o.db, params.Token); err != nil {
+		return sendEmailTokenError(ctx, err)
+	}
+	return sendApiMessage(ctx, http.StatusOK, "email verified")
+}
+
+// Send a password reset email
+// (POST /user/password/forgot)
+func (o *ObsyncServer) PostUserPasswordForgot(ctx echo.Context) error {
+	if o.mailer == nil {
+		return sendEmailDisabled(ctx)
+	}
+	var body api.PasswordForgot
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+
+	// the response can't depend on whether the user exists, or it would tell
+	// anyone who has an account
+	user, err := database.GetUserByEmail(o.db, body.Email)
+	switch {
+	case errors.Is(err, database.ErrEmailFormat):
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid email")
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	// a reset sent to an unverified address could go to someone who doesn't
+	// own the account
+	case user.EmailVerified && !user.Disabled:
+		o.queueEmail(ctx, func() error { return o.sendPasswordResetEmail(user) })
+	}
+
+	return sendApiMessage(ctx, http.StatusAccepted, "if the email belongs to a verified account, a reset email was sent")
+}
+
+// Reset a forgotten password
+// (POST /user/password/reset)
+func (o *ObsyncServer) PostUserPasswordReset(ctx echo.Context) error {
+	var body api.PasswordReset
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+
+	user, err := database.ResetPasswordWithToken(o.db, body.Token, body.Password)
+	if err != nil {
+		if errors.Is(err, database.ErrPasswordLength) {
+			return sendApiMessage(ctx, http.StatusBadRequest, "password too short")
+		}
+		return sendEmailTokenError(ctx, err)
+	}
+	o.davLogins.forget(user.Id)
+
+	return sendApiMessage(ctx, http.StatusOK, "password reset")
+}
+
+// Send the response for an error using up an email token.
+func sendEmailTokenError(ctx echo.Context, err error) error {
+	if errors.Is(err, database.ErrNoResults) || errors.Is(err, database.ErrTokenExpired) {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid or expired token")
+	}
+	ctx.Logger().Print(err)
+	return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+}
+
+func sendEmailDisabled(ctx echo.Context) error {
+	return sendApiMessage(ctx, http.StatusNotImplemented, "email is not set up on this server")
+}
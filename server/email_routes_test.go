@@ -0,0 +1,271 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/mailer"
+	"github.com/raian621/obsync-server/mailer/smtptest"
+	"github.com/stretchr/testify/assert"
+)
+
+var emailTokenPattern = regexp.MustCompile(`[A-Za-z0-9_-]{43}`)
+
+// Start an SMTP stand-in and have srv send its emails to it.
+func startTestMailer(t *testing.T, srv *ObsyncServer, publicURL string) *smtptest.Server {
+	t.Helper()
+	smtpServer, err := smtptest.NewServer()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { smtpServer.Close() })
+	templates, err := mailer.LoadTemplates("")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	sender := mailer.NewSMTPSender(mailer.SMTPConfig{
+		Host: smtpServer.Host(),
+		Port: smtpServer.Port(),
+		From: "Obsync <obsync@example.com>",
+		TLS:  mailer.TLSNone,
+	})
+	srv.SetMailer(mailer.New(sender, templates), publicURL)
+	return smtpServer
+}
+
+// Get the messages sent to an address, waiting for the emails being sent in
+// the background first.
+func sentTo(srv *ObsyncServer, smtpServer *smtptest.Server, address string) []smtptest.Message {
+	srv.WaitForEmails()
+	messages := []smtptest.Message{}
+	for _, msg := range smtpServer.Messages() {
+		if len(msg.To) == 1 && msg.To[0] == address {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// Get the subject, body and token of an email.
+func readTokenEmail(t *testing.T, msg smtptest.Message) (string, string, string) {
+	t.Helper()
+	subject, err := msg.Subject()
+	assert.NoError(t, err)
+	body, err := msg.Body()
+	assert.NoError(t, err)
+	token := emailTokenPattern.FindString(body)
+	if !assert.NotEmpty(t, token, body) {
+		t.FailNow()
+	}
+	return subject, body, token
+}
+
+func TestEmailVerification(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	api.RegisterHandlersWithBaseURL(e, srv, BaseURL)
+	smtpServer := startTestMailer(t, srv, "https://obsync.example.com/")
+
+	request := func(method, target string, body []byte, cookie *http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, bytes.NewBuffer(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// signing up sends a verification link
+	body, _ := json.Marshal(map[string]string{
+		"username": "test-email-verify",
+		"email":    "test-email-verify@example.com",
+		"password": "not a password",
+	})
+	rec := request(http.MethodPost, BaseURL+"/user", body, nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+	messages := sentTo(srv, smtpServer, "test-email-verify@example.com")
+	if !assert.Len(t, messages, 1) {
+		t.FailNow()
+	}
+	subject, text, token := readTokenEmail(t, messages[0])
+	assert.Equal(t, "Verify your Obsync email address", subject)
+	assert.Contains(t, text, "Hi test-email-verify,")
+	assert.Contains(t, text, "https://obsync.example.com/api/v1/user/email/verify?token="+token)
+	assert.Contains(t, text, "24 hours")
+
+	rec = request(http.MethodGet, BaseURL+"/user/email/verify?token="+token, nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	user, err := database.GetUserByUsername(db, "test-email-verify")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.True(t, user.EmailVerified)
+	rec = request(http.MethodGet, BaseURL+"/user/email/verify?token="+token, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "tokens can only be used once")
+	rec = request(http.MethodGet, BaseURL+"/user/email/verify?token=not-a-token", nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	session, err := database.CreateSession(db, user.Id)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cookie := &http.Cookie{Name: "OBSYNC_SESSION_ID", Value: session.SessionKey}
+	rec = request(http.MethodPost, BaseURL+"/user/email/verification", nil, cookie)
+	assert.Equal(t, http.StatusConflict, rec.Code, "verified emails don't need verifying")
+	rec = request(http.MethodPost, BaseURL+"/user/email/verification", nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// changing the email sends a link to the new address
+	rec = request(http.MethodPut, BaseURL+"/user/email", []byte("test-email-verify-2@example.com"), cookie)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	user, _ = database.GetUserById(db, user.Id)
+	assert.False(t, user.EmailVerified)
+	messages = sentTo(srv, smtpServer, "test-email-verify-2@example.com")
+	if !assert.Len(t, messages, 1) {
+		t.FailNow()
+	}
+	_, _, changedToken := readTokenEmail(t, messages[0])
+
+	// asking again replaces the earlier token, until too many were sent
+	rec = request(http.MethodPost, BaseURL+"/user/email/verification", nil, cookie)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	messages = sentTo(srv, smtpServer, "test-email-verify-2@example.com")
+	if !assert.Len(t, messages, 2) {
+		t.FailNow()
+	}
+	_, _, resentToken := readTokenEmail(t, messages[1])
+	assert.NotEqual(t, changedToken, resentToken)
+	// the email sent when signing up counts too
+	rec = request(http.MethodPost, BaseURL+"/user/email/verification", nil, cookie)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	assert.Len(t, sentTo(srv, smtpServer, "test-email-verify-2@example.com"), MaxEmailsPerWindow-1)
+
+	rec = request(http.MethodGet, BaseURL+"/user/email/verify?token="+changedToken, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = request(http.MethodGet, BaseURL+"/user/email/verify?token="+resentToken, nil, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	user, _ = database.GetUserById(db, user.Id)
+	assert.True(t, user.EmailVerified)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestPasswordReset(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	api.RegisterHandlersWithBaseURL(e, srv, BaseURL)
+
+	request := func(target string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		data, err := json.Marshal(body)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewBuffer(data))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	forgot := func(email string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(BaseURL+"/user/password/forgot", map[string]string{"email": email})
+	}
+	reset := func(token, password string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(BaseURL+"/user/password/reset", map[string]string{"token": token, "password": password})
+	}
+
+	t.Run("email disabled", func(t *testing.T) {
+		rec := forgot("test-reset@example.com")
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+
+	smtpServer := startTestMailer(t, srv, "")
+	user, cookie := createTestSession(t, db, "test-reset")
+	unverified, _ := createTestSession(t, db, "test-reset-unverified")
+	disabled, _ := createTestSession(t, db, "test-reset-disabled")
+	for _, u := range []*database.User{user, disabled} {
+		token, err := database.CreateEmailToken(db, u.Id, database.PurposeVerifyEmail, u.Email, VerifyEmailTokenLifetime)
+		assert.NoError(t, err)
+		_, err = database.VerifyEmailWithToken(db, token)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, database.SetUserDisabled(db, disabled.Id, true))
+
+	t.Run("no email for unknown, unverified or disabled users", func(t *testing.T) {
+		for _, email := range []string{"test-reset-nobody@example.com", unverified.Email, disabled.Email} {
+			rec := forgot(email)
+			assert.Equal(t, http.StatusAccepted, rec.Code, email)
+			assert.Empty(t, sentTo(srv, smtpServer, email), email)
+		}
+		rec := forgot("not an email")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		rec := forgot(user.Email)
+		assert.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		messages := sentTo(srv, smtpServer, user.Email)
+		if !assert.Len(t, messages, 1) {
+			t.FailNow()
+		}
+		subject, text, token := readTokenEmail(t, messages[0])
+		assert.Equal(t, "Reset your Obsync password", subject)
+		assert.Contains(t, text, "1 hour")
+		assert.NotContains(t, text, "https://", "reset tokens aren't used from a browser")
+
+		rec = reset(token, "short")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "password too short")
+		rec = reset(token, "a brand new password")
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		found, _ := database.GetUserById(db, user.Id)
+		assert.NoError(t, database.ValidateHash("a brand new password", found.Passhash))
+		_, err := database.GetSessionBySessionKey(db, cookie.Value)
+		assert.Error(t, err, "resetting the password logs the user out")
+
+		rec = reset(token, "another new password")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "tokens can only be used once")
+		rec = reset("not-a-token", "another new password")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		for i := 0; i < MaxEmailsPerWindow+1; i++ {
+			rec := forgot(user.Email)
+			assert.Equal(t, http.StatusAccepted, rec.Code, "rate limits can't tell who has an account")
+		}
+		messages := sentTo(srv, smtpServer, user.Email)
+		assert.Len(t, messages, MaxEmailsPerWindow)
+		for _, msg := range messages {
+			body, _ := msg.Body()
+			assert.True(t, strings.Contains(body, "reset the password"))
+		}
+	})
+
+	for _, u := range []*database.User{user, unverified, disabled} {
+		assert.NoError(t, database.DeleteUser(db, u.Id))
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLifetime(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		lifetime time.Duration
+		want     string
+	}{
+		{24 * time.Hour, "24 hours"},
+		{time.Hour, "1 hour"},
+		{90 * time.Minute, "1 hour"},
+		{30 * time.Minute, "30 minutes"},
+		{time.Minute, "1 minute"},
+	} {
+		assert.Equal(t, tc.want, formatLifetime(tc.lifetime), tc.lifetime)
+	}
+}
+
+func TestSendEmailToken(t *testing.T) {
+	db := createTestDB(t)
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	user, _ := createTestSession(t, db, "test-send-email-token")
+
+	assert.ErrorIs(t, srv.sendVerificationEmail(user), ErrEmailDisabled)
+
+	smtpServer := startTestMailer(t, srv, "")
+	assert.NoError(t, srv.sendVerificationEmail(user))
+	messages := smtpServer.Messages()
+	if assert.Len(t, messages, 1) {
+		_, body, _ := readTokenEmail(t, messages[0])
+		assert.NotContains(t, body, "https://", "links need the server's public URL")
+	}
+
+	// each kind of email is limited separately
+	for i := 1; i < MaxEmailsPerWindow; i++ {
+		assert.NoError(t, srv.sendVerificationEmail(user))
+	}
+	assert.ErrorIs(t, srv.sendVerificationEmail(user), ErrTooManyEmails)
+	assert.NoError(t, srv.sendPasswordResetEmail(user))
+	assert.Len(t, smtpServer.Messages(), MaxEmailsPerWindow+1)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+)
+
+// Path of the manifest in exports, in the reserved folder so it can't clash
+// with the user's files.
+const exportManifestName = ReservedFolder + "/manifest.json"
+
+type exportManifest struct {
+	ExportedAt time.Time    `json:"exportedAt"`
+	Files      api.FileList `json:"files"`
+}
+
+// archiveWriter is the part of zip.Writer and tar.Writer that exports need.
+type archiveWriter interface {
+	// Add a file to the archive, returning a writer for the file's contents.
+	addFile(name string, size int64, modified time.Time) (io.Writer, error)
+	Close() error
+}
+
+// Download an archive of every file synced to the server
+// (GET /export)
+func (o *ObsyncServer) GetExport(ctx echo.Context, params api.GetExportParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	format := api.Zip
+	if params.Format != nil {
+		format = *params.Format
+	}
+	var contentType string
+	switch format {
+	case api.Zip:
+		contentType = "application/zip"
+	case api.TarGz:
+		contentType = "application/gzip"
+	default:
+		return sendApiMessage(ctx, http.StatusBadRequest, "unsupported archive format")
+	}
+
+	syncFiles, err := database.GetSyncFilesByUserId(o.db, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if params.Prefix != nil {
+		syncFiles = filterFolder(syncFiles, *params.Prefix)
+	}
+	matcher, err := o.ignoreMatcher(vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	syncFiles = filterIgnored(syncFiles, matcher)
+
+	// the archive is streamed straight to the client, so errors after this
+	// point can't be reported with a status code anymore
+	now := time.Now().UTC()
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(
+		echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("obsync-export-%s.%s", now.Format("2006-01-02"), format)),
+	)
+	res.WriteHeader(http.StatusOK)
+
+	var archive archiveWriter
+	if format == api.TarGz {
+		archive = newTarGzArchive(res)
+	} else {
+		archive = zipArchive{zip.NewWriter(res)}
+	}
+
+	manifest := exportManifest{ExportedAt: now, Files: make(api.FileList, 0, len(syncFiles))}
+	for _, syncFile := range syncFiles {
+		err := o.exportFile(archive, vault.OwnerId, syncFile)
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			// the file is missing from the file store, leave it out of the export
+			ctx.Logger().Printf("file %q is missing from the file store", syncFile.Filepath)
+			continue
+		} else if err != nil {
+			ctx.Logger().Print(err)
+			return nil
+		}
+		manifest.Files = append(manifest.Files, toApiFile(syncFile))
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		ctx.Logger().Print(err)
+		return nil
+	}
+	writer, err := archive.addFile(exportManifestName, int64(len(data)), now)
+	if err == nil {
+		_, err = writer.Write(data)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		ctx.Logger().Print(err)
+	}
+
+	return nil
+}
+
+// Copy a synced file from the file store into an archive.
+func (o *ObsyncServer) exportFile(archive archiveWriter, userId uint64, syncFile *database.SyncFile) error {
+	filePath, err := userFilePath(userId, syncFile.Filepath)
+	if err != nil {
+		return err
+	}
+	file, err := filestore.OpenReadSeeker(o.fstore, filePath, syncFile.Size)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// tar headers need the exact size of the file before its contents
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	writer, err := archive.addFile(syncFile.Filepath, size, syncFile.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+type zipArchive struct {
+	*zip.Writer
+}
+
+func (z zipArchive) addFile(name string, size int64, modified time.Time) (io.Writer, error) {
+	return z.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+}
+
+type tarGzArchive struct {
+	gzipWriter *gzip.Writer
+	tarWriter  *tar.Writer
+}
+
+func newTarGzArchive(w io.Writer) *tarGzArchive {
+	gzipWriter := gzip.NewWriter(w)
+	return &tarGzArchive{
+		gzipWriter: gzipWriter,
+		tarWriter:  tar.NewWriter(gzipWriter),
+	}
+}
+
+func (t *tarGzArchive) addFile(name string, size int64, modified time.Time) (io.Writer, error) {
+	err := t.tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  modified,
+		Format:   tar.FormatPAX,
+	})
+	return t.tarWriter, err
+}
+
+func (t *tarGzArchive) Close() error {
+	if err := t.tarWriter.Close(); err != nil {
+		return err
+	}
+	return t.gzipWriter.Close()
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetExport(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-export")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	files := map[string][]byte{
+		"todo.md":                 []byte("- [ ] export vault\n"),
+		"school/os/scheduling.md": []byte("# Process Scheduling\n"),
+		"school/os/paging.md":     []byte("# Paging\n"),
+		"school/notes.md":         []byte("# Notes\n"),
+		"schoolwork/essay.md":     []byte("# Essay\n"),
+		"attachments/diagram.png": {0x89, 'P', 'N', 'G', 0, 1, 2, 3},
+		// the export's own manifest doesn't replace files with the same name
+		"manifest.json": []byte(`{"name": "obsidian-plugin"}`),
+	}
+	for filename, data := range files {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBuffer(data))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) ||
+			!assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+	}
+
+	zipFormat, tarGzFormat, badFormat := api.Zip, api.TarGz, api.GetExportParamsFormat("rar")
+	folder := "/school/"
+	testCases := []struct {
+		name      string
+		params    api.GetExportParams
+		wantFiles []string
+		wantCode  int
+	}{
+		{
+			name:      "zip is the default format",
+			wantFiles: []string{"todo.md", "school/os/scheduling.md", "school/os/paging.md", "school/notes.md", "schoolwork/essay.md", "attachments/diagram.png", "manifest.json"},
+		},
+		{
+			name:      "tar.gz archive",
+			params:    api.GetExportParams{Format: &tarGzFormat},
+			wantFiles: []string{"todo.md", "school/os/scheduling.md", "school/os/paging.md", "school/notes.md", "schoolwork/essay.md", "attachments/diagram.png", "manifest.json"},
+		},
+		{
+			name:      "export a folder",
+			params:    api.GetExportParams{Format: &zipFormat, Prefix: &folder},
+			wantFiles: []string{"school/os/scheduling.md", "school/os/paging.md", "school/notes.md"},
+		},
+		{
+			name:     "unsupported format",
+			params:   api.GetExportParams{Format: &badFormat},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/export", nil)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			if !assert.NoError(t, srv.GetExport(e.NewContext(req, rec), tc.params)) {
+				t.FailNow()
+			}
+			if tc.wantCode != 0 {
+				assert.Equal(t, tc.wantCode, rec.Code)
+				return
+			}
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment")
+
+			var archived map[string][]byte
+			if tc.params.Format != nil && *tc.params.Format == api.TarGz {
+				assert.Equal(t, "application/gzip", rec.Header().Get(echo.HeaderContentType))
+				archived = readTarGz(t, rec.Body.Bytes())
+			} else {
+				assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+				archived = readZip(t, rec.Body.Bytes())
+			}
+
+			var manifest struct {
+				Files api.FileList `json:"files"`
+			}
+			if assert.Contains(t, archived, exportManifestName) {
+				assert.NoError(t, json.Unmarshal(archived[exportManifestName], &manifest))
+				delete(archived, exportManifestName)
+			}
+			assert.Len(t, archived, len(tc.wantFiles))
+			assert.Len(t, manifest.Files, len(tc.wantFiles))
+			for _, filename := range tc.wantFiles {
+				assert.Equal(t, files[filename], archived[filename], filename)
+			}
+			for _, file := range manifest.Files {
+				assert.Contains(t, tc.wantFiles, *file.Filename)
+			}
+		})
+	}
+
+	// unauthenticated requests are rejected
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(t, srv.GetExport(e.NewContext(req, rec), api.GetExportParams{})) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func readZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		contents, err := io.ReadAll(rc)
+		assert.NoError(t, err)
+		rc.Close()
+		files[file.Name] = contents
+	}
+	return files
+}
+
+func readTarGz(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	reader := tar.NewReader(gzipReader)
+	files := make(map[string][]byte)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		contents, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		files[header.Name] = contents
+	}
+	return files
+}
+
+func TestReservedFolder(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-reserved-folder")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	api.RegisterHandlersWithBaseURL(e, srv, BaseURL)
+	srv.RegisterWebDAV(e, DefaultWebDAVPath)
+
+	request := func(method, target string, body []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	manifestPath := url.PathEscape(exportManifestName)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, BaseURL+"/files/a.md", []byte("# A")).Code)
+
+	// files can't be synced into the reserved folder
+	for _, tc := range []struct {
+		method string
+		target string
+		body   string
+	}{
+		{http.MethodPost, BaseURL + "/files/" + manifestPath, `{"exportedAt": "2024-10-29T00:00:00Z", "files": []}`},
+		{http.MethodPost, BaseURL + "/files/a.md/rename", `{"filename": "` + exportManifestName + `"}`},
+		{http.MethodPost, BaseURL + "/folders/" + ReservedFolder, ""},
+		{http.MethodPut, DefaultWebDAVPath + "/" + exportManifestName, "# Manifest"},
+		{"MKCOL", DefaultWebDAVPath + "/" + ReservedFolder, ""},
+	} {
+		rec := request(tc.method, tc.target, []byte(tc.body))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, tc.method+" "+tc.target)
+		assert.Contains(t, rec.Body.String(), "filename is reserved for the server", tc.method+" "+tc.target)
+	}
+	syncFiles, err := database.GetSyncFilesByUserId(db, user.Id)
+	if assert.NoError(t, err) && assert.Len(t, syncFiles, 1) {
+		assert.Equal(t, "a.md", syncFiles[0].Filepath)
+	}
+
+	// so the only file at the manifest's path in an export is the manifest,
+	// and exports can be imported back without losing anything
+	rec := request(http.MethodGet, BaseURL+"/export", nil)
+	if !assert.Equal(t, http.StatusOK, rec.Code) {
+		t.FailNow()
+	}
+	archive := rec.Body.Bytes()
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if assert.NoError(t, err) && assert.Len(t, reader.File, 2) {
+		assert.Equal(t, "a.md", reader.File[0].Name)
+		assert.Equal(t, exportManifestName, reader.File[1].Name)
+	}
+	rec = request(http.MethodDelete, BaseURL+"/files/a.md", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = request(http.MethodPost, BaseURL+"/import", archive)
+	var report api.ImportReport
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report)) {
+		assert.Equal(t, 1, report.Created)
+		assert.Len(t, report.Files, 1)
+	}
+	assert.Equal(t, "# A", request(http.MethodGet, BaseURL+"/files/a.md", nil).Body.String())
+
+	// other files in the reserved folder of an archive are skipped
+	rec = request(http.MethodPost, BaseURL+"/import", createZip(t, []zipEntry{
+		{ReservedFolder + "/notes.md", []byte("# Notes")},
+	}))
+	if assert.Equal(t, http.StatusOK, rec.Code) && assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report)) {
+		assert.Equal(t, 1, report.Skipped)
+	}
+	_, err = database.GetUserSyncFileByFilepath(db, user.Id, ReservedFolder+"/notes.md")
+	assert.ErrorIs(t, err, database.ErrNoResults)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
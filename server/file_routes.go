@@ -0,0 +1,547 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+)
+
+// Delete a file on the sync server
+// (DELETE /files/{filename})
+func (o *ObsyncServer) DeleteFilesFilename(ctx echo.Context, filename string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	if !vault.canWrite(filename) {
+		return sendForbidden(ctx)
+	}
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	if err := o.deleteFile(ctx, vault, syncFile); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "file deleted")
+}
+
+// Download a file from the sync server
+// (GET /files/{filename})
+func (o *ObsyncServer) GetFilesFilename(ctx echo.Context, filename string, params api.GetFilesFilenameParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	header := ctx.Response().Header()
+	header.Set("ETag", fmt.Sprintf("%q", syncFile.Etag))
+	if params.IfNoneMatch != nil && etagMatches(*params.IfNoneMatch, syncFile.Etag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	path, err := userFilePath(vault.OwnerId, filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	contentType := contentTypeForFile(filename)
+
+	// send compressed files as they are stored if the client can decode them.
+	// ranges always refer to the original bytes, so range requests get the
+	// decompressed file instead.
+	if encodedStore, ok := o.fstore.(filestore.EncodedFileStore); ok {
+		data, codec, err := encodedStore.LoadEncodedFile(path)
+		if err != nil {
+			ctx.Logger().Print(err)
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+		if codec != filestore.CodecIdentity {
+			header.Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			if params.Range == nil && acceptsEncoding(ctx.Request().Header.Get(echo.HeaderAcceptEncoding), string(codec)) {
+				header.Set(echo.HeaderLastModified, syncFile.UpdatedAt.Format(http.TimeFormat))
+				header.Set(echo.HeaderContentEncoding, string(codec))
+				return ctx.Blob(http.StatusOK, contentType, data)
+			}
+		}
+	}
+
+	file, err := filestore.OpenReadSeeker(o.fstore, path, syncFile.Size)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			ctx.Logger().Print(err)
+		}
+	}()
+
+	// http.ServeContent takes care of Range, If-Range, If-Modified-Since and
+	// multipart/byteranges responses
+	header.Set(echo.HeaderContentType, contentType)
+	http.ServeContent(ctx.Response(), ctx.Request(), filename, syncFile.UpdatedAt, file)
+	return nil
+}
+
+// Upload a file to the sync server
+// (POST /files/{filename})
+func (o *ObsyncServer) PostFilesFilename(ctx echo.Context, filename string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	if reservedFilename(filename) {
+		return sendReserved(ctx)
+	}
+	if !vault.canWrite(filename) {
+		return sendForbidden(ctx)
+	}
+	if ignored, err := o.isIgnored(vault.OwnerId, filename); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	} else if ignored {
+		return sendIgnored(ctx)
+	}
+
+	_, err = database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err == nil {
+		return sendApiMessage(ctx, http.StatusConflict, "file already exists")
+	} else if !errors.Is(err, database.ErrNoResults) {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	data, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	if _, err := o.saveFile(ctx, vault, filename, nil, data); err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "file created")
+}
+
+// Update a file on the sync server
+// (PUT /files/{filename})
+func (o *ObsyncServer) PutFilesFilename(ctx echo.Context, filename string, params api.PutFilesFilenameParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	if reservedFilename(filename) {
+		return sendReserved(ctx)
+	}
+	if !vault.canWrite(filename) {
+		return sendForbidden(ctx)
+	}
+	if ignored, err := o.isIgnored(vault.OwnerId, filename); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	} else if ignored {
+		return sendIgnored(ctx)
+	}
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if params.IfNoneMatch != nil && etagMatches(*params.IfNoneMatch, syncFile.Etag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	data, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if filestore.GetEtag(data) == syncFile.Etag {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	if _, err := o.saveFile(ctx, vault, filename, syncFile, data); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "file updated")
+}
+
+// Rename a file on the sync server
+// (POST /files/{filename}/rename)
+func (o *ObsyncServer) PostFilesFilenameRename(ctx echo.Context, filename string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+
+	var body api.PostFilesFilenameRenameJSONRequestBody
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	newFilename, err := cleanFilename(body.Filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	if reservedFilename(newFilename) {
+		return sendReserved(ctx)
+	}
+	if !vault.canWrite(filename) || !vault.canWrite(newFilename) {
+		return sendForbidden(ctx)
+	}
+	if ignored, err := o.isIgnored(vault.OwnerId, newFilename); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	} else if ignored {
+		return sendIgnored(ctx)
+	}
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if newFilename == syncFile.Filepath {
+		return sendApiMessage(ctx, http.StatusOK, "file renamed")
+	}
+	_, err = database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, newFilename)
+	if err == nil {
+		return sendApiMessage(ctx, http.StatusConflict, "file already exists")
+	} else if !errors.Is(err, database.ErrNoResults) {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	if err := o.moveFile(ctx, vault, syncFile, newFilename); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "file renamed")
+}
+
+// Save the content of a file and update its sync record and indexes. existing
+// is the file's sync record, or nil if the file is new.
+func (o *ObsyncServer) saveFile(ctx echo.Context, vault *vaultAccess, filename string, existing *database.SyncFile, data []byte) (*database.SyncFile, error) {
+	o.fileMu.RLock()
+	syncFile, err := o.writeFile(ctx, vault, filename, existing, data)
+	o.fileMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	o.indexFile(ctx, syncFile, data, existing == nil)
+	return syncFile, nil
+}
+
+// Write the content of a file to the file store and update its sync record.
+func (o *ObsyncServer) writeFile(ctx echo.Context, vault *vaultAccess, filename string, existing *database.SyncFile, data []byte) (*database.SyncFile, error) {
+	if reservedFilename(filename) {
+		return nil, ErrReservedPath
+	}
+	etag, size := filestore.GetEtag(data), int64(len(data))
+	filePath, err := userFilePath(vault.OwnerId, filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.storeFor(ctx, vault).SaveFile(filePath, data); err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return database.CreateSyncFile(o.db, filename, etag, size, vault.OwnerId, vault.UserId)
+	}
+
+	if err := database.UpdateSyncFileContent(o.db, existing.Id, etag, size, vault.UserId); err != nil {
+		return nil, err
+	}
+	existing.Etag, existing.Size = etag, size
+	return existing, nil
+}
+
+// Delete a synced file along with its sync record and thumbnails.
+func (o *ObsyncServer) deleteFile(ctx echo.Context, vault *vaultAccess, syncFile *database.SyncFile) error {
+	filePath, err := userFilePath(vault.OwnerId, syncFile.Filepath)
+	if err != nil {
+		return err
+	}
+	o.fileMu.RLock()
+	err = o.storeFor(ctx, vault).DeleteFile(filePath)
+	if err != nil && !errors.Is(err, filestore.ErrFileNotFound) {
+		o.fileMu.RUnlock()
+		return err
+	}
+	o.thumbnailMu.Lock()
+	o.deleteThumbnails(ctx, syncFile.Id)
+	err = database.DeleteSyncFile(o.db, syncFile.Id)
+	o.thumbnailMu.Unlock()
+	o.fileMu.RUnlock()
+	if err != nil {
+		return err
+	}
+	// links to the deleted file might resolve to another file with the same
+	// name now
+	o.resolveLinksTo(ctx, vault.OwnerId, syncFile.Filepath)
+	return nil
+}
+
+// Move a synced file to a path that's free, updating the links to and from
+// it.
+func (o *ObsyncServer) moveFile(ctx echo.Context, vault *vaultAccess, syncFile *database.SyncFile, newFilename string) error {
+	oldFilename := syncFile.Filepath
+	if err := o.renameFile(ctx, vault, syncFile, newFilename); err != nil {
+		return err
+	}
+	syncFile.Filepath = newFilename
+	o.fileRenamed(ctx, syncFile, oldFilename)
+	return nil
+}
+
+// Rename a file in the file store along with its sync record.
+func (o *ObsyncServer) renameFile(ctx echo.Context, vault *vaultAccess, syncFile *database.SyncFile, newFilename string) error {
+	if reservedFilename(newFilename) {
+		return ErrReservedPath
+	}
+	oldPath, err := userFilePath(vault.OwnerId, syncFile.Filepath)
+	if err != nil {
+		return err
+	}
+	newPath, err := userFilePath(vault.OwnerId, newFilename)
+	if err != nil {
+		return err
+	}
+	o.fileMu.RLock()
+	defer o.fileMu.RUnlock()
+	if err := o.storeFor(ctx, vault).RenameFile(oldPath, newPath); err != nil {
+		return err
+	}
+	return database.RenameSyncFile(o.db, syncFile.Id, newFilename, vault.UserId)
+}
+
+// Get a list of files that are synced to the server
+// (GET /list-files)
+func (o *ObsyncServer) GetListFiles(ctx echo.Context, params api.GetListFilesParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	query, err := parseListFilesParams(params)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, err.Error())
+	}
+	matcher, err := o.ignoreMatcher(vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	syncFiles, next, err := listVisibleFiles(o.db, vault.OwnerId, query, matcher)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	if next != nil {
+		cursor, err := encodeListCursor(query, next)
+		if err != nil {
+			ctx.Logger().Print(err)
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+		ctx.Response().Header().Set(NextCursorHeader, cursor)
+	}
+
+	files := make(api.FileList, 0, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		files = append(files, toApiFile(syncFile))
+	}
+
+	return ctx.JSON(http.StatusOK, files)
+}
+
+// Update everything the server indexes from a file's contents after the file
+// is created or changed. Indexes are extras, so errors are logged instead of
+// failing the request that changed the file.
+func (o *ObsyncServer) indexFile(ctx echo.Context, syncFile *database.SyncFile, data []byte, created bool) {
+	o.updateSearchIndex(ctx, syncFile, data)
+	o.updateLinks(ctx, syncFile, data)
+	o.updateTags(ctx, syncFile, data)
+	o.updateProperties(ctx, syncFile, data)
+	o.queueThumbnail(ctx, syncFile)
+	if created {
+		// links in other notes might point to the new file
+		o.resolveLinksTo(ctx, syncFile.UserId, syncFile.Filepath)
+	}
+}
+
+func toApiFile(syncFile *database.SyncFile) api.File {
+	id := int64(syncFile.Id)
+	file := api.File{
+		Id:        &id,
+		Filename:  &syncFile.Filepath,
+		Etag:      &syncFile.Etag,
+		Size:      &syncFile.Size,
+		CreatedAt: &syncFile.CreatedAt,
+		UpdatedAt: &syncFile.UpdatedAt,
+	}
+	if syncFile.UpdatedBy != 0 {
+		updatedBy := int64(syncFile.UpdatedBy)
+		file.UpdatedBy = &updatedBy
+	}
+	return file
+}
+
+const maxListFilesLimit = 1000
+
+// Header with the cursor for the next page of /list-files.
+const NextCursorHeader = "Obsync-Next-Cursor"
+
+// Position in a list of files, encoded as base64 JSON so clients treat it as
+// opaque. Cursors remember the sort they were made with, since they can't be
+// used with a different one.
+type listCursor struct {
+	Sort       database.SyncFileSort `json:"s"`
+	Descending bool                  `json:"d,omitempty"`
+	Filepath   string                `json:"p"`
+	UpdatedAt  time.Time             `json:"u"`
+	Id         uint64                `json:"i"`
+}
+
+func parseListFilesParams(params api.GetListFilesParams) (database.SyncFileQuery, error) {
+	query := database.SyncFileQuery{Sort: database.SortByFilepath}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxListFilesLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxListFilesLimit)
+		}
+		query.Limit = *params.Limit
+	}
+	if params.Sort != nil {
+		switch *params.Sort {
+		case api.Path:
+			query.Sort = database.SortByFilepath
+		case api.UpdatedAt:
+			query.Sort = database.SortByUpdatedAt
+		default:
+			return query, errors.New("sort must be path or updated_at")
+		}
+	}
+	if params.Order != nil {
+		switch *params.Order {
+		case api.Asc:
+		case api.Desc:
+			query.Descending = true
+		default:
+			return query, errors.New("order must be asc or desc")
+		}
+	}
+	if params.Prefix != nil {
+		query.Prefix = *params.Prefix
+	}
+	if params.ModifiedSince != nil {
+		query.ModifiedSince = *params.ModifiedSince
+	}
+	if params.Extension != nil {
+		for _, extension := range *params.Extension {
+			if extension = strings.TrimSpace(extension); len(extension) > 0 {
+				query.Extensions = append(query.Extensions, extension)
+			}
+		}
+	}
+	if params.MinSize != nil {
+		query.MinSize = params.MinSize
+	}
+	if params.MaxSize != nil {
+		query.MaxSize = params.MaxSize
+	}
+	if params.Cursor != nil {
+		data, err := base64.RawURLEncoding.DecodeString(*params.Cursor)
+		if err != nil {
+			return query, errors.New("invalid cursor")
+		}
+		var cursor listCursor
+		if err := json.Unmarshal(data, &cursor); err != nil {
+			return query, errors.New("invalid cursor")
+		}
+		if cursor.Sort != query.Sort || cursor.Descending != query.Descending {
+			return query, errors.New("cursor is for a different sort")
+		}
+		query.After = &database.SyncFileCursor{
+			Filepath:  cursor.Filepath,
+			UpdatedAt: cursor.UpdatedAt,
+			Id:        cursor.Id,
+		}
+	}
+	return query, nil
+}
+
+func encodeListCursor(query database.SyncFileQuery, next *database.SyncFileCursor) (string, error) {
+	data, err := json.Marshal(listCursor{
+		Sort:       query.Sort,
+		Descending: query.Descending,
+		Filepath:   next.Filepath,
+		UpdatedAt:  next.UpdatedAt,
+		Id:         next.Id,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,513 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-file-routes")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	note := []byte("# Process Scheduling\n")
+	newNote := []byte("# Process Scheduling\n\nRound robin\n")
+
+	// unauthenticated requests are rejected
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/list-files", nil)
+	rec := httptest.NewRecorder()
+	if assert.NoError(t, srv.GetListFiles(e.NewContext(req, rec), api.GetListFilesParams{})) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// empty vaults have an empty list of files
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/list-files", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetListFiles(e.NewContext(req, rec), api.GetListFilesParams{})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, "[]", rec.Body.String())
+	}
+
+	// upload a file
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/files/note.md", bytes.NewBuffer(note))
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), "note.md")) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// uploading the same file again results in a conflict
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/files/note.md", bytes.NewBuffer(note))
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), "note.md")) {
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	}
+
+	// download the file
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/files/note.md", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetFilesFilename(e.NewContext(req, rec), "note.md", api.GetFilesFilenameParams{})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, note, rec.Body.Bytes())
+		assert.Equal(t, `"`+filestore.GetEtag(note)+`"`, rec.Header().Get("ETag"))
+		assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	}
+
+	// the file hasn't changed since it was downloaded
+	etag := filestore.GetEtag(note)
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/files/note.md", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetFilesFilename(e.NewContext(req, rec), "note.md", api.GetFilesFilenameParams{IfNoneMatch: &etag})) {
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	}
+
+	// update the file
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/files/note.md", bytes.NewBuffer(newNote))
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PutFilesFilename(e.NewContext(req, rec), "note.md", api.PutFilesFilenameParams{})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// updating the file with the same contents doesn't change anything
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/files/note.md", bytes.NewBuffer(newNote))
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PutFilesFilename(e.NewContext(req, rec), "note.md", api.PutFilesFilenameParams{})) {
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	}
+
+	// updating a file that doesn't exist
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/files/missing.md", bytes.NewBuffer(newNote))
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PutFilesFilename(e.NewContext(req, rec), "missing.md", api.PutFilesFilenameParams{})) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	// the list of files reflects the update
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/list-files", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetListFiles(e.NewContext(req, rec), api.GetListFilesParams{})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var files api.FileList
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files))
+		if assert.Len(t, files, 1) {
+			assert.Equal(t, "note.md", *files[0].Filename)
+			assert.Equal(t, filestore.GetEtag(newNote), *files[0].Etag)
+			assert.Equal(t, int64(len(newNote)), *files[0].Size)
+		}
+	}
+
+	// other users can't see the file
+	_, otherCookie := createTestSession(t, db, "test-file-routes-other")
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/files/note.md", nil)
+	req.AddCookie(otherCookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetFilesFilename(e.NewContext(req, rec), "note.md", api.GetFilesFilenameParams{})) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	// delete the file
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/files/note.md", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.DeleteFilesFilename(e.NewContext(req, rec), "note.md")) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/files/note.md", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.DeleteFilesFilename(e.NewContext(req, rec), "note.md")) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestCompressedFileDownloads(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-compressed-downloads")
+	fstore, err := filestore.NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cstore, err := filestore.NewCompressedFileStore(fstore, filestore.CodecGzip)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv := NewServerWithFileStore(db, cstore)
+
+	note := []byte(strings.Repeat("- [ ] finish the scheduling homework\n", 64))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/todo.md", bytes.NewBuffer(note))
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	if assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), "todo.md")) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// the etag and size describe the original bytes
+	syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, "todo.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, filestore.GetEtag(note), syncFile.Etag)
+		assert.Equal(t, int64(len(note)), syncFile.Size)
+	}
+
+	testCases := []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{
+			name:           "client accepts gzip",
+			acceptEncoding: "gzip, deflate, br",
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "client accepts any encoding",
+			acceptEncoding: "*",
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "client refuses gzip",
+			acceptEncoding: "gzip;q=0, deflate",
+		},
+		{
+			name: "client doesn't send Accept-Encoding",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/files/todo.md", nil)
+			req.AddCookie(cookie)
+			if len(tc.acceptEncoding) > 0 {
+				req.Header.Set(echo.HeaderAcceptEncoding, tc.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			err := srv.GetFilesFilename(e.NewContext(req, rec), "todo.md", api.GetFilesFilenameParams{})
+			if !assert.NoError(t, err) || !assert.Equal(t, http.StatusOK, rec.Code) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.wantEncoding, rec.Header().Get(echo.HeaderContentEncoding))
+			assert.Equal(t, echo.HeaderAcceptEncoding, rec.Header().Get(echo.HeaderVary))
+			assert.Equal(t, `"`+filestore.GetEtag(note)+`"`, rec.Header().Get("ETag"))
+
+			body := rec.Body.Bytes()
+			if tc.wantEncoding == "gzip" {
+				assert.Less(t, len(body), len(note))
+				reader, err := gzip.NewReader(bytes.NewReader(body))
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+				body, err = io.ReadAll(reader)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, note, body)
+		})
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestFileDownloadRanges(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-download-ranges")
+	fstore, err := filestore.NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cstore, err := filestore.NewCompressedFileStore(fstore, filestore.CodecGzip)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	data := []byte(strings.Repeat("abcdefghijklmnopqrstuvwxyz\n", 40))
+	etag := `"` + filestore.GetEtag(data) + `"`
+
+	// ranges work the same whether or not the file store can seek natively
+	for name, store := range map[string]filestore.FileStore{
+		"file system":     fstore,
+		"compressed gzip": cstore,
+	} {
+		srv := NewServerWithFileStore(db, store)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/alphabet.txt", bytes.NewBuffer(data))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), "alphabet.txt")) {
+			t.FailNow()
+		}
+		syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, "alphabet.txt")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		lastModified := syncFile.UpdatedAt.UTC().Format(http.TimeFormat)
+
+		testCases := []struct {
+			name             string
+			headers          map[string]string
+			wantCode         int
+			wantBody         []byte
+			wantContentRange string
+			wantMultipart    bool
+		}{
+			{
+				name:     "whole file",
+				wantCode: http.StatusOK,
+				wantBody: data,
+			},
+			{
+				name:             "single range",
+				headers:          map[string]string{"Range": "bytes=27-52"},
+				wantCode:         http.StatusPartialContent,
+				wantBody:         data[27:53],
+				wantContentRange: "bytes 27-52/1080",
+			},
+			{
+				name:             "suffix range",
+				headers:          map[string]string{"Range": "bytes=-27"},
+				wantCode:         http.StatusPartialContent,
+				wantBody:         data[len(data)-27:],
+				wantContentRange: "bytes 1053-1079/1080",
+			},
+			{
+				name:          "several ranges",
+				headers:       map[string]string{"Range": "bytes=500-509,0-9"},
+				wantCode:      http.StatusPartialContent,
+				wantMultipart: true,
+			},
+			{
+				name:             "unsatisfiable range",
+				headers:          map[string]string{"Range": "bytes=5000-6000"},
+				wantCode:         http.StatusRequestedRangeNotSatisfiable,
+				wantContentRange: "bytes */1080",
+			},
+			{
+				name:             "If-Range matches etag",
+				headers:          map[string]string{"Range": "bytes=0-9", "If-Range": etag},
+				wantCode:         http.StatusPartialContent,
+				wantBody:         data[:10],
+				wantContentRange: "bytes 0-9/1080",
+			},
+			{
+				name:     "If-Range doesn't match etag",
+				headers:  map[string]string{"Range": "bytes=0-9", "If-Range": `"stale"`},
+				wantCode: http.StatusOK,
+				wantBody: data,
+			},
+			{
+				name:             "If-Range matches last modification date",
+				headers:          map[string]string{"Range": "bytes=0-9", "If-Range": lastModified},
+				wantCode:         http.StatusPartialContent,
+				wantBody:         data[:10],
+				wantContentRange: "bytes 0-9/1080",
+			},
+			{
+				name:     "not modified since last download",
+				headers:  map[string]string{"If-Modified-Since": lastModified},
+				wantCode: http.StatusNotModified,
+			},
+		}
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(name+"/"+tc.name, func(t *testing.T) {
+				var params api.GetFilesFilenameParams
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/files/alphabet.txt", nil)
+				req.AddCookie(cookie)
+				for key, value := range tc.headers {
+					req.Header.Set(key, value)
+				}
+				if value, ok := tc.headers["Range"]; ok {
+					params.Range = &value
+				}
+				rec := httptest.NewRecorder()
+				if !assert.NoError(t, srv.GetFilesFilename(e.NewContext(req, rec), "alphabet.txt", params)) {
+					t.FailNow()
+				}
+
+				assert.Equal(t, tc.wantCode, rec.Code)
+				if tc.wantCode != http.StatusNotModified {
+					assert.Equal(t, lastModified, rec.Header().Get(echo.HeaderLastModified))
+				}
+				assert.Equal(t, tc.wantContentRange, rec.Header().Get("Content-Range"))
+				if tc.wantBody != nil {
+					assert.Equal(t, tc.wantBody, rec.Body.Bytes())
+				}
+				if tc.wantMultipart {
+					assert.True(t, strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), "multipart/byteranges"))
+					assert.Contains(t, rec.Body.String(), "Content-Range: bytes 500-509/1080")
+					assert.Contains(t, rec.Body.String(), "Content-Range: bytes 0-9/1080")
+					assert.Contains(t, rec.Body.String(), string(data[500:510]))
+				}
+			})
+		}
+
+		_, err = db.Exec("DELETE FROM file_syncs WHERE id=?", syncFile.Id)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestListFilesPages(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-list-files-pages")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	filenames := []string{"a.md", "b.png", "notes/c.md", "notes/d.md", "notes/e.pdf"}
+	for _, filename := range filenames {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+filename, strings.NewReader(filename))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) {
+			t.FailNow()
+		}
+	}
+
+	list := func(params api.GetListFilesParams) (*httptest.ResponseRecorder, []string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/list-files", nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.GetListFiles(e.NewContext(req, rec), params)) {
+			t.FailNow()
+		}
+		var files []api.File
+		got := []string{}
+		if rec.Code == http.StatusOK && assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files)) {
+			for _, file := range files {
+				got = append(got, *file.Filename)
+			}
+		}
+		return rec, got
+	}
+
+	// page through the files newest first
+	limit, sort, order := 2, api.UpdatedAt, api.Desc
+	params := api.GetListFilesParams{Limit: &limit, Sort: &sort, Order: &order}
+	var pages [][]string
+	for {
+		rec, got := list(params)
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			break
+		}
+		pages = append(pages, got)
+		cursor := rec.Header().Get(NextCursorHeader)
+		if len(cursor) == 0 {
+			break
+		}
+		params.Cursor = &cursor
+	}
+	assert.Equal(t, [][]string{{"notes/e.pdf", "notes/d.md"}, {"notes/c.md", "b.png"}, {"a.md"}}, pages)
+
+	// filters
+	prefix, extensions, minSize := "notes/", []string{"md"}, int64(len("notes/d.md"))
+	_, got := list(api.GetListFilesParams{Prefix: &prefix, Extension: &extensions})
+	assert.Equal(t, []string{"notes/c.md", "notes/d.md"}, got)
+	_, got = list(api.GetListFilesParams{MinSize: &minSize})
+	assert.Equal(t, []string{"notes/c.md", "notes/d.md", "notes/e.pdf"}, got)
+	future := time.Now().Add(time.Hour)
+	_, got = list(api.GetListFilesParams{ModifiedSince: &future})
+	assert.Empty(t, got)
+
+	// cursors only work with the sort they were made with
+	badLimit, badCursor := 0, "not a cursor"
+	for _, params := range []api.GetListFilesParams{
+		{Limit: &badLimit},
+		{Cursor: &badCursor},
+		{Cursor: params.Cursor},
+	} {
+		rec, _ := list(params)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestFilePathTraversal(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	api.RegisterHandlersWithBaseURL(e, srv, BaseURL)
+	victim, victimCookie := createTestSession(t, db, "test-traversal-victim")
+	attacker, cookie := createTestSession(t, db, "test-traversal-attacker")
+
+	secret := []byte("# Secret\n")
+	request := func(method, filename, suffix string, body []byte, cookie *http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, BaseURL+"/files/"+filename+suffix, bytes.NewBuffer(body))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := request(http.MethodPost, "secret.md", "", secret, victimCookie)
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		t.FailNow()
+	}
+
+	escaped := "..%2F" + strconv.FormatUint(victim.Id, 10) + "%2Fsecret.md"
+	for _, tc := range []struct {
+		method string
+		suffix string
+	}{
+		{http.MethodGet, ""},
+		{http.MethodPost, ""},
+		{http.MethodPut, ""},
+		{http.MethodDelete, ""},
+		{http.MethodPost, "/rename"},
+		{http.MethodGet, "/render"},
+		{http.MethodGet, "/thumbnail"},
+		{http.MethodGet, "/properties"},
+		{http.MethodGet, "/backlinks"},
+	} {
+		rec := request(tc.method, escaped, tc.suffix, []byte("# Overwritten\n"), cookie)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, tc.method+" "+tc.suffix+": "+rec.Body.String())
+		assert.Contains(t, rec.Body.String(), "invalid filename")
+	}
+
+	// the victim's file is untouched, and the attacker's vault is still empty
+	data, err := srv.fstore.LoadFile(testFilePath(t, victim.Id, "secret.md"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, secret, data)
+	}
+	syncFiles, _, err := database.ListSyncFiles(db, attacker.Id, database.SyncFileQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, syncFiles)
+
+	assert.NoError(t, database.DeleteUser(db, victim.Id))
+	assert.NoError(t, database.DeleteUser(db, attacker.Id))
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+)
+
+// Header that names the device a request was made from, which is recorded in
+// the file history along with the user.
+const DeviceHeader = "Obsync-Device"
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// Get the commits that changed a file or folder
+// (GET /history)
+func (o *ObsyncServer) GetHistory(ctx echo.Context, params api.GetHistoryParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	history, ok := o.fstore.(filestore.HistoryFileStore)
+	if !ok {
+		return sendHistoryDisabled(ctx)
+	}
+
+	root := ""
+	if params.Path != nil && len(strings.Trim(*params.Path, "/")) > 0 {
+		root, err = cleanFolder(*params.Path)
+		if err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid path")
+		}
+	}
+	limit := defaultHistoryLimit
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+	if limit < 1 || limit > maxHistoryLimit {
+		return sendApiMessage(ctx, http.StatusBadRequest, "limit must be between 1 and 1000")
+	}
+
+	logPath := userDir(vault.OwnerId)
+	if len(root) > 0 {
+		if logPath, err = userFilePath(vault.OwnerId, root); err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid path")
+		}
+	}
+	commits, err := history.Log(logPath, limit)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	apiCommits := make([]api.Commit, 0, len(commits))
+	for _, commit := range commits {
+		apiCommits = append(apiCommits, toApiCommit(commit, vault.OwnerId))
+	}
+
+	return ctx.JSON(http.StatusOK, apiCommits)
+}
+
+// Download a file as it was after a commit
+// (GET /files/{filename}/history/{commit})
+func (o *ObsyncServer) GetFilesFilenameHistoryCommit(ctx echo.Context, filename string, commit string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	history, ok := o.fstore.(filestore.HistoryFileStore)
+	if !ok {
+		return sendHistoryDisabled(ctx)
+	}
+
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	filePath, err := userFilePath(vault.OwnerId, filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	data, err := history.LoadFileAt(filePath, commit)
+	if err != nil {
+		if errors.Is(err, filestore.ErrInvalidCommit) {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid commit hash")
+		}
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return ctx.Blob(http.StatusOK, contentTypeForFile(filename), data)
+}
+
+// Get the file store to change a vault's files through. When the file store
+// keeps history, the changes are recorded as made by the user making the
+// request, from the device named in the request's Obsync-Device header.
+func (o *ObsyncServer) storeFor(ctx echo.Context, vault *vaultAccess) filestore.FileStore {
+	history, ok := o.fstore.(filestore.HistoryFileStore)
+	if !ok {
+		return o.fstore
+	}
+	user, err := database.GetUserById(o.db, vault.UserId)
+	if err != nil {
+		// the change is still made, just without its author
+		ctx.Logger().Print(err)
+		return history
+	}
+	return history.WithAuthor(filestore.Author{
+		Name:   user.Username,
+		Email:  user.Email,
+		Device: ctx.Request().Header.Get(DeviceHeader),
+	})
+}
+
+func sendHistoryDisabled(ctx echo.Context) error {
+	return sendApiMessage(ctx, http.StatusNotImplemented, "file history is not enabled on this server")
+}
+
+// Convert a commit to its API model. Paths in the message are made relative
+// to the vault, since the vault's files are kept in the owner's folder.
+func toApiCommit(commit filestore.Commit, ownerId uint64) api.Commit {
+	apiCommit := api.Commit{
+		Hash:    commit.Hash,
+		Author:  commit.Author.Name,
+		Email:   commit.Author.Email,
+		Message: strings.ReplaceAll(commit.Message, " "+strconv.FormatUint(ownerId, 10)+"/", " "),
+		Date:    commit.Time,
+	}
+	if len(commit.Author.Device) > 0 {
+		apiCommit.Device = &commit.Author.Device
+	}
+	return apiCommit
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryRoutes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-history-routes")
+	editor, editorCookie := createTestSession(t, db, "test-history-routes-editor")
+	assert.NoError(t, database.SetVaultMember(db, user.Id, editor.Id, database.VaultEditor, nil))
+	fstore, err := filestore.NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	gstore, err := filestore.NewGitFileStore(fstore, filestore.GitOptions{Exclude: []string{ThumbnailDir}})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv := NewServerWithFileStore(db, gstore)
+
+	request := func(cookie *http.Cookie, device, body string, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(body))
+		req.AddCookie(cookie)
+		req.Header.Set(VaultHeader, user.Username)
+		if len(device) > 0 {
+			req.Header.Set(DeviceHeader, device)
+		}
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	getHistory := func(params api.GetHistoryParams) []api.Commit {
+		t.Helper()
+		rec := request(cookie, "", "", func(ctx echo.Context) error {
+			return srv.GetHistory(ctx, params)
+		})
+		commits := []api.Commit{}
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &commits))
+		}
+		return commits
+	}
+	getVersion := func(filename, commit string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(cookie, "", "", func(ctx echo.Context) error {
+			return srv.GetFilesFilenameHistoryCommit(ctx, filename, commit)
+		})
+	}
+
+	rec := request(cookie, "laptop", "first", func(ctx echo.Context) error {
+		return srv.PostFilesFilename(ctx, "notes/a.md")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = request(editorCookie, "", "second", func(ctx echo.Context) error {
+		return srv.PutFilesFilename(ctx, "notes/a.md", api.PutFilesFilenameParams{})
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = request(cookie, "phone", `{"filename": "notes/b.md"}`, func(ctx echo.Context) error {
+		return srv.PostFilesFilenameRename(ctx, "notes/a.md")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = request(cookie, "", "other", func(ctx echo.Context) error {
+		return srv.PostFilesFilename(ctx, "other.md")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// paths in messages are relative to the vault, and the log of a file
+	// follows it across renames
+	path := "notes/b.md"
+	commits := getHistory(api.GetHistoryParams{Path: &path})
+	if assert.Len(t, commits, 3) {
+		assert.Equal(t, "Rename notes/a.md to notes/b.md", commits[0].Message)
+		assert.Equal(t, user.Username, commits[0].Author)
+		assert.Equal(t, user.Email, commits[0].Email)
+		assert.Equal(t, "phone", *commits[0].Device)
+		assert.Equal(t, "Update notes/a.md", commits[1].Message)
+		assert.Equal(t, editor.Username, commits[1].Author)
+		assert.Nil(t, commits[1].Device)
+		assert.Equal(t, "Add notes/a.md", commits[2].Message)
+		assert.Equal(t, "laptop", *commits[2].Device)
+
+		rec = getVersion("notes/a.md", commits[2].Hash)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Equal(t, "first", rec.Body.String())
+			assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+		}
+		assert.Equal(t, http.StatusNotFound, getVersion("notes/b.md", commits[2].Hash).Code)
+		assert.Equal(t, http.StatusNotFound, getVersion("notes/a.md", commits[0].Hash).Code)
+		assert.Equal(t, http.StatusBadRequest, getVersion("notes/a.md", "HEAD").Code)
+		assert.Equal(t, http.StatusBadRequest, getVersion("../a.md", commits[0].Hash).Code)
+	}
+
+	limit := 2
+	assert.Len(t, getHistory(api.GetHistoryParams{Limit: &limit}), 2)
+	assert.Len(t, getHistory(api.GetHistoryParams{}), 4)
+	folder := "notes"
+	assert.Len(t, getHistory(api.GetHistoryParams{Path: &folder}), 3)
+	limit = 0
+	rec = request(cookie, "", "", func(ctx echo.Context) error {
+		return srv.GetHistory(ctx, api.GetHistoryParams{Limit: &limit})
+	})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// servers that don't keep history say so
+	plain, err := NewServer(db, t.TempDir())
+	if assert.NoError(t, err) {
+		rec = request(cookie, "", "", func(ctx echo.Context) error {
+			return plain.GetHistory(ctx, api.GetHistoryParams{})
+		})
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+	assert.NoError(t, database.DeleteUser(db, editor.Id))
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/ignore"
+)
+
+// Get the vault's ignore rules
+// (GET /ignore-rules)
+func (o *ObsyncServer) GetIgnoreRules(ctx echo.Context) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	rules, err := database.GetIgnoreRules(o.db, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return ctx.JSON(http.StatusOK, toApiIgnoreRules(rules))
+}
+
+// Replace the vault's ignore rules
+// (PUT /ignore-rules)
+func (o *ObsyncServer) PutIgnoreRules(ctx echo.Context) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	if !vault.isOwner() {
+		return sendApiMessage(ctx, http.StatusForbidden, "only owners can change the vault's ignore rules")
+	}
+
+	var body api.IgnoreRules
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	if _, err := ignore.Parse(body.Rules); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, err.Error())
+	}
+
+	rules, err := database.SetIgnoreRules(o.db, vault.OwnerId, body.Rules)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return ctx.JSON(http.StatusOK, toApiIgnoreRules(rules))
+}
+
+// Get the matcher for a vault's ignore rules.
+func (o *ObsyncServer) ignoreMatcher(ownerId uint64) (*ignore.Matcher, error) {
+	rules, err := database.GetIgnoreRules(o.db, ownerId)
+	if err != nil {
+		return nil, err
+	}
+	return ignore.Parse(rules.Rules)
+}
+
+// Check whether a file is ignored by a vault's ignore rules.
+func (o *ObsyncServer) isIgnored(ownerId uint64, filename string) (bool, error) {
+	matcher, err := o.ignoreMatcher(ownerId)
+	if err != nil {
+		return false, err
+	}
+	return matcher.Match(filename), nil
+}
+
+// Leave out the synced files that are ignored by a vault's ignore rules.
+func filterIgnored(syncFiles []*database.SyncFile, matcher *ignore.Matcher) []*database.SyncFile {
+	filtered := make([]*database.SyncFile, 0, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		if !matcher.Match(syncFile.Filepath) {
+			filtered = append(filtered, syncFile)
+		}
+	}
+	return filtered
+}
+
+// Send the response for a file that's ignored by the vault's ignore rules.
+func sendIgnored(ctx echo.Context) error {
+	return sendApiMessage(ctx, http.StatusUnprocessableEntity, "file is ignored by the vault's ignore rules")
+}
+
+func toApiIgnoreRules(rules *database.IgnoreRules) api.IgnoreRules {
+	apiRules := api.IgnoreRules{Rules: rules.Rules}
+	if !rules.UpdatedAt.IsZero() {
+		apiRules.UpdatedAt = &rules.UpdatedAt
+	}
+	return apiRules
+}
+
+// Lists a page of a user's sync files that aren't ignored, fetching more files
+// until the page is full or there are no more files. The cursor is nil once
+// every file has been listed.
+func listVisibleFiles(
+	db *sql.DB,
+	userId uint64,
+	query database.SyncFileQuery,
+	matcher *ignore.Matcher,
+) ([]*database.SyncFile, *database.SyncFileCursor, error) {
+	limit := query.Limit
+	visible := []*database.SyncFile{}
+	for {
+		if limit > 0 {
+			// only fetch what's left of the page so the cursor never
+			// skips a visible file
+			query.Limit = limit - len(visible)
+		}
+		syncFiles, next, err := database.ListSyncFiles(db, userId, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		visible = append(visible, filterIgnored(syncFiles, matcher)...)
+		if next == nil || len(visible) == limit {
+			return visible, next, nil
+		}
+		query.After = next
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-ignore-routes")
+	member, memberCookie := createTestSession(t, db, "test-ignore-routes-member")
+	assert.NoError(t, database.SetVaultMember(db, user.Id, member.Id, database.VaultEditor, nil))
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	request := func(cookie *http.Cookie, body string, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(body))
+		req.AddCookie(cookie)
+		req.Header.Set(VaultHeader, user.Username)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	listFiles := func(ctx echo.Context) error {
+		return srv.GetListFiles(ctx, api.GetListFilesParams{})
+	}
+	upload := func(filename string) *httptest.ResponseRecorder {
+		return request(cookie, "content", func(ctx echo.Context) error {
+			return srv.PostFilesFilename(ctx, filename)
+		})
+	}
+
+	// files synced before the rules exist stay on the server
+	assert.Equal(t, http.StatusOK, upload(".obsidian/workspace.json").Code)
+	assert.Equal(t, http.StatusOK, upload("notes/todo.md").Code)
+
+	rec := request(cookie, "", srv.GetIgnoreRules)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.JSONEq(t, `{"rules": ""}`, rec.Body.String())
+	}
+
+	rules := ".obsidian/workspace.json\n.trash/\nVideos/**/*.mp4\n"
+	body, _ := json.Marshal(api.IgnoreRules{Rules: rules})
+	assert.Equal(t, http.StatusForbidden, request(memberCookie, string(body), srv.PutIgnoreRules).Code)
+	assert.Equal(t, http.StatusBadRequest, request(cookie, `{"rules": "[unclosed"}`, srv.PutIgnoreRules).Code)
+	assert.Equal(t, http.StatusBadRequest, request(cookie, `not json`, srv.PutIgnoreRules).Code)
+	rec = request(cookie, string(body), srv.PutIgnoreRules)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// members can fetch the rules to apply them locally
+	rec = request(memberCookie, "", srv.GetIgnoreRules)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		var got api.IgnoreRules
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, rules, got.Rules)
+		assert.NotNil(t, got.UpdatedAt)
+	}
+
+	// writes to ignored paths are rejected
+	assert.Equal(t, http.StatusUnprocessableEntity, upload(".trash/note.md").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, upload("Videos/2024/lecture.mp4").Code)
+	assert.Equal(t, http.StatusOK, upload("Videos/notes.md").Code)
+	rec = request(cookie, "changed", func(ctx echo.Context) error {
+		return srv.PutFilesFilename(ctx, ".obsidian/workspace.json", api.PutFilesFilenameParams{})
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	rec = request(memberCookie, `{"filename": ".trash/todo.md"}`, func(ctx echo.Context) error {
+		return srv.PostFilesFilenameRename(ctx, "notes/todo.md")
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// imports skip ignored files
+	archive := createZip(t, []zipEntry{
+		{name: ".trash/deleted.md", data: []byte("deleted")},
+		{name: "notes/imported.md", data: []byte("imported")},
+	})
+	rec = request(cookie, string(archive), func(ctx echo.Context) error {
+		return srv.PostImport(ctx, api.PostImportParams{})
+	})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		var report api.ImportReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 1, report.Skipped)
+	}
+
+	// listings and exports leave ignored files out
+	wantFiles := []string{"Videos/notes.md", "notes/imported.md", "notes/todo.md"}
+	rec = request(memberCookie, "", listFiles)
+	var files []api.File
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files))
+	filenames := []string{}
+	for _, file := range files {
+		filenames = append(filenames, *file.Filename)
+	}
+	assert.ElementsMatch(t, wantFiles, filenames)
+
+	// pages are filled past ignored files, even when a whole page of the
+	// listing is ignored
+	limit := 1
+	params := api.GetListFilesParams{Limit: &limit}
+	filenames = []string{}
+	for range wantFiles {
+		rec = request(memberCookie, "", func(ctx echo.Context) error {
+			return srv.GetListFiles(ctx, params)
+		})
+		files = nil
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files))
+		if !assert.Len(t, files, 1) {
+			break
+		}
+		filenames = append(filenames, *files[0].Filename)
+		cursor := rec.Header().Get(NextCursorHeader)
+		params.Cursor = &cursor
+	}
+	assert.Equal(t, wantFiles, filenames)
+	assert.Empty(t, rec.Header().Get(NextCursorHeader))
+
+	rec = request(cookie, "", func(ctx echo.Context) error {
+		return srv.GetExport(ctx, api.GetExportParams{})
+	})
+	reader, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if assert.NoError(t, err) {
+		filenames = []string{}
+		for _, file := range reader.File {
+			filenames = append(filenames, file.Name)
+		}
+		assert.ElementsMatch(t, append(wantFiles, exportManifestName), filenames)
+	}
+
+	// ignored files can still be deleted
+	rec = request(cookie, "", func(ctx echo.Context) error {
+		return srv.DeleteFilesFilename(ctx, ".obsidian/workspace.json")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+	assert.NoError(t, database.DeleteUser(db, member.Id))
+}
@@ -0,0 +1,371 @@
+package server
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/ignore"
+)
+
+const (
+	// Archives with more files than this are rejected by default.
+	DefaultImportMaxEntries = 10000
+	// Archives larger than this many bytes, compressed or not, are rejected by
+	// default.
+	DefaultImportMaxSize = 1 << 30
+)
+
+type ImportLimits struct {
+	// Maximum number of files in an archive
+	MaxEntries int
+	// Maximum size of an archive in bytes. The limit applies to both the
+	// uploaded archive and the total size of the files extracted from it.
+	MaxSize int64
+}
+
+// Upload a ZIP archive of files to sync to the server
+// (POST /import)
+func (o *ObsyncServer) PostImport(ctx echo.Context, params api.PostImportParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	conflict := api.Skip
+	if params.Conflict != nil {
+		conflict = *params.Conflict
+	}
+	switch conflict {
+	case api.Skip, api.Overwrite, api.KeepBoth:
+	default:
+		return sendApiMessage(ctx, http.StatusBadRequest, "unsupported conflict policy")
+	}
+
+	// zip archives are read from the end, so the upload has to be spooled
+	// somewhere first
+	archiveFile, err := os.CreateTemp("", "obsync-import-*.zip")
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	defer func() {
+		archiveFile.Close()
+		os.Remove(archiveFile.Name())
+	}()
+
+	size, err := io.Copy(archiveFile, io.LimitReader(ctx.Request().Body, o.importLimits.MaxSize+1))
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if size > o.importLimits.MaxSize {
+		return sendApiMessage(ctx, http.StatusRequestEntityTooLarge, "archive is too large")
+	}
+
+	archive, err := zip.NewReader(archiveFile, size)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid zip archive")
+	}
+
+	// check the whole archive before changing any files, so unsafe archives
+	// are rejected without being partially imported
+	entries := make([]*zip.File, 0, len(archive.File))
+	filenames := make(map[*zip.File]string, len(archive.File))
+	seen := make(map[string]struct{}, len(archive.File))
+	var totalSize uint64
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		filename, err := cleanFilename(entry.Name)
+		if err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, fmt.Sprintf("unsafe path in archive: %q", entry.Name))
+		}
+		if !vault.canWrite(filename) {
+			return sendForbidden(ctx)
+		}
+		if _, ok := seen[filename]; ok {
+			return sendApiMessage(ctx, http.StatusBadRequest, fmt.Sprintf("duplicate path in archive: %q", entry.Name))
+		}
+		seen[filename] = struct{}{}
+
+		entries = append(entries, entry)
+		filenames[entry] = filename
+		totalSize += entry.UncompressedSize64
+		if len(entries) > o.importLimits.MaxEntries {
+			return sendApiMessage(ctx, http.StatusRequestEntityTooLarge, "archive has too many files")
+		}
+		if totalSize > uint64(o.importLimits.MaxSize) {
+			return sendApiMessage(ctx, http.StatusRequestEntityTooLarge, "archive is too large")
+		}
+	}
+
+	matcher, err := o.ignoreMatcher(vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	o.fileMu.Lock()
+	report, imported, err := o.importFiles(ctx, vault, entries, filenames, matcher, conflict)
+	o.fileMu.Unlock()
+	if errors.Is(err, errUnreadableEntry) {
+		return sendApiMessage(ctx, http.StatusBadRequest, err.Error())
+	} else if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	o.indexImportedFiles(ctx, imported)
+
+	return ctx.JSON(http.StatusOK, report)
+}
+
+var errUnreadableEntry = errors.New("file couldn't be read from the archive")
+
+// Files an import synced, to be indexed once the import is done.
+type importedFiles struct {
+	saved   []*database.SyncFile
+	entries []*zip.File
+	created []bool
+}
+
+// A file an import wrote to the file store, along with what was there before.
+type importedWrite struct {
+	change   database.SyncFileChange
+	existed  bool
+	previous []byte
+}
+
+// Sync the files of an import archive. The caller holds fileMu for writing.
+// Like snapshot restores, the files are written first and their sync records
+// are changed in one transaction afterwards; if anything fails, the files
+// already written are put back, so either every file is imported or none are.
+func (o *ObsyncServer) importFiles(
+	ctx echo.Context,
+	vault *vaultAccess,
+	entries []*zip.File,
+	filenames map[*zip.File]string,
+	matcher *ignore.Matcher,
+	conflict api.PostImportParamsConflict,
+) (*api.ImportReport, *importedFiles, error) {
+	store := o.storeFor(ctx, vault)
+	report := &api.ImportReport{Files: make([]api.ImportResult, 0, len(entries))}
+	imported := &importedFiles{}
+	written := []importedWrite{}
+	rollback := func(err error) (*api.ImportReport, *importedFiles, error) {
+		o.rollbackImport(ctx, vault, store, written)
+		return nil, nil, err
+	}
+
+	// copies kept under a new name can't take the name of a file later in
+	// the archive
+	taken := make(map[string]bool, len(entries))
+	for _, filename := range filenames {
+		taken[filename] = true
+	}
+
+	for _, entry := range entries {
+		filename := filenames[entry]
+		if filename == exportManifestName {
+			// the manifest of archives made by /export isn't one of the
+			// user's files
+			continue
+		}
+		result := api.ImportResult{Path: entry.Name, Status: api.Skipped}
+		var change *database.SyncFileChange
+		if reservedFilename(filename) {
+			message := "filename is reserved for the server"
+			result.Message = &message
+		} else if matcher.Match(filename) {
+			message := "file is ignored by the vault's ignore rules"
+			result.Message = &message
+		} else {
+			data, err := readZipFile(entry)
+			if err != nil {
+				ctx.Logger().Print(err)
+				return rollback(fmt.Errorf("%w: %q", errUnreadableEntry, entry.Name))
+			}
+			result, change, err = o.planImportFile(vault, entry, filename, data, conflict, taken)
+			if err != nil {
+				return rollback(err)
+			}
+			if change != nil {
+				write, err := o.writeImportedFile(store, vault, *change, data)
+				if write != nil {
+					written = append(written, *write)
+				}
+				if err != nil {
+					return rollback(err)
+				}
+				imported.entries = append(imported.entries, entry)
+				imported.created = append(imported.created, change.Existing == nil)
+			}
+		}
+
+		switch result.Status {
+		case api.Created:
+			report.Created++
+		case api.Overwritten:
+			report.Overwritten++
+		case api.Skipped:
+			report.Skipped++
+		}
+		report.Files = append(report.Files, result)
+	}
+
+	changes := make([]database.SyncFileChange, 0, len(written))
+	for _, write := range written {
+		changes = append(changes, write.change)
+	}
+	saved, err := database.ApplySyncFileChanges(o.db, vault.OwnerId, vault.UserId, changes)
+	if err != nil {
+		return rollback(err)
+	}
+	imported.saved = saved
+	return report, imported, nil
+}
+
+// Decide what to do with a file from an import archive. The change is nil if
+// the file is skipped.
+func (o *ObsyncServer) planImportFile(
+	vault *vaultAccess,
+	entry *zip.File,
+	filename string,
+	data []byte,
+	conflict api.PostImportParamsConflict,
+	taken map[string]bool,
+) (api.ImportResult, *database.SyncFileChange, error) {
+	result := api.ImportResult{Path: entry.Name, Status: api.Created}
+	skip := func(message string) (api.ImportResult, *database.SyncFileChange, error) {
+		result.Status = api.Skipped
+		result.Message = &message
+		return result, nil, nil
+	}
+
+	change := &database.SyncFileChange{
+		Filepath: filename,
+		Etag:     filestore.GetEtag(data),
+		Size:     int64(len(data)),
+	}
+	existing, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil && !errors.Is(err, database.ErrNoResults) {
+		return result, nil, err
+	}
+
+	if existing != nil {
+		result.Filename = &existing.Filepath
+		if existing.Etag == change.Etag {
+			return skip("file is unchanged")
+		}
+		switch conflict {
+		case api.Skip:
+			return skip("file already exists")
+		case api.Overwrite:
+			change.Existing = existing
+			result.Status = api.Overwritten
+		case api.KeepBoth:
+			change.Filepath, err = o.availableFilename(vault.OwnerId, filename, taken)
+			if err != nil {
+				return result, nil, err
+			}
+			taken[change.Filepath] = true
+		}
+	}
+	result.Filename = &change.Filepath
+	return result, change, nil
+}
+
+// Write a file from an import archive to the file store, keeping the content
+// it replaces so the import can be rolled back. The write is nil if the file
+// store wasn't touched.
+func (o *ObsyncServer) writeImportedFile(
+	store filestore.FileStore,
+	vault *vaultAccess,
+	change database.SyncFileChange,
+	data []byte,
+) (*importedWrite, error) {
+	filePath, err := userFilePath(vault.OwnerId, change.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	write := &importedWrite{change: change}
+	if change.Existing != nil {
+		write.previous, err = store.LoadFile(filePath)
+		if err != nil && !errors.Is(err, filestore.ErrFileNotFound) {
+			return nil, err
+		}
+		write.existed = err == nil
+	}
+	return write, store.SaveFile(filePath, data)
+}
+
+// Put back the files a failed import already wrote.
+func (o *ObsyncServer) rollbackImport(ctx echo.Context, vault *vaultAccess, store filestore.FileStore, written []importedWrite) {
+	for _, write := range written {
+		filePath, err := userFilePath(vault.OwnerId, write.change.Filepath)
+		if err == nil && write.existed {
+			err = store.SaveFile(filePath, write.previous)
+		} else if err == nil {
+			err = store.DeleteFile(filePath)
+		}
+		if err != nil {
+			ctx.Logger().Print(err)
+		}
+	}
+}
+
+// Update the search index, links, tags, properties and thumbnails of the files
+// an import synced. The files are read from the archive again rather than kept
+// in memory for the whole import.
+func (o *ObsyncServer) indexImportedFiles(ctx echo.Context, imported *importedFiles) {
+	for i, syncFile := range imported.saved {
+		data, err := readZipFile(imported.entries[i])
+		if err != nil {
+			ctx.Logger().Print(err)
+			continue
+		}
+		o.indexFile(ctx, syncFile, data, imported.created[i])
+	}
+}
+
+// Read the content of a file in a zip archive. zip.File checks that entries
+// don't decompress to more than their recorded size, so the total size checked
+// before importing can't be exceeded.
+func readZipFile(entry *zip.File) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Find a filename that isn't synced or taken yet by adding a number to the end
+// of the filename, like "note (1).md".
+func (o *ObsyncServer) availableFilename(userId uint64, filename string, taken map[string]bool) (string, error) {
+	ext := path.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if taken[candidate] {
+			continue
+		}
+		_, err := database.GetUserSyncFileByFilepath(o.db, userId, candidate)
+		if errors.Is(err, database.ErrNoResults) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+type zipEntry struct {
+	name string
+	data []byte
+}
+
+func TestPostImport(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-import")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	importArchive := func(t *testing.T, archive []byte, params api.PostImportParams) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/import", bytes.NewReader(archive))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostImport(e.NewContext(req, rec), params)) {
+			t.FailNow()
+		}
+		return rec
+	}
+	readReport := func(t *testing.T, rec *httptest.ResponseRecorder) (api.ImportReport, map[string]api.ImportResult) {
+		t.Helper()
+		var report api.ImportReport
+		if !assert.Equal(t, http.StatusOK, rec.Code) || !assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report)) {
+			t.FailNow()
+		}
+		results := make(map[string]api.ImportResult)
+		for _, result := range report.Files {
+			results[result.Path] = result
+		}
+		return report, results
+	}
+	fileContents := func(t *testing.T, filename string) []byte {
+		t.Helper()
+		data, err := srv.fstore.LoadFile(testFilePath(t, user.Id, filename))
+		assert.NoError(t, err)
+		return data
+	}
+
+	// import a new vault
+	rec := importArchive(t, createZip(t, []zipEntry{
+		{"todo.md", []byte("- [ ] import vault\n")},
+		{"school/", nil},
+		{"school/os/scheduling.md", []byte("# Process Scheduling\n")},
+		{"./school/notes.md", []byte("# Notes\n")},
+		{exportManifestName, []byte(`{"exportedAt": "2024-10-29T00:00:00Z", "files": []}`)},
+		// only the manifest at the path exports use is left out
+		{"manifest.json", []byte(`{"exportedAt": "2024-10-29T00:00:00Z", "files": []}`)},
+	}), api.PostImportParams{})
+	report, results := readReport(t, rec)
+	assert.Equal(t, 4, report.Created)
+	assert.Len(t, report.Files, 4)
+	assert.NotContains(t, results, exportManifestName)
+	if assert.Contains(t, results, "manifest.json") {
+		assert.Equal(t, api.Created, results["manifest.json"].Status)
+	}
+	if assert.Contains(t, results, "./school/notes.md") {
+		assert.Equal(t, api.Created, results["./school/notes.md"].Status)
+		assert.Equal(t, "school/notes.md", *results["./school/notes.md"].Filename)
+	}
+	assert.Equal(t, []byte("# Process Scheduling\n"), fileContents(t, "school/os/scheduling.md"))
+	syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, "todo.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(len("- [ ] import vault\n")), syncFile.Size)
+	}
+
+	conflicting := createZip(t, []zipEntry{
+		{"todo.md", []byte("- [x] import vault\n")},
+		{"school/notes.md", []byte("# Notes\n")},
+	})
+
+	// existing files are skipped by default
+	report, results = readReport(t, importArchive(t, conflicting, api.PostImportParams{}))
+	assert.Equal(t, 2, report.Skipped)
+	assert.Equal(t, api.Skipped, results["todo.md"].Status)
+	assert.Equal(t, []byte("- [ ] import vault\n"), fileContents(t, "todo.md"))
+
+	// keep both copies of changed files
+	keepBoth := api.KeepBoth
+	report, results = readReport(t, importArchive(t, conflicting, api.PostImportParams{Conflict: &keepBoth}))
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Skipped)
+	if assert.Equal(t, api.Created, results["todo.md"].Status) {
+		assert.Equal(t, "todo (1).md", *results["todo.md"].Filename)
+		assert.Equal(t, []byte("- [x] import vault\n"), fileContents(t, "todo (1).md"))
+	}
+	assert.Equal(t, []byte("- [ ] import vault\n"), fileContents(t, "todo.md"))
+
+	// overwrite changed files
+	overwrite := api.Overwrite
+	report, results = readReport(t, importArchive(t, conflicting, api.PostImportParams{Conflict: &overwrite}))
+	assert.Equal(t, 1, report.Overwritten)
+	assert.Equal(t, api.Overwritten, results["todo.md"].Status)
+	assert.Equal(t, []byte("- [x] import vault\n"), fileContents(t, "todo.md"))
+
+	// unauthenticated requests are rejected
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import", bytes.NewReader(conflicting))
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PostImport(e.NewContext(req, rec), api.PostImportParams{})) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestPostImportAtomic(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-import-atomic")
+	fstore, err := filestore.NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	store := &hookedFileStore{FileStore: fstore}
+	srv := NewServerWithFileStore(db, store)
+
+	importArchive := func(archive []byte, conflict api.PostImportParamsConflict) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/import", bytes.NewReader(archive))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostImport(e.NewContext(req, rec), api.PostImportParams{Conflict: &conflict})) {
+			t.FailNow()
+		}
+		return rec
+	}
+	filenames := func() []string {
+		t.Helper()
+		syncFiles, err := database.GetSyncFilesByUserId(db, user.Id)
+		assert.NoError(t, err)
+		filenames := []string{}
+		for _, syncFile := range syncFiles {
+			filenames = append(filenames, syncFile.Filepath)
+		}
+		return filenames
+	}
+
+	rec := importArchive(createZip(t, []zipEntry{{"todo.md", []byte("old")}}), api.Skip)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	archive := createZip(t, []zipEntry{
+		{"todo.md", []byte("new")},
+		{"notes/new.md", []byte("new")},
+		{"fail.md", []byte("fail")},
+	})
+	failPath := testFilePath(t, user.Id, "fail.md")
+	store.beforeSave = func(filePath string) error {
+		if filePath == failPath {
+			return errors.New("disk is full")
+		}
+		return nil
+	}
+
+	// files written before the failure are put back, and no sync records
+	// are added
+	for _, conflict := range []api.PostImportParamsConflict{api.Overwrite, api.KeepBoth} {
+		rec = importArchive(archive, conflict)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code, conflict)
+		assert.ElementsMatch(t, []string{"todo.md"}, filenames(), conflict)
+		data, err := store.LoadFile(testFilePath(t, user.Id, "todo.md"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte("old"), data)
+		}
+		_, err = store.LoadFile(testFilePath(t, user.Id, "notes/new.md"))
+		assert.ErrorIs(t, err, filestore.ErrFileNotFound)
+	}
+
+	// retrying a failed import doesn't leave extra copies behind, and copies
+	// don't take the names of other files in the archive
+	store.beforeSave = nil
+	archive = createZip(t, []zipEntry{
+		{"todo.md", []byte("new")},
+		{"todo (1).md", []byte("copy")},
+		{"notes/new.md", []byte("new")},
+		{"fail.md", []byte("fail")},
+	})
+	rec = importArchive(archive, api.KeepBoth)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		var report api.ImportReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, 4, report.Created)
+	}
+	assert.ElementsMatch(t, []string{"todo.md", "todo (1).md", "todo (2).md", "notes/new.md", "fail.md"}, filenames())
+	data, err := store.LoadFile(testFilePath(t, user.Id, "todo (2).md"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte("new"), data)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestPostImportRejectsArchives(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-import-rejects")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv.SetImportLimits(ImportLimits{MaxEntries: 3, MaxSize: 4096})
+
+	badConflict := api.PostImportParamsConflict("merge")
+	testCases := []struct {
+		name     string
+		archive  []byte
+		params   api.PostImportParams
+		wantCode int
+	}{
+		{
+			name:     "not a zip archive",
+			archive:  []byte("# Process Scheduling\n"),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unsupported conflict policy",
+			archive:  createZip(t, []zipEntry{{"todo.md", []byte("todo")}}),
+			params:   api.PostImportParams{Conflict: &badConflict},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "path outside of the vault",
+			archive:  createZip(t, []zipEntry{{"todo.md", []byte("todo")}, {"../../etc/passwd", []byte("root")}}),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "duplicate paths",
+			archive:  createZip(t, []zipEntry{{"todo.md", []byte("todo")}, {"./todo.md", []byte("todo")}}),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "absolute path",
+			archive:  createZip(t, []zipEntry{{"/etc/passwd", []byte("root")}}),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "windows path",
+			archive:  createZip(t, []zipEntry{{`..\..\etc\passwd`, []byte("root")}}),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "too many files",
+			archive: createZip(t, []zipEntry{
+				{"1.md", nil}, {"2.md", nil}, {"3.md", nil}, {"4.md", nil},
+			}),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "too much data once decompressed",
+			archive: createZip(t, []zipEntry{
+				{"bomb.md", []byte(strings.Repeat("a", 8192))},
+			}),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:     "archive is too large",
+			archive:  bytes.Repeat([]byte{0}, 8192),
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/import", bytes.NewReader(tc.archive))
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			if assert.NoError(t, srv.PostImport(e.NewContext(req, rec), tc.params)) {
+				assert.Equal(t, tc.wantCode, rec.Code)
+			}
+		})
+	}
+
+	// rejected archives don't import any files
+	_, err = database.GetUserSyncFileByFilepath(db, user.Id, "todo.md")
+	assert.ErrorIs(t, err, database.ErrNoResults)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestCleanFilename(t *testing.T) {
+	t.Parallel()
+
+	for filename, want := range map[string]string{
+		"note.md":             "note.md",
+		"./school/notes.md":   "school/notes.md",
+		"school//os/a.md":     "school/os/a.md",
+		"school/./os/../a.md": "",
+		"../note.md":          "",
+		"/note.md":            "",
+		`school\note.md`:      "",
+		"C:note.md":           "",
+		".":                   "",
+		"":                    "",
+	} {
+		cleaned, err := cleanFilename(filename)
+		if len(want) == 0 {
+			assert.ErrorIs(t, err, ErrUnsafePath, filename)
+		} else if assert.NoError(t, err, filename) {
+			assert.Equal(t, want, cleaned)
+		}
+	}
+}
+
+func TestUserFilePath(t *testing.T) {
+	t.Parallel()
+
+	filePath, err := userFilePath(7, "school/notes.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, filepath.Join("7", "school", "notes.md"), filePath)
+	}
+	for _, filename := range []string{"", ".", "..", "../1/secret.md", "school/../../1/secret.md"} {
+		_, err := userFilePath(7, filename)
+		assert.ErrorIs(t, err, ErrUnsafePath, filename)
+	}
+}
+
+func createZip(t *testing.T, entries []zipEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		file, err := writer.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Deflate})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		_, err = file.Write(entry.data)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+)
+
+const (
+	// Invites made without an expiry or a use limit get these
+	DefaultInviteLifetime = 7 * 24 * time.Hour
+	DefaultInviteUses     = 1
+	// Users who aren't admins can't make invites that last longer or can be
+	// used more than this
+	MaxInviteLifetime = 30 * 24 * time.Hour
+	MaxInviteUses     = 10
+)
+
+// Get the user's invites
+// (GET /invites)
+func (o *ObsyncServer) GetInvites(ctx echo.Context) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	invites, err := database.GetUserInvites(o.db, userId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	apiInvites := make([]api.Invite, 0, len(invites))
+	for _, invite := range invites {
+		apiInvites = append(apiInvites, toApiInvite(invite))
+	}
+	return ctx.JSON(http.StatusOK, apiInvites)
+}
+
+// Create an invite
+// (POST /invites)
+func (o *ObsyncServer) PostInvites(ctx echo.Context) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	user, err := database.GetUserById(o.db, userId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	var body api.InviteCreate
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	now := time.Now()
+	invite := &database.Invite{
+		UserId:  userId,
+		Expires: now.Add(DefaultInviteLifetime),
+		MaxUses: DefaultInviteUses,
+	}
+	if body.ExpiresAt != nil {
+		if !body.ExpiresAt.After(now) {
+			return sendApiMessage(ctx, http.StatusBadRequest, "expires_at must be in the future")
+		}
+		if !user.IsAdmin && body.ExpiresAt.After(now.Add(MaxInviteLifetime)) {
+			return sendApiMessage(ctx, http.StatusBadRequest, "expires_at can be at most 30 days away")
+		}
+		invite.Expires = *body.ExpiresAt
+	}
+	if body.MaxUses != nil {
+		if *body.MaxUses < 1 {
+			return sendApiMessage(ctx, http.StatusBadRequest, "max_uses must be at least 1")
+		}
+		if !user.IsAdmin && *body.MaxUses > MaxInviteUses {
+			return sendApiMessage(ctx, http.StatusBadRequest, "max_uses can be at most 10")
+		}
+		invite.MaxUses = *body.MaxUses
+	}
+
+	invite, err = database.CreateInvite(o.db, invite)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return ctx.JSON(http.StatusCreated, toApiInvite(invite))
+}
+
+// Revoke an invite
+// (DELETE /invites/{id})
+func (o *ObsyncServer) DeleteInvitesId(ctx echo.Context, id int64) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	if err := database.DeleteInvite(o.db, userId, uint64(id)); err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "invite not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return sendApiMessage(ctx, http.StatusOK, "invite revoked")
+}
+
+func toApiInvite(invite *database.Invite) api.Invite {
+	apiInvite := api.Invite{
+		Id:        int64(invite.Id),
+		Code:      invite.Code,
+		Uses:      invite.Uses,
+		CreatedAt: invite.CreatedAt,
+	}
+	if !invite.Expires.IsZero() {
+		apiInvite.ExpiresAt = &invite.Expires
+	}
+	if invite.MaxUses > 0 {
+		apiInvite.MaxUses = &invite.MaxUses
+	}
+	return apiInvite
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, userCookie := createTestSession(t, db, "test-invite-routes-user")
+	admin, adminCookie := createTestSession(t, db, "test-invite-routes-admin")
+	assert.NoError(t, database.SetUserAdmin(db, admin.Id, true))
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	request := func(cookie *http.Cookie, body io.Reader, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	createInvite := func(cookie *http.Cookie, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(cookie, strings.NewReader(body), srv.PostInvites)
+	}
+
+	rec := createInvite(nil, `{}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = createInvite(userCookie, `{}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	invite := api.Invite{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invite))
+	assert.NotEmpty(t, invite.Code)
+	// invites expire and are used up by default
+	if assert.NotNil(t, invite.ExpiresAt) && assert.NotNil(t, invite.MaxUses) {
+		assert.WithinDuration(t, time.Now().Add(DefaultInviteLifetime), *invite.ExpiresAt, time.Minute)
+		assert.Equal(t, DefaultInviteUses, *invite.MaxUses)
+	}
+
+	later := time.Now().Add(60 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	earlier := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	for _, tc := range []struct {
+		cookie   *http.Cookie
+		body     string
+		wantCode int
+	}{
+		{userCookie, `{"max_uses": 5, "expires_at": "` + time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`, http.StatusCreated},
+		{userCookie, `{"max_uses": 0}`, http.StatusBadRequest},
+		{userCookie, fmt.Sprintf(`{"max_uses": %d}`, MaxInviteUses+1), http.StatusBadRequest},
+		{userCookie, `{"expires_at": "` + later + `"}`, http.StatusBadRequest},
+		{userCookie, `{"expires_at": "` + earlier + `"}`, http.StatusBadRequest},
+		{userCookie, `not json`, http.StatusBadRequest},
+		// admins can make invites that last longer and can be used more
+		{adminCookie, fmt.Sprintf(`{"max_uses": %d, "expires_at": "%s"}`, MaxInviteUses+1, later), http.StatusCreated},
+	} {
+		rec := createInvite(tc.cookie, tc.body)
+		assert.Equal(t, tc.wantCode, rec.Code, tc.body)
+	}
+
+	rec = request(userCookie, nil, srv.GetInvites)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	invites := []api.Invite{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invites))
+	if assert.Len(t, invites, 2) {
+		assert.Equal(t, invite.Id, invites[1].Id)
+		assert.Equal(t, 5, *invites[0].MaxUses)
+	}
+
+	rec = request(adminCookie, nil, func(ctx echo.Context) error {
+		return srv.DeleteInvitesId(ctx, invite.Id)
+	})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = request(userCookie, nil, func(ctx echo.Context) error {
+		return srv.DeleteInvitesId(ctx, invite.Id)
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = request(userCookie, nil, srv.GetInvites)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &invites))
+	assert.Len(t, invites, 1)
+
+	for _, user := range []*database.User{user, admin} {
+		assert.NoError(t, database.DeleteUser(db, user.Id))
+	}
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/markdown"
+)
+
+// Get the links to a file from other notes
+// (GET /files/{filename}/backlinks)
+func (o *ObsyncServer) GetFilesFilenameBacklinks(ctx echo.Context, filename string) error {
+	return o.sendLinks(ctx, filename, database.GetBacklinks)
+}
+
+// Get the links in a note
+// (GET /files/{filename}/outlinks)
+func (o *ObsyncServer) GetFilesFilenameOutlinks(ctx echo.Context, filename string) error {
+	return o.sendLinks(ctx, filename, database.GetOutlinks)
+}
+
+func (o *ObsyncServer) sendLinks(
+	ctx echo.Context,
+	filename string,
+	getLinks func(db *sql.DB, fileId uint64) ([]*database.Link, error),
+) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	links, err := getLinks(o.db, syncFile.Id)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiLinks := make([]api.Link, 0, len(links))
+	for _, link := range links {
+		apiLinks = append(apiLinks, toApiLink(link))
+	}
+	return ctx.JSON(http.StatusOK, apiLinks)
+}
+
+// Get the graph of links between files
+// (GET /graph)
+func (o *ObsyncServer) GetGraph(ctx echo.Context, params api.GetGraphParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	syncFiles, err := database.GetSyncFilesByUserId(o.db, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if params.Folder != nil {
+		syncFiles = filterFolder(syncFiles, *params.Folder)
+	}
+	links, err := database.GetUserLinks(o.db, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	graph := api.Graph{
+		Nodes: make([]api.GraphNode, 0, len(syncFiles)),
+		Edges: []api.GraphEdge{},
+	}
+	inGraph := make(map[uint64]bool, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		inGraph[syncFile.Id] = true
+		graph.Nodes = append(graph.Nodes, api.GraphNode{
+			Id:       int64(syncFile.Id),
+			Filename: syncFile.Filepath,
+		})
+	}
+
+	type edgeKey struct{ source, target uint64 }
+	edges := make(map[edgeKey]int)
+	for _, link := range links {
+		if !inGraph[link.SourceId] || !inGraph[link.TargetId] {
+			continue
+		}
+		key := edgeKey{link.SourceId, link.TargetId}
+		if _, ok := edges[key]; !ok {
+			edges[key] = len(graph.Edges)
+			graph.Edges = append(graph.Edges, api.GraphEdge{
+				Source: int64(link.SourceId),
+				Target: int64(link.TargetId),
+			})
+		}
+		graph.Edges[edges[key]].Count++
+	}
+
+	return ctx.JSON(http.StatusOK, graph)
+}
+
+// Parse the links in a note after it's created or changed, and resolve them to
+// the user's files.
+func (o *ObsyncServer) updateLinks(ctx echo.Context, syncFile *database.SyncFile, data []byte) {
+	if !isMarkdownFile(syncFile.Filepath) {
+		return
+	}
+	filesByPath, filepaths, err := o.userFilepaths(syncFile.UserId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return
+	}
+
+	parsed := markdown.ParseLinks(string(data))
+	links := make([]*database.Link, 0, len(parsed))
+	for _, link := range parsed {
+		dbLink := &database.Link{
+			Target:     link.Target,
+			TargetName: markdown.LinkName(link.Target),
+			Subpath:    link.Subpath,
+			Alias:      link.Alias,
+			Embed:      link.Embed,
+			Markdown:   link.Markdown,
+		}
+		if resolved, ok := markdown.ResolveLink(link, syncFile.Filepath, filepaths); ok {
+			dbLink.TargetId = filesByPath[resolved]
+		}
+		links = append(links, dbLink)
+	}
+
+	if err := database.ReplaceLinks(o.db, syncFile.Id, syncFile.UserId, links); err != nil {
+		ctx.Logger().Print(err)
+	}
+}
+
+// Resolve the links that could point to files at the given paths again, after
+// files are created, renamed or deleted at those paths.
+func (o *ObsyncServer) resolveLinksTo(ctx echo.Context, userId uint64, filePaths ...string) {
+	names := make([]string, 0, len(filePaths))
+	for _, filePath := range filePaths {
+		names = append(names, markdown.FileLinkName(filePath))
+	}
+	links, err := database.GetUserLinksByTargetName(o.db, userId, names...)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return
+	}
+	o.resolveLinks(ctx, userId, links)
+}
+
+// Update the link graph after a file is renamed. Links to the file's old and
+// new names might resolve differently, and so might relative links in the
+// file itself.
+func (o *ObsyncServer) fileRenamed(ctx echo.Context, syncFile *database.SyncFile, oldFilepath string) {
+	o.resolveLinksTo(ctx, syncFile.UserId, oldFilepath, syncFile.Filepath)
+
+	outlinks, err := database.GetOutlinks(o.db, syncFile.Id)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return
+	}
+	o.resolveLinks(ctx, syncFile.UserId, outlinks)
+}
+
+func (o *ObsyncServer) resolveLinks(ctx echo.Context, userId uint64, links []*database.Link) {
+	if len(links) == 0 {
+		return
+	}
+	filesByPath, filepaths, err := o.userFilepaths(userId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return
+	}
+
+	for _, link := range links {
+		var targetId uint64
+		resolved, ok := markdown.ResolveLink(
+			markdown.Link{Target: link.Target, Markdown: link.Markdown},
+			link.SourcePath,
+			filepaths,
+		)
+		if ok {
+			targetId = filesByPath[resolved]
+		}
+		if targetId == link.TargetId {
+			continue
+		}
+		if err := database.SetLinkTarget(o.db, link.Id, targetId); err != nil {
+			ctx.Logger().Print(err)
+		}
+	}
+}
+
+// Get the paths of a user's files, along with a map of the paths to their
+// sync file ids.
+func (o *ObsyncServer) userFilepaths(userId uint64) (map[string]uint64, []string, error) {
+	syncFiles, err := database.GetSyncFilesByUserId(o.db, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filesByPath := make(map[string]uint64, len(syncFiles))
+	filepaths := make([]string, 0, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		filesByPath[syncFile.Filepath] = syncFile.Id
+		filepaths = append(filepaths, syncFile.Filepath)
+	}
+	return filesByPath, filepaths, nil
+}
+
+func toApiLink(link *database.Link) api.Link {
+	apiLink := api.Link{
+		Source: link.SourcePath,
+		Link:   link.Target,
+		Embed:  link.Embed,
+	}
+	if link.TargetId != 0 {
+		apiLink.Target = &link.TargetPath
+	}
+	if len(link.Subpath) > 0 {
+		apiLink.Subpath = &link.Subpath
+	}
+	if len(link.Alias) > 0 {
+		apiLink.Alias = &link.Alias
+	}
+	return apiLink
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-links")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	upload := func(t *testing.T, filename, content string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBufferString(content))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) ||
+			!assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+	}
+	getLinks := func(t *testing.T, filename string, handler func(echo.Context, string) error) []api.Link {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/files/"+url.PathEscape(filename)+"/links", nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec), filename)) ||
+			!assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+		var links []api.Link
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &links))
+		return links
+	}
+	backlinkSources := func(t *testing.T, filename string) []string {
+		t.Helper()
+		sources := []string{}
+		for _, link := range getLinks(t, filename, srv.GetFilesFilenameBacklinks) {
+			sources = append(sources, link.Source)
+		}
+		return sources
+	}
+	rename := func(t *testing.T, filename, newFilename string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(api.FileRename{Filename: newFilename})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename)+"/rename", bytes.NewBuffer(body))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilenameRename(e.NewContext(req, rec), filename)) {
+			t.FailNow()
+		}
+		return rec
+	}
+
+	// links to files that don't exist yet are resolved once they're uploaded
+	upload(t, "school/os/Scheduling.md", "Uses [[Round Robin|RR]] and ![[diagram.png]], see [paging](../Paging.md).")
+	upload(t, "school/os/Round Robin.md", "Back to [[Scheduling#Overview]].")
+	upload(t, "school/Paging.md", "# Paging")
+	upload(t, "school/os/diagram.png", "png")
+
+	outlinks := getLinks(t, "school/os/Scheduling.md", srv.GetFilesFilenameOutlinks)
+	if assert.Len(t, outlinks, 3) {
+		assert.Equal(t, "Round Robin", outlinks[0].Link)
+		assert.Equal(t, "RR", *outlinks[0].Alias)
+		assert.Equal(t, "school/os/Round Robin.md", *outlinks[0].Target)
+		assert.True(t, outlinks[1].Embed)
+		assert.Equal(t, "school/os/diagram.png", *outlinks[1].Target)
+		assert.Equal(t, "school/Paging.md", *outlinks[2].Target)
+	}
+	backlinks := getLinks(t, "school/os/Scheduling.md", srv.GetFilesFilenameBacklinks)
+	if assert.Len(t, backlinks, 1) {
+		assert.Equal(t, "school/os/Round Robin.md", backlinks[0].Source)
+		assert.Equal(t, "#Overview", *backlinks[0].Subpath)
+	}
+
+	// renaming a file keeps its backlinks when the links still point to it
+	rec := rename(t, "school/os/Round Robin.md", "school/algorithms/Round Robin.md")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"school/os/Scheduling.md"}, backlinkSources(t, "school/algorithms/Round Robin.md"))
+
+	// relative links are resolved again when the note they're in moves
+	rec = rename(t, "school/os/Scheduling.md", "school/Scheduling.md")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	outlinks = getLinks(t, "school/Scheduling.md", srv.GetFilesFilenameOutlinks)
+	if assert.Len(t, outlinks, 3) {
+		assert.Nil(t, outlinks[2].Target)
+	}
+
+	// links stop resolving to a file once it's renamed to something else
+	rec = rename(t, "school/algorithms/Round Robin.md", "school/algorithms/RR.md")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, backlinkSources(t, "school/algorithms/RR.md"))
+
+	// a file with the same name takes over the links to a deleted file
+	upload(t, "attachments/diagram.png", "png")
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/files/school%2Fos%2Fdiagram.png", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.DeleteFilesFilename(e.NewContext(req, rec), "school/os/diagram.png")) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, []string{"school/Scheduling.md"}, backlinkSources(t, "attachments/diagram.png"))
+
+	// the graph of the school folder
+	folder := "school"
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/graph?folder=school", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetGraph(e.NewContext(req, rec), api.GetGraphParams{Folder: &folder})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var graph api.Graph
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &graph))
+		filenames := make(map[int64]string)
+		for _, node := range graph.Nodes {
+			filenames[node.Id] = node.Filename
+		}
+		assert.ElementsMatch(t, []string{"school/Scheduling.md", "school/algorithms/RR.md", "school/Paging.md"}, mapValues(filenames))
+		// the link from RR.md to Scheduling.md still resolves after both moved
+		if assert.Len(t, graph.Edges, 1) {
+			assert.Equal(t, "school/algorithms/RR.md", filenames[graph.Edges[0].Source])
+			assert.Equal(t, "school/Scheduling.md", filenames[graph.Edges[0].Target])
+			assert.Equal(t, 1, graph.Edges[0].Count)
+		}
+	}
+
+	// missing files
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/files/missing.md/backlinks", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetFilesFilenameBacklinks(e.NewContext(req, rec), "missing.md")) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestPostFilesFilenameRename(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-rename")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, filename := range []string{"note.md", "other.md"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+filename, bytes.NewBufferString("# "+filename))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) {
+			t.FailNow()
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		filename    string
+		body        string
+		wantCode    int
+		wantRenamed string
+	}{
+		{
+			name:     "invalid body",
+			filename: "note.md",
+			body:     "not json",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unsafe filename",
+			filename: "note.md",
+			body:     `{"filename": "../note.md"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "file doesn't exist",
+			filename: "missing.md",
+			body:     `{"filename": "found.md"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "new filename is taken",
+			filename: "note.md",
+			body:     `{"filename": "other.md"}`,
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:        "rename into a folder",
+			filename:    "note.md",
+			body:        `{"filename": "notes/note.md"}`,
+			wantCode:    http.StatusOK,
+			wantRenamed: "notes/note.md",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+tc.filename+"/rename", bytes.NewBufferString(tc.body))
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			if !assert.NoError(t, srv.PostFilesFilenameRename(e.NewContext(req, rec), tc.filename)) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.wantCode, rec.Code)
+			if len(tc.wantRenamed) > 0 {
+				_, err := database.GetUserSyncFileByFilepath(db, user.Id, tc.filename)
+				assert.ErrorIs(t, err, database.ErrNoResults)
+				syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, tc.wantRenamed)
+				if assert.NoError(t, err) {
+					data, err := srv.fstore.LoadFile(testFilePath(t, user.Id, syncFile.Filepath))
+					assert.NoError(t, err)
+					assert.Equal(t, []byte("# "+tc.filename), data)
+				}
+			}
+		})
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func mapValues(m map[int64]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
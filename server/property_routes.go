@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/markdown"
+)
+
+const maxQueryLimit = 1000
+
+// Get the frontmatter properties of a note
+// (GET /files/{filename}/properties)
+func (o *ObsyncServer) GetFilesFilenameProperties(ctx echo.Context, filename string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	fileProperties, err := o.fileProperties(syncFile)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	message, err := database.GetPropertyError(o.db, syncFile.Id)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if len(message) > 0 {
+		fileProperties.Error = &message
+	}
+
+	return ctx.JSON(http.StatusOK, fileProperties)
+}
+
+// Query notes by their frontmatter properties
+// (GET /query)
+func (o *ObsyncServer) GetQuery(ctx echo.Context, params api.GetQueryParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	var query database.PropertyQuery
+	if params.Where != nil {
+		for _, where := range *params.Where {
+			condition, err := parsePropertyCondition(where)
+			if err != nil {
+				return sendApiMessage(ctx, http.StatusBadRequest, err.Error())
+			}
+			query.Conditions = append(query.Conditions, condition)
+		}
+	}
+	if params.Sort != nil {
+		if query.Sort, err = parsePropertySort(*params.Sort); err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, err.Error())
+		}
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxQueryLimit {
+			return sendApiMessage(ctx, http.StatusBadRequest, "limit must be between 1 and 1000")
+		}
+		query.Limit = *params.Limit
+	}
+
+	syncFiles, err := database.QueryProperties(o.db, vault.OwnerId, query)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	results := make([]api.FileProperties, 0, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		fileProperties, err := o.fileProperties(syncFile)
+		if err != nil {
+			ctx.Logger().Print(err)
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+		results = append(results, fileProperties)
+	}
+
+	return ctx.JSON(http.StatusOK, results)
+}
+
+// Get the notes with frontmatter that couldn't be parsed
+// (GET /properties/errors)
+func (o *ObsyncServer) GetPropertiesErrors(ctx echo.Context) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	propertyErrors, err := database.GetPropertyErrors(o.db, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiErrors := make([]api.PropertyError, 0, len(propertyErrors))
+	for _, propertyError := range propertyErrors {
+		apiErrors = append(apiErrors, api.PropertyError{
+			Filename: propertyError.Filepath,
+			Message:  propertyError.Message,
+		})
+	}
+	return ctx.JSON(http.StatusOK, apiErrors)
+}
+
+// Parse the frontmatter properties of a note after it's created or changed.
+// Notes with frontmatter that isn't valid YAML lose their properties, and the
+// error is kept so it can be reported to the user.
+func (o *ObsyncServer) updateProperties(ctx echo.Context, syncFile *database.SyncFile, data []byte) {
+	if !isMarkdownFile(syncFile.Filepath) {
+		return
+	}
+
+	var message string
+	parsed, err := markdown.ParseProperties(string(data))
+	if err != nil {
+		message = err.Error()
+	}
+	properties := make([]*database.Property, 0, len(parsed))
+	for _, property := range parsed {
+		value, err := json.Marshal(property.Value)
+		if err != nil {
+			ctx.Logger().Print(err)
+			continue
+		}
+		properties = append(properties, &database.Property{
+			Key:   property.Key,
+			Name:  markdown.PropertyName(property.Key),
+			Type:  string(property.Type),
+			Value: string(value),
+		})
+	}
+
+	if err := database.ReplaceProperties(o.db, syncFile.Id, syncFile.UserId, properties); err != nil {
+		ctx.Logger().Print(err)
+	}
+	if err := database.SetPropertyError(o.db, syncFile.Id, syncFile.UserId, message); err != nil {
+		ctx.Logger().Print(err)
+	}
+}
+
+func (o *ObsyncServer) fileProperties(syncFile *database.SyncFile) (api.FileProperties, error) {
+	properties, err := database.GetSyncFileProperties(o.db, syncFile.Id)
+	if err != nil {
+		return api.FileProperties{}, err
+	}
+
+	fileProperties := api.FileProperties{
+		Filename:   syncFile.Filepath,
+		Properties: make(map[string]any, len(properties)),
+		Types:      make(map[string]api.FilePropertiesTypes, len(properties)),
+	}
+	for _, property := range properties {
+		var value any
+		if err := json.Unmarshal([]byte(property.Value), &value); err != nil {
+			return api.FileProperties{}, err
+		}
+		fileProperties.Properties[property.Key] = value
+		fileProperties.Types[property.Key] = api.FilePropertiesTypes(property.Type)
+	}
+	return fileProperties, nil
+}
+
+// Parse a condition like status=open or due<2024-06-01. A property name on its
+// own matches notes with the property, and !name matches notes without it.
+func parsePropertyCondition(where string) (database.PropertyCondition, error) {
+	where = strings.TrimSpace(where)
+	i := strings.IndexAny(where, "=!<>")
+	if i == 0 && strings.HasPrefix(where, "!") && !strings.ContainsAny(where[1:], "=!<>") {
+		name := strings.TrimSpace(where[1:])
+		if len(name) > 0 {
+			return database.PropertyCondition{
+				Name:     markdown.PropertyName(name),
+				Operator: database.PropertyMissing,
+			}, nil
+		}
+	}
+	if i == -1 && len(where) > 0 {
+		return database.PropertyCondition{
+			Name:     markdown.PropertyName(where),
+			Operator: database.PropertyExists,
+		}, nil
+	}
+
+	name := strings.TrimSpace(where[:max(i, 0)])
+	if len(name) == 0 {
+		return database.PropertyCondition{}, fmt.Errorf("condition %q is missing a property name", where)
+	}
+	var operator database.PropertyOperator
+	for _, op := range []database.PropertyOperator{
+		database.PropertyNotEquals,
+		database.PropertyLessOrEqual,
+		database.PropertyGreaterOrEqual,
+		database.PropertyEquals,
+		database.PropertyLess,
+		database.PropertyGreater,
+	} {
+		if strings.HasPrefix(where[i:], string(op)) {
+			operator = op
+			break
+		}
+	}
+	if len(operator) == 0 {
+		return database.PropertyCondition{}, fmt.Errorf("condition %q has an invalid operator", where)
+	}
+
+	value := strings.TrimSpace(where[i+len(operator):])
+	if len(value) == 0 && operator != database.PropertyEquals && operator != database.PropertyNotEquals {
+		return database.PropertyCondition{}, fmt.Errorf("condition %q is missing a value", where)
+	}
+	return database.PropertyCondition{
+		Name:     markdown.PropertyName(name),
+		Operator: operator,
+		Value:    value,
+	}, nil
+}
+
+// Parse a comma-separated list of properties to sort by, like due,-priority.
+func parsePropertySort(sort string) ([]database.PropertySort, error) {
+	var sorts []database.PropertySort
+	for _, name := range strings.Split(sort, ",") {
+		name = strings.TrimSpace(name)
+		descending := strings.HasPrefix(name, "-")
+		name = strings.TrimSpace(strings.TrimPrefix(name, "-"))
+		if len(name) == 0 {
+			return nil, fmt.Errorf("invalid sort %q", sort)
+		}
+		sorts = append(sorts, database.PropertySort{
+			Name:       markdown.PropertyName(name),
+			Descending: descending,
+		})
+	}
+	return sorts, nil
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPropertyRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-properties")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	files := map[string]string{
+		"projects/alpha.md": "---\nstatus: open\ndue: 2024-05-01\npriority: 2\n---\n# Alpha",
+		"projects/beta.md":  "---\nStatus: Done\ndue: 2024-04-01\npriority: 1\n---\n# Beta",
+		"projects/gamma.md": "---\nstatus: open\npriority: 3\ntags: [book]\n---\n# Gamma",
+		"broken.md":         "---\nstatus: [open\n---\n# Broken",
+		"plain.md":          "# No properties",
+	}
+	for filename, content := range files {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBufferString(content))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) {
+			t.FailNow()
+		}
+	}
+
+	getProperties := func(t *testing.T, filename string) api.FileProperties {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/files/"+url.PathEscape(filename)+"/properties", nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.GetFilesFilenameProperties(e.NewContext(req, rec), filename)) ||
+			!assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+		var properties api.FileProperties
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &properties))
+		return properties
+	}
+
+	properties := getProperties(t, "projects/gamma.md")
+	assert.Equal(t, map[string]any{"status": "open", "priority": float64(3), "tags": []any{"book"}}, properties.Properties)
+	assert.Equal(t, api.List, properties.Types["tags"])
+	assert.Nil(t, properties.Error)
+
+	// malformed frontmatter is reported
+	properties = getProperties(t, "broken.md")
+	assert.Empty(t, properties.Properties)
+	if assert.NotNil(t, properties.Error) {
+		assert.Contains(t, *properties.Error, "yaml")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/properties/errors", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	if assert.NoError(t, srv.GetPropertiesErrors(e.NewContext(req, rec))) {
+		var propertyErrors []api.PropertyError
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &propertyErrors))
+		if assert.Len(t, propertyErrors, 1) {
+			assert.Equal(t, "broken.md", propertyErrors[0].Filename)
+		}
+	}
+
+	// fixing the frontmatter clears the error
+	req = httptest.NewRequest(http.MethodPut, "/api/v1/files/broken.md", bytes.NewBufferString("---\nstatus: open\n---\n# Fixed"))
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PutFilesFilename(e.NewContext(req, rec), "broken.md", api.PutFilesFilenameParams{})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	properties = getProperties(t, "broken.md")
+	assert.Nil(t, properties.Error)
+	assert.Equal(t, map[string]any{"status": "open"}, properties.Properties)
+
+	strPtr := func(s string) *string { return &s }
+	intPtr := func(i int) *int { return &i }
+	testCases := []struct {
+		name      string
+		params    api.GetQueryParams
+		wantCode  int
+		wantFiles []string
+	}{
+		{
+			name:      "condition and sort",
+			params:    api.GetQueryParams{Where: &[]string{"status=open"}, Sort: strPtr("due")},
+			wantCode:  http.StatusOK,
+			wantFiles: []string{"projects/alpha.md", "broken.md", "projects/gamma.md"},
+		},
+		{
+			name:      "several conditions",
+			params:    api.GetQueryParams{Where: &[]string{"priority >= 2", "!tags"}},
+			wantCode:  http.StatusOK,
+			wantFiles: []string{"projects/alpha.md"},
+		},
+		{
+			name:      "property names are case-insensitive",
+			params:    api.GetQueryParams{Where: &[]string{"STATUS=done"}},
+			wantCode:  http.StatusOK,
+			wantFiles: []string{"projects/beta.md"},
+		},
+		{
+			name:      "descending sort with a limit",
+			params:    api.GetQueryParams{Sort: strPtr("-priority"), Limit: intPtr(2)},
+			wantCode:  http.StatusOK,
+			wantFiles: []string{"projects/gamma.md", "projects/alpha.md"},
+		},
+		{
+			name:     "missing property name",
+			params:   api.GetQueryParams{Where: &[]string{"=open"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing value",
+			params:   api.GetQueryParams{Where: &[]string{"due<"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid operator",
+			params:   api.GetQueryParams{Where: &[]string{"status!open"}},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid sort",
+			params:   api.GetQueryParams{Sort: strPtr("due,")},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid limit",
+			params:   api.GetQueryParams{Limit: intPtr(0)},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			if !assert.NoError(t, srv.GetQuery(e.NewContext(req, rec), tc.params)) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.wantCode, rec.Code)
+			if tc.wantCode == http.StatusOK {
+				var results []api.FileProperties
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results))
+				filenames := []string{}
+				for _, result := range results {
+					filenames = append(filenames, result.Filename)
+				}
+				assert.Equal(t, tc.wantFiles, filenames)
+			}
+		})
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var ErrInvalidTrustedProxy = errors.New("trusted proxies must be IP addresses or CIDR ranges")
+
+// Who can sign up with POST /user.
+type RegistrationMode string
+
+const (
+	// Anyone can sign up
+	RegistrationOpen RegistrationMode = "open"
+	// Only people with an invite code can sign up
+	RegistrationInvite RegistrationMode = "invite"
+	// No one can sign up, and only admins create users
+	RegistrationClosed RegistrationMode = "closed"
+)
+
+const (
+	DefaultSignupRateLimit  = 5
+	DefaultSignupRateWindow = time.Hour
+)
+
+type RegistrationPolicy struct {
+	Mode RegistrationMode
+	// Email domains users can sign up with, or empty to allow every domain
+	AllowedEmailDomains []string
+	// Number of sign-ups each IP address can attempt in RateWindow. Zero
+	// uses the default, and a negative limit turns rate limiting off.
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// Set who can sign up and how often. Policies without a mode are open.
+func (o *ObsyncServer) SetRegistration(policy RegistrationPolicy) {
+	if len(policy.Mode) == 0 {
+		policy.Mode = RegistrationOpen
+	}
+	if policy.RateLimit == 0 {
+		policy.RateLimit = DefaultSignupRateLimit
+	}
+	if policy.RateWindow <= 0 {
+		policy.RateWindow = DefaultSignupRateWindow
+	}
+	o.registration = policy
+}
+
+// Check whether an email address is in one of the allowed domains. Every
+// address is allowed when there are no allowed domains, and subdomains have
+// to be allowed separately.
+func emailDomainAllowed(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range domains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Requests each client made recently, for limiting how many requests a
+// client can make in a window of time.
+type rateLimiter struct {
+	mu       sync.Mutex
+	requests map[string][]time.Time
+}
+
+// Count a request from a client, returning false without counting it if the
+// client already made limit requests in the window before now.
+func (r *rateLimiter) allow(client string, limit int, window time.Duration, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.limitedLocked(client, limit, window, now) {
+		return false
+	}
+	r.requests[client] = append(r.requests[client], now)
+	return true
+}
+
+// Check whether a client already made limit requests in the window before
+// now, without counting a request. Used with add to only count requests that
+// fail.
+func (r *rateLimiter) limited(client string, limit int, window time.Duration, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.limitedLocked(client, limit, window, now)
+}
+
+// Count a request from a client.
+func (r *rateLimiter) add(client string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.requests == nil {
+		r.requests = map[string][]time.Time{}
+	}
+	r.requests[client] = append(r.requests[client], now)
+}
+
+func (r *rateLimiter) limitedLocked(client string, limit int, window time.Duration, now time.Time) bool {
+	if r.requests == nil {
+		r.requests = map[string][]time.Time{}
+	}
+
+	start := now.Add(-window)
+	for key, times := range r.requests {
+		recent := times[:0]
+		for _, t := range times {
+			if t.After(start) {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(r.requests, key)
+		} else {
+			r.requests[key] = recent
+		}
+	}
+	return len(r.requests[client]) >= limit
+}
+
+// Create the IP extractor rate limits identify clients with. Without trusted
+// proxies the address of the connection is used, since anyone can send an
+// X-Forwarded-For header. Behind trusted proxies, the client is the last
+// address in X-Forwarded-For that isn't a trusted proxy.
+func NewIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range trustedProxies {
+		ipNet, err := parseTrustedProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// Parse a trusted proxy's address or CIDR range.
+func parseTrustedProxy(proxy string) (*net.IPNet, error) {
+	if strings.Contains(proxy, "/") {
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, ErrInvalidTrustedProxy
+		}
+		return ipNet, nil
+	}
+	ip := net.ParseIP(proxy)
+	if ip == nil {
+		return nil, ErrInvalidTrustedProxy
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
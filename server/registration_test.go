@@ -0,0 +1,60 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailDomainAllowed(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		email   string
+		domains []string
+		want    bool
+	}{
+		{"alice@example.com", nil, true},
+		{"alice@example.com", []string{"example.com"}, true},
+		{"alice@Example.COM", []string{"example.com"}, true},
+		{"alice@example.com", []string{"@example.com"}, true},
+		{"alice@example.org", []string{"example.com", "example.org"}, true},
+		{"alice@mail.example.com", []string{"example.com"}, false},
+		{"alice@example.com.evil", []string{"example.com"}, false},
+		{"alice@evil.com@example.com", []string{"evil.com"}, false},
+		{"alice", []string{"example.com"}, false},
+	} {
+		assert.Equal(t, tc.want, emailDomainAllowed(tc.email, tc.domains), tc.email)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	var limiter rateLimiter
+	now := time.Now()
+	for range 3 {
+		assert.True(t, limiter.allow("192.0.2.1", 3, time.Hour, now))
+	}
+	assert.False(t, limiter.allow("192.0.2.1", 3, time.Hour, now.Add(time.Minute)))
+	// clients are limited separately
+	assert.True(t, limiter.allow("192.0.2.2", 3, time.Hour, now.Add(time.Minute)))
+	// refused requests aren't counted, so the window ends an hour after
+	// the first requests
+	assert.True(t, limiter.allow("192.0.2.1", 3, time.Hour, now.Add(time.Hour+time.Second)))
+	assert.Len(t, limiter.requests, 2)
+	assert.Len(t, limiter.requests["192.0.2.1"], 1)
+	// clients without recent requests are forgotten
+	assert.True(t, limiter.allow("192.0.2.3", 3, time.Hour, now.Add(3*time.Hour)))
+	assert.Len(t, limiter.requests, 1)
+
+	// requests can be counted separately from checking the limit
+	later := now.Add(4 * time.Hour)
+	for range 2 {
+		assert.False(t, limiter.limited("192.0.2.4", 2, time.Hour, later))
+		limiter.add("192.0.2.4", later)
+	}
+	assert.True(t, limiter.limited("192.0.2.4", 2, time.Hour, later))
+	assert.False(t, limiter.limited("192.0.2.4", 2, time.Hour, later.Add(time.Hour)))
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/markdown"
+)
+
+// Rendered notes can only load images from the server, in case anything
+// unsafe gets past the sanitizer.
+const renderContentSecurityPolicy = "default-src 'none'; img-src 'self'"
+
+// Render a note as HTML
+// (GET /files/{filename}/render)
+func (o *ObsyncServer) GetFilesFilenameRender(ctx echo.Context, filename string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if !isMarkdownFile(syncFile.Filepath) {
+		return sendApiMessage(ctx, http.StatusBadRequest, "only markdown files can be rendered")
+	}
+
+	filePath, err := userFilePath(vault.OwnerId, syncFile.Filepath)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	data, err := o.fstore.LoadFile(filePath)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	_, filepaths, err := o.userFilepaths(vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	// links can't send the vault header, so they name the vault they're in
+	// when it's someone else's
+	owner := ""
+	if vault.OwnerId != vault.UserId {
+		ownerUser, err := database.GetUserById(o.db, vault.OwnerId)
+		if err != nil {
+			ctx.Logger().Print(err)
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+		owner = ownerUser.Username
+	}
+
+	html, err := markdown.Render(string(data), func(link markdown.Link) (string, bool) {
+		resolved, ok := markdown.ResolveLink(link, syncFile.Filepath, filepaths)
+		if !ok {
+			return "", false
+		}
+		return fileURL(owner, resolved), true
+	})
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	ctx.Response().Header().Set("Content-Security-Policy", renderContentSecurityPolicy)
+	return ctx.HTMLBlob(http.StatusOK, html)
+}
+
+// Get the URL rendered notes link to a file with. Notes link to their
+// rendered HTML, and other files link to their contents. Files in another
+// user's vault name the vault's owner.
+func fileURL(owner, filePath string) string {
+	fileURL := BaseURL + "/files/" + url.PathEscape(filePath)
+	if isMarkdownFile(filePath) {
+		fileURL += "/render"
+	}
+	if len(owner) > 0 {
+		fileURL += "?" + url.Values{VaultParam: {owner}}.Encode()
+	}
+	return fileURL
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFilesFilenameRender(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-render")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	files := map[string]string{
+		"school/os/Scheduling.md":  "# Scheduling\nSee [[Round Robin]] and ![[diagram.png]].\n<script>alert(1)</script>",
+		"school/os/Round Robin.md": "# Round Robin",
+		"attachments/diagram.png":  "png",
+	}
+	for filename, content := range files {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBufferString(content))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) {
+			t.FailNow()
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		filename string
+		wantCode int
+		wantHTML string
+	}{
+		{
+			name:     "note",
+			filename: "school/os/Scheduling.md",
+			wantCode: http.StatusOK,
+			wantHTML: `<h1 id="scheduling">Scheduling</h1>` + "\n" +
+				`<p>See <a class="internal-link" href="/api/v1/files/school%2Fos%2FRound%20Robin.md/render" rel="nofollow">Round Robin</a> ` +
+				`and <img class="internal-embed" src="/api/v1/files/attachments%2Fdiagram.png" alt="diagram.png">.</p>` + "\n",
+		},
+		{
+			name:     "not a note",
+			filename: "attachments/diagram.png",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "missing file",
+			filename: "missing.md",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/files/"+url.PathEscape(tc.filename)+"/render", nil)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			if !assert.NoError(t, srv.GetFilesFilenameRender(e.NewContext(req, rec), tc.filename)) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.wantCode, rec.Code)
+			if len(tc.wantHTML) > 0 {
+				assert.Equal(t, tc.wantHTML, rec.Body.String())
+				assert.Equal(t, "text/html; charset=UTF-8", rec.Header().Get(echo.HeaderContentType))
+				assert.Equal(t, renderContentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+			}
+		})
+	}
+
+	// links in notes rendered through a shared vault open the same vault
+	member, memberCookie := createTestSession(t, db, "test-render-member")
+	assert.NoError(t, database.SetVaultMember(db, user.Id, member.Id, database.VaultViewer, nil))
+	api.RegisterHandlersWithBaseURL(e, srv, BaseURL)
+	get := func(target string, header bool) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header {
+			req.Header.Set(VaultHeader, user.Username)
+		}
+		req.AddCookie(memberCookie)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := get("/api/v1/files/"+url.PathEscape("school/os/Scheduling.md")+"/render", true)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, `<h1 id="scheduling">Scheduling</h1>`+"\n"+
+			`<p>See <a class="internal-link" href="/api/v1/files/school%2Fos%2FRound%20Robin.md/render?vault=test-render" rel="nofollow">Round Robin</a> `+
+			`and <img class="internal-embed" src="/api/v1/files/attachments%2Fdiagram.png?vault=test-render" alt="diagram.png">.</p>`+"\n",
+			rec.Body.String())
+	}
+	rec = get("/api/v1/files/school%2Fos%2FRound%20Robin.md/render?vault=test-render", false)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Contains(t, rec.Body.String(), "Round Robin")
+	}
+	rec = get("/api/v1/files/attachments%2Fdiagram.png?vault=test-render", false)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, "png", rec.Body.String())
+	}
+	// the member's own vault doesn't have the files
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/files/attachments%2Fdiagram.png", false).Code)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+	assert.NoError(t, database.DeleteUser(db, member.Id))
+}
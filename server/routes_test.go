@@ -53,3 +53,33 @@ func createTestDB(t *testing.T) *sql.DB {
 
 	return db
 }
+
+// Create a user with a session, returning the user and their session cookie.
+func createTestSession(t *testing.T, db *sql.DB, username string) (*database.User, *http.Cookie) {
+	user, err := database.CreateUser(db, username, username+"@example.com", "not a password")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := database.CreateSession(db, user.Id)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return user, &http.Cookie{
+		Name:     "OBSYNC_SESSION_ID",
+		Value:    session.SessionKey,
+		Expires:  session.Expires,
+		HttpOnly: true,
+		Path:     "/",
+	}
+}
+
+// Get the path of a user's file in the file store, failing the test if the
+// filename is unsafe.
+func testFilePath(t *testing.T, userId uint64, filename string) string {
+	filePath, err := userFilePath(userId, filename)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return filePath
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Search the contents of synced markdown files
+// (GET /search)
+func (o *ObsyncServer) GetSearch(ctx echo.Context, params api.GetSearchParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	if len(strings.TrimSpace(params.Q)) == 0 {
+		return sendApiMessage(ctx, http.StatusBadRequest, "missing search query")
+	}
+	query := database.SearchQuery{
+		Text:  params.Q,
+		Limit: defaultSearchLimit,
+	}
+	if params.Phrase != nil {
+		query.Phrase = *params.Phrase
+	}
+	if params.Prefix != nil {
+		query.Prefix = *params.Prefix
+	}
+	if params.Folder != nil {
+		query.Folder = *params.Folder
+	}
+	if params.Limit != nil {
+		if *params.Limit < 1 || *params.Limit > maxSearchLimit {
+			return sendApiMessage(ctx, http.StatusBadRequest, "limit must be between 1 and 100")
+		}
+		query.Limit = *params.Limit
+	}
+
+	results, err := database.SearchSyncFiles(o.db, vault.OwnerId, query)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrSearchUnavailable) {
+			return sendApiMessage(ctx, http.StatusNotImplemented, "full-text search is not available")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiResults := make([]api.SearchResult, 0, len(results))
+	for _, result := range results {
+		apiResults = append(apiResults, api.SearchResult{
+			Filename: result.Filepath,
+			Snippet:  highlightSnippet(result.Snippet),
+			Rank:     result.Rank,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, apiResults)
+}
+
+// Update the search index after a file is created or changed. Only markdown
+// files are indexed.
+func (o *ObsyncServer) updateSearchIndex(ctx echo.Context, syncFile *database.SyncFile, data []byte) {
+	if !isMarkdownFile(syncFile.Filepath) {
+		return
+	}
+	err := database.IndexSyncFile(o.db, syncFile, string(data))
+	if err != nil && !errors.Is(err, database.ErrSearchUnavailable) {
+		ctx.Logger().Print(err)
+	}
+}
+
+// Rebuild the search index from the contents of every user's markdown files
+// in the file store, returning the number of files indexed.
+func RebuildSearchIndex(db *sql.DB, fstore filestore.FileStore) (int, error) {
+	if err := database.ClearSearchIndex(db); err != nil {
+		return 0, err
+	}
+	syncFiles, err := database.GetAllSyncFiles(db)
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	for _, syncFile := range syncFiles {
+		if !isMarkdownFile(syncFile.Filepath) {
+			continue
+		}
+		filePath, err := userFilePath(syncFile.UserId, syncFile.Filepath)
+		if err != nil {
+			continue
+		}
+		data, err := fstore.LoadFile(filePath)
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			continue
+		} else if err != nil {
+			return indexed, err
+		}
+		if err := database.IndexSyncFile(db, syncFile, string(data)); err != nil {
+			return indexed, err
+		}
+		indexed++
+	}
+
+	return indexed, nil
+}
+
+// Escape a search snippet for HTML, then mark the matching terms in it.
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer(
+		database.SnippetMatchStart, "<mark>",
+		database.SnippetMatchEnd, "</mark>",
+	).Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSearch(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-search")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	search := func(t *testing.T, params api.GetSearchParams) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search", nil)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.GetSearch(e.NewContext(req, rec), params)) {
+			t.FailNow()
+		}
+		return rec
+	}
+
+	available, err := database.SearchAvailable(db)
+	assert.NoError(t, err)
+	if !available {
+		assert.Equal(t, http.StatusNotImplemented, search(t, api.GetSearchParams{Q: "round robin"}).Code)
+		t.Skip("SQLite was built without FTS5")
+	}
+
+	files := map[string][]byte{
+		"school/os/scheduling.md": []byte("# Scheduling\n\nProcesses take turns with <round robin> scheduling.\n"),
+		"school/os/diagram.png":   []byte("round robin"),
+		"todo.md":                 []byte("- [ ] study round robin\n"),
+	}
+	for filename, data := range files {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBuffer(data))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) ||
+			!assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+	}
+
+	// only markdown files are searched, and snippets are escaped
+	rec := search(t, api.GetSearchParams{Q: "robin"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var results []api.SearchResult
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results)) && assert.Len(t, results, 2) {
+		for _, result := range results {
+			if result.Filename == "school/os/scheduling.md" {
+				assert.Contains(t, result.Snippet, "&lt;round <mark>robin</mark>&gt;")
+			}
+		}
+	}
+
+	// updated files are reindexed
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files/todo.md", bytes.NewBufferString("- [x] study paging\n"))
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.PutFilesFilename(e.NewContext(req, rec), "todo.md", api.PutFilesFilenameParams{})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	folder, phrase, prefix, limit := "school", true, true, 5
+	rec = search(t, api.GetSearchParams{Q: "round rob", Phrase: &phrase, Prefix: &prefix, Folder: &folder, Limit: &limit})
+	results = nil
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results)) && assert.Len(t, results, 1) {
+		assert.Equal(t, "school/os/scheduling.md", results[0].Filename)
+	}
+
+	// deleted files are removed from the index
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/files/todo.md", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.DeleteFilesFilename(e.NewContext(req, rec), "todo.md")) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	rec = search(t, api.GetSearchParams{Q: "paging"})
+	assert.JSONEq(t, "[]", rec.Body.String())
+
+	// the index can be rebuilt from the file store
+	assert.NoError(t, database.ClearSearchIndex(db))
+	indexed, err := RebuildSearchIndex(db, srv.fstore)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, indexed, 1)
+	rec = search(t, api.GetSearchParams{Q: "scheduling"})
+	results = nil
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &results)) {
+		assert.Len(t, results, 1)
+	}
+
+	// invalid queries
+	zero := 0
+	assert.Equal(t, http.StatusBadRequest, search(t, api.GetSearchParams{Q: "  "}).Code)
+	assert.Equal(t, http.StatusBadRequest, search(t, api.GetSearchParams{Q: "robin", Limit: &zero}).Code)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	t.Parallel()
+
+	snippet := "a <b>" + database.SnippetMatchStart + "robin" + database.SnippetMatchEnd + " & more"
+	assert.Equal(t, "a &lt;b&gt;<mark>robin</mark> &amp; more", highlightSnippet(snippet))
+}
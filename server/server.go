@@ -2,14 +2,53 @@ package server
 
 import (
 	"database/sql"
+	"runtime"
+	"sync"
 
 	"github.com/raian621/obsync-server/api"
 	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/mailer"
+	"golang.org/x/net/webdav"
 )
 
+// Path the API is served under.
+const BaseURL = "/api/v1"
+
 type ObsyncServer struct {
-	db     *sql.DB
-	fstore filestore.FileStore
+	db           *sql.DB
+	fstore       filestore.FileStore
+	importLimits ImportLimits
+	// thumbnails being generated in the background, which take up one of
+	// the slots while they're generated
+	thumbnailJobs  sync.WaitGroup
+	thumbnailSlots chan struct{}
+	// held while thumbnails are cached, and while files are deleted along
+	// with their thumbnails
+	thumbnailMu sync.Mutex
+	// WebDAV locks of each vault, by the id of the vault's owner
+	davLocks   map[uint64]webdav.LockSystem
+	davLocksMu sync.Mutex
+	davLogins  loginCache
+	// failed WebDAV logins each client made for each username recently
+	davPasswords rateLimiter
+	// held while snapshots are taken, deleted or restored
+	snapshotMu sync.Mutex
+	// held for reading while a file and its sync record are changed
+	// together, and for writing while backups copy both
+	fileMu sync.RWMutex
+	// who can sign up, and the sign-ups each IP address attempted recently
+	registration RegistrationPolicy
+	signups      rateLimiter
+	// sends verification and password reset emails, or nil if email isn't
+	// set up
+	mailer    *mailer.Mailer
+	publicURL string
+	emails    rateLimiter
+	emailJobs sync.WaitGroup
+	// wrong share passwords each client gave recently, and the embeds rendered
+	// shared notes can load without counting another view
+	sharePasswords rateLimiter
+	shareEmbeds    shareEmbeds
 }
 
 // check that ObsyncServer implements ServerInterface:
@@ -20,8 +59,41 @@ func NewServer(db *sql.DB, rootDir string) (*ObsyncServer, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewServerWithFileStore(db, fstore), nil
+}
+
+func NewServerWithFileStore(db *sql.DB, fstore filestore.FileStore) *ObsyncServer {
 	return &ObsyncServer{
-		db,
-		fstore,
-	}, nil
+		db:     db,
+		fstore: fstore,
+		importLimits: ImportLimits{
+			MaxEntries: DefaultImportMaxEntries,
+			MaxSize:    DefaultImportMaxSize,
+		},
+		thumbnailSlots: make(chan struct{}, runtime.NumCPU()),
+		davLocks:       map[uint64]webdav.LockSystem{},
+		registration: RegistrationPolicy{
+			Mode:       RegistrationOpen,
+			RateLimit:  DefaultSignupRateLimit,
+			RateWindow: DefaultSignupRateWindow,
+		},
+	}
+}
+
+// Set the limits on archives uploaded to /import. Limits that are zero or
+// less are left at their current value.
+func (o *ObsyncServer) SetImportLimits(limits ImportLimits) {
+	if limits.MaxEntries > 0 {
+		o.importLimits.MaxEntries = limits.MaxEntries
+	}
+	if limits.MaxSize > 0 {
+		o.importLimits.MaxSize = limits.MaxSize
+	}
+}
+
+// Get the lock that keeps files and their sync records from changing. Holding
+// it waits for changes being made to finish, and blocks new ones until it's
+// released.
+func (o *ObsyncServer) FileLock() sync.Locker {
+	return &o.fileMu
 }
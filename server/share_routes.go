@@ -0,0 +1,453 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/markdown"
+)
+
+const (
+	// Shared files are served from the server's origin, so files served as
+	// they are get a sandbox that keeps HTML and SVG files from running
+	// scripts.
+	rawShareContentSecurityPolicy = "default-src 'none'; img-src 'self'; sandbox"
+	// Wrong passwords a client can give for a share in SharePasswordWindow
+	MaxSharePasswordAttempts = 10
+	SharePasswordWindow      = 15 * time.Minute
+	// How long the embeds of a rendered shared note can be loaded without
+	// counting another view
+	ShareEmbedLifetime = time.Hour
+)
+
+// Get the user's share links
+// (GET /shares)
+func (o *ObsyncServer) GetShares(ctx echo.Context) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	shares, err := database.GetUserShares(o.db, userId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiShares := make([]api.Share, 0, len(shares))
+	for _, share := range shares {
+		apiShares = append(apiShares, toApiShare(share))
+	}
+	return ctx.JSON(http.StatusOK, apiShares)
+}
+
+// Create a share link for a file or folder
+// (POST /shares)
+func (o *ObsyncServer) PostShares(ctx echo.Context) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	var body api.ShareCreate
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	if (body.Filename == nil) == (body.Folder == nil) {
+		return sendApiMessage(ctx, http.StatusBadRequest, "share either a filename or a folder")
+	}
+
+	share := &database.Share{UserId: userId}
+	if body.Filename != nil {
+		filename, err := cleanFilename(*body.Filename)
+		if err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+		}
+		syncFile, err := database.GetUserSyncFileByFilepath(o.db, userId, filename)
+		if err != nil {
+			ctx.Logger().Print(err)
+			if errors.Is(err, database.ErrNoResults) {
+				return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+			}
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+		share.FileId = syncFile.Id
+		share.Filepath = syncFile.Filepath
+	} else {
+		folder, err := cleanFilename(strings.Trim(*body.Folder, "/"))
+		if err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid folder")
+		}
+		syncFiles, err := database.GetSyncFilesByUserId(o.db, userId)
+		if err != nil {
+			ctx.Logger().Print(err)
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+		if len(filterFolder(syncFiles, folder)) == 0 {
+			return sendApiMessage(ctx, http.StatusNotFound, "folder not found")
+		}
+		share.Folder = folder
+	}
+	if body.ExpiresAt != nil {
+		if !body.ExpiresAt.After(time.Now()) {
+			return sendApiMessage(ctx, http.StatusBadRequest, "expires_at must be in the future")
+		}
+		share.Expires = *body.ExpiresAt
+	}
+	if body.MaxViews != nil {
+		if *body.MaxViews < 1 {
+			return sendApiMessage(ctx, http.StatusBadRequest, "max_views must be at least 1")
+		}
+		share.MaxViews = *body.MaxViews
+	}
+	var password string
+	if body.Password != nil {
+		password = *body.Password
+	}
+
+	share, err = database.CreateShare(o.db, share, password)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return ctx.JSON(http.StatusCreated, toApiShare(share))
+}
+
+// Revoke a share link
+// (DELETE /shares/{id})
+func (o *ObsyncServer) DeleteSharesId(ctx echo.Context, id int64) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	if err := database.DeleteShare(o.db, userId, uint64(id)); err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "share not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return sendApiMessage(ctx, http.StatusOK, "share revoked")
+}
+
+// View a shared file or folder
+// (GET /s/{token})
+func (o *ObsyncServer) GetSToken(ctx echo.Context, token string, params api.GetSTokenParams) error {
+	share, err := database.GetShareByToken(o.db, token)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "share not found")
+		}
+		if errors.Is(err, database.ErrShareExpired) {
+			return sendApiMessage(ctx, http.StatusGone, "share expired")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	if len(share.Passhash) > 0 {
+		// wrong passwords are counted for each share and client, and requests
+		// without a password are only asking for one
+		client := strconv.FormatUint(share.Id, 10) + " " + ctx.RealIP()
+		if o.sharePasswords.limited(client, MaxSharePasswordAttempts, SharePasswordWindow, time.Now()) {
+			return sendApiMessage(ctx, http.StatusTooManyRequests, "too many wrong passwords, try again later")
+		}
+		_, password, ok := ctx.Request().BasicAuth()
+		if err := database.ValidateHash(password, share.Passhash); err != nil {
+			if !errors.Is(err, database.ErrInvalidPassword) {
+				ctx.Logger().Print(err)
+			} else if ok {
+				o.sharePasswords.add(client, time.Now())
+			}
+			ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="share", charset="UTF-8"`)
+			return sendApiMessage(ctx, http.StatusUnauthorized, "share needs a password")
+		}
+	}
+
+	if len(share.Folder) > 0 && params.File == nil {
+		if err := database.AddShareView(o.db, share.Id); err != nil {
+			return sendShareViewError(ctx, err)
+		}
+		return o.sendSharedFolder(ctx, share)
+	}
+	syncFile, err := o.sharedFile(share, params.File)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	render := isMarkdownFile(syncFile.Filepath)
+	if params.Format != nil {
+		switch *params.Format {
+		case api.Html:
+			if !render {
+				return sendApiMessage(ctx, http.StatusBadRequest, "only markdown files can be rendered")
+			}
+		case api.Raw:
+			render = false
+		default:
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid format")
+		}
+	}
+
+	// embeds of a note that was just rendered can be loaded as often as the
+	// note is, since the note already counted as a view
+	viewKey := ""
+	if params.View != nil && o.shareEmbeds.allowed(*params.View, share.Id, syncFile.Filepath) {
+		viewKey = *params.View
+	} else if err := database.AddShareView(o.db, share.Id); err != nil {
+		return sendShareViewError(ctx, err)
+	}
+
+	if render {
+		if len(viewKey) == 0 {
+			if viewKey, err = o.shareEmbeds.start(share.Id); err != nil {
+				ctx.Logger().Print(err)
+				return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+			}
+		}
+		return o.sendSharedNote(ctx, share, syncFile, viewKey)
+	}
+	return o.sendSharedRawFile(ctx, syncFile)
+}
+
+// Get the file a share request is for. Shares of a file can also serve the
+// files embedded in the note, and shares of a folder serve files in the
+// folder.
+func (o *ObsyncServer) sharedFile(share *database.Share, file *string) (*database.SyncFile, error) {
+	if len(share.Folder) > 0 {
+		filename, err := cleanFilename(*file)
+		if err != nil {
+			return nil, database.ErrNoResults
+		}
+		return database.GetUserSyncFileByFilepath(o.db, share.UserId, share.Folder+"/"+filename)
+	}
+
+	if file == nil || *file == share.Filepath {
+		return database.GetSyncFileById(o.db, share.FileId)
+	}
+	if !isMarkdownFile(share.Filepath) {
+		return nil, database.ErrNoResults
+	}
+	outlinks, err := database.GetOutlinks(o.db, share.FileId)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range outlinks {
+		if link.Embed && link.TargetId != 0 && link.TargetPath == *file {
+			return database.GetSyncFileById(o.db, link.TargetId)
+		}
+	}
+	return nil, database.ErrNoResults
+}
+
+// Send the response for an error counting a view of a share.
+func sendShareViewError(ctx echo.Context, err error) error {
+	if errors.Is(err, database.ErrShareViewLimit) {
+		return sendApiMessage(ctx, http.StatusGone, "share reached its view limit")
+	}
+	ctx.Logger().Print(err)
+	return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+}
+
+// List the files in a shared folder, relative to the folder.
+func (o *ObsyncServer) sendSharedFolder(ctx echo.Context, share *database.Share) error {
+	syncFiles, err := database.GetSyncFilesByUserId(o.db, share.UserId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	syncFiles = filterFolder(syncFiles, share.Folder)
+	files := make([]api.SharedFile, 0, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		files = append(files, api.SharedFile{
+			Filename:  strings.TrimPrefix(syncFile.Filepath, share.Folder+"/"),
+			Size:      syncFile.Size,
+			UpdatedAt: syncFile.UpdatedAt,
+		})
+	}
+	return ctx.JSON(http.StatusOK, files)
+}
+
+// Render a shared note. Links only resolve to files the share gives access
+// to, so a shared note doesn't give away the names of other files. Embeds
+// are loaded with the key of the note's view.
+func (o *ObsyncServer) sendSharedNote(ctx echo.Context, share *database.Share, syncFile *database.SyncFile, viewKey string) error {
+	filePath, err := userFilePath(share.UserId, syncFile.Filepath)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+	}
+	data, err := o.fstore.LoadFile(filePath)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	_, filepaths, err := o.userFilepaths(share.UserId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	html, err := markdown.Render(string(data), func(link markdown.Link) (string, bool) {
+		resolved, ok := markdown.ResolveLink(link, syncFile.Filepath, filepaths)
+		if !ok {
+			return "", false
+		}
+		file := resolved
+		if len(share.Folder) > 0 {
+			if !strings.HasPrefix(resolved, share.Folder+"/") {
+				return "", false
+			}
+			file = strings.TrimPrefix(resolved, share.Folder+"/")
+		} else if !link.Embed {
+			return "", false
+		}
+		if !link.Embed {
+			return shareURL(share.Token, file), true
+		}
+		o.shareEmbeds.allow(viewKey, resolved)
+		return shareURL(share.Token, file) + "&view=" + url.QueryEscape(viewKey), true
+	})
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	ctx.Response().Header().Set("Content-Security-Policy", renderContentSecurityPolicy)
+	return ctx.HTMLBlob(http.StatusOK, html)
+}
+
+func (o *ObsyncServer) sendSharedRawFile(ctx echo.Context, syncFile *database.SyncFile) error {
+	filePath, err := userFilePath(syncFile.UserId, syncFile.Filepath)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+	}
+	file, err := filestore.OpenReadSeeker(o.fstore, filePath, syncFile.Size)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, filestore.ErrFileNotFound) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			ctx.Logger().Print(err)
+		}
+	}()
+
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderContentType, contentTypeForFile(syncFile.Filepath))
+	header.Set("Content-Security-Policy", rawShareContentSecurityPolicy)
+	header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+	http.ServeContent(ctx.Response(), ctx.Request(), syncFile.Filepath, syncFile.UpdatedAt, file)
+	return nil
+}
+
+// Get the URL of a share, or of a file in it.
+func shareURL(token, file string) string {
+	shareURL := BaseURL + "/s/" + url.PathEscape(token)
+	if len(file) > 0 {
+		shareURL += "?file=" + url.QueryEscape(file)
+	}
+	return shareURL
+}
+
+func toApiShare(share *database.Share) api.Share {
+	apiShare := api.Share{
+		Id:                int64(share.Id),
+		Token:             share.Token,
+		Url:               shareURL(share.Token, ""),
+		PasswordProtected: len(share.Passhash) > 0,
+		Views:             share.Views,
+		CreatedAt:         share.CreatedAt,
+	}
+	if share.FileId != 0 {
+		apiShare.Filename = &share.Filepath
+	}
+	if len(share.Folder) > 0 {
+		apiShare.Folder = &share.Folder
+	}
+	if !share.Expires.IsZero() {
+		apiShare.ExpiresAt = &share.Expires
+	}
+	if share.MaxViews > 0 {
+		apiShare.MaxViews = &share.MaxViews
+	}
+	return apiShare
+}
+
+// Views of rendered shared notes by their keys, with the embeds each note
+// can load without counting another view.
+type shareEmbeds struct {
+	mu    sync.Mutex
+	views map[string]*shareView
+}
+
+type shareView struct {
+	shareId uint64
+	// paths of the embedded files
+	files   map[string]bool
+	expires time.Time
+}
+
+// Start a view of a share, returning its key.
+func (s *shareEmbeds) start(shareId uint64) (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := base64.RawURLEncoding.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.views == nil {
+		s.views = map[string]*shareView{}
+	}
+	for key, view := range s.views {
+		if now.After(view.expires) {
+			delete(s.views, key)
+		}
+	}
+	s.views[key] = &shareView{shareId: shareId, files: map[string]bool{}, expires: now.Add(ShareEmbedLifetime)}
+	return key, nil
+}
+
+// Let a view load a file embedded in the note.
+func (s *shareEmbeds) allow(key, filepath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if view, ok := s.views[key]; ok {
+		view.files[filepath] = true
+	}
+}
+
+// Check whether a view of a share can load a file without counting another
+// view.
+func (s *shareEmbeds) allowed(key string, shareId uint64, filepath string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	view, ok := s.views[key]
+	return ok && view.shareId == shareId && view.files[filepath] && time.Now().Before(view.expires)
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-shares")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	files := map[string]string{
+		"projects/alpha.md":       "# Alpha\nSee [[beta]], [[private]] and ![[diagram.png]].\n\n![[chart.png]]",
+		"projects/beta.md":        "# Beta",
+		"projects/page.html":      "<script>alert(1)</script>",
+		"projects/chart.png":      "chart",
+		"attachments/diagram.png": "png",
+		"private.md":              "# Private",
+	}
+	for filename, content := range files {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBufferString(content))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) {
+			t.FailNow()
+		}
+	}
+
+	createShare := func(t *testing.T, body string) (*httptest.ResponseRecorder, api.Share) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/shares", bytes.NewBufferString(body))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostShares(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		var share api.Share
+		if rec.Code == http.StatusCreated {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &share))
+		}
+		return rec, share
+	}
+	view := func(t *testing.T, token string, params api.GetSTokenParams, password string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/s/"+token, nil)
+		if len(password) > 0 {
+			req.SetBasicAuth("", password)
+		}
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.GetSToken(e.NewContext(req, rec), token, params)) {
+			t.FailNow()
+		}
+		return rec
+	}
+	file := func(file string) api.GetSTokenParams { return api.GetSTokenParams{File: &file} }
+	embed := func(file, viewKey string) api.GetSTokenParams {
+		return api.GetSTokenParams{File: &file, View: &viewKey}
+	}
+	viewKeyPattern := regexp.MustCompile(`view=([A-Za-z0-9_-]+)`)
+	viewKeyOf := func(t *testing.T, rec *httptest.ResponseRecorder) string {
+		t.Helper()
+		match := viewKeyPattern.FindStringSubmatch(rec.Body.String())
+		if !assert.NotNil(t, match, rec.Body.String()) {
+			t.FailNow()
+		}
+		return match[1]
+	}
+	views := func(t *testing.T, token string) int {
+		t.Helper()
+		share, err := database.GetShareByToken(db, token)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		return share.Views
+	}
+
+	t.Run("invalid shares", func(t *testing.T) {
+		for body, wantCode := range map[string]int{
+			`not json`: http.StatusBadRequest,
+			`{}`:       http.StatusBadRequest,
+			`{"filename": "projects/alpha.md", "folder": "projects"}`:      http.StatusBadRequest,
+			`{"filename": "../alpha.md"}`:                                  http.StatusBadRequest,
+			`{"filename": "missing.md"}`:                                   http.StatusNotFound,
+			`{"folder": "missing"}`:                                        http.StatusNotFound,
+			`{"folder": "projects", "max_views": 0}`:                       http.StatusBadRequest,
+			`{"folder": "projects", "expires_at": "2000-01-01T00:00:00Z"}`: http.StatusBadRequest,
+		} {
+			rec, _ := createShare(t, body)
+			assert.Equal(t, wantCode, rec.Code, body)
+		}
+	})
+
+	t.Run("shared note", func(t *testing.T) {
+		rec, share := createShare(t, `{"filename": "projects/alpha.md", "password": "hunter22", "max_views": 3}`)
+		if !assert.Equal(t, http.StatusCreated, rec.Code) {
+			t.FailNow()
+		}
+		assert.True(t, share.PasswordProtected)
+		assert.Equal(t, "/api/v1/s/"+share.Token, share.Url)
+
+		rec = view(t, share.Token, api.GetSTokenParams{}, "")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), "Basic")
+		assert.Equal(t, http.StatusUnauthorized, view(t, share.Token, api.GetSTokenParams{}, "wrong").Code)
+
+		// only embeds resolve in a shared note, since other notes aren't shared
+		rec = view(t, share.Token, api.GetSTokenParams{}, "hunter22")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<span class="internal-link is-unresolved">beta</span>`)
+		viewKey := viewKeyOf(t, rec)
+		assert.Contains(t, rec.Body.String(), `src="/api/v1/s/`+share.Token+`?file=attachments%2Fdiagram.png&amp;view=`+viewKey+`"`)
+		assert.NotContains(t, rec.Body.String(), "private.md")
+		assert.Equal(t, 1, views(t, share.Token))
+
+		// embeds loaded by the rendered note don't count as views, but
+		// fetching them directly does, and other files aren't shared
+		rec = view(t, share.Token, embed("attachments/diagram.png", viewKey), "hunter22")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "png", rec.Body.String())
+		assert.Equal(t, 1, views(t, share.Token))
+		rec = view(t, share.Token, file("attachments/diagram.png"), "hunter22")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 2, views(t, share.Token))
+		assert.Equal(t, http.StatusNotFound, view(t, share.Token, file("private.md"), "hunter22").Code)
+
+		format := api.Raw
+		rec = view(t, share.Token, api.GetSTokenParams{Format: &format}, "hunter22")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, files["projects/alpha.md"], rec.Body.String())
+
+		// the view limit is reached, so only the embeds of the note that was
+		// already viewed load
+		assert.Equal(t, http.StatusGone, view(t, share.Token, api.GetSTokenParams{}, "hunter22").Code)
+		assert.Equal(t, http.StatusGone, view(t, share.Token, file("attachments/diagram.png"), "hunter22").Code)
+		assert.Equal(t, http.StatusGone, view(t, share.Token, embed("attachments/diagram.png", "not a key"), "hunter22").Code)
+		assert.Equal(t, http.StatusOK, view(t, share.Token, embed("attachments/diagram.png", viewKey), "hunter22").Code)
+		// view keys only cover the note's embeds
+		params := embed("projects/alpha.md", viewKey)
+		params.Format = &format
+		assert.Equal(t, http.StatusGone, view(t, share.Token, params, "hunter22").Code)
+	})
+
+	t.Run("share password attempts", func(t *testing.T) {
+		rec, share := createShare(t, `{"filename": "projects/beta.md", "password": "hunter22"}`)
+		if !assert.Equal(t, http.StatusCreated, rec.Code) {
+			t.FailNow()
+		}
+		// asking for the password isn't a wrong password
+		for range 3 {
+			assert.Equal(t, http.StatusUnauthorized, view(t, share.Token, api.GetSTokenParams{}, "").Code)
+		}
+		for i := 0; i < MaxSharePasswordAttempts; i++ {
+			assert.Equal(t, http.StatusUnauthorized, view(t, share.Token, api.GetSTokenParams{}, "wrong").Code)
+		}
+		assert.Equal(t, http.StatusTooManyRequests, view(t, share.Token, api.GetSTokenParams{}, "hunter22").Code)
+		assert.Equal(t, 0, views(t, share.Token))
+		assert.NoError(t, database.DeleteShare(db, user.Id, uint64(share.Id)))
+	})
+
+	t.Run("shared folder", func(t *testing.T) {
+		rec, share := createShare(t, `{"folder": "/projects/"}`)
+		if !assert.Equal(t, http.StatusCreated, rec.Code) {
+			t.FailNow()
+		}
+
+		rec = view(t, share.Token, api.GetSTokenParams{}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var sharedFiles []api.SharedFile
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &sharedFiles))
+		filenames := []string{}
+		for _, sharedFile := range sharedFiles {
+			filenames = append(filenames, sharedFile.Filename)
+		}
+		assert.ElementsMatch(t, []string{"alpha.md", "beta.md", "chart.png", "page.html"}, filenames)
+
+		// links resolve to files in the folder
+		rec = view(t, share.Token, file("alpha.md"), "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `href="/api/v1/s/`+share.Token+`?file=beta.md"`)
+		assert.Contains(t, rec.Body.String(), `<span class="internal-link is-unresolved">private</span>`)
+		assert.Contains(t, rec.Body.String(), `<span class="internal-link is-unresolved">diagram.png</span>`)
+
+		// files are served in a sandbox
+		rec = view(t, share.Token, file("page.html"), "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "sandbox")
+
+		params := file("page.html")
+		format := api.Html
+		params.Format = &format
+		assert.Equal(t, http.StatusBadRequest, view(t, share.Token, params, "").Code)
+		assert.Equal(t, http.StatusNotFound, view(t, share.Token, file("../private.md"), "").Code)
+		assert.Equal(t, http.StatusNotFound, view(t, share.Token, file("missing.md"), "").Code)
+	})
+
+	t.Run("shared folder view limit", func(t *testing.T) {
+		rec, share := createShare(t, `{"folder": "projects", "max_views": 3}`)
+		if !assert.Equal(t, http.StatusCreated, rec.Code) {
+			t.FailNow()
+		}
+
+		// listing the folder and downloading files count as views
+		assert.Equal(t, http.StatusOK, view(t, share.Token, api.GetSTokenParams{}, "").Code)
+		assert.Equal(t, http.StatusOK, view(t, share.Token, file("chart.png"), "").Code)
+		assert.Equal(t, 2, views(t, share.Token))
+
+		rec = view(t, share.Token, file("alpha.md"), "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		viewKey := viewKeyOf(t, rec)
+		assert.Contains(t, rec.Body.String(), `src="/api/v1/s/`+share.Token+`?file=chart.png&amp;view=`+viewKey+`"`)
+		assert.Equal(t, http.StatusOK, view(t, share.Token, embed("chart.png", viewKey), "").Code)
+		assert.Equal(t, 3, views(t, share.Token))
+
+		for _, params := range []api.GetSTokenParams{{}, file("chart.png"), file("page.html"), embed("page.html", viewKey)} {
+			assert.Equal(t, http.StatusGone, view(t, share.Token, params, "").Code)
+		}
+		assert.NoError(t, database.DeleteShare(db, user.Id, uint64(share.Id)))
+	})
+
+	t.Run("renamed, deleted and revoked shares", func(t *testing.T) {
+		_, share := createShare(t, `{"filename": "projects/beta.md"}`)
+		_, expiring := createShare(t, `{"folder": "projects", "expires_at": "`+time.Now().Add(time.Hour).Format(time.RFC3339)+`"}`)
+		assert.NotNil(t, expiring.ExpiresAt)
+
+		body, _ := json.Marshal(api.FileRename{Filename: "archive/beta.md"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/projects%2Fbeta.md/rename", bytes.NewBuffer(body))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if assert.NoError(t, srv.PostFilesFilenameRename(e.NewContext(req, rec), "projects/beta.md")) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+		rec = view(t, share.Token, api.GetSTokenParams{}, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Body.String(), `<h1 id="beta">Beta</h1>`))
+
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/shares", nil)
+		req.AddCookie(cookie)
+		rec = httptest.NewRecorder()
+		if assert.NoError(t, srv.GetShares(e.NewContext(req, rec))) {
+			var shares []api.Share
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &shares))
+			if assert.Len(t, shares, 4) {
+				assert.Equal(t, expiring.Id, shares[0].Id)
+				assert.Equal(t, "archive/beta.md", *shares[1].Filename)
+				assert.Equal(t, 1, shares[1].Views)
+			}
+		}
+
+		req = httptest.NewRequest(http.MethodDelete, "/api/v1/files/archive%2Fbeta.md", nil)
+		req.AddCookie(cookie)
+		rec = httptest.NewRecorder()
+		if assert.NoError(t, srv.DeleteFilesFilename(e.NewContext(req, rec), "archive/beta.md")) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+		assert.Equal(t, http.StatusNotFound, view(t, share.Token, api.GetSTokenParams{}, "").Code)
+
+		for _, wantCode := range []int{http.StatusOK, http.StatusNotFound} {
+			req = httptest.NewRequest(http.MethodDelete, "/api/v1/shares/"+strconv.FormatInt(expiring.Id, 10), nil)
+			req.AddCookie(cookie)
+			rec = httptest.NewRecorder()
+			if assert.NoError(t, srv.DeleteSharesId(e.NewContext(req, rec), expiring.Id)) {
+				assert.Equal(t, wantCode, rec.Code)
+			}
+		}
+		assert.Equal(t, http.StatusNotFound, view(t, expiring.Token, api.GetSTokenParams{}, "").Code)
+	})
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
@@ -0,0 +1,477 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+)
+
+// Folder in the file store that the content of snapshot files is kept in.
+// Content is stored once per vault for every etag, so snapshots of files that
+// haven't changed share it.
+const SnapshotDir = ".snapshots"
+
+var (
+	ErrSnapshotContentMissing = errors.New("snapshot content is missing")
+	snapshotNamePattern       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+)
+
+// Get the vault's snapshots
+// (GET /snapshots)
+func (o *ObsyncServer) GetSnapshots(ctx echo.Context) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	snapshots, err := database.GetSnapshots(o.db, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	usernames := map[uint64]string{}
+	apiSnapshots := make([]api.Snapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		apiSnapshot, err := o.toApiSnapshot(snapshot, usernames)
+		if err != nil {
+			ctx.Logger().Print(err)
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+		apiSnapshots = append(apiSnapshots, apiSnapshot)
+	}
+
+	return ctx.JSON(http.StatusOK, apiSnapshots)
+}
+
+// Take a snapshot of the vault
+// (POST /snapshots)
+func (o *ObsyncServer) PostSnapshots(ctx echo.Context) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	if !vault.canWrite("") {
+		return sendForbidden(ctx)
+	}
+
+	var body api.SnapshotCreate
+	if err := ctx.Bind(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid snapshot")
+	}
+	if !snapshotNamePattern.MatchString(body.Name) {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid snapshot name")
+	}
+
+	o.snapshotMu.Lock()
+	defer o.snapshotMu.Unlock()
+
+	snapshot, err := o.takeSnapshot(vault, body.Name)
+	if err != nil {
+		if errors.Is(err, database.ErrSnapshotExists) {
+			return sendApiMessage(ctx, http.StatusConflict, "snapshot already exists")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	apiSnapshot, err := o.toApiSnapshot(snapshot, map[uint64]string{})
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return ctx.JSON(http.StatusCreated, apiSnapshot)
+}
+
+// Delete a snapshot
+// (DELETE /snapshots/{snapshot})
+func (o *ObsyncServer) DeleteSnapshotsSnapshot(ctx echo.Context, snapshot string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	if !vault.isOwner() {
+		return sendForbidden(ctx)
+	}
+
+	o.snapshotMu.Lock()
+	defer o.snapshotMu.Unlock()
+
+	// backups copy the database before the file store, so content can't be
+	// deleted while they do
+	o.fileMu.RLock()
+	defer o.fileMu.RUnlock()
+	unused, err := database.DeleteSnapshot(o.db, vault.OwnerId, snapshot)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "snapshot not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	for _, etag := range unused {
+		err := o.fstore.DeleteFile(snapshotContentPath(vault.OwnerId, etag))
+		if err != nil && !errors.Is(err, filestore.ErrFileNotFound) {
+			ctx.Logger().Print(err)
+		}
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "snapshot deleted")
+}
+
+// Get the files in a snapshot
+// (GET /snapshots/{snapshot}/files)
+func (o *ObsyncServer) GetSnapshotsSnapshotFiles(ctx echo.Context, snapshot string, params api.GetSnapshotsSnapshotFilesParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	folder := ""
+	if params.Folder != nil && len(strings.Trim(*params.Folder, "/")) > 0 {
+		folder, err = cleanFolder(*params.Folder)
+		if err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid folder")
+		}
+	}
+	found, err := database.GetSnapshot(o.db, vault.OwnerId, snapshot)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "snapshot not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	files, err := database.GetSnapshotFiles(o.db, found.Id, folder)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiFiles := make([]api.SnapshotFile, 0, len(files))
+	for _, file := range files {
+		apiFiles = append(apiFiles, api.SnapshotFile{
+			Filename:  file.Filepath,
+			Etag:      file.Etag,
+			Size:      file.Size,
+			UpdatedAt: file.UpdatedAt,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, apiFiles)
+}
+
+// Download a file as it was in a snapshot
+// (GET /snapshots/{snapshot}/files/{filename})
+func (o *ObsyncServer) GetSnapshotsSnapshotFilesFilename(ctx echo.Context, snapshot string, filename string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+	found, err := database.GetSnapshot(o.db, vault.OwnerId, snapshot)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "snapshot not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	file, err := database.GetSnapshotFile(o.db, found.Id, filename)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	data, err := o.fstore.LoadFile(snapshotContentPath(vault.OwnerId, file.Etag))
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	ctx.Response().Header().Set("ETag", fmt.Sprintf("%q", file.Etag))
+	return ctx.Blob(http.StatusOK, contentTypeForFile(filename), data)
+}
+
+// Restore the vault to a snapshot
+// (POST /snapshots/{snapshot}/restore)
+func (o *ObsyncServer) PostSnapshotsSnapshotRestore(ctx echo.Context, snapshot string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	if !vault.isOwner() {
+		return sendForbidden(ctx)
+	}
+
+	o.snapshotMu.Lock()
+	defer o.snapshotMu.Unlock()
+
+	found, err := database.GetSnapshot(o.db, vault.OwnerId, snapshot)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "snapshot not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	files, err := database.GetSnapshotFiles(o.db, found.Id, "")
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	// make sure the restore can't fail halfway because of missing content
+	for _, file := range files {
+		if _, err := o.fstore.GetFilePath(snapshotContentPath(vault.OwnerId, file.Etag)); err != nil {
+			ctx.Logger().Print(fmt.Errorf("%w: %s in %s", ErrSnapshotContentMissing, file.Filepath, found.Name))
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+	}
+
+	// other changes to the vault's files wait until the restore is done, so
+	// the backup has the files as they were right before the restore and
+	// nothing changes them halfway through it
+	o.fileMu.Lock()
+	backup, err := o.takeBackupSnapshot(vault)
+	if err != nil {
+		o.fileMu.Unlock()
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	report, restored, err := o.restoreSnapshot(ctx, vault, files)
+	o.fileMu.Unlock()
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	o.indexRestoredFiles(ctx, vault, restored)
+	report.Backup = backup.Name
+
+	return ctx.JSON(http.StatusOK, report)
+}
+
+// Take a snapshot of the vault's files, storing the content of files the
+// vault's other snapshots don't have yet.
+func (o *ObsyncServer) takeSnapshot(vault *vaultAccess, name string) (*database.Snapshot, error) {
+	syncFiles, err := database.GetSyncFilesByUserId(o.db, vault.OwnerId)
+	if err != nil {
+		return nil, err
+	}
+	for i, syncFile := range syncFiles {
+		contentPath := snapshotContentPath(vault.OwnerId, syncFile.Etag)
+		if _, err := o.fstore.GetFilePath(contentPath); err == nil {
+			continue
+		}
+		filePath, err := userFilePath(vault.OwnerId, syncFile.Filepath)
+		if err != nil {
+			return nil, err
+		}
+		data, err := o.fstore.LoadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		// the file could have changed since its record was read, so keep the
+		// content under the etag of what was actually read
+		if etag := filestore.GetEtag(data); etag != syncFile.Etag {
+			copied := *syncFile
+			copied.Etag, copied.Size = etag, int64(len(data))
+			syncFiles[i], contentPath = &copied, snapshotContentPath(vault.OwnerId, etag)
+		}
+		if err := o.fstore.SaveFile(contentPath, data); err != nil {
+			return nil, err
+		}
+	}
+
+	return database.CreateSnapshot(o.db, vault.OwnerId, name, vault.UserId, syncFiles)
+}
+
+// Take a snapshot of the vault before it's restored to another snapshot.
+func (o *ObsyncServer) takeBackupSnapshot(vault *vaultAccess) (*database.Snapshot, error) {
+	name := "before-restore-" + time.Now().UTC().Format("20060102T150405Z")
+	for i := 2; ; i++ {
+		snapshot, err := o.takeSnapshot(vault, name)
+		if !errors.Is(err, database.ErrSnapshotExists) {
+			return snapshot, err
+		}
+		name = fmt.Sprintf("before-restore-%s-%d", time.Now().UTC().Format("20060102T150405Z"), i)
+	}
+}
+
+// Files changed by a snapshot restore, which are indexed once the restore is
+// done.
+type restoredFiles struct {
+	saved   []*database.SyncFile
+	data    [][]byte
+	created []bool
+	deleted []*database.SyncFile
+}
+
+// Make the vault's files match the files of a snapshot. The caller holds
+// fileMu for writing. The files are written first and their sync records are
+// changed in one transaction afterwards; if anything fails, the files already
+// written are put back from the content of the backup snapshot, leaving the
+// vault as it was.
+func (o *ObsyncServer) restoreSnapshot(ctx echo.Context, vault *vaultAccess, files []*database.SnapshotFile) (*api.SnapshotRestoreReport, *restoredFiles, error) {
+	syncFiles, err := database.GetSyncFilesByUserId(o.db, vault.OwnerId)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := make(map[string]*database.SyncFile, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		current[syncFile.Filepath] = syncFile
+	}
+
+	store := o.storeFor(ctx, vault)
+	report := &api.SnapshotRestoreReport{Restored: []string{}, Deleted: []string{}}
+	restored := &restoredFiles{}
+	changes := []database.SyncFileChange{}
+	written := []database.SyncFileChange{}
+	rollback := func(err error) (*api.SnapshotRestoreReport, *restoredFiles, error) {
+		o.rollbackRestore(ctx, vault, store, written)
+		return nil, nil, err
+	}
+	for _, file := range files {
+		existing := current[file.Filepath]
+		delete(current, file.Filepath)
+		if existing != nil && existing.Etag == file.Etag {
+			report.Unchanged++
+			continue
+		}
+		filePath, err := userFilePath(vault.OwnerId, file.Filepath)
+		if err != nil {
+			return rollback(err)
+		}
+		data, err := o.fstore.LoadFile(snapshotContentPath(vault.OwnerId, file.Etag))
+		if err != nil {
+			return rollback(err)
+		}
+		change := database.SyncFileChange{
+			Filepath: file.Filepath,
+			Etag:     filestore.GetEtag(data),
+			Size:     int64(len(data)),
+			Existing: existing,
+		}
+		if err := store.SaveFile(filePath, data); err != nil {
+			return rollback(err)
+		}
+		written = append(written, change)
+		changes = append(changes, change)
+		restored.data = append(restored.data, data)
+		restored.created = append(restored.created, existing == nil)
+		report.Restored = append(report.Restored, file.Filepath)
+	}
+	for _, syncFile := range syncFiles {
+		if current[syncFile.Filepath] == nil {
+			continue
+		}
+		changes = append(changes, database.SyncFileChange{Existing: syncFile, Delete: true})
+		restored.deleted = append(restored.deleted, syncFile)
+		report.Deleted = append(report.Deleted, syncFile.Filepath)
+	}
+
+	// the thumbnail records of deleted files go along with their sync records
+	o.thumbnailMu.Lock()
+	for _, syncFile := range restored.deleted {
+		o.deleteThumbnails(ctx, syncFile.Id)
+	}
+	changed, err := database.ApplySyncFileChanges(o.db, vault.OwnerId, vault.UserId, changes)
+	o.thumbnailMu.Unlock()
+	if err != nil {
+		return rollback(err)
+	}
+	restored.saved = changed[:len(written)]
+
+	// the sync records of deleted files are gone, so they can't come back if
+	// removing the files themselves fails
+	for _, syncFile := range restored.deleted {
+		filePath, err := userFilePath(vault.OwnerId, syncFile.Filepath)
+		if err == nil {
+			err = store.DeleteFile(filePath)
+		}
+		if err != nil && !errors.Is(err, filestore.ErrFileNotFound) {
+			ctx.Logger().Print(err)
+		}
+	}
+
+	return report, restored, nil
+}
+
+// Put back the files a failed restore already wrote. The backup snapshot taken
+// before the restore has the content of every file that existed.
+func (o *ObsyncServer) rollbackRestore(ctx echo.Context, vault *vaultAccess, store filestore.FileStore, written []database.SyncFileChange) {
+	for _, change := range written {
+		filePath, err := userFilePath(vault.OwnerId, change.Filepath)
+		if err != nil {
+			ctx.Logger().Print(err)
+			continue
+		}
+		if change.Existing == nil {
+			err = store.DeleteFile(filePath)
+		} else {
+			var data []byte
+			data, err = o.fstore.LoadFile(snapshotContentPath(vault.OwnerId, change.Existing.Etag))
+			if err == nil {
+				err = store.SaveFile(filePath, data)
+			}
+		}
+		if err != nil {
+			ctx.Logger().Print(err)
+		}
+	}
+}
+
+// Update the search index, links, tags, properties and thumbnails of the files
+// a restore changed.
+func (o *ObsyncServer) indexRestoredFiles(ctx echo.Context, vault *vaultAccess, restored *restoredFiles) {
+	for i, syncFile := range restored.saved {
+		o.indexFile(ctx, syncFile, restored.data[i], restored.created[i])
+	}
+	for _, syncFile := range restored.deleted {
+		// links to the deleted file might resolve to another file with the
+		// same name now
+		o.resolveLinksTo(ctx, vault.OwnerId, syncFile.Filepath)
+	}
+}
+
+func (o *ObsyncServer) toApiSnapshot(snapshot *database.Snapshot, usernames map[uint64]string) (api.Snapshot, error) {
+	username, ok := usernames[snapshot.CreatedBy]
+	if !ok {
+		// users that took snapshots of vaults they were members of might
+		// have been deleted since
+		user, err := database.GetUserById(o.db, snapshot.CreatedBy)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return api.Snapshot{}, err
+		} else if err == nil {
+			username = user.Username
+		}
+		usernames[snapshot.CreatedBy] = username
+	}
+
+	return api.Snapshot{
+		Name:      snapshot.Name,
+		CreatedBy: username,
+		CreatedAt: snapshot.CreatedAt,
+		FileCount: snapshot.FileCount,
+		TotalSize: snapshot.TotalSize,
+	}, nil
+}
+
+func snapshotContentPath(ownerId uint64, etag string) string {
+	return path.Join(SnapshotDir, strconv.FormatUint(ownerId, 10), etag)
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-snapshot-routes")
+	viewer, viewerCookie := createTestSession(t, db, "test-snapshot-routes-viewer")
+	assert.NoError(t, database.SetVaultMember(db, user.Id, viewer.Id, database.VaultViewer, nil))
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	request := func(cookie *http.Cookie, body string, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(VaultHeader, user.Username)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	upload := func(filename, content string) {
+		t.Helper()
+		rec := request(cookie, content, func(ctx echo.Context) error {
+			if _, err := database.GetUserSyncFileByFilepath(db, user.Id, filename); err == nil {
+				return srv.PutFilesFilename(ctx, filename, api.PutFilesFilenameParams{})
+			}
+			return srv.PostFilesFilename(ctx, filename)
+		})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	takeSnapshot := func(cookie *http.Cookie, name string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(cookie, `{"name":"`+name+`"}`, srv.PostSnapshots)
+	}
+	fileContent := func(filename string) string {
+		t.Helper()
+		data, err := srv.fstore.LoadFile(testFilePath(t, user.Id, filename))
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		return string(data)
+	}
+
+	upload("a.md", "# A")
+	upload("notes/b.md", "# B")
+	upload("notes/copy.md", "# B")
+
+	rec := takeSnapshot(cookie, "first")
+	if assert.Equal(t, http.StatusCreated, rec.Code) {
+		var snapshot api.Snapshot
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &snapshot))
+		assert.Equal(t, "first", snapshot.Name)
+		assert.Equal(t, user.Username, snapshot.CreatedBy)
+		assert.Equal(t, 3, snapshot.FileCount)
+		assert.Equal(t, int64(9), snapshot.TotalSize)
+	}
+	assert.Equal(t, http.StatusConflict, takeSnapshot(cookie, "first").Code)
+	assert.Equal(t, http.StatusBadRequest, takeSnapshot(cookie, "../first").Code)
+	assert.Equal(t, http.StatusForbidden, takeSnapshot(viewerCookie, "viewer").Code)
+
+	upload("a.md", "# A changed")
+	upload("c.md", "# C")
+	rec = request(cookie, "", func(ctx echo.Context) error {
+		return srv.DeleteFilesFilename(ctx, "notes/b.md")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	folder := "notes"
+	rec = request(viewerCookie, "", func(ctx echo.Context) error {
+		return srv.GetSnapshotsSnapshotFiles(ctx, "first", api.GetSnapshotsSnapshotFilesParams{Folder: &folder})
+	})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		files := []api.SnapshotFile{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files))
+		if assert.Len(t, files, 2) {
+			assert.Equal(t, "notes/b.md", files[0].Filename)
+			assert.Equal(t, "notes/copy.md", files[1].Filename)
+		}
+	}
+	rec = request(viewerCookie, "", func(ctx echo.Context) error {
+		return srv.GetSnapshotsSnapshotFilesFilename(ctx, "first", "a.md")
+	})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, "# A", rec.Body.String())
+	}
+	rec = request(viewerCookie, "", func(ctx echo.Context) error {
+		return srv.GetSnapshotsSnapshotFilesFilename(ctx, "first", "c.md")
+	})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = request(viewerCookie, "", func(ctx echo.Context) error {
+		return srv.GetSnapshotsSnapshotFiles(ctx, "missing", api.GetSnapshotsSnapshotFilesParams{})
+	})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// only the owner can restore the vault
+	restore := func(cookie *http.Cookie, name string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(cookie, "", func(ctx echo.Context) error {
+			return srv.PostSnapshotsSnapshotRestore(ctx, name)
+		})
+	}
+	assert.Equal(t, http.StatusForbidden, restore(viewerCookie, "first").Code)
+	assert.Equal(t, http.StatusNotFound, restore(cookie, "missing").Code)
+
+	var report api.SnapshotRestoreReport
+	rec = restore(cookie, "first")
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.ElementsMatch(t, []string{"a.md", "notes/b.md"}, report.Restored)
+		assert.Equal(t, []string{"c.md"}, report.Deleted)
+		assert.Equal(t, 1, report.Unchanged)
+		assert.True(t, strings.HasPrefix(report.Backup, "before-restore-"))
+	}
+	assert.Equal(t, "# A", fileContent("a.md"))
+	assert.Equal(t, "# B", fileContent("notes/b.md"))
+	_, err = database.GetUserSyncFileByFilepath(db, user.Id, "c.md")
+	assert.ErrorIs(t, err, database.ErrNoResults)
+
+	// the vault can be put back the way it was before the restore
+	rec = restore(cookie, report.Backup)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, "# A changed", fileContent("a.md"))
+		assert.Equal(t, "# C", fileContent("c.md"))
+		_, err = database.GetUserSyncFileByFilepath(db, user.Id, "notes/b.md")
+		assert.ErrorIs(t, err, database.ErrNoResults)
+	}
+
+	rec = request(viewerCookie, "", srv.GetSnapshots)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		snapshots := []api.Snapshot{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &snapshots))
+		assert.Len(t, snapshots, 3)
+	}
+
+	// deleting snapshots removes content that no snapshot has anymore
+	deleteSnapshot := func(cookie *http.Cookie, name string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(cookie, "", func(ctx echo.Context) error {
+			return srv.DeleteSnapshotsSnapshot(ctx, name)
+		})
+	}
+	assert.Equal(t, http.StatusForbidden, deleteSnapshot(viewerCookie, "first").Code)
+	snapshots, err := database.GetSnapshots(db, user.Id)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, snapshot := range snapshots {
+		assert.Equal(t, http.StatusOK, deleteSnapshot(cookie, snapshot.Name).Code)
+	}
+	assert.Equal(t, http.StatusNotFound, deleteSnapshot(cookie, "first").Code)
+	syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, "a.md")
+	if assert.NoError(t, err) {
+		_, err = srv.fstore.GetFilePath(snapshotContentPath(user.Id, syncFile.Etag))
+		assert.Error(t, err)
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+	assert.NoError(t, database.DeleteUser(db, viewer.Id))
+}
+
+// File store that runs hooks before files are loaded or saved.
+type hookedFileStore struct {
+	filestore.FileStore
+	beforeLoad func(filePath string)
+	beforeSave func(filePath string) error
+}
+
+func (h *hookedFileStore) LoadFile(filePath string) ([]byte, error) {
+	if h.beforeLoad != nil {
+		h.beforeLoad(filePath)
+	}
+	return h.FileStore.LoadFile(filePath)
+}
+
+func (h *hookedFileStore) SaveFile(filePath string, data []byte) error {
+	if h.beforeSave != nil {
+		if err := h.beforeSave(filePath); err != nil {
+			return err
+		}
+	}
+	return h.FileStore.SaveFile(filePath, data)
+}
+
+func TestSnapshotRestoreAtomic(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-snapshot-restore-atomic")
+	fstore, err := filestore.NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	store := &hookedFileStore{FileStore: fstore}
+	srv := NewServerWithFileStore(db, store)
+
+	request := func(body string, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		assert.NoError(t, handler(e.NewContext(req, rec)))
+		return rec
+	}
+	upload := func(filename, content string) int {
+		return request(content, func(ctx echo.Context) error {
+			if _, err := database.GetUserSyncFileByFilepath(db, user.Id, filename); err == nil {
+				return srv.PutFilesFilename(ctx, filename, api.PutFilesFilenameParams{})
+			}
+			return srv.PostFilesFilename(ctx, filename)
+		}).Code
+	}
+	restore := func(name string) int {
+		return request("", func(ctx echo.Context) error {
+			return srv.PostSnapshotsSnapshotRestore(ctx, name)
+		}).Code
+	}
+	// the file's content and its sync record have to agree
+	assertFile := func(filename, content string) {
+		t.Helper()
+		data, err := fstore.LoadFile(testFilePath(t, user.Id, filename))
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(data))
+		syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, filename)
+		if assert.NoError(t, err) {
+			assert.Equal(t, filestore.GetEtag([]byte(content)), syncFile.Etag)
+		}
+	}
+
+	assert.Equal(t, http.StatusOK, upload("a.md", "# A"))
+	assert.Equal(t, http.StatusOK, upload("b.md", "# B"))
+	assert.Equal(t, http.StatusCreated, request(`{"name":"first"}`, srv.PostSnapshots).Code)
+	assert.Equal(t, http.StatusOK, upload("a.md", "# A changed"))
+	assert.Equal(t, http.StatusOK, upload("b.md", "# B changed"))
+	assert.Equal(t, http.StatusOK, upload("c.md", "# C"))
+
+	t.Run("failed restore changes nothing", func(t *testing.T) {
+		store.beforeSave = func(filePath string) error {
+			if filePath == testFilePath(t, user.Id, "b.md") {
+				return errors.New("disk full")
+			}
+			return nil
+		}
+		defer func() { store.beforeSave = nil }()
+
+		assert.Equal(t, http.StatusInternalServerError, restore("first"))
+		assertFile("a.md", "# A changed")
+		assertFile("b.md", "# B changed")
+		assertFile("c.md", "# C")
+	})
+
+	t.Run("writes wait for the restore", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		var once sync.Once
+		store.beforeLoad = func(filePath string) {
+			if strings.HasPrefix(filePath, SnapshotDir+"/") {
+				once.Do(func() {
+					close(started)
+					<-release
+				})
+			}
+		}
+		defer func() { store.beforeLoad = nil }()
+
+		restored := make(chan int)
+		go func() { restored <- restore("first") }()
+		<-started
+		written := make(chan int)
+		go func() { written <- upload("a.md", "# A written") }()
+
+		writtenCode := 0
+		select {
+		case writtenCode = <-written:
+			t.Error("file was written during the restore")
+		case <-time.After(100 * time.Millisecond):
+		}
+		close(release)
+		assert.Equal(t, http.StatusOK, <-restored)
+		if writtenCode == 0 {
+			writtenCode = <-written
+		}
+		assert.Equal(t, http.StatusOK, writtenCode)
+
+		assertFile("a.md", "# A written")
+		assertFile("b.md", "# B")
+		_, err := database.GetUserSyncFileByFilepath(db, user.Id, "c.md")
+		assert.ErrorIs(t, err, database.ErrNoResults)
+	})
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/markdown"
+)
+
+// Get the tags in a user's notes
+// (GET /tags)
+func (o *ObsyncServer) GetTags(ctx echo.Context, params api.GetTagsParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	var prefix string
+	if params.Prefix != nil {
+		prefix = markdown.TagName(*params.Prefix)
+	}
+	counts, err := database.GetTagCounts(o.db, vault.OwnerId, prefix)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	tags := make([]api.TagCount, 0, len(counts))
+	for _, count := range counts {
+		tags = append(tags, api.TagCount{Tag: count.Tag, Count: count.Count})
+	}
+	return ctx.JSON(http.StatusOK, tags)
+}
+
+// Get the files with a tag
+// (GET /tags/files)
+func (o *ObsyncServer) GetTagsFiles(ctx echo.Context, params api.GetTagsFilesParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	name := strings.TrimSuffix(markdown.TagName(strings.TrimSpace(params.Tag)), "/")
+	if len(name) == 0 {
+		return sendApiMessage(ctx, http.StatusBadRequest, "missing tag")
+	}
+	match := database.TagMatchExact
+	if params.Match != nil {
+		switch *params.Match {
+		case api.Exact:
+		case api.Nested:
+			match = database.TagMatchNested
+		case api.Prefix:
+			match = database.TagMatchPrefix
+		default:
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid match mode")
+		}
+	}
+
+	syncFiles, err := database.GetSyncFilesByTag(o.db, vault.OwnerId, name, match)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	files := make(api.FileList, 0, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		files = append(files, toApiFile(syncFile))
+	}
+	return ctx.JSON(http.StatusOK, files)
+}
+
+// Parse the tags in a note after it's created or changed.
+func (o *ObsyncServer) updateTags(ctx echo.Context, syncFile *database.SyncFile, data []byte) {
+	if !isMarkdownFile(syncFile.Filepath) {
+		return
+	}
+
+	parsed := markdown.ParseTags(string(data))
+	tags := make([]*database.Tag, 0, len(parsed))
+	for _, tag := range parsed {
+		tags = append(tags, &database.Tag{Tag: tag, Name: markdown.TagName(tag)})
+	}
+
+	if err := database.ReplaceTags(o.db, syncFile.Id, syncFile.UserId, tags); err != nil {
+		ctx.Logger().Print(err)
+	}
+}
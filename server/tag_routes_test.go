@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-tags")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	files := map[string]string{
+		"alpha.md":   "---\ntags: [project/alpha]\n---\n# Alpha #Project",
+		"beta.md":    "# Beta\n#project/beta and `#not-a-tag`",
+		"ideas.md":   "#projects someday",
+		"diagram.md": "```\n#include <stdio.h>\n```",
+	}
+	for filename, content := range files {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/files/"+url.PathEscape(filename), bytes.NewBufferString(content))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostFilesFilename(e.NewContext(req, rec), filename)) {
+			t.FailNow()
+		}
+	}
+	// changing a note replaces its tags
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/files/ideas.md", bytes.NewBufferString("#ideas"))
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	if !assert.NoError(t, srv.PutFilesFilename(e.NewContext(req, rec), "ideas.md", api.PutFilesFilenameParams{})) {
+		t.FailNow()
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/tags", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, srv.GetTags(e.NewContext(req, rec), api.GetTagsParams{})) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var tags []api.TagCount
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tags))
+		assert.Equal(t, []api.TagCount{
+			{Tag: "ideas", Count: 1},
+			{Tag: "Project", Count: 1},
+			{Tag: "project/alpha", Count: 1},
+			{Tag: "project/beta", Count: 1},
+		}, tags)
+	}
+
+	match := func(m api.GetTagsFilesParamsMatch) *api.GetTagsFilesParamsMatch { return &m }
+	testCases := []struct {
+		name      string
+		params    api.GetTagsFilesParams
+		wantCode  int
+		wantFiles []string
+	}{
+		{
+			name:      "exact match",
+			params:    api.GetTagsFilesParams{Tag: "#PROJECT"},
+			wantCode:  http.StatusOK,
+			wantFiles: []string{"alpha.md"},
+		},
+		{
+			name:      "nested tags",
+			params:    api.GetTagsFilesParams{Tag: "project", Match: match(api.Nested)},
+			wantCode:  http.StatusOK,
+			wantFiles: []string{"alpha.md", "beta.md"},
+		},
+		{
+			name:      "prefix",
+			params:    api.GetTagsFilesParams{Tag: "project/", Match: match(api.Prefix)},
+			wantCode:  http.StatusOK,
+			wantFiles: []string{"alpha.md", "beta.md"},
+		},
+		{
+			name:     "missing tag",
+			params:   api.GetTagsFilesParams{Tag: "#"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid match mode",
+			params:   api.GetTagsFilesParams{Tag: "project", Match: match("fuzzy")},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/tags/files", nil)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			if !assert.NoError(t, srv.GetTagsFiles(e.NewContext(req, rec), tc.params)) {
+				t.FailNow()
+			}
+			assert.Equal(t, tc.wantCode, rec.Code)
+			if tc.wantCode == http.StatusOK {
+				var files api.FileList
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files))
+				filenames := []string{}
+				for _, file := range files {
+					filenames = append(filenames, *file.Filename)
+				}
+				assert.Equal(t, tc.wantFiles, filenames)
+			}
+		})
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/thumbnail"
+)
+
+const (
+	// Size of the thumbnails generated when images are uploaded
+	DefaultThumbnailSize = 256
+	minThumbnailSize     = 16
+	maxThumbnailSize     = 1024
+	// Folder in the file store that thumbnails are cached in
+	ThumbnailDir = ".thumbnails"
+)
+
+// Sizes thumbnails are generated in. Requested sizes are rounded up to one of
+// them, so only a few thumbnails of each image are ever cached.
+var thumbnailSizes = []int{32, 64, 128, 256, 512, 1024}
+
+// Get a thumbnail of an image
+// (GET /files/{filename}/thumbnail)
+func (o *ObsyncServer) GetFilesFilenameThumbnail(ctx echo.Context, filename string, params api.GetFilesFilenameThumbnailParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	filename, err = cleanFilename(filename)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid filename")
+	}
+
+	size := DefaultThumbnailSize
+	if params.Size != nil {
+		size = *params.Size
+	}
+	if size < minThumbnailSize || size > maxThumbnailSize {
+		return sendApiMessage(ctx, http.StatusBadRequest, "size must be between 16 and 1024")
+	}
+	size = thumbnailSize(size)
+
+	syncFile, err := database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, filename)
+	if err != nil {
+		ctx.Logger().Print(err)
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "file not found")
+		}
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if !thumbnail.Supported(filename) {
+		return sendApiMessage(ctx, http.StatusUnsupportedMediaType, thumbnail.ErrUnsupported.Error())
+	}
+
+	etag := fmt.Sprintf("%s-%d", syncFile.Etag, size)
+	ctx.Response().Header().Set("ETag", fmt.Sprintf("%q", etag))
+	if params.IfNoneMatch != nil && etagMatches(*params.IfNoneMatch, etag) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	data, err := o.loadThumbnail(syncFile, size)
+	if err != nil {
+		if errors.Is(err, thumbnail.ErrInvalidImage) || errors.Is(err, thumbnail.ErrTooLarge) {
+			return sendApiMessage(ctx, http.StatusUnsupportedMediaType, err.Error())
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return ctx.Blob(http.StatusOK, thumbnail.ContentType(filename), data)
+}
+
+// Get a cached thumbnail of a file, generating it if it's missing or the file
+// changed since it was generated.
+func (o *ObsyncServer) loadThumbnail(syncFile *database.SyncFile, size int) ([]byte, error) {
+	cached, err := database.GetThumbnail(o.db, syncFile.Id, size)
+	if err != nil && !errors.Is(err, database.ErrNoResults) {
+		return nil, err
+	}
+	if cached != nil && cached.Etag == syncFile.Etag {
+		data, err := o.fstore.LoadFile(thumbnailPath(cached))
+		if err == nil {
+			return data, nil
+		} else if !errors.Is(err, filestore.ErrFileNotFound) {
+			return nil, err
+		}
+	}
+
+	filePath, err := userFilePath(syncFile.UserId, syncFile.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := o.fstore.LoadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return o.cacheThumbnail(syncFile, data, size)
+}
+
+// Generate a thumbnail of a file and cache it, replacing the thumbnail of the
+// same size generated from an older version of the file.
+func (o *ObsyncServer) cacheThumbnail(syncFile *database.SyncFile, data []byte, size int) ([]byte, error) {
+	generated, err := thumbnail.Generate(syncFile.Filepath, data, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// files are deleted along with their thumbnails while thumbnailMu is
+	// held, so a file that was deleted or changed while its thumbnail was
+	// generated doesn't get a thumbnail nothing will clean up
+	o.thumbnailMu.Lock()
+	defer o.thumbnailMu.Unlock()
+	current, err := database.GetSyncFileById(o.db, syncFile.Id)
+	if errors.Is(err, database.ErrNoResults) || (err == nil && current.Etag != syncFile.Etag) {
+		return generated, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	previous, err := database.GetThumbnail(o.db, syncFile.Id, size)
+	if err != nil && !errors.Is(err, database.ErrNoResults) {
+		return nil, err
+	}
+	cached := &database.Thumbnail{FileId: syncFile.Id, Size: size, Etag: syncFile.Etag}
+	// thumbnails are stored under their etag, so a thumbnail that's being
+	// generated from an older version of the file can't overwrite a newer one
+	if err := o.fstore.SaveFile(thumbnailPath(cached), generated); err != nil {
+		return nil, err
+	}
+	if _, err := database.SetThumbnail(o.db, syncFile.Id, size, syncFile.Etag); err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.Etag != syncFile.Etag {
+		err := o.fstore.DeleteFile(thumbnailPath(previous))
+		if err != nil && !errors.Is(err, filestore.ErrFileNotFound) {
+			return nil, err
+		}
+	}
+	return generated, nil
+}
+
+// Generate the default thumbnail of an uploaded image in the background, so
+// the first request for it doesn't have to wait. Images are only loaded once
+// the job gets a slot, so uploading many images doesn't keep them all in
+// memory.
+func (o *ObsyncServer) queueThumbnail(ctx echo.Context, syncFile *database.SyncFile) {
+	if !thumbnail.Supported(syncFile.Filepath) {
+		return
+	}
+
+	logger := ctx.Logger()
+	fileId, etag := syncFile.Id, syncFile.Etag
+	o.thumbnailJobs.Add(1)
+	go func() {
+		defer o.thumbnailJobs.Done()
+		o.thumbnailSlots <- struct{}{}
+		defer func() { <-o.thumbnailSlots }()
+
+		// files changed since the job was queued have jobs of their own
+		syncFile, err := database.GetSyncFileById(o.db, fileId)
+		if errors.Is(err, database.ErrNoResults) || (err == nil && syncFile.Etag != etag) {
+			return
+		} else if err != nil {
+			logger.Print(err)
+			return
+		}
+		_, err = o.loadThumbnail(syncFile, DefaultThumbnailSize)
+		// files with an image's extension that aren't images are only an
+		// error when their thumbnail is requested
+		if err != nil && !errors.Is(err, thumbnail.ErrInvalidImage) && !errors.Is(err, thumbnail.ErrTooLarge) {
+			logger.Print(err)
+		}
+	}()
+}
+
+// Wait for the thumbnails being generated in the background.
+func (o *ObsyncServer) WaitForThumbnails() {
+	o.thumbnailJobs.Wait()
+}
+
+// Delete the cached thumbnails of a file.
+func (o *ObsyncServer) deleteThumbnails(ctx echo.Context, fileId uint64) {
+	thumbnails, err := database.GetThumbnails(o.db, fileId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return
+	}
+	for _, cached := range thumbnails {
+		err := o.fstore.DeleteFile(thumbnailPath(cached))
+		if err != nil && !errors.Is(err, filestore.ErrFileNotFound) {
+			ctx.Logger().Print(err)
+		}
+	}
+}
+
+// Round a requested thumbnail size up to the size it's generated in.
+func thumbnailSize(size int) int {
+	for _, thumbnailSize := range thumbnailSizes {
+		if size <= thumbnailSize {
+			return thumbnailSize
+		}
+	}
+	return thumbnailSizes[len(thumbnailSizes)-1]
+}
+
+func thumbnailPath(cached *database.Thumbnail) string {
+	return path.Join(
+		ThumbnailDir,
+		strconv.FormatUint(cached.FileId, 10),
+		strconv.Itoa(cached.Size)+"-"+cached.Etag,
+	)
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func createPng(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if !assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))) {
+		t.FailNow()
+	}
+	return buf.Bytes()
+}
+
+func TestThumbnailRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-thumbnail-routes")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	request := func(body []byte, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", bytes.NewReader(body))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	getThumbnail := func(filename string, params api.GetFilesFilenameThumbnailParams) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(nil, func(ctx echo.Context) error {
+			return srv.GetFilesFilenameThumbnail(ctx, filename, params)
+		})
+	}
+	decodeSize := func(rec *httptest.ResponseRecorder) (int, int) {
+		t.Helper()
+		config, format, err := image.DecodeConfig(rec.Body)
+		if !assert.NoError(t, err) {
+			return 0, 0
+		}
+		assert.Equal(t, "png", format)
+		return config.Width, config.Height
+	}
+	size := func(size int) *int { return &size }
+
+	for filename, data := range map[string][]byte{
+		"photos/wide.png":   createPng(t, 400, 200),
+		"photos/broken.png": []byte("not a png"),
+		"notes/note.md":     []byte("# Note"),
+	} {
+		rec := request(data, func(ctx echo.Context) error {
+			return srv.PostFilesFilename(ctx, filename)
+		})
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+	}
+	srv.WaitForThumbnails()
+
+	// the default thumbnail is generated when the image is uploaded
+	syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, "photos/wide.png")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cached, err := database.GetThumbnail(db, syncFile.Id, DefaultThumbnailSize)
+	if assert.NoError(t, err) {
+		assert.Equal(t, syncFile.Etag, cached.Etag)
+	}
+	_, err = database.GetThumbnail(db, syncFile.Id+1, DefaultThumbnailSize)
+	assert.ErrorIs(t, err, database.ErrNoResults)
+
+	rec := getThumbnail("photos/wide.png", api.GetFilesFilenameThumbnailParams{})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, "image/png", rec.Header().Get(echo.HeaderContentType))
+		etag := rec.Header().Get("ETag")
+		width, height := decodeSize(rec)
+		assert.Equal(t, 256, width)
+		assert.Equal(t, 128, height)
+
+		rec = getThumbnail("photos/wide.png", api.GetFilesFilenameThumbnailParams{IfNoneMatch: &etag})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	}
+
+	rec = getThumbnail("photos/wide.png", api.GetFilesFilenameThumbnailParams{Size: size(64)})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		width, height := decodeSize(rec)
+		assert.Equal(t, 64, width)
+		assert.Equal(t, 32, height)
+	}
+	oldThumbnails, err := database.GetThumbnails(db, syncFile.Id)
+	assert.NoError(t, err)
+	assert.Len(t, oldThumbnails, 2)
+
+	// changing the image invalidates its thumbnails
+	rec = request(createPng(t, 100, 300), func(ctx echo.Context) error {
+		return srv.PutFilesFilename(ctx, "photos/wide.png", api.PutFilesFilenameParams{})
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	srv.WaitForThumbnails()
+	rec = getThumbnail("photos/wide.png", api.GetFilesFilenameThumbnailParams{Size: size(64)})
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		width, height := decodeSize(rec)
+		assert.Equal(t, 21, width)
+		assert.Equal(t, 64, height)
+	}
+	for _, old := range oldThumbnails {
+		_, err := srv.fstore.LoadFile(thumbnailPath(old))
+		assert.Error(t, err, "thumbnail %d wasn't deleted", old.Size)
+	}
+
+	// sizes are rounded up to the sizes thumbnails are generated in, so
+	// requesting every size doesn't cache a thumbnail for each of them
+	changed, err := database.GetUserSyncFileByFilepath(db, user.Id, "photos/wide.png")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, requested := range []int{100, 128} {
+		rec = getThumbnail("photos/wide.png", api.GetFilesFilenameThumbnailParams{Size: size(requested)})
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Equal(t, `"`+changed.Etag+`-128"`, rec.Header().Get("ETag"))
+			width, height := decodeSize(rec)
+			assert.Equal(t, 42, width)
+			assert.Equal(t, 128, height)
+		}
+	}
+	thumbnails, err := database.GetThumbnails(db, syncFile.Id)
+	assert.NoError(t, err)
+	assert.Len(t, thumbnails, 3)
+
+	for _, tc := range []struct {
+		filename string
+		size     *int
+		wantCode int
+	}{
+		{"notes/note.md", nil, http.StatusUnsupportedMediaType},
+		{"photos/broken.png", nil, http.StatusUnsupportedMediaType},
+		{"photos/missing.png", nil, http.StatusNotFound},
+		{"photos/wide.png", size(8), http.StatusBadRequest},
+		{"photos/wide.png", size(2048), http.StatusBadRequest},
+	} {
+		rec := getThumbnail(tc.filename, api.GetFilesFilenameThumbnailParams{Size: tc.size})
+		assert.Equal(t, tc.wantCode, rec.Code, tc.filename)
+	}
+
+	// deleting the image deletes its thumbnails
+	thumbnails, err = database.GetThumbnails(db, syncFile.Id)
+	assert.NoError(t, err)
+	rec = request(nil, func(ctx echo.Context) error {
+		return srv.DeleteFilesFilename(ctx, "photos/wide.png")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	for _, cached := range thumbnails {
+		_, err := srv.fstore.LoadFile(thumbnailPath(cached))
+		assert.Error(t, err)
+	}
+
+	// images deleted before their thumbnail is generated in the background
+	// don't leave a thumbnail behind
+	for i := 0; i < cap(srv.thumbnailSlots); i++ {
+		srv.thumbnailSlots <- struct{}{}
+	}
+	rec = request(createPng(t, 400, 200), func(ctx echo.Context) error {
+		return srv.PostFilesFilename(ctx, "photos/deleted.png")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	deleted, err := database.GetUserSyncFileByFilepath(db, user.Id, "photos/deleted.png")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	rec = request(nil, func(ctx echo.Context) error {
+		return srv.DeleteFilesFilename(ctx, "photos/deleted.png")
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	for i := 0; i < cap(srv.thumbnailSlots); i++ {
+		<-srv.thumbnailSlots
+	}
+	srv.WaitForThumbnails()
+	thumbnails, err = database.GetThumbnails(db, deleted.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, thumbnails)
+	_, err = srv.fstore.LoadFile(thumbnailPath(&database.Thumbnail{FileId: deleted.Id, Size: DefaultThumbnailSize, Etag: deleted.Etag}))
+	assert.ErrorIs(t, err, filestore.ErrFileNotFound)
+
+	// and neither do images deleted while their thumbnail is generated
+	_, err = srv.cacheThumbnail(deleted, createPng(t, 400, 200), DefaultThumbnailSize)
+	assert.NoError(t, err)
+	thumbnails, err = database.GetThumbnails(db, deleted.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, thumbnails)
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+)
+
+const (
+	defaultTreeDepth = 1
+	maxTreeDepth     = 64
+)
+
+// Get the folder tree of the vault
+// (GET /tree)
+func (o *ObsyncServer) GetTree(ctx echo.Context, params api.GetTreeParams) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	root := ""
+	if params.Path != nil && len(strings.Trim(*params.Path, "/")) > 0 {
+		root, err = cleanFolder(*params.Path)
+		if err != nil {
+			return sendApiMessage(ctx, http.StatusBadRequest, "invalid path")
+		}
+	}
+	depth := defaultTreeDepth
+	if params.Depth != nil {
+		depth = *params.Depth
+	}
+	if depth < 0 || depth > maxTreeDepth {
+		return sendApiMessage(ctx, http.StatusBadRequest, "depth must be between 0 and 64")
+	}
+
+	query := database.SyncFileQuery{}
+	if len(root) > 0 {
+		query.Prefix = root + "/"
+	}
+	syncFiles, _, err := database.ListSyncFiles(o.db, vault.OwnerId, query)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	folders, err := database.GetFolders(o.db, vault.OwnerId, root)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	matcher, err := o.ignoreMatcher(vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	// folders only exist while they contain something or were created
+	if len(root) > 0 && len(syncFiles) == 0 && len(folders) == 0 {
+		if _, err := database.GetFolder(o.db, vault.OwnerId, root); err != nil {
+			if errors.Is(err, database.ErrNoResults) {
+				return sendApiMessage(ctx, http.StatusNotFound, "folder not found")
+			}
+			ctx.Logger().Print(err)
+			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		}
+	}
+
+	tree := newFolderTree(root)
+	for _, folder := range folders {
+		if !matcher.MatchDir(folder.Path) {
+			tree.folder(relativeParts(root, folder.Path))
+		}
+	}
+	for _, syncFile := range filterIgnored(syncFiles, matcher) {
+		tree.addFile(relativeParts(root, syncFile.Filepath), syncFile)
+	}
+	tree.aggregate()
+
+	return ctx.JSON(http.StatusOK, tree.toApi(depth))
+}
+
+// Create an empty folder
+// (POST /folders/{folder})
+func (o *ObsyncServer) PostFoldersFolder(ctx echo.Context, folder string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	folder, err = cleanFolder(folder)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid folder path")
+	}
+	if reservedFilename(folder) {
+		return sendReserved(ctx)
+	}
+	if !vault.canWrite(folder) {
+		return sendForbidden(ctx)
+	}
+	matcher, err := o.ignoreMatcher(vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if matcher.MatchDir(folder) {
+		return sendIgnored(ctx)
+	}
+
+	_, err = database.GetUserSyncFileByFilepath(o.db, vault.OwnerId, folder)
+	if err == nil {
+		return sendApiMessage(ctx, http.StatusConflict, "file already exists")
+	} else if !errors.Is(err, database.ErrNoResults) {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	hasFiles, err := o.folderHasFiles(vault.OwnerId, folder)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if hasFiles {
+		return sendApiMessage(ctx, http.StatusConflict, "folder already exists")
+	}
+
+	if _, err := database.CreateFolder(o.db, vault.OwnerId, folder); err != nil {
+		if errors.Is(err, database.ErrFolderExists) {
+			return sendApiMessage(ctx, http.StatusConflict, "folder already exists")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "folder created")
+}
+
+// Delete an empty folder
+// (DELETE /folders/{folder})
+func (o *ObsyncServer) DeleteFoldersFolder(ctx echo.Context, folder string) error {
+	vault, err := o.openVault(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	folder, err = cleanFolder(folder)
+	if err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid folder path")
+	}
+	if !vault.canWrite(folder) {
+		return sendForbidden(ctx)
+	}
+
+	// ignored files count too, since they're still stored in the folder
+	hasFiles, err := o.folderHasFiles(vault.OwnerId, folder)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	subfolders, err := database.GetFolders(o.db, vault.OwnerId, folder)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if hasFiles || len(subfolders) > 0 {
+		return sendApiMessage(ctx, http.StatusConflict, "folder is not empty")
+	}
+
+	if err := database.DeleteFolder(o.db, vault.OwnerId, folder); err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "folder not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	return sendApiMessage(ctx, http.StatusOK, "folder deleted")
+}
+
+// Check whether any files are synced inside a folder or its subfolders.
+func (o *ObsyncServer) folderHasFiles(ownerId uint64, folder string) (bool, error) {
+	syncFiles, _, err := database.ListSyncFiles(o.db, ownerId, database.SyncFileQuery{
+		Prefix: folder + "/",
+		Limit:  1,
+	})
+	return len(syncFiles) > 0, err
+}
+
+// Clean a folder path, which can have leading and trailing slashes.
+func cleanFolder(folder string) (string, error) {
+	return cleanFilename(strings.Trim(folder, "/"))
+}
+
+// Split a path inside the root folder into the names of the folders and file
+// below the root.
+func relativeParts(root, filename string) []string {
+	if len(root) > 0 {
+		filename = strings.TrimPrefix(filename, root+"/")
+	}
+	return strings.Split(filename, "/")
+}
+
+// Folder in the tree of a vault's files, with every file and folder below it.
+type folderTree struct {
+	node    api.TreeNode
+	folders map[string]*folderTree
+	files   []api.TreeNode
+	// totals of everything below the folder, set by aggregate
+	fileCount   int
+	folderCount int
+}
+
+func newFolderTree(folder string) *folderTree {
+	name := ""
+	if len(folder) > 0 {
+		name = path.Base(folder)
+	}
+	return &folderTree{
+		node: api.TreeNode{
+			Name: name,
+			Path: folder,
+			Type: api.TreeNodeTypeFolder,
+		},
+		folders: map[string]*folderTree{},
+	}
+}
+
+// Get the folder at the path below this one, adding it and the folders
+// between them to the tree if they're missing.
+func (f *folderTree) folder(parts []string) *folderTree {
+	if len(parts) == 0 {
+		return f
+	}
+	child, ok := f.folders[parts[0]]
+	if !ok {
+		child = newFolderTree(path.Join(f.node.Path, parts[0]))
+		f.folders[parts[0]] = child
+	}
+	return child.folder(parts[1:])
+}
+
+// Add a file at the path below this folder.
+func (f *folderTree) addFile(parts []string, syncFile *database.SyncFile) {
+	parent := f.folder(parts[:len(parts)-1])
+	updatedAt := syncFile.UpdatedAt
+	parent.files = append(parent.files, api.TreeNode{
+		Name:      parts[len(parts)-1],
+		Path:      syncFile.Filepath,
+		Type:      api.TreeNodeTypeFile,
+		Size:      syncFile.Size,
+		UpdatedAt: &updatedAt,
+	})
+}
+
+// Total the counts, sizes and modification times of every folder in the tree.
+func (f *folderTree) aggregate() {
+	var latest time.Time
+	for _, child := range f.folders {
+		child.aggregate()
+		f.fileCount += child.fileCount
+		f.folderCount += child.folderCount + 1
+		f.node.Size += child.node.Size
+		if child.node.UpdatedAt != nil && child.node.UpdatedAt.After(latest) {
+			latest = *child.node.UpdatedAt
+		}
+	}
+	for _, file := range f.files {
+		f.fileCount++
+		f.node.Size += file.Size
+		if file.UpdatedAt.After(latest) {
+			latest = *file.UpdatedAt
+		}
+	}
+	if !latest.IsZero() {
+		f.node.UpdatedAt = &latest
+	}
+}
+
+// Convert the tree to its API model, including the files and folders down to
+// depth levels below this folder.
+func (f *folderTree) toApi(depth int) api.TreeNode {
+	node := f.node
+	fileCount, folderCount := f.fileCount, f.folderCount
+	node.FileCount, node.FolderCount = &fileCount, &folderCount
+	if depth == 0 {
+		return node
+	}
+
+	names := make([]string, 0, len(f.folders))
+	for name := range f.folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	children := make([]api.TreeNode, 0, len(f.folders)+len(f.files))
+	for _, name := range names {
+		children = append(children, f.folders[name].toApi(depth-1))
+	}
+	files := append([]api.TreeNode{}, f.files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	children = append(children, files...)
+	node.Children = &children
+	return node
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-tree-routes")
+	viewer, viewerCookie := createTestSession(t, db, "test-tree-routes-viewer")
+	assert.NoError(t, database.SetVaultMember(db, user.Id, viewer.Id, database.VaultViewer, nil))
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	request := func(cookie *http.Cookie, body string, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader(body))
+		req.AddCookie(cookie)
+		req.Header.Set(VaultHeader, user.Username)
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	createFolder := func(cookie *http.Cookie, folder string) int {
+		t.Helper()
+		return request(cookie, "", func(ctx echo.Context) error {
+			return srv.PostFoldersFolder(ctx, folder)
+		}).Code
+	}
+	deleteFolder := func(folder string) int {
+		t.Helper()
+		return request(cookie, "", func(ctx echo.Context) error {
+			return srv.DeleteFoldersFolder(ctx, folder)
+		}).Code
+	}
+	getTree := func(path string, depth int) (api.TreeNode, int) {
+		t.Helper()
+		rec := request(cookie, "", func(ctx echo.Context) error {
+			return srv.GetTree(ctx, api.GetTreeParams{Path: &path, Depth: &depth})
+		})
+		var tree api.TreeNode
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tree))
+		}
+		return tree, rec.Code
+	}
+
+	for filename, data := range map[string]string{
+		"todo.md":                    "12345",
+		"notes/a.md":                 "123",
+		"notes/b.md":                 "1234567",
+		"notes/drafts/c.md":          "12",
+		".obsidian/workspace.json":   "{}",
+		"attachments/image/logo.png": "1",
+	} {
+		rec := request(cookie, data, func(ctx echo.Context) error {
+			return srv.PostFilesFilename(ctx, filename)
+		})
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+	}
+	body, _ := json.Marshal(api.IgnoreRules{Rules: ".obsidian/\n"})
+	assert.Equal(t, http.StatusOK, request(cookie, string(body), srv.PutIgnoreRules).Code)
+
+	t.Run("create and delete folders", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, createFolder(cookie, "/notes/empty/"))
+		assert.Equal(t, http.StatusOK, createFolder(cookie, "archive/2023"))
+		assert.Equal(t, http.StatusConflict, createFolder(cookie, "notes/empty"))
+		assert.Equal(t, http.StatusConflict, createFolder(cookie, "notes/drafts"))
+		assert.Equal(t, http.StatusConflict, createFolder(cookie, "todo.md"))
+		assert.Equal(t, http.StatusBadRequest, createFolder(cookie, "../outside"))
+		assert.Equal(t, http.StatusUnprocessableEntity, createFolder(cookie, ".obsidian/plugins"))
+		assert.Equal(t, http.StatusForbidden, createFolder(viewerCookie, "viewer"))
+
+		assert.Equal(t, http.StatusOK, createFolder(cookie, "archive"))
+		assert.Equal(t, http.StatusConflict, deleteFolder("archive"))
+		assert.Equal(t, http.StatusConflict, deleteFolder("notes"))
+		assert.Equal(t, http.StatusNotFound, deleteFolder("missing"))
+		assert.Equal(t, http.StatusOK, deleteFolder("archive/2023"))
+		assert.Equal(t, http.StatusOK, deleteFolder("archive"))
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, createFolder(cookie, "archive"))
+
+		tree, code := getTree("", 1)
+		if assert.Equal(t, http.StatusOK, code) {
+			assert.Equal(t, "", tree.Name)
+			assert.Equal(t, api.TreeNodeTypeFolder, tree.Type)
+			assert.Equal(t, 5, *tree.FileCount)
+			// archive, attachments, attachments/image, notes, notes/drafts and
+			// notes/empty
+			assert.Equal(t, 6, *tree.FolderCount)
+			assert.Equal(t, int64(18), tree.Size)
+			names := []string{}
+			for _, child := range *tree.Children {
+				names = append(names, child.Name)
+				if child.Type == api.TreeNodeTypeFolder {
+					assert.Nil(t, child.Children, child.Name)
+				}
+			}
+			assert.Equal(t, []string{"archive", "attachments", "notes", "todo.md"}, names)
+			archive := (*tree.Children)[0]
+			assert.Equal(t, 0, *archive.FileCount)
+			assert.Nil(t, archive.UpdatedAt)
+		}
+
+		tree, code = getTree("notes/", 2)
+		if assert.Equal(t, http.StatusOK, code) {
+			assert.Equal(t, "notes", tree.Name)
+			assert.Equal(t, "notes", tree.Path)
+			assert.Equal(t, 3, *tree.FileCount)
+			assert.Equal(t, 2, *tree.FolderCount)
+			assert.Equal(t, int64(12), tree.Size)
+			if assert.Len(t, *tree.Children, 4) {
+				drafts := (*tree.Children)[0]
+				assert.Equal(t, "notes/drafts", drafts.Path)
+				if assert.Len(t, *drafts.Children, 1) {
+					file := (*drafts.Children)[0]
+					assert.Equal(t, api.TreeNodeTypeFile, file.Type)
+					assert.Equal(t, "notes/drafts/c.md", file.Path)
+					assert.Equal(t, int64(2), file.Size)
+					assert.True(t, drafts.UpdatedAt.Equal(*file.UpdatedAt))
+				}
+				assert.Equal(t, "notes/empty", (*tree.Children)[1].Path)
+				assert.Equal(t, "b.md", (*tree.Children)[3].Name)
+				assert.Nil(t, (*tree.Children)[3].Children)
+			}
+		}
+
+		tree, code = getTree("notes/empty", 0)
+		if assert.Equal(t, http.StatusOK, code) {
+			assert.Equal(t, 0, *tree.FileCount)
+			assert.Nil(t, tree.Children)
+		}
+
+		_, code = getTree("missing", 1)
+		assert.Equal(t, http.StatusNotFound, code)
+		_, code = getTree("../notes", 1)
+		assert.Equal(t, http.StatusBadRequest, code)
+		_, code = getTree("", maxTreeDepth+1)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+	assert.NoError(t, database.DeleteUser(db, viewer.Id))
+}
@@ -2,8 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/raian621/obsync-server/api"
@@ -36,6 +38,16 @@ func (o *ObsyncServer) DeleteUser(ctx echo.Context) error {
 // Create a user
 // (POST /user)
 func (o *ObsyncServer) PostUser(ctx echo.Context) error {
+	policy := o.registration
+	if policy.Mode == RegistrationClosed {
+		return sendApiMessage(ctx, http.StatusForbidden, "registration is closed")
+	}
+	// attempts are counted rather than sign-ups, so invite codes can't be
+	// guessed quickly either
+	if policy.RateLimit > 0 && !o.signups.allow(ctx.RealIP(), policy.RateLimit, policy.RateWindow, time.Now()) {
+		return sendApiMessage(ctx, http.StatusTooManyRequests, "too many sign-ups, try again later")
+	}
+
 	var user api.User
 
 	err := json.NewDecoder(ctx.Request().Body).Decode(&user)
@@ -43,33 +55,60 @@ func (o *ObsyncServer) PostUser(ctx echo.Context) error {
 		ctx.Logger().Print(err)
 		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
 	}
+	if !emailDomainAllowed(string(user.Email), policy.AllowedEmailDomains) {
+		return sendApiMessage(ctx, http.StatusBadRequest, "email domain not allowed")
+	}
 
 	// create user
-	_, err = database.CreateUser(o.db, user.Username, string(user.Email), user.Password)
+	var created *database.User
+	if policy.Mode == RegistrationInvite {
+		if user.Invite == nil || len(*user.Invite) == 0 {
+			return sendApiMessage(ctx, http.StatusForbidden, "an invite code is needed to sign up")
+		}
+		created, err = database.CreateUserWithInvite(o.db, user.Username, string(user.Email), user.Password, *user.Invite)
+	} else {
+		created, err = database.CreateUser(o.db, user.Username, string(user.Email), user.Password)
+	}
 	if err != nil {
-		ctx.Logger().Print(err)
-		switch err {
-		case database.ErrUsernameFormat:
-			return sendApiMessage(ctx, http.StatusBadRequest, "invalid username")
-		case database.ErrEmailFormat:
-			return sendApiMessage(ctx, http.StatusBadRequest, "invalid email")
-		case database.ErrPasswordLength:
-			return sendApiMessage(ctx, http.StatusBadRequest, "password too short")
-		default:
-			return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+		switch {
+		case errors.Is(err, database.ErrNoResults):
+			return sendApiMessage(ctx, http.StatusForbidden, "invalid invite code")
+		case errors.Is(err, database.ErrInviteExpired):
+			return sendApiMessage(ctx, http.StatusForbidden, "invite code expired")
+		case errors.Is(err, database.ErrInviteUsedUp):
+			return sendApiMessage(ctx, http.StatusForbidden, "invite code has been used up")
 		}
+		return sendCreateUserError(ctx, err)
 	}
+	o.queueEmail(ctx, func() error { return o.sendVerificationEmail(created) })
 
 	return ctx.JSON(
 		http.StatusOK,
 		map[string]any{
-			"username": user.Username,
-			"email":    user.Email,
-			"id":       user.Id,
+			"username": created.Username,
+			"email":    created.Email,
+			"id":       created.Id,
 		},
 	)
 }
 
+// Send the response for an error returned by database.CreateUser.
+func sendCreateUserError(ctx echo.Context, err error) error {
+	ctx.Logger().Print(err)
+	switch {
+	case errors.Is(err, database.ErrUsernameFormat):
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid username")
+	case errors.Is(err, database.ErrEmailFormat):
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid email")
+	case errors.Is(err, database.ErrPasswordLength):
+		return sendApiMessage(ctx, http.StatusBadRequest, "password too short")
+	case errors.Is(err, database.ErrUserExists):
+		return sendApiMessage(ctx, http.StatusConflict, "username or email already taken")
+	default:
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+}
+
 // Log in a user
 // (POST /user/login)
 func (o *ObsyncServer) PostUserLogin(ctx echo.Context) error {
@@ -92,6 +131,9 @@ func (o *ObsyncServer) PostUserLogin(ctx echo.Context) error {
 		ctx.Logger().Print(err)
 		return sendApiMessage(ctx, http.StatusNotFound, "username or password invalid")
 	}
+	if user.Disabled {
+		return sendApiMessage(ctx, http.StatusForbidden, "account is disabled")
+	}
 
 	// create user session if user is authenticated
 	session, err := database.CreateSession(o.db, user.Id)
@@ -152,6 +194,9 @@ func (o *ObsyncServer) PutUserEmail(ctx echo.Context) error {
 		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
 	}
 
+	if !emailDomainAllowed(email, o.registration.AllowedEmailDomains) {
+		return sendApiMessage(ctx, http.StatusBadRequest, "email domain not allowed")
+	}
 	err = database.UpdateUserEmail(o.db, session.UserId, email)
 	if err != nil {
 		ctx.Logger().Print(err)
@@ -160,6 +205,15 @@ func (o *ObsyncServer) PutUserEmail(ctx echo.Context) error {
 		}
 		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
 	}
+	// new addresses have to be verified again
+	user, err := database.GetUserById(o.db, session.UserId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if !user.EmailVerified {
+		o.queueEmail(ctx, func() error { return o.sendVerificationEmail(user) })
+	}
 
 	return sendApiMessage(ctx, http.StatusOK, "email updated")
 }
@@ -197,6 +251,7 @@ func (o *ObsyncServer) PutUserPassword(ctx echo.Context) error {
 		}
 		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
 	}
+	o.davLogins.forget(session.UserId)
 
 	return sendApiMessage(ctx, http.StatusOK, "password updated")
 }
@@ -234,6 +289,7 @@ func (o *ObsyncServer) PutUserUsername(ctx echo.Context) error {
 		}
 		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
 	}
+	o.davLogins.forget(session.UserId)
 
 	return sendApiMessage(ctx, http.StatusOK, "username updated")
 }
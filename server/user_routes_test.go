@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/raian621/obsync-server/database"
@@ -79,6 +80,20 @@ func TestUserLoginAndLogout(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, rec.Code)
 	}
 
+	// disabled users can't log in
+	assert.NoError(t, database.SetUserDisabled(db, user.Id, true))
+	credentials["username"] = user.Username
+	body, err = json.Marshal(credentials)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/user/login", bytes.NewBuffer(body))
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	if assert.NoError(t, srv.PostUserLogin(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	}
+
 	// logout as user
 	req = httptest.NewRequest(http.MethodPost, "/api/v1/user/logout", nil)
 	req.AddCookie(cookie)
@@ -209,6 +224,162 @@ func TestPostUser(t *testing.T) {
 	}
 }
 
+func TestPostUserRegistration(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	inviter, _ := createTestSession(t, db, "test-registration-inviter")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	invite, err := database.CreateInvite(db, &database.Invite{UserId: inviter.Id, MaxUses: 1})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	expired, err := database.CreateInvite(db, &database.Invite{UserId: inviter.Id, Expires: time.Now().Add(-time.Hour)})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	signUpWithHeader := func(ip string, header http.Header, username, email, invite string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(map[string]string{
+			"username": username,
+			"email":    email,
+			"password": "not a password",
+			"invite":   invite,
+		})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/user", bytes.NewBuffer(body))
+		req.RemoteAddr = ip + ":1234"
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, srv.PostUser(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	signUp := func(ip, username, email, invite string) *httptest.ResponseRecorder {
+		t.Helper()
+		return signUpWithHeader(ip, nil, username, email, invite)
+	}
+	created := []string{}
+
+	t.Run("closed", func(t *testing.T) {
+		srv.SetRegistration(RegistrationPolicy{Mode: RegistrationClosed})
+		rec := signUp("192.0.2.10", "test-registration-closed", "test-registration-closed@example.com", "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("invite only", func(t *testing.T) {
+		srv.SetRegistration(RegistrationPolicy{Mode: RegistrationInvite, RateLimit: -1})
+		for _, tc := range []struct {
+			invite   string
+			wantCode int
+		}{
+			{"", http.StatusForbidden},
+			{"not an invite", http.StatusForbidden},
+			{expired.Code, http.StatusForbidden},
+			{invite.Code, http.StatusOK},
+			{invite.Code, http.StatusForbidden},
+		} {
+			rec := signUp("192.0.2.11", "test-registration-invited", "test-registration-invited@example.com", tc.invite)
+			assert.Equal(t, tc.wantCode, rec.Code, tc.invite)
+		}
+		created = append(created, "test-registration-invited")
+	})
+
+	t.Run("email domains", func(t *testing.T) {
+		srv.SetRegistration(RegistrationPolicy{AllowedEmailDomains: []string{"example.com"}, RateLimit: -1})
+		rec := signUp("192.0.2.12", "test-registration-domain", "test-registration-domain@example.org", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = signUp("192.0.2.12", "test-registration-domain", "test-registration-domain@example.com", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		created = append(created, "test-registration-domain")
+		// usernames and emails can't be taken twice
+		rec = signUp("192.0.2.12", "test-registration-domain", "test-registration-domain@example.com", "")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		srv.SetRegistration(RegistrationPolicy{RateLimit: 2, RateWindow: time.Hour})
+		// invalid sign-ups count too
+		rec := signUp("192.0.2.13", "", "test-registration-limited@example.com", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = signUp("192.0.2.13", "test-registration-limited", "test-registration-limited@example.com", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		created = append(created, "test-registration-limited")
+		rec = signUp("192.0.2.13", "test-registration-limited-2", "test-registration-limited-2@example.com", "")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		rec = signUp("192.0.2.14", "test-registration-limited-2", "test-registration-limited-2@example.com", "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		created = append(created, "test-registration-limited-2")
+	})
+
+	t.Run("spoofed client addresses", func(t *testing.T) {
+		extractor, err := NewIPExtractor(nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		e.IPExtractor = extractor
+		defer func() { e.IPExtractor = nil }()
+		srv.SetRegistration(RegistrationPolicy{RateLimit: 1, RateWindow: time.Hour})
+
+		rec := signUp("192.0.2.15", "", "test-registration-spoofed@example.com", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		for _, header := range []http.Header{
+			{echo.HeaderXForwardedFor: {"198.51.100.1"}},
+			{echo.HeaderXRealIP: {"198.51.100.2"}},
+			{echo.HeaderXForwardedFor: {"198.51.100.3, 198.51.100.4"}, echo.HeaderXRealIP: {"198.51.100.5"}},
+		} {
+			rec := signUpWithHeader("192.0.2.15", header, "", "test-registration-spoofed@example.com", "")
+			assert.Equal(t, http.StatusTooManyRequests, rec.Code, header)
+		}
+	})
+
+	t.Run("trusted proxies", func(t *testing.T) {
+		extractor, err := NewIPExtractor([]string{"192.0.2.16", "2001:db8::/32"})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		e.IPExtractor = extractor
+		defer func() { e.IPExtractor = nil }()
+		srv.SetRegistration(RegistrationPolicy{RateLimit: 1, RateWindow: time.Hour})
+
+		forwardedFor := func(addresses string) http.Header {
+			return http.Header{echo.HeaderXForwardedFor: {addresses}}
+		}
+		// clients behind the proxy are limited separately
+		rec := signUpWithHeader("192.0.2.16", forwardedFor("198.51.100.6"), "", "test-registration-proxied@example.com", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = signUpWithHeader("192.0.2.16", forwardedFor("198.51.100.7"), "", "test-registration-proxied@example.com", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		// addresses the client adds before the proxy's are ignored
+		rec = signUpWithHeader("192.0.2.16", forwardedFor("198.51.100.8, 198.51.100.6"), "", "test-registration-proxied@example.com", "")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		// untrusted proxies can't pick the client's address
+		rec = signUpWithHeader("192.0.2.17", forwardedFor("198.51.100.9"), "", "test-registration-proxied@example.com", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = signUpWithHeader("192.0.2.17", forwardedFor("198.51.100.10"), "", "test-registration-proxied@example.com", "")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+		_, err = NewIPExtractor([]string{"proxy.example.com"})
+		assert.ErrorIs(t, err, ErrInvalidTrustedProxy)
+	})
+
+	for _, username := range created {
+		user, err := database.GetUserByUsername(db, username)
+		if assert.NoError(t, err) {
+			assert.NoError(t, database.DeleteUser(db, user.Id))
+		}
+	}
+	assert.NoError(t, database.DeleteUser(db, inviter.Id))
+}
+
 func TestUserUpdateRoutes(t *testing.T) {
 	db := createTestDB(t)
 	user, err := database.CreateUser(db, "test-user-update", "test-user-update@example.com", "not a password")
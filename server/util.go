@@ -1,10 +1,33 @@
 package server
 
 import (
+	"database/sql"
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
 	"github.com/labstack/echo/v4"
 	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+)
+
+var (
+	ErrNotAuthenticated = errors.New("user is not authenticated")
+	ErrVaultNotFound    = errors.New("vault not found")
 )
 
+// Header that names the owner of the vault a request reads or changes files
+// in. Requests without it use the user's own vault.
+const VaultHeader = "Obsync-Vault"
+
+// Query parameter that names the vault's owner in place of the vault header,
+// for requests that can't set headers like links in rendered notes.
+const VaultParam = "vault"
+
 func sendApiMessage(ctx echo.Context, code int32, message string) error {
 	var res api.ApiResponse
 	res.Code = &code
@@ -14,3 +37,226 @@ func sendApiMessage(ctx echo.Context, code int32, message string) error {
 		res,
 	)
 }
+
+// Get the id of the user making the request using their session cookie.
+func (o *ObsyncServer) authenticate(ctx echo.Context) (uint64, error) {
+	sessionCookie, err := ctx.Cookie("OBSYNC_SESSION_ID")
+	if err != nil {
+		return 0, ErrNotAuthenticated
+	}
+
+	session, err := database.GetSessionBySessionKey(o.db, sessionCookie.Value)
+	if err != nil {
+		if errors.Is(err, database.ErrExpiredSession) || errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotAuthenticated
+		}
+		return 0, err // unexpected error
+	}
+
+	return session.UserId, nil
+}
+
+// Vault a request reads or changes files in, along with the user making the
+// request.
+type vaultAccess struct {
+	UserId uint64
+	// Id of the vault's owner, which the vault's files are synced under
+	OwnerId uint64
+	// Membership of the user in the vault, or nil for the user's own vault
+	member *database.VaultMember
+}
+
+// Authenticate the user making the request and open the vault named by the
+// request's Obsync-Vault header, or its vault query parameter without one.
+// Users can only open vaults they own or are members of, and other vaults
+// give ErrVaultNotFound.
+func (o *ObsyncServer) openVault(ctx echo.Context) (*vaultAccess, error) {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owner := ctx.Request().Header.Get(VaultHeader)
+	if len(owner) == 0 {
+		owner = ctx.QueryParam(VaultParam)
+	}
+	if len(owner) == 0 {
+		return &vaultAccess{UserId: userId, OwnerId: userId}, nil
+	}
+	return o.userVault(userId, owner)
+}
+
+// Open the vault owned by a user with the given username.
+func (o *ObsyncServer) userVault(userId uint64, owner string) (*vaultAccess, error) {
+	ownerUser, err := database.GetUserByUsername(o.db, owner)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, database.ErrUsernameFormat) {
+			return nil, ErrVaultNotFound
+		}
+		return nil, err
+	}
+	if ownerUser.Id == userId {
+		return &vaultAccess{UserId: userId, OwnerId: userId}, nil
+	}
+
+	member, err := database.GetVaultMember(o.db, ownerUser.Id, userId)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return nil, ErrVaultNotFound
+		}
+		return nil, err
+	}
+	return &vaultAccess{UserId: userId, OwnerId: ownerUser.Id, member: member}, nil
+}
+
+// Get the user's role for a file in the vault. Folder roles override the
+// member's role, and the most specific folder wins. The empty filename is the
+// vault's root, and unsafe filenames only get the viewer role so they can't
+// slip past a folder's role with "..".
+func (v *vaultAccess) role(filename string) database.VaultRole {
+	if v.member == nil {
+		return database.VaultOwner
+	}
+	if len(filename) > 0 {
+		cleaned, err := cleanFilename(filename)
+		if err != nil {
+			return database.VaultViewer
+		}
+		filename = cleaned
+	}
+	role, matched := v.member.Role, -1
+	for folder, folderRole := range v.member.Folders {
+		if (filename == folder || strings.HasPrefix(filename, folder+"/")) && len(folder) > matched {
+			role, matched = folderRole, len(folder)
+		}
+	}
+	return role
+}
+
+func (v *vaultAccess) canWrite(filename string) bool {
+	return v.role(filename).CanWrite()
+}
+
+// Check whether the user can manage the vault's members.
+func (v *vaultAccess) isOwner() bool {
+	return v.member == nil || v.member.Role == database.VaultOwner
+}
+
+// Send the response for an error returned by authenticate.
+func sendAuthError(ctx echo.Context, err error) error {
+	ctx.Logger().Print(err)
+	if errors.Is(err, ErrNotAuthenticated) {
+		return sendApiMessage(ctx, http.StatusUnauthorized, "not authenticated")
+	}
+	if errors.Is(err, ErrVaultNotFound) {
+		return sendApiMessage(ctx, http.StatusNotFound, "vault not found")
+	}
+	return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+}
+
+// Send the response for a change the user's role in a vault doesn't allow.
+func sendForbidden(ctx echo.Context) error {
+	return sendApiMessage(ctx, http.StatusForbidden, "not allowed to change files in this vault")
+}
+
+// Get the directory a user's files are kept in, named after the user's id.
+func userDir(userId uint64) string {
+	return strconv.FormatUint(userId, 10)
+}
+
+// Get the path of a user's file in the file store. Filenames that would leave
+// the user's directory give ErrUnsafePath, so a filename that wasn't cleaned
+// can't reach another user's files.
+func userFilePath(userId uint64, filename string) (string, error) {
+	dir := userDir(userId)
+	filePath := filepath.Join(dir, filename)
+	if !strings.HasPrefix(filePath, dir+string(filepath.Separator)) {
+		return "", ErrUnsafePath
+	}
+	return filePath, nil
+}
+
+func contentTypeForFile(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".md" {
+		return "text/markdown; charset=utf-8"
+	}
+	if contentType := mime.TypeByExtension(ext); len(contentType) > 0 {
+		return contentType
+	}
+	return echo.MIMEOctetStream
+}
+
+func isMarkdownFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".md")
+}
+
+// Check whether an If-None-Match header value matches an etag. Etags are
+// accepted with or without quotes.
+func etagMatches(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		value = strings.TrimPrefix(value, "W/")
+		if value == "*" || strings.Trim(value, `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the synced files inside a folder, including files in its subfolders. An
+// empty folder matches every file.
+func filterFolder(syncFiles []*database.SyncFile, folder string) []*database.SyncFile {
+	folder = strings.Trim(folder, "/")
+	if len(folder) == 0 {
+		return syncFiles
+	}
+
+	filtered := make([]*database.SyncFile, 0, len(syncFiles))
+	for _, syncFile := range syncFiles {
+		if syncFile.Filepath == folder || strings.HasPrefix(syncFile.Filepath, folder+"/") {
+			filtered = append(filtered, syncFile)
+		}
+	}
+	return filtered
+}
+
+var (
+	ErrUnsafePath   = errors.New("path is not a safe relative path")
+	ErrReservedPath = errors.New("path is reserved for the server")
+)
+
+// Folder the server keeps its own files in, like the manifest of exports.
+// Files can't be synced into it, so they never clash with the server's.
+const ReservedFolder = ".obsync"
+
+// Check whether a cleaned filename is in the reserved folder.
+func reservedFilename(filename string) bool {
+	return filename == ReservedFolder || strings.HasPrefix(filename, ReservedFolder+"/")
+}
+
+// Send the response for a file that would be in the reserved folder.
+func sendReserved(ctx echo.Context) error {
+	return sendApiMessage(ctx, http.StatusBadRequest, "filename is reserved for the server")
+}
+
+// Clean a filename that came from a client, making sure it stays inside the
+// user's files once it's joined onto their directory.
+func cleanFilename(filename string) (string, error) {
+	if len(filename) == 0 ||
+		strings.ContainsAny(filename, "\\\x00") ||
+		strings.HasPrefix(filename, "/") ||
+		(len(filename) > 1 && filename[1] == ':') {
+		return "", ErrUnsafePath
+	}
+	for _, part := range strings.Split(filename, "/") {
+		if part == ".." {
+			return "", ErrUnsafePath
+		}
+	}
+
+	cleaned := path.Clean(filename)
+	if cleaned == "." {
+		return "", ErrUnsafePath
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+)
+
+// Get the vaults the user can access
+// (GET /vaults)
+func (o *ObsyncServer) GetVaults(ctx echo.Context) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	vaults, err := database.GetUserVaults(o.db, userId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiVaults := make([]api.Vault, 0, len(vaults))
+	for _, vault := range vaults {
+		apiVaults = append(apiVaults, api.Vault{
+			Owner: vault.OwnerUsername,
+			Role:  api.VaultRole(vault.Role),
+		})
+	}
+	return ctx.JSON(http.StatusOK, apiVaults)
+}
+
+// Get the members of a vault
+// (GET /vaults/{owner}/members)
+func (o *ObsyncServer) GetVaultsOwnerMembers(ctx echo.Context, owner string) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	vault, err := o.userVault(userId, owner)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	members, err := database.GetVaultMembers(o.db, vault.OwnerId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+
+	apiMembers := make([]api.VaultMember, 0, len(members))
+	for _, member := range members {
+		apiMembers = append(apiMembers, toApiVaultMember(member))
+	}
+	return ctx.JSON(http.StatusOK, apiMembers)
+}
+
+// Add a member to a vault or change their role
+// (PUT /vaults/{owner}/members/{username})
+func (o *ObsyncServer) PutVaultsOwnerMembersUsername(ctx echo.Context, owner string, username string) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	vault, err := o.userVault(userId, owner)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	if !vault.isOwner() {
+		return sendApiMessage(ctx, http.StatusForbidden, "only owners can manage the vault's members")
+	}
+
+	var body api.VaultMemberUpdate
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	role := database.VaultRole(body.Role)
+	if !role.Valid() {
+		return sendApiMessage(ctx, http.StatusBadRequest, "invalid role")
+	}
+	folders := map[string]database.VaultRole{}
+	if body.Folders != nil {
+		for folder, folderRole := range *body.Folders {
+			folder, err := cleanFilename(strings.Trim(folder, "/"))
+			if err != nil {
+				return sendApiMessage(ctx, http.StatusBadRequest, "invalid folder")
+			}
+			if folderRole != api.Editor && folderRole != api.Viewer {
+				return sendApiMessage(ctx, http.StatusBadRequest, "folder roles can only be editor or viewer")
+			}
+			folders[folder] = database.VaultRole(folderRole)
+		}
+	}
+
+	memberId, err := o.userIdByUsername(username)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "user not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	if memberId == vault.OwnerId {
+		return sendApiMessage(ctx, http.StatusBadRequest, "the vault's owner can't be a member of it")
+	}
+
+	if err := database.SetVaultMember(o.db, vault.OwnerId, memberId, role, folders); err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	member, err := database.GetVaultMember(o.db, vault.OwnerId, memberId)
+	if err != nil {
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return ctx.JSON(http.StatusOK, toApiVaultMember(member))
+}
+
+// Remove a member from a vault
+// (DELETE /vaults/{owner}/members/{username})
+func (o *ObsyncServer) DeleteVaultsOwnerMembersUsername(ctx echo.Context, owner string, username string) error {
+	userId, err := o.authenticate(ctx)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+	vault, err := o.userVault(userId, owner)
+	if err != nil {
+		return sendAuthError(ctx, err)
+	}
+
+	memberId, err := o.userIdByUsername(username)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "member not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	// members can always leave a vault
+	if !vault.isOwner() && memberId != userId {
+		return sendApiMessage(ctx, http.StatusForbidden, "only owners can manage the vault's members")
+	}
+
+	if err := database.DeleteVaultMember(o.db, vault.OwnerId, memberId); err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return sendApiMessage(ctx, http.StatusNotFound, "member not found")
+		}
+		ctx.Logger().Print(err)
+		return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+	}
+	return sendApiMessage(ctx, http.StatusOK, "member removed")
+}
+
+// Get the id of a user by their username, returning ErrNoResults if there's
+// no user with the username.
+func (o *ObsyncServer) userIdByUsername(username string) (uint64, error) {
+	user, err := database.GetUserByUsername(o.db, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, database.ErrUsernameFormat) {
+			return 0, database.ErrNoResults
+		}
+		return 0, err
+	}
+	return user.Id, nil
+}
+
+func toApiVaultMember(member *database.VaultMember) api.VaultMember {
+	folders := make(map[string]api.VaultRole, len(member.Folders))
+	for folder, role := range member.Folders {
+		folders[folder] = api.VaultRole(role)
+	}
+	return api.VaultMember{
+		Username:  member.Username,
+		Role:      api.VaultRole(member.Role),
+		Folders:   folders,
+		CreatedAt: member.CreatedAt,
+	}
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/api"
+	"github.com/raian621/obsync-server/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVaultRoutes(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	owner, ownerCookie := createTestSession(t, db, "test-vault-owner")
+	coOwner, coOwnerCookie := createTestSession(t, db, "test-vault-co-owner")
+	editor, editorCookie := createTestSession(t, db, "test-vault-editor")
+	viewer, viewerCookie := createTestSession(t, db, "test-vault-viewer")
+	outsider, outsiderCookie := createTestSession(t, db, "test-vault-outsider")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	request := func(cookie *http.Cookie, vault string, body io.Reader, handler func(echo.Context) error) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", body)
+		req.AddCookie(cookie)
+		if len(vault) > 0 {
+			req.Header.Set(VaultHeader, vault)
+		}
+		rec := httptest.NewRecorder()
+		if !assert.NoError(t, handler(e.NewContext(req, rec))) {
+			t.FailNow()
+		}
+		return rec
+	}
+	listFiles := func(ctx echo.Context) error {
+		return srv.GetListFiles(ctx, api.GetListFilesParams{})
+	}
+	setMember := func(cookie *http.Cookie, username, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		return request(cookie, "", strings.NewReader(body), func(ctx echo.Context) error {
+			return srv.PutVaultsOwnerMembersUsername(ctx, owner.Username, username)
+		})
+	}
+
+	for _, filename := range []string{"notes/existing.md", "shared/existing.md", "archive/existing.md"} {
+		rec := request(ownerCookie, "", strings.NewReader("# Existing\n#project"), func(ctx echo.Context) error {
+			return srv.PostFilesFilename(ctx, filename)
+		})
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			t.FailNow()
+		}
+	}
+
+	t.Run("manage members", func(t *testing.T) {
+		for _, tc := range []struct {
+			username string
+			body     string
+			wantCode int
+		}{
+			{coOwner.Username, `{"role": "owner"}`, http.StatusOK},
+			{editor.Username, `{"role": "editor", "folders": {"/archive/": "viewer"}}`, http.StatusOK},
+			{viewer.Username, `{"role": "viewer", "folders": {"shared": "editor"}}`, http.StatusOK},
+			{viewer.Username, `{"role": "admin"}`, http.StatusBadRequest},
+			{viewer.Username, `{"role": "viewer", "folders": {"shared": "owner"}}`, http.StatusBadRequest},
+			{viewer.Username, `{"role": "viewer", "folders": {"../shared": "editor"}}`, http.StatusBadRequest},
+			{viewer.Username, `not json`, http.StatusBadRequest},
+			{owner.Username, `{"role": "owner"}`, http.StatusBadRequest},
+			{"test-vault-missing", `{"role": "viewer"}`, http.StatusNotFound},
+		} {
+			rec := setMember(ownerCookie, tc.username, tc.body)
+			assert.Equal(t, tc.wantCode, rec.Code, tc.body)
+		}
+
+		rec := setMember(coOwnerCookie, outsider.Username, `{"role": "viewer"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		for _, cookie := range []*http.Cookie{editorCookie, viewerCookie} {
+			rec = setMember(cookie, outsider.Username, `{"role": "owner"}`)
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+		}
+		rec = request(editorCookie, "", nil, func(ctx echo.Context) error {
+			return srv.DeleteVaultsOwnerMembersUsername(ctx, owner.Username, outsider.Username)
+		})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		for _, wantCode := range []int{http.StatusOK, http.StatusNotFound} {
+			rec = request(coOwnerCookie, "", nil, func(ctx echo.Context) error {
+				return srv.DeleteVaultsOwnerMembersUsername(ctx, owner.Username, outsider.Username)
+			})
+			assert.Equal(t, wantCode, rec.Code)
+		}
+
+		// only members can see a vault's members
+		rec = request(outsiderCookie, "", nil, func(ctx echo.Context) error {
+			return srv.GetVaultsOwnerMembers(ctx, owner.Username)
+		})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = request(viewerCookie, "", nil, func(ctx echo.Context) error {
+			return srv.GetVaultsOwnerMembers(ctx, owner.Username)
+		})
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			var members []api.VaultMember
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &members))
+			if assert.Len(t, members, 3) {
+				assert.Equal(t, coOwner.Username, members[0].Username)
+				assert.Equal(t, api.Owner, members[0].Role)
+				assert.Equal(t, map[string]api.VaultRole{"archive": api.Viewer}, members[1].Folders)
+			}
+		}
+
+		rec = request(editorCookie, "", nil, srv.GetVaults)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.JSONEq(t, `[
+				{"owner": "test-vault-editor", "role": "owner"},
+				{"owner": "test-vault-owner", "role": "editor"}
+			]`, rec.Body.String())
+		}
+	})
+
+	// every member can read the vault's files, and outsiders can't tell the
+	// vault exists
+	t.Run("read files", func(t *testing.T) {
+		handlers := map[string]func(echo.Context) error{
+			"get": func(ctx echo.Context) error {
+				return srv.GetFilesFilename(ctx, "notes/existing.md", api.GetFilesFilenameParams{})
+			},
+			"list": listFiles,
+			"render": func(ctx echo.Context) error {
+				return srv.GetFilesFilenameRender(ctx, "notes/existing.md")
+			},
+			"backlinks": func(ctx echo.Context) error {
+				return srv.GetFilesFilenameBacklinks(ctx, "notes/existing.md")
+			},
+			"properties": func(ctx echo.Context) error {
+				return srv.GetFilesFilenameProperties(ctx, "notes/existing.md")
+			},
+			"graph": func(ctx echo.Context) error { return srv.GetGraph(ctx, api.GetGraphParams{}) },
+			"tags":  func(ctx echo.Context) error { return srv.GetTags(ctx, api.GetTagsParams{}) },
+			"export": func(ctx echo.Context) error {
+				return srv.GetExport(ctx, api.GetExportParams{})
+			},
+		}
+		for name, handler := range handlers {
+			for _, cookie := range []*http.Cookie{ownerCookie, coOwnerCookie, editorCookie, viewerCookie} {
+				rec := request(cookie, owner.Username, nil, handler)
+				assert.Equal(t, http.StatusOK, rec.Code, name)
+			}
+			rec := request(outsiderCookie, owner.Username, nil, handler)
+			assert.Equal(t, http.StatusNotFound, rec.Code, name)
+			rec = request(viewerCookie, "test-vault-missing", nil, handler)
+			assert.Equal(t, http.StatusNotFound, rec.Code, name)
+		}
+
+		// members have their own vaults too
+		rec := request(viewerCookie, "", nil, listFiles)
+		assert.JSONEq(t, "[]", rec.Body.String())
+		rec = request(viewerCookie, viewer.Username, nil, listFiles)
+		assert.JSONEq(t, "[]", rec.Body.String())
+	})
+
+	t.Run("change files", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			cookie   *http.Cookie
+			folder   string
+			wantCode int
+		}{
+			{"owner", ownerCookie, "archive", http.StatusOK},
+			{"co-owner", coOwnerCookie, "archive", http.StatusOK},
+			{"editor", editorCookie, "notes", http.StatusOK},
+			{"editor", editorCookie, "archive", http.StatusForbidden},
+			{"viewer", viewerCookie, "notes", http.StatusForbidden},
+			{"viewer", viewerCookie, "shared", http.StatusOK},
+			{"outsider", outsiderCookie, "notes", http.StatusNotFound},
+		} {
+			filename := tc.folder + "/" + tc.name + ".md"
+			renamed := tc.folder + "/" + tc.name + " renamed.md"
+			deleted := renamed
+			if tc.wantCode != http.StatusOK {
+				// files that can't be created are missing, so check the
+				// routes that change files against a file that exists
+				filename, deleted = tc.folder+"/existing.md", tc.folder+"/existing.md"
+			}
+			rename, _ := json.Marshal(api.FileRename{Filename: renamed})
+
+			for _, step := range []struct {
+				name    string
+				body    string
+				handler func(echo.Context) error
+			}{
+				{"post", "# New", func(ctx echo.Context) error {
+					return srv.PostFilesFilename(ctx, tc.folder+"/"+tc.name+".md")
+				}},
+				{"put", "# Changed", func(ctx echo.Context) error {
+					return srv.PutFilesFilename(ctx, filename, api.PutFilesFilenameParams{})
+				}},
+				{"rename", string(rename), func(ctx echo.Context) error {
+					return srv.PostFilesFilenameRename(ctx, filename)
+				}},
+				{"delete", "", func(ctx echo.Context) error {
+					return srv.DeleteFilesFilename(ctx, deleted)
+				}},
+				{"import", string(createZip(t, []zipEntry{{name: tc.folder + "/imported.md", data: []byte("# Imported")}})), func(ctx echo.Context) error {
+					return srv.PostImport(ctx, api.PostImportParams{})
+				}},
+			} {
+				rec := request(tc.cookie, owner.Username, strings.NewReader(step.body), step.handler)
+				assert.Equal(t, tc.wantCode, rec.Code, "%s %s in %s", tc.name, step.name, tc.folder)
+			}
+		}
+
+		// files can't be moved out of a folder the member can change into one
+		// they can't
+		rename, _ := json.Marshal(api.FileRename{Filename: "notes/moved.md"})
+		rec := request(viewerCookie, owner.Username, bytes.NewBuffer(rename), func(ctx echo.Context) error {
+			return srv.PostFilesFilenameRename(ctx, "shared/existing.md")
+		})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		// changes are synced under the owner and attributed to the member
+		rec = request(viewerCookie, owner.Username, strings.NewReader("# Changed by a viewer"), func(ctx echo.Context) error {
+			return srv.PutFilesFilename(ctx, "shared/existing.md", api.PutFilesFilenameParams{})
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		syncFile, err := database.GetUserSyncFileByFilepath(db, owner.Id, "shared/existing.md")
+		if assert.NoError(t, err) {
+			assert.Equal(t, viewer.Id, syncFile.UpdatedBy)
+		}
+		rec = request(ownerCookie, "", nil, listFiles)
+		var files []api.File
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &files))
+		for _, file := range files {
+			if *file.Filename == "shared/existing.md" {
+				assert.Equal(t, int64(viewer.Id), *file.UpdatedBy)
+			} else if *file.Filename == "notes/existing.md" {
+				assert.Equal(t, int64(owner.Id), *file.UpdatedBy)
+			}
+		}
+	})
+
+	t.Run("leave vault", func(t *testing.T) {
+		rec := request(viewerCookie, "", nil, func(ctx echo.Context) error {
+			return srv.DeleteVaultsOwnerMembersUsername(ctx, owner.Username, viewer.Username)
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = request(viewerCookie, owner.Username, nil, listFiles)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	for _, user := range []*database.User{owner, coOwner, editor, viewer, outsider} {
+		assert.NoError(t, database.DeleteUser(db, user.Id))
+	}
+}
+
+func TestVaultAccessRole(t *testing.T) {
+	t.Parallel()
+
+	vault := &vaultAccess{member: &database.VaultMember{
+		Role: database.VaultViewer,
+		Folders: map[string]database.VaultRole{
+			"shared":         database.VaultEditor,
+			"archive":        database.VaultViewer,
+			"shared/archive": database.VaultViewer,
+		},
+	}}
+	for filename, want := range map[string]database.VaultRole{
+		"":                         database.VaultViewer,
+		"private.md":               database.VaultViewer,
+		"shared":                   database.VaultEditor,
+		"shared/a.md":              database.VaultEditor,
+		"./shared/a.md":            database.VaultEditor,
+		"shared//a.md":             database.VaultEditor,
+		"shared-notes/a.md":        database.VaultViewer,
+		"shared/archive/a.md":      database.VaultViewer,
+		"shared/../private.md":     database.VaultViewer,
+		"shared/../../1/secret.md": database.VaultViewer,
+	} {
+		assert.Equal(t, want, vault.role(filename), filename)
+	}
+
+	// dot-dot paths can't leave a folder whose role is lower either
+	vault.member.Role = database.VaultEditor
+	for filename, want := range map[string]database.VaultRole{
+		"notes/a.md":             database.VaultEditor,
+		"archive/a.md":           database.VaultViewer,
+		"x/../archive/a.md":      database.VaultViewer,
+		"shared/./archive/a.md":  database.VaultViewer,
+		"shared/archive/../a.md": database.VaultViewer,
+	} {
+		assert.Equal(t, want, vault.role(filename), filename)
+		assert.Equal(t, want.CanWrite(), vault.canWrite(filename), filename)
+	}
+}
@@ -0,0 +1,864 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/raian621/obsync-server/ignore"
+	"golang.org/x/net/webdav"
+)
+
+// Path WebDAV is served under when the config doesn't set one.
+const DefaultWebDAVPath = "/dav"
+
+// How long a successful basic auth login is remembered, since WebDAV clients
+// send their credentials with every request and checking them is slow on
+// purpose. Remembered logins still stop working once the password or API key
+// they used changes.
+const davLoginTTL = 5 * time.Minute
+
+const (
+	// Failed basic auth logins a client can make for a username in
+	// DavLoginWindow
+	MaxDavLoginAttempts = 10
+	DavLoginWindow      = 15 * time.Minute
+)
+
+var ErrTooManyLogins = errors.New("too many failed logins")
+
+var davMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodDelete,
+	http.MethodOptions,
+	"PROPFIND",
+	"PROPPATCH",
+	"MKCOL",
+	"COPY",
+	"MOVE",
+	"LOCK",
+	"UNLOCK",
+}
+
+// Serve vaults over WebDAV under the prefix.
+func (o *ObsyncServer) RegisterWebDAV(e *echo.Echo, prefix string) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		prefix = DefaultWebDAVPath
+	}
+	handler := o.WebDAVHandler(prefix)
+	e.Match(davMethods, prefix, handler)
+	e.Match(davMethods, prefix+"/*", handler)
+}
+
+// Get a handler that serves the vault named by the request's Obsync-Vault
+// header over WebDAV, or the user's own vault without it. Users authenticate
+// with HTTP basic auth, using their password or one of their API keys, or
+// with their session cookie.
+func (o *ObsyncServer) WebDAVHandler(prefix string) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		userId, err := o.authenticateBasic(ctx)
+		if errors.Is(err, ErrTooManyLogins) {
+			return sendApiMessage(ctx, http.StatusTooManyRequests, "too many failed logins, try again later")
+		} else if err != nil {
+			if errors.Is(err, ErrNotAuthenticated) {
+				ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="Obsync"`)
+			}
+			return sendAuthError(ctx, err)
+		}
+		vault := &vaultAccess{UserId: userId, OwnerId: userId}
+		if owner := ctx.Request().Header.Get(VaultHeader); len(owner) > 0 {
+			vault, err = o.userVault(userId, owner)
+			if err != nil {
+				return sendAuthError(ctx, err)
+			}
+		}
+
+		// check changes up front so clients get the same responses as the
+		// REST API, instead of whatever WebDAV makes of a permission error
+		fs := &vaultFileSystem{o: o, ctx: ctx, vault: vault}
+		for _, change := range davChanges(ctx.Request(), prefix) {
+			if !vault.canWrite(change.filename) {
+				return sendForbidden(ctx)
+			}
+			if !change.adds {
+				continue
+			}
+			if reservedFilename(change.filename) {
+				return sendReserved(ctx)
+			}
+			matcher, err := fs.matcher()
+			if err != nil {
+				ctx.Logger().Print(err)
+				return sendApiMessage(ctx, http.StatusInternalServerError, "unexpected error occurred")
+			}
+			if (change.dir && matcher.MatchDir(change.filename)) || (!change.dir && matcher.Match(change.filename)) {
+				return sendIgnored(ctx)
+			}
+		}
+
+		handler := &webdav.Handler{
+			Prefix:     prefix,
+			FileSystem: fs,
+			LockSystem: o.davLockSystem(vault.OwnerId),
+			Logger: func(r *http.Request, err error) {
+				if err != nil {
+					ctx.Logger().Print(err)
+				}
+			},
+		}
+		handler.ServeHTTP(ctx.Response(), ctx.Request())
+		return nil
+	}
+}
+
+// Authenticate a user with HTTP basic auth, where the password can be the
+// user's password or one of their active API keys. Requests without basic
+// auth are authenticated with their session cookie. Clients that fail to log
+// in as a user too often get ErrTooManyLogins.
+func (o *ObsyncServer) authenticateBasic(ctx echo.Context) (uint64, error) {
+	username, password, ok := ctx.Request().BasicAuth()
+	if !ok {
+		return o.authenticate(ctx)
+	}
+	client := username + " " + ctx.RealIP()
+	if o.davPasswords.limited(client, MaxDavLoginAttempts, DavLoginWindow, time.Now()) {
+		return 0, ErrTooManyLogins
+	}
+	userId, err := o.checkBasicAuth(username, password)
+	if errors.Is(err, ErrNotAuthenticated) {
+		o.davPasswords.add(client, time.Now())
+	}
+	return userId, err
+}
+
+// Check a username and a password or API key given with basic auth.
+func (o *ObsyncServer) checkBasicAuth(username, password string) (uint64, error) {
+	user, err := database.GetUserByUsername(o.db, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, database.ErrUsernameFormat) {
+			return 0, ErrNotAuthenticated
+		}
+		return 0, err
+	}
+	if user.Disabled {
+		return 0, ErrNotAuthenticated
+	}
+
+	// remembered logins are checked against the hash they matched, which is
+	// quick, so changed passwords and revoked API keys stop working right
+	// away, even when they were changed by another process like the CLI
+	credentials := sha256.Sum256([]byte(username + "\x00" + password))
+	if login, ok := o.davLogins.get(credentials); ok && login.userId == user.Id {
+		if login.hash == user.Passhash {
+			return user.Id, nil
+		}
+		apiKeys, err := database.GetApiKeys(o.db, user.Id)
+		if err != nil {
+			return 0, err
+		}
+		for _, apiKey := range apiKeys {
+			if apiKey.Active && apiKey.Hash == login.hash {
+				return user.Id, nil
+			}
+		}
+	}
+
+	hash := user.Passhash
+	if err := database.ValidateHash(password, user.Passhash); err != nil {
+		apiKeys, err := database.GetApiKeys(o.db, user.Id)
+		if err != nil {
+			return 0, err
+		}
+		hash = ""
+		for _, apiKey := range apiKeys {
+			if apiKey.Active && database.ValidateHash(password, apiKey.Hash) == nil {
+				hash = apiKey.Hash
+				break
+			}
+		}
+		if len(hash) == 0 {
+			return 0, ErrNotAuthenticated
+		}
+	}
+
+	o.davLogins.add(credentials, user.Id, hash)
+	return user.Id, nil
+}
+
+// Get the lock system of a vault. Each vault has its own, since WebDAV paths
+// are relative to the vault.
+func (o *ObsyncServer) davLockSystem(ownerId uint64) webdav.LockSystem {
+	o.davLocksMu.Lock()
+	defer o.davLocksMu.Unlock()
+	locks, ok := o.davLocks[ownerId]
+	if !ok {
+		locks = webdav.NewMemLS()
+		o.davLocks[ownerId] = locks
+	}
+	return locks
+}
+
+// File or folder in a vault that a WebDAV request changes.
+type davChange struct {
+	filename string
+	// whether the request puts a file or folder at the path
+	adds bool
+	dir  bool
+}
+
+// Get the files and folders in the vault that a WebDAV request changes.
+func davChanges(req *http.Request, prefix string) []davChange {
+	var changes []davChange
+	add := func(urlPath string, adds, dir bool) {
+		filename, err := davFilename(strings.TrimPrefix(urlPath, prefix))
+		if err == nil && len(filename) > 0 {
+			changes = append(changes, davChange{filename: filename, adds: adds, dir: dir})
+		}
+	}
+	switch req.Method {
+	case http.MethodPut:
+		add(req.URL.Path, true, false)
+	case "MKCOL":
+		add(req.URL.Path, true, true)
+	case http.MethodDelete, "PROPPATCH", "MOVE":
+		add(req.URL.Path, false, false)
+	}
+	switch req.Method {
+	case "COPY", "MOVE":
+		if destination, err := url.Parse(req.Header.Get("Destination")); err == nil {
+			add(destination.Path, true, false)
+		}
+	}
+	return changes
+}
+
+// Get the path of a file in a vault from its WebDAV name. The vault's root
+// is an empty path.
+func davFilename(name string) (string, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if len(name) == 0 {
+		return "", nil
+	}
+	filename, err := cleanFilename(name)
+	if err != nil {
+		return "", os.ErrNotExist
+	}
+	return filename, nil
+}
+
+// Basic auth logins that succeeded recently, by the hash of their credentials
+type loginCache struct {
+	mu     sync.Mutex
+	logins map[[sha256.Size]byte]cachedLogin
+}
+
+type cachedLogin struct {
+	userId uint64
+	// hash of the password or API key the credentials matched
+	hash    string
+	expires time.Time
+}
+
+func (c *loginCache) get(credentials [sha256.Size]byte) (cachedLogin, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	login, ok := c.logins[credentials]
+	if !ok || time.Now().After(login.expires) {
+		return cachedLogin{}, false
+	}
+	return login, true
+}
+
+func (c *loginCache) add(credentials [sha256.Size]byte, userId uint64, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.logins == nil {
+		c.logins = map[[sha256.Size]byte]cachedLogin{}
+	}
+	for key, login := range c.logins {
+		if now.After(login.expires) {
+			delete(c.logins, key)
+		}
+	}
+	c.logins[credentials] = cachedLogin{userId: userId, hash: hash, expires: now.Add(davLoginTTL)}
+}
+
+// Forget a user's logins, so their credentials are checked again on their
+// next request.
+func (c *loginCache) forget(userId uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, login := range c.logins {
+		if login.userId == userId {
+			delete(c.logins, key)
+		}
+	}
+}
+
+// WebDAV file system of a vault for a single request. Changes go through
+// the same bookkeeping as the REST API, so they're synced, indexed and
+// recorded in the file history the same way.
+type vaultFileSystem struct {
+	o     *ObsyncServer
+	ctx   echo.Context
+	vault *vaultAccess
+	// the vault's ignore rules, loaded when they're first needed
+	ignore *ignore.Matcher
+}
+
+var _ webdav.FileSystem = (*vaultFileSystem)(nil)
+
+func (fs *vaultFileSystem) Mkdir(_ context.Context, name string, _ os.FileMode) error {
+	folder, err := davFilename(name)
+	if err != nil {
+		return err
+	}
+	if len(folder) == 0 {
+		return os.ErrExist
+	}
+	if err := fs.checkWrite(folder, true); err != nil {
+		return err
+	}
+	if _, _, err := fs.stat(folder); err == nil {
+		return os.ErrExist
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := fs.checkParent(folder); err != nil {
+		return err
+	}
+
+	if _, err := database.CreateFolder(fs.o.db, fs.vault.OwnerId, folder); err != nil {
+		if errors.Is(err, database.ErrFolderExists) {
+			return os.ErrExist
+		}
+		return err
+	}
+	return nil
+}
+
+func (fs *vaultFileSystem) OpenFile(_ context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	filename, err := davFilename(name)
+	if err != nil {
+		return nil, err
+	}
+	info, syncFile, err := fs.stat(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return &davFolder{fs: fs, folder: filename, info: info}, nil
+		}
+		filePath, err := userFilePath(fs.vault.OwnerId, filename)
+		if err != nil {
+			return nil, err
+		}
+		file, err := filestore.OpenReadSeeker(fs.o.fstore, filePath, syncFile.Size)
+		if err != nil {
+			return nil, err
+		}
+		return &davFile{ReadSeekCloser: file, info: info}, nil
+	}
+
+	if info != nil && info.IsDir() {
+		return nil, os.ErrPermission
+	}
+	if err := fs.checkWrite(filename, false); err != nil {
+		return nil, err
+	}
+	if syncFile == nil {
+		if flag&os.O_CREATE == 0 {
+			return nil, os.ErrNotExist
+		}
+		if err := fs.checkParent(filename); err != nil {
+			return nil, err
+		}
+	} else if flag&os.O_EXCL != 0 {
+		return nil, os.ErrExist
+	}
+
+	file := &davWriteFile{fs: fs, filename: filename, existing: syncFile}
+	if syncFile != nil && flag&os.O_TRUNC == 0 {
+		filePath, err := userFilePath(fs.vault.OwnerId, filename)
+		if err != nil {
+			return nil, err
+		}
+		file.data, err = fs.o.fstore.LoadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if flag&os.O_APPEND != 0 {
+			file.offset = int64(len(file.data))
+		}
+	}
+	return file, nil
+}
+
+// Remove a file, or a folder and everything in it.
+func (fs *vaultFileSystem) RemoveAll(_ context.Context, name string) error {
+	filename, err := davFilename(name)
+	if err != nil {
+		return err
+	}
+	if len(filename) == 0 {
+		return os.ErrPermission
+	}
+	info, syncFile, err := fs.stat(filename)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if !fs.vault.canWrite(filename) {
+			return os.ErrPermission
+		}
+		return fs.o.deleteFile(fs.ctx, fs.vault, syncFile)
+	}
+
+	// ignored files are removed too, since they're still in the folder
+	syncFiles, _, err := database.ListSyncFiles(fs.o.db, fs.vault.OwnerId, database.SyncFileQuery{Prefix: filename + "/"})
+	if err != nil {
+		return err
+	}
+	if !fs.vault.canWrite(filename) {
+		return os.ErrPermission
+	}
+	for _, syncFile := range syncFiles {
+		if !fs.vault.canWrite(syncFile.Filepath) {
+			return os.ErrPermission
+		}
+	}
+	for _, syncFile := range syncFiles {
+		if err := fs.o.deleteFile(fs.ctx, fs.vault, syncFile); err != nil {
+			return err
+		}
+	}
+	return fs.moveFolders(filename, "")
+}
+
+func (fs *vaultFileSystem) Rename(_ context.Context, oldName, newName string) error {
+	oldFilename, err := davFilename(oldName)
+	if err != nil {
+		return err
+	}
+	newFilename, err := davFilename(newName)
+	if err != nil {
+		return err
+	}
+	if len(oldFilename) == 0 || len(newFilename) == 0 || inFolder(newFilename, oldFilename) {
+		return os.ErrPermission
+	}
+	info, syncFile, err := fs.stat(oldFilename)
+	if err != nil {
+		return err
+	}
+	if err := fs.checkWrite(newFilename, info.IsDir()); err != nil {
+		return err
+	}
+	if _, _, err := fs.stat(newFilename); err == nil {
+		return os.ErrExist
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := fs.checkParent(newFilename); err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if !fs.vault.canWrite(oldFilename) {
+			return os.ErrPermission
+		}
+		return fs.o.moveFile(fs.ctx, fs.vault, syncFile, newFilename)
+	}
+
+	syncFiles, _, err := database.ListSyncFiles(fs.o.db, fs.vault.OwnerId, database.SyncFileQuery{Prefix: oldFilename + "/"})
+	if err != nil {
+		return err
+	}
+	if !fs.vault.canWrite(oldFilename) {
+		return os.ErrPermission
+	}
+	for _, syncFile := range syncFiles {
+		moved := newFilename + strings.TrimPrefix(syncFile.Filepath, oldFilename)
+		if !fs.vault.canWrite(syncFile.Filepath) || !fs.vault.canWrite(moved) {
+			return os.ErrPermission
+		}
+	}
+	for _, syncFile := range syncFiles {
+		moved := newFilename + strings.TrimPrefix(syncFile.Filepath, oldFilename)
+		if err := fs.o.moveFile(fs.ctx, fs.vault, syncFile, moved); err != nil {
+			return err
+		}
+	}
+	return fs.moveFolders(oldFilename, newFilename)
+}
+
+func (fs *vaultFileSystem) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	filename, err := davFilename(name)
+	if err != nil {
+		return nil, err
+	}
+	info, _, err := fs.stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Get the info of a file or folder in the vault, along with the file's sync
+// record. Ignored files and folders don't exist.
+func (fs *vaultFileSystem) stat(filename string) (*davFileInfo, *database.SyncFile, error) {
+	matcher, err := fs.matcher()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(filename) > 0 {
+		syncFile, err := database.GetUserSyncFileByFilepath(fs.o.db, fs.vault.OwnerId, filename)
+		if err == nil {
+			if matcher.Match(filename) {
+				return nil, nil, os.ErrNotExist
+			}
+			return fileInfo(syncFile), syncFile, nil
+		} else if !errors.Is(err, database.ErrNoResults) {
+			return nil, nil, err
+		}
+		if matcher.MatchDir(filename) {
+			return nil, nil, os.ErrNotExist
+		}
+	}
+
+	// folders exist while they contain something or were created, and were
+	// last changed when the latest file in them was
+	query := database.SyncFileQuery{Sort: database.SortByUpdatedAt, Descending: true, Limit: 1}
+	if len(filename) > 0 {
+		query.Prefix = filename + "/"
+	}
+	latest, _, err := database.ListSyncFiles(fs.o.db, fs.vault.OwnerId, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	info := &davFileInfo{name: path.Base("/" + filename), dir: true}
+	if len(latest) > 0 {
+		info.modTime = latest[0].UpdatedAt
+		return info, nil, nil
+	}
+	if len(filename) == 0 {
+		return info, nil, nil
+	}
+	folder, err := database.GetFolder(fs.o.db, fs.vault.OwnerId, filename)
+	if err != nil {
+		if errors.Is(err, database.ErrNoResults) {
+			return nil, nil, os.ErrNotExist
+		}
+		return nil, nil, err
+	}
+	info.modTime = folder.CreatedAt
+	return info, nil, nil
+}
+
+// Check that the user can change a file or folder, and that the vault
+// doesn't ignore it.
+func (fs *vaultFileSystem) checkWrite(filename string, dir bool) error {
+	if !fs.vault.canWrite(filename) {
+		return os.ErrPermission
+	}
+	matcher, err := fs.matcher()
+	if err != nil {
+		return err
+	}
+	if (dir && matcher.MatchDir(filename)) || (!dir && matcher.Match(filename)) {
+		return os.ErrPermission
+	}
+	return nil
+}
+
+// Check that the folder a file or folder would be put in exists, like WebDAV
+// requires.
+func (fs *vaultFileSystem) checkParent(filename string) error {
+	parent := path.Dir(filename)
+	if parent == "." {
+		return nil
+	}
+	info, _, err := fs.stat(parent)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return os.ErrNotExist
+	}
+	return nil
+}
+
+// Move the created folders in a folder, along with the folder itself, to
+// another folder, or delete them if newFolder is empty.
+func (fs *vaultFileSystem) moveFolders(oldFolder, newFolder string) error {
+	folders, err := database.GetFolders(fs.o.db, fs.vault.OwnerId, oldFolder)
+	if err != nil {
+		return err
+	}
+	if folder, err := database.GetFolder(fs.o.db, fs.vault.OwnerId, oldFolder); err == nil {
+		folders = append(folders, folder)
+	} else if !errors.Is(err, database.ErrNoResults) {
+		return err
+	}
+	for _, folder := range folders {
+		if len(newFolder) > 0 {
+			moved := newFolder + strings.TrimPrefix(folder.Path, oldFolder)
+			_, err := database.CreateFolder(fs.o.db, fs.vault.OwnerId, moved)
+			if err != nil && !errors.Is(err, database.ErrFolderExists) {
+				return err
+			}
+		}
+		err := database.DeleteFolder(fs.o.db, fs.vault.OwnerId, folder.Path)
+		if err != nil && !errors.Is(err, database.ErrNoResults) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List the files and folders directly in a folder, sorted by name.
+func (fs *vaultFileSystem) readFolder(folder string) ([]os.FileInfo, error) {
+	query := database.SyncFileQuery{}
+	if len(folder) > 0 {
+		query.Prefix = folder + "/"
+	}
+	syncFiles, _, err := database.ListSyncFiles(fs.o.db, fs.vault.OwnerId, query)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := database.GetFolders(fs.o.db, fs.vault.OwnerId, folder)
+	if err != nil {
+		return nil, err
+	}
+	matcher, err := fs.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	children := map[string]*davFileInfo{}
+	addFolder := func(name string, modTime time.Time) {
+		child, ok := children[name]
+		if !ok {
+			child = &davFileInfo{name: name, dir: true}
+			children[name] = child
+		}
+		if modTime.After(child.modTime) {
+			child.modTime = modTime
+		}
+	}
+	for _, created := range folders {
+		parts := relativeParts(folder, created.Path)
+		if !matcher.MatchDir(path.Join(folder, parts[0])) {
+			addFolder(parts[0], created.CreatedAt)
+		}
+	}
+	for _, syncFile := range filterIgnored(syncFiles, matcher) {
+		parts := relativeParts(folder, syncFile.Filepath)
+		if len(parts) == 1 {
+			children[parts[0]] = fileInfo(syncFile)
+		} else if !matcher.MatchDir(path.Join(folder, parts[0])) {
+			addFolder(parts[0], syncFile.UpdatedAt)
+		}
+	}
+
+	infos := make([]os.FileInfo, 0, len(children))
+	for _, child := range children {
+		infos = append(infos, child)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	return infos, nil
+}
+
+func (fs *vaultFileSystem) matcher() (*ignore.Matcher, error) {
+	if fs.ignore == nil {
+		matcher, err := fs.o.ignoreMatcher(fs.vault.OwnerId)
+		if err != nil {
+			return nil, err
+		}
+		fs.ignore = matcher
+	}
+	return fs.ignore, nil
+}
+
+// Info of a file or folder in a vault. Files have their etag, which WebDAV
+// clients get instead of one made up from the size and modification time.
+type davFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+	etag    string
+}
+
+var _ webdav.ETager = (*davFileInfo)(nil)
+var _ webdav.ContentTyper = (*davFileInfo)(nil)
+
+func fileInfo(syncFile *database.SyncFile) *davFileInfo {
+	return &davFileInfo{
+		name:    path.Base(syncFile.Filepath),
+		size:    syncFile.Size,
+		modTime: syncFile.UpdatedAt,
+		etag:    syncFile.Etag,
+	}
+}
+
+func (i *davFileInfo) Name() string       { return i.name }
+func (i *davFileInfo) Size() int64        { return i.size }
+func (i *davFileInfo) ModTime() time.Time { return i.modTime }
+func (i *davFileInfo) IsDir() bool        { return i.dir }
+func (i *davFileInfo) Sys() any           { return nil }
+
+func (i *davFileInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (i *davFileInfo) ETag(context.Context) (string, error) {
+	if i.dir {
+		return "", webdav.ErrNotImplemented
+	}
+	return fmt.Sprintf("%q", i.etag), nil
+}
+
+func (i *davFileInfo) ContentType(context.Context) (string, error) {
+	if i.dir {
+		return "", webdav.ErrNotImplemented
+	}
+	return contentTypeForFile(i.name), nil
+}
+
+// File in a vault opened for reading.
+type davFile struct {
+	io.ReadSeekCloser
+	info *davFileInfo
+}
+
+func (f *davFile) Readdir(int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+func (f *davFile) Stat() (os.FileInfo, error)         { return f.info, nil }
+func (f *davFile) Write([]byte) (int, error)          { return 0, os.ErrPermission }
+
+// Folder in a vault, which can only be listed.
+type davFolder struct {
+	fs     *vaultFileSystem
+	folder string
+	info   *davFileInfo
+	// children that haven't been listed yet, loaded by the first Readdir
+	children []os.FileInfo
+	listed   bool
+}
+
+func (f *davFolder) Close() error                   { return nil }
+func (f *davFolder) Read([]byte) (int, error)       { return 0, os.ErrInvalid }
+func (f *davFolder) Seek(int64, int) (int64, error) { return 0, os.ErrInvalid }
+func (f *davFolder) Write([]byte) (int, error)      { return 0, os.ErrInvalid }
+func (f *davFolder) Stat() (os.FileInfo, error)     { return f.info, nil }
+
+func (f *davFolder) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.listed {
+		children, err := f.fs.readFolder(f.folder)
+		if err != nil {
+			return nil, err
+		}
+		f.children, f.listed = children, true
+	}
+	if count <= 0 {
+		children := f.children
+		f.children = nil
+		return children, nil
+	}
+	if len(f.children) == 0 {
+		return nil, io.EOF
+	}
+	count = min(count, len(f.children))
+	children := f.children[:count]
+	f.children = f.children[count:]
+	return children, nil
+}
+
+// File in a vault opened for writing. Writes are kept in memory, and saved
+// when the file is closed.
+type davWriteFile struct {
+	fs       *vaultFileSystem
+	filename string
+	// sync record of the file, or nil if it's new
+	existing *database.SyncFile
+	data     []byte
+	offset   int64
+}
+
+func (f *davWriteFile) Read(p []byte) (int, error) {
+	if f.offset >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[f.offset:])
+	f.offset += int64(n)
+	return n, nil
+}
+
+func (f *davWriteFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.data))
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *davWriteFile) Write(p []byte) (int, error) {
+	end := f.offset + int64(len(p))
+	if end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	copy(f.data[f.offset:], p)
+	f.offset = end
+	return len(p), nil
+}
+
+func (f *davWriteFile) Readdir(int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+
+func (f *davWriteFile) Stat() (os.FileInfo, error) {
+	return &davFileInfo{
+		name:    path.Base(f.filename),
+		size:    int64(len(f.data)),
+		modTime: time.Now().UTC(),
+		etag:    filestore.GetEtag(f.data),
+	}, nil
+}
+
+func (f *davWriteFile) Close() error {
+	if f.existing != nil && f.existing.Etag == filestore.GetEtag(f.data) {
+		return nil
+	}
+	_, err := f.fs.o.saveFile(f.fs.ctx, f.fs.vault, f.filename, f.existing, f.data)
+	return err
+}
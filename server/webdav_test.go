@@ -0,0 +1,277 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/raian621/obsync-server/database"
+	"github.com/raian621/obsync-server/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebDAV(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, _ := createTestSession(t, db, "test-webdav")
+	viewer, _ := createTestSession(t, db, "test-webdav-viewer")
+	assert.NoError(t, database.SetVaultMember(db, user.Id, viewer.Id, database.VaultViewer, nil))
+	_, err := database.CreateApiKey(db, user.Id, "test-webdav-key", "webdav api key")
+	assert.NoError(t, err)
+	_, err = database.SetIgnoreRules(db, user.Id, "*.tmp\n")
+	assert.NoError(t, err)
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv.RegisterWebDAV(e, "/dav/")
+
+	request := func(method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetBasicAuth(user.Username, "not a password")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	syncFile := func(filename string) *database.SyncFile {
+		t.Helper()
+		syncFile, err := database.GetUserSyncFileByFilepath(db, user.Id, filename)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		return syncFile
+	}
+	missing := func(filename string) {
+		t.Helper()
+		_, err := database.GetUserSyncFileByFilepath(db, user.Id, filename)
+		assert.ErrorIs(t, err, database.ErrNoResults)
+	}
+
+	// clients have to log in with a password or API key
+	req := httptest.NewRequest("PROPFIND", "/dav/", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Basic realm="Obsync"`, rec.Header().Get(echo.HeaderWWWAuthenticate))
+	for _, password := range []string{"wrong password", "webdav api key"} {
+		req = httptest.NewRequest("PROPFIND", "/dav/", nil)
+		req.SetBasicAuth(user.Username, password)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if password == "wrong password" {
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		} else {
+			assert.Equal(t, http.StatusMultiStatus, rec.Code)
+		}
+	}
+
+	// files written over WebDAV are synced like uploads
+	rec = request(http.MethodPut, "/dav/notes/a.md", "# A", nil)
+	assert.Equal(t, http.StatusConflict, rec.Code, "parent folder doesn't exist yet")
+	assert.Equal(t, http.StatusCreated, request("MKCOL", "/dav/notes", "", nil).Code)
+	_, err = database.GetFolder(db, user.Id, "notes")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, request("MKCOL", "/dav/notes", "", nil).Code)
+
+	rec = request(http.MethodPut, "/dav/notes/a.md", "# A\n\n[[b]]", nil)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	if created := syncFile("notes/a.md"); created != nil {
+		assert.Equal(t, fmt.Sprintf("%q", created.Etag), rec.Header().Get("ETag"))
+		assert.Equal(t, int64(len("# A\n\n[[b]]")), created.Size)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, request(http.MethodPut, "/dav/notes/a.tmp", "", nil).Code)
+	missing("notes/a.tmp")
+
+	rec = request(http.MethodGet, "/dav/notes/a.md", "", nil)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, "# A\n\n[[b]]", rec.Body.String())
+		assert.Equal(t, "text/markdown; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	}
+
+	rec = request("PROPFIND", "/dav/", "", map[string]string{"Depth": "1"})
+	if assert.Equal(t, http.StatusMultiStatus, rec.Code) {
+		assert.Contains(t, rec.Body.String(), "<D:href>/dav/notes/</D:href>")
+		assert.NotContains(t, rec.Body.String(), "a.md")
+	}
+	rec = request("PROPFIND", "/dav/notes/", "", map[string]string{"Depth": "1"})
+	if assert.Equal(t, http.StatusMultiStatus, rec.Code) {
+		assert.Contains(t, rec.Body.String(), "<D:href>/dav/notes/a.md</D:href>")
+	}
+
+	// moves and deletes update the sync records too
+	rec = request("MOVE", "/dav/notes/a.md", "", map[string]string{"Destination": "http://example.com/dav/notes/b.md"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	missing("notes/a.md")
+	syncFile("notes/b.md")
+
+	rec = request("COPY", "/dav/notes/b.md", "", map[string]string{"Destination": "/dav/notes/c.md"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	if copied := syncFile("notes/c.md"); copied != nil {
+		assert.Equal(t, syncFile("notes/b.md").Etag, copied.Etag)
+	}
+
+	rec = request("MOVE", "/dav/notes", "", map[string]string{"Destination": "/dav/archive"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	missing("notes/b.md")
+	syncFile("archive/b.md")
+	syncFile("archive/c.md")
+	_, err = database.GetFolder(db, user.Id, "archive")
+	assert.NoError(t, err)
+	_, err = database.GetFolder(db, user.Id, "notes")
+	assert.ErrorIs(t, err, database.ErrNoResults)
+	data, err := srv.fstore.LoadFile(testFilePath(t, user.Id, "archive/b.md"))
+	assert.NoError(t, err)
+	assert.Equal(t, "# A\n\n[[b]]", string(data))
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/dav/archive", "", nil).Code)
+	missing("archive/b.md")
+	missing("archive/c.md")
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/dav/archive/b.md", "", nil).Code)
+
+	// members can only change what their role allows
+	assert.Equal(t, http.StatusCreated, request(http.MethodPut, "/dav/shared.md", "shared", nil).Code)
+	viewerRequest := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader("changed"))
+		req.SetBasicAuth(viewer.Username, "not a password")
+		req.Header.Set(VaultHeader, user.Username)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	rec = viewerRequest(http.MethodGet, "/dav/shared.md")
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		body, _ := io.ReadAll(rec.Body)
+		assert.Equal(t, "shared", string(body))
+	}
+	assert.Equal(t, http.StatusForbidden, viewerRequest(http.MethodPut, "/dav/shared.md").Code)
+	assert.Equal(t, http.StatusForbidden, viewerRequest(http.MethodDelete, "/dav/shared.md").Code)
+	syncFile("shared.md")
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+	assert.NoError(t, database.DeleteUser(db, viewer.Id))
+}
+
+func TestWebDAVCredentialChanges(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, cookie := createTestSession(t, db, "test-webdav-creds")
+	_, err := database.CreateApiKey(db, user.Id, "test-webdav-creds-key", "webdav api key")
+	assert.NoError(t, err)
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv.RegisterWebDAV(e, "/dav/")
+
+	propfind := func(username, password string) int {
+		t.Helper()
+		req := httptest.NewRequest("PROPFIND", "/dav/", nil)
+		req.SetBasicAuth(username, password)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	update := func(handler func(echo.Context) error, body string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/user", strings.NewReader(body))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		if assert.NoError(t, handler(e.NewContext(req, rec))) {
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		}
+	}
+
+	// logins are remembered, but stop working once the password changes
+	assert.Equal(t, http.StatusMultiStatus, propfind(user.Username, "not a password"))
+	assert.Equal(t, http.StatusMultiStatus, propfind(user.Username, "webdav api key"))
+	update(srv.PutUserPassword, "a new password")
+	assert.Equal(t, http.StatusUnauthorized, propfind(user.Username, "not a password"))
+	assert.Equal(t, http.StatusMultiStatus, propfind(user.Username, "a new password"))
+	assert.Equal(t, http.StatusMultiStatus, propfind(user.Username, "webdav api key"))
+
+	// or the username does
+	update(srv.PutUserUsername, "test-webdav-creds-2")
+	assert.Equal(t, http.StatusUnauthorized, propfind(user.Username, "a new password"))
+	assert.Equal(t, http.StatusMultiStatus, propfind("test-webdav-creds-2", "a new password"))
+
+	// API keys revoked outside the server, like with the CLI, stop working too
+	assert.NoError(t, database.SetApiKeyActivation(db, user.Id, "test-webdav-creds-key", false))
+	assert.Equal(t, http.StatusUnauthorized, propfind("test-webdav-creds-2", "webdav api key"))
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestWebDAVLoginAttempts(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, _ := createTestSession(t, db, "test-webdav-attempts")
+	srv, err := NewServer(db, t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv.RegisterWebDAV(e, "/dav/")
+
+	propfind := func(username, password, remoteAddr string) int {
+		t.Helper()
+		req := httptest.NewRequest("PROPFIND", "/dav/", nil)
+		req.SetBasicAuth(username, password)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < MaxDavLoginAttempts; i++ {
+		assert.Equal(t, http.StatusUnauthorized, propfind(user.Username, "wrong password", "192.0.2.1:1234"))
+	}
+	// the right password doesn't help once the client made too many attempts
+	assert.Equal(t, http.StatusTooManyRequests, propfind(user.Username, "not a password", "192.0.2.1:1234"))
+	// other clients can still log in
+	assert.Equal(t, http.StatusMultiStatus, propfind(user.Username, "not a password", "192.0.2.2:1234"))
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
+
+func TestWebDAVUnknownSize(t *testing.T) {
+	e := echo.New()
+	db := createTestDB(t)
+	user, _ := createTestSession(t, db, "test-webdav-unknown-size")
+	fstore, err := filestore.NewFsFileStore(t.TempDir())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cstore, err := filestore.NewCompressedFileStore(fstore, filestore.CodecGzip)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	srv := NewServerWithFileStore(db, cstore)
+	srv.RegisterWebDAV(e, "/dav/")
+
+	content := strings.Repeat("# Notes\n", 100)
+	req := httptest.NewRequest(http.MethodPut, "/dav/notes.md", strings.NewReader(content))
+	req.SetBasicAuth(user.Username, "not a password")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	// files synced before sizes were recorded have a size of 0
+	_, err = db.Exec("UPDATE file_syncs SET size=0 WHERE user_id=?", user.Id)
+	assert.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/dav/notes.md", nil)
+	req.SetBasicAuth(user.Username, "not a password")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, content, rec.Body.String())
+	}
+
+	assert.NoError(t, database.DeleteUser(db, user.Id))
+}
@@ -0,0 +1,110 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// Largest image, in pixels, that thumbnails are generated for. Decoding an
+// image takes about 4 bytes for each pixel.
+const MaxPixels = 50_000_000
+
+var (
+	ErrUnsupported  = errors.New("file is not a supported image")
+	ErrInvalidImage = errors.New("image could not be decoded")
+	ErrTooLarge     = errors.New("image is too large to generate a thumbnail for")
+)
+
+type format struct {
+	decode       func(io.Reader) (image.Image, error)
+	decodeConfig func(io.Reader) (image.Config, error)
+	// thumbnails of JPEGs are JPEGs, and thumbnails of other images are PNGs
+	// so transparency is kept
+	jpeg bool
+}
+
+var formats = map[string]format{
+	".jpg":  {decode: jpeg.Decode, decodeConfig: jpeg.DecodeConfig, jpeg: true},
+	".jpeg": {decode: jpeg.Decode, decodeConfig: jpeg.DecodeConfig, jpeg: true},
+	".png":  {decode: png.Decode, decodeConfig: png.DecodeConfig},
+	".gif":  {decode: gif.Decode, decodeConfig: gif.DecodeConfig},
+	".webp": {decode: webp.Decode, decodeConfig: webp.DecodeConfig},
+}
+
+// Check whether thumbnails can be generated for a file, based on its
+// extension.
+func Supported(filename string) bool {
+	_, ok := formats[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// Get the content type of the thumbnails of a file.
+func ContentType(filename string) string {
+	if formats[strings.ToLower(filepath.Ext(filename))].jpeg {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// Generate a thumbnail of an image that fits in a size by size square. The
+// image keeps its aspect ratio, and images that already fit aren't scaled up.
+func Generate(filename string, data []byte, size int) ([]byte, error) {
+	format, ok := formats[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid thumbnail size %d", size)
+	}
+
+	// check the dimensions before decoding the whole image, so huge images
+	// are rejected before memory is allocated for them
+	config, err := format.decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	src, err := format.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidImage, err)
+	}
+
+	width, height := fit(src.Bounds().Dx(), src.Bounds().Dy(), size)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if format.jpeg {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Get the dimensions of an image scaled down to fit in a size by size square.
+func fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
@@ -0,0 +1,91 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeImage(t *testing.T, encode func(*bytes.Buffer, image.Image) error, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if !assert.NoError(t, encode(&buf, img)) {
+		t.FailNow()
+	}
+	return buf.Bytes()
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	encodePng := func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }
+	encodeJpeg := func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }
+	encodeGif := func(buf *bytes.Buffer, img image.Image) error { return gif.Encode(buf, img, nil) }
+
+	testCases := []struct {
+		name       string
+		filename   string
+		data       []byte
+		size       int
+		wantFormat string
+		wantWidth  int
+		wantHeight int
+	}{
+		{"wide png", "a.png", encodeImage(t, encodePng, 400, 200), 100, "png", 100, 50},
+		{"tall jpeg", "a.JPG", encodeImage(t, encodeJpeg, 150, 300), 100, "jpeg", 50, 100},
+		{"gif", "a.gif", encodeImage(t, encodeGif, 64, 64), 32, "png", 32, 32},
+		{"small images aren't scaled up", "a.jpeg", encodeImage(t, encodeJpeg, 40, 20), 100, "jpeg", 40, 20},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := Generate(tc.filename, tc.data, tc.size)
+			if !assert.NoError(t, err) {
+				return
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.wantFormat, format)
+				assert.Equal(t, tc.wantWidth, config.Width)
+				assert.Equal(t, tc.wantHeight, config.Height)
+			}
+			assert.Equal(t, "image/"+tc.wantFormat, ContentType(tc.filename))
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Generate("note.md", []byte("# Note"), 100)
+	assert.ErrorIs(t, err, ErrUnsupported)
+	_, err = Generate("broken.png", []byte("not a png"), 100)
+	assert.ErrorIs(t, err, ErrInvalidImage)
+
+	// the header claims a huge image, which is rejected before it's decoded
+	huge := encodeImage(t, func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }, 1, 1)
+	copy(huge[16:24], []byte{0, 1, 0, 0, 0, 1, 0, 0})
+	binary.BigEndian.PutUint32(huge[29:33], crc32.ChecksumIEEE(huge[12:29]))
+	_, err = Generate("huge.png", huge, 100)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	assert.True(t, Supported("photos/Cat.WEBP"))
+	assert.False(t, Supported("photos/cat.svg"))
+}